@@ -0,0 +1,11 @@
+-- Tracks the last execution of each background scheduler job so that
+-- job status survives server restarts.
+CREATE TABLE IF NOT EXISTS scheduler_job_runs (
+    job_name TEXT PRIMARY KEY NOT NULL,
+    last_started_at TIMESTAMP,
+    last_finished_at TIMESTAMP,
+    last_status TEXT, -- 'running', 'success', 'failed'
+    last_error TEXT,
+    run_count INTEGER DEFAULT 0,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_background_jobs_user;
+DROP INDEX IF EXISTS idx_background_jobs_status;
+DROP TABLE IF EXISTS background_jobs;
@@ -0,0 +1,23 @@
+-- Persistent queue for long-running per-portfolio work (history rebuilds,
+-- re-pricing, metric refreshes) processed by the background worker pool.
+CREATE TABLE IF NOT EXISTS background_jobs (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL,
+    portfolio_id INTEGER NOT NULL,
+    job_type TEXT NOT NULL, -- 'rebuild_history', 'reprice', 'refresh_metrics'
+    status TEXT NOT NULL DEFAULT 'queued', -- 'queued', 'running', 'completed', 'failed', 'cancelled'
+    progress INTEGER NOT NULL DEFAULT 0, -- 0-100
+    message TEXT,
+    error TEXT,
+    cancel_requested BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    started_at TIMESTAMP,
+    finished_at TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+    FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY(portfolio_id) REFERENCES portfolios(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_background_jobs_status ON background_jobs(status, created_at);
+CREATE INDEX IF NOT EXISTS idx_background_jobs_user ON background_jobs(user_id, portfolio_id);
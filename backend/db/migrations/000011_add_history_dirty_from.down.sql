@@ -0,0 +1 @@
+ALTER TABLE portfolios DROP COLUMN history_dirty_from;
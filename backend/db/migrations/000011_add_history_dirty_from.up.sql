@@ -0,0 +1,3 @@
+-- Earliest date (YYYY-MM-DD) whose snapshots are stale because transactions on or
+-- after it were added or removed. NULL means the stored history is up to date.
+ALTER TABLE portfolios ADD COLUMN history_dirty_from TEXT;
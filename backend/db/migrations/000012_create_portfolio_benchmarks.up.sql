@@ -0,0 +1,16 @@
+-- User-selected benchmarks per portfolio. A benchmark is a weighted blend of one or
+-- more tickers (a single ticker is a blend with one component at weight 1).
+CREATE TABLE IF NOT EXISTS portfolio_benchmarks (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL,
+    portfolio_id INTEGER NOT NULL,
+    name TEXT NOT NULL,
+    components TEXT NOT NULL, -- JSON array of {"symbol", "ticker", "weight"}
+    rebalance TEXT NOT NULL DEFAULT 'none', -- 'none', 'monthly', 'quarterly', 'yearly'
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+    FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY(portfolio_id) REFERENCES portfolios(id) ON DELETE CASCADE,
+    UNIQUE(portfolio_id, name)
+);
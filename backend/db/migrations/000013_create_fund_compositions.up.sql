@@ -0,0 +1,17 @@
+-- Look-through weights of funds (ETFs) per allocation dimension. Rows with user_id = 0
+-- come from the market data provider and are shared; user rows override them for that
+-- user and dimension.
+CREATE TABLE IF NOT EXISTS fund_compositions (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    fund_isin TEXT NOT NULL,
+    user_id INTEGER NOT NULL DEFAULT 0,
+    dimension TEXT NOT NULL, -- 'sector', 'country', 'currency', 'asset_type'
+    bucket TEXT NOT NULL,
+    weight REAL NOT NULL, -- Fraction of the fund (0-1)
+    source TEXT NOT NULL, -- 'provider' or 'user'
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+    UNIQUE(fund_isin, user_id, dimension, bucket)
+);
+
+CREATE INDEX IF NOT EXISTS idx_fund_compositions_isin ON fund_compositions(fund_isin, user_id);
@@ -0,0 +1,15 @@
+-- Target weights per portfolio. All targets of a portfolio use the same dimension:
+-- individual ISINs or one allocation category (sector, country, currency, asset_type).
+CREATE TABLE IF NOT EXISTS portfolio_targets (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL,
+    portfolio_id INTEGER NOT NULL,
+    dimension TEXT NOT NULL, -- 'isin', 'sector', 'country', 'currency', 'asset_type'
+    target_key TEXT NOT NULL,
+    weight REAL NOT NULL, -- Fraction of the total portfolio value, cash included (0-1)
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+    FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY(portfolio_id) REFERENCES portfolios(id) ON DELETE CASCADE,
+    UNIQUE(portfolio_id, target_key)
+);
@@ -0,0 +1,21 @@
+-- Withholding tax reclaims filed with a source country, one row per claim.
+CREATE TABLE IF NOT EXISTS dividend_reclaims (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL,
+    portfolio_id INTEGER NOT NULL,
+    year INTEGER NOT NULL, -- Year the dividends were paid
+    country_code TEXT NOT NULL, -- ISO 3166-1 alpha-2 of the source country
+    status TEXT NOT NULL DEFAULT 'pending', -- 'pending', 'filed', 'refunded', 'rejected'
+    claimed_eur REAL NOT NULL DEFAULT 0,
+    refunded_eur REAL NOT NULL DEFAULT 0,
+    filed_date TEXT, -- YYYY-MM-DD
+    refunded_date TEXT, -- YYYY-MM-DD
+    notes TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+    FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY(portfolio_id) REFERENCES portfolios(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_dividend_reclaims_portfolio ON dividend_reclaims(portfolio_id, year);
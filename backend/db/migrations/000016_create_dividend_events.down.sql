@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS dividend_event_fetches;
+DROP TABLE IF EXISTS dividend_events;
@@ -0,0 +1,19 @@
+-- Individual dividend events per ticker as reported by the price provider.
+-- Amounts are per share in the quote currency of the ticker.
+CREATE TABLE IF NOT EXISTS dividend_events (
+    ticker_symbol TEXT NOT NULL,
+    ex_date TEXT NOT NULL, -- YYYY-MM-DD
+    pay_date TEXT, -- YYYY-MM-DD, NULL when the provider does not report it
+    amount REAL NOT NULL,
+    currency TEXT NOT NULL DEFAULT '',
+    source TEXT NOT NULL DEFAULT 'history', -- 'history' (paid) or 'announced' (upcoming)
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+    PRIMARY KEY (ticker_symbol, ex_date)
+);
+
+-- When the events of a ticker were last fetched, so tickers without dividends are not re-fetched on every request.
+CREATE TABLE IF NOT EXISTS dividend_event_fetches (
+    ticker_symbol TEXT PRIMARY KEY,
+    fetched_at TIMESTAMP NOT NULL
+);
@@ -0,0 +1 @@
+ALTER TABLE processed_transactions DROP COLUMN units;
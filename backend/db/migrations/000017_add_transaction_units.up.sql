@@ -0,0 +1,4 @@
+-- Fractional quantity of a transaction. The integer quantity column truncates the units of
+-- crypto assets, which are traded in fractions.
+ALTER TABLE processed_transactions ADD COLUMN units REAL;
+UPDATE processed_transactions SET units = quantity;
@@ -0,0 +1,17 @@
+-- User-defined column mappings for importing the CSV exports of unsupported brokers.
+CREATE TABLE IF NOT EXISTS import_templates (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL,
+    name TEXT NOT NULL,
+    delimiter TEXT NOT NULL DEFAULT ',',
+    decimal_separator TEXT NOT NULL DEFAULT '.',
+    date_format TEXT NOT NULL, -- e.g. 'DD/MM/YYYY' or 'YYYY-MM-DD HH:mm:ss'
+    default_currency TEXT NOT NULL DEFAULT 'EUR', -- Used when no currency column is mapped
+    columns TEXT NOT NULL, -- JSON object of field -> CSV header name
+    type_mapping TEXT NOT NULL, -- JSON object of CSV type value -> BUY, SELL, DIVIDEND, ...
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+    FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE,
+    UNIQUE(user_id, name)
+);
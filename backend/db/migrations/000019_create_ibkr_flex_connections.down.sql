@@ -0,0 +1 @@
+DROP TABLE IF EXISTS ibkr_flex_connections;
@@ -0,0 +1,17 @@
+-- Credentials of the IBKR Flex Web Service per portfolio. The Flex query is defined in
+-- the IBKR Client Portal; its period decides how far back each import reaches.
+CREATE TABLE IF NOT EXISTS ibkr_flex_connections (
+    portfolio_id INTEGER PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    token TEXT NOT NULL,
+    query_id TEXT NOT NULL,
+    enabled BOOLEAN NOT NULL DEFAULT 1, -- Included in the scheduled import
+    last_sync_at TIMESTAMP, -- Last import attempt
+    last_success_at TIMESTAMP, -- Last import that completed
+    last_error TEXT NOT NULL DEFAULT '', -- Error of the last attempt, empty when it succeeded
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+    FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY(portfolio_id) REFERENCES portfolios(id) ON DELETE CASCADE
+);
@@ -12,6 +12,8 @@ require (
 	golang.org/x/net v0.38.0
 )
 
+require github.com/pquerna/otp v1.5.0
+
 require (
 	cloud.google.com/go/compute/metadata v0.3.0 // indirect
 	github.com/aymerick/douceur v0.2.0 // indirect
@@ -19,7 +21,6 @@ require (
 	github.com/gorilla/css v1.0.0 // indirect
 	github.com/hashicorp/errwrap v1.1.0 // indirect
 	github.com/hashicorp/go-multierror v1.1.1 // indirect
-	github.com/pquerna/otp v1.5.0 // indirect
 	github.com/stretchr/testify v1.10.0 // indirect
 	go.uber.org/atomic v1.11.0 // indirect
 )
@@ -1,13 +1,16 @@
 package main
 
 import (
+	"context"
 	"crypto/tls"
 	"encoding/json"
 	stdlog "log"
 	"net/http"
 	"os"
+	"os/signal"
 	"strings"
 	"sync"
+	"syscall"
 	"time"
 
 	"github.com/go-chi/chi/v5"
@@ -16,9 +19,11 @@ import (
 	"github.com/username/taxfolio/backend/src/config"
 	"github.com/username/taxfolio/backend/src/database"
 	"github.com/username/taxfolio/backend/src/handlers"
+	"github.com/username/taxfolio/backend/src/jobs"
 	"github.com/username/taxfolio/backend/src/logger"
 	_ "github.com/username/taxfolio/backend/src/models"
 	"github.com/username/taxfolio/backend/src/processors"
+	"github.com/username/taxfolio/backend/src/scheduler"
 	"github.com/username/taxfolio/backend/src/security"
 	"github.com/username/taxfolio/backend/src/services"
 	"github.com/username/taxfolio/backend/src/utils"
@@ -139,6 +144,42 @@ func enableCORS(next http.Handler) http.Handler {
 	})
 }
 
+// registerScheduledJobs wires the daily maintenance tasks into the scheduler.
+// Prices are refreshed first so that snapshots and metrics use closing prices.
+func registerScheduledJobs(s *scheduler.Scheduler, maintenance services.MaintenanceService, flexService services.IBKRFlexService) {
+	jobs := []scheduler.Job{
+		{
+			Name:        "price_refresh",
+			Description: "Refresh closing prices for all mapped tickers",
+			RunAt:       config.Cfg.PriceRefreshRunAt,
+			Run:         maintenance.RefreshMarketPrices,
+		},
+		{
+			Name:        "daily_snapshots",
+			Description: "Write today's snapshot for every portfolio",
+			RunAt:       config.Cfg.SnapshotRefreshRunAt,
+			Run:         maintenance.RefreshAllSnapshots,
+		},
+		{
+			Name:        "user_metrics",
+			Description: "Recompute portfolio value and top holdings per user",
+			RunAt:       config.Cfg.MetricsRefreshRunAt,
+			Run:         maintenance.RefreshAllUserMetrics,
+		},
+		{
+			Name:        "ibkr_flex_sync",
+			Description: "Import new IBKR Flex statements for every connected portfolio",
+			RunAt:       config.Cfg.IBKRFlexSyncRunAt,
+			Run:         flexService.SyncAll,
+		},
+	}
+	for _, job := range jobs {
+		if err := s.Register(job); err != nil {
+			logger.L.Error("Failed to register scheduled job", "job", job.Name, "error", err)
+		}
+	}
+}
+
 func main() {
 	config.LoadConfig()
 	logger.InitLogger(config.Cfg.LogLevel)
@@ -158,6 +199,16 @@ func main() {
 	logger.L.Info("Initializing database...", "path", config.Cfg.DatabasePath)
 	database.InitDB(config.Cfg.DatabasePath)
 	database.RunMigrations(config.Cfg.DatabasePath)
+	if n, err := services.UpgradeIBKRTradeHashes(database.DB); err != nil {
+		logger.L.Error("Failed to upgrade stored IBKR trade hashes", "error", err)
+	} else if n > 0 {
+		logger.L.Info("Upgraded stored IBKR trade hashes", "count", n)
+	}
+	if n, err := services.UpgradeDeGiroTradeHashes(database.DB); err != nil {
+		logger.L.Error("Failed to upgrade stored DeGiro trade hashes", "error", err)
+	} else if n > 0 {
+		logger.L.Info("Upgraded stored DeGiro trade hashes", "count", n)
+	}
 
 	reportCache := cache.New(services.DefaultCacheExpiration, services.CacheCleanupInterval)
 
@@ -166,6 +217,7 @@ func main() {
 	authService := security.NewAuthService(config.Cfg.JWTSecret)
 	emailService := services.NewEmailService()
 	priceService := services.NewPriceService()
+	benchmarkService := services.NewBenchmarkService(priceService)
 
 	transactionProcessor := processors.NewTransactionProcessor()
 	dividendProcessor := processors.NewDividendProcessor()
@@ -173,6 +225,7 @@ func main() {
 	optionProcessor := processors.NewOptionProcessor()
 	cashMovementProcessor := processors.NewCashMovementProcessor()
 	feeProcessor := processors.NewFeeProcessor()
+	cryptoProcessor := processors.NewCryptoProcessor()
 
 	uploadService := services.NewUploadService(
 		transactionProcessor,
@@ -181,17 +234,56 @@ func main() {
 		optionProcessor,
 		cashMovementProcessor,
 		feeProcessor,
+		cryptoProcessor,
 		priceService,
+		benchmarkService,
 		reportCache,
 	)
 
 	userHandler := handlers.NewUserHandler(authService, emailService, uploadService, reportCache)
 	uploadHandler := handlers.NewUploadHandler(uploadService)
 	portfolioHandler := handlers.NewPortfolioHandler(uploadService, priceService)
-	dividendHandler := handlers.NewDividendHandler(uploadService)
-	txHandler := handlers.NewTransactionHandler(uploadService)
+	dividendCalendarService := services.NewDividendCalendarService(uploadService, priceService)
+	dividendHistoryService := services.NewDividendHistoryService(uploadService, priceService)
+	dividendHandler := handlers.NewDividendHandler(uploadService, dividendCalendarService, dividendHistoryService)
+	withholdingService := services.NewWithholdingService(uploadService)
+	withholdingHandler := handlers.NewWithholdingHandler(withholdingService)
 	feeHandler := handlers.NewFeeHandler(uploadService)
 	pfManagerHandler := handlers.NewPortfolioManagerHandler()
+	benchmarkHandler := handlers.NewBenchmarkHandler(benchmarkService)
+	importTemplateService := services.NewImportTemplateService()
+	importTemplateHandler := handlers.NewImportTemplateHandler(importTemplateService)
+
+	performanceService := services.NewPerformanceService()
+	riskService := services.NewRiskService(benchmarkService)
+	lotService := services.NewLotService(uploadService, priceService)
+	fxAttributionService := services.NewFXAttributionService(uploadService, lotService)
+	analyticsHandler := handlers.NewAnalyticsHandler(performanceService, riskService, fxAttributionService)
+	allocationService := services.NewAllocationService(uploadService, priceService)
+	allocationHandler := handlers.NewAllocationHandler(allocationService)
+	rebalanceService := services.NewRebalanceService(uploadService, priceService)
+	rebalanceHandler := handlers.NewRebalanceHandler(rebalanceService)
+	harvestService := services.NewHarvestService(uploadService, priceService)
+	lotHandler := handlers.NewLotHandler(lotService, harvestService)
+	flexClient := services.NewIBKRFlexClient(nil, config.Cfg.IBKRFlexBaseURL, config.Cfg.IBKRFlexPollInterval, config.Cfg.IBKRFlexMaxAttempts)
+	ibkrFlexService := services.NewIBKRFlexService(uploadService, flexClient)
+
+	maintenanceService := services.NewMaintenanceService(uploadService, priceService)
+	jobScheduler := scheduler.New()
+	registerScheduledJobs(jobScheduler, maintenanceService, ibkrFlexService)
+	if config.Cfg.SchedulerEnabled {
+		jobScheduler.Start()
+	} else {
+		logger.L.Info("Background scheduler disabled by configuration")
+	}
+	schedulerHandler := handlers.NewSchedulerHandler(jobScheduler)
+
+	jobQueue := jobs.NewQueue(uploadService, ibkrFlexService, config.Cfg.JobWorkerCount, config.Cfg.JobPollInterval)
+	uploadService.SetJobQueue(jobQueue)
+	jobQueue.Start()
+	txHandler := handlers.NewTransactionHandler(uploadService, jobQueue)
+	jobHandler := handlers.NewJobHandler(jobQueue)
+	ibkrFlexHandler := handlers.NewIBKRFlexHandler(ibkrFlexService, jobQueue)
 
 	r := chi.NewRouter()
 
@@ -235,25 +327,62 @@ func main() {
 			r.Post("/portfolios", pfManagerHandler.CreatePortfolio)
 			r.Delete("/portfolios/{id}", pfManagerHandler.DeletePortfolio)
 			r.Post("/portfolios/{id}/refresh-snapshot", portfolioHandler.HandleRefreshSnapshot)
+			r.Get("/portfolios/{id}/benchmarks", benchmarkHandler.HandleListBenchmarks)
+			r.Post("/portfolios/{id}/benchmarks", benchmarkHandler.HandleCreateBenchmark)
+			r.Put("/portfolios/{id}/benchmarks/{benchmarkID}", benchmarkHandler.HandleUpdateBenchmark)
+			r.Delete("/portfolios/{id}/benchmarks/{benchmarkID}", benchmarkHandler.HandleDeleteBenchmark)
+			r.Get("/portfolios/{id}/reclaims", withholdingHandler.HandleListReclaims)
+			r.Post("/portfolios/{id}/reclaims", withholdingHandler.HandleCreateReclaim)
+			r.Put("/portfolios/{id}/reclaims/{reclaimID}", withholdingHandler.HandleUpdateReclaim)
+			r.Delete("/portfolios/{id}/reclaims/{reclaimID}", withholdingHandler.HandleDeleteReclaim)
+			r.Get("/portfolios/{id}/targets", rebalanceHandler.HandleGetTargets)
+			r.Put("/portfolios/{id}/targets", rebalanceHandler.HandleSetTargets)
+			r.Delete("/portfolios/{id}/targets", rebalanceHandler.HandleDeleteTargets)
+			r.Get("/portfolios/{id}/rebalance", rebalanceHandler.HandleGetRebalancePlan)
+			r.Get("/portfolios/{id}/ibkr-flex", ibkrFlexHandler.HandleGetConnection)
+			r.Put("/portfolios/{id}/ibkr-flex", ibkrFlexHandler.HandleSaveConnection)
+			r.Delete("/portfolios/{id}/ibkr-flex", ibkrFlexHandler.HandleDeleteConnection)
+			r.Post("/portfolios/{id}/ibkr-flex/sync", ibkrFlexHandler.HandleSync)
 
 			r.Post("/upload", uploadHandler.HandleUpload)
+			r.Get("/import-templates", importTemplateHandler.HandleListTemplates)
+			r.Post("/import-templates", importTemplateHandler.HandleCreateTemplate)
+			r.Put("/import-templates/{templateID}", importTemplateHandler.HandleUpdateTemplate)
+			r.Delete("/import-templates/{templateID}", importTemplateHandler.HandleDeleteTemplate)
 			r.Get("/realizedgains-data", uploadHandler.HandleGetRealizedGainsData)
 			r.Get("/transactions/processed", txHandler.HandleGetProcessedTransactions)
 			r.Post("/transactions/manual", txHandler.HandleAddManualTransaction)
 			r.Get("/holdings/current-value", portfolioHandler.HandleGetCurrentHoldingsValue)
 			r.Get("/holdings/stocks", portfolioHandler.HandleGetStockHoldings)
 			r.Get("/holdings/options", portfolioHandler.HandleGetOptionHoldings)
+			r.Get("/holdings/lots", lotHandler.HandleGetUnrealizedLots)
+			r.Get("/holdings/harvest", lotHandler.HandleGetHarvestPlan)
 			r.Get("/stock-sales", portfolioHandler.HandleGetStockSales)
 			r.Get("/option-sales", portfolioHandler.HandleGetOptionSales)
 			r.Get("/dividend-tax-summary", dividendHandler.HandleGetDividendTaxSummary)
 			r.Get("/dividend-transactions", dividendHandler.HandleGetDividendTransactions)
 			r.Get("/dividend-metrics", dividendHandler.HandleGetDividendMetrics)
+			r.Get("/dividend-withholding", withholdingHandler.HandleGetReconciliation)
+			r.Get("/dividend-calendar", dividendHandler.HandleGetDividendCalendar)
+			r.Get("/dividend-history", dividendHandler.HandleGetDividendHistory)
 			r.Get("/fees", feeHandler.HandleGetFeeDetails)
 			r.Delete("/transactions/all", txHandler.HandleDeleteAllProcessedTransactions)
 			r.Get("/user/has-data", userHandler.HandleCheckUserData)
 			r.Post("/user/change-password", userHandler.ChangePasswordHandler)
 			r.Post("/user/delete-account", userHandler.DeleteAccountHandler)
 			r.Get("/history/chart", portfolioHandler.HandleGetHistoricalChartData)
+			r.Get("/analytics/returns", analyticsHandler.HandleGetReturns)
+			r.Get("/analytics/risk", analyticsHandler.HandleGetRisk)
+			r.Get("/analytics/fx-attribution", analyticsHandler.HandleGetFXAttribution)
+			r.Get("/allocation", allocationHandler.HandleGetAllocation)
+			r.Get("/fund-compositions/{isin}", allocationHandler.HandleGetFundComposition)
+			r.Put("/fund-compositions/{isin}", allocationHandler.HandleSetFundComposition)
+			r.Delete("/fund-compositions/{isin}", allocationHandler.HandleDeleteFundComposition)
+
+			r.Post("/jobs", jobHandler.HandleEnqueueJob)
+			r.Get("/jobs", jobHandler.HandleListJobs)
+			r.Get("/jobs/{id}", jobHandler.HandleGetJob)
+			r.Post("/jobs/{id}/cancel", jobHandler.HandleCancelJob)
 
 			// Rotas de Administração
 			r.Group(func(r chi.Router) {
@@ -264,6 +393,8 @@ func main() {
 				r.Get("/admin/users/{userID}", userHandler.HandleGetAdminUserDetails)
 				r.Post("/admin/users/refresh-metrics-batch", userHandler.HandleAdminRefreshMultipleUserMetrics)
 				r.Post("/admin/stats/clear-cache", userHandler.HandleAdminClearStatsCache)
+				r.Get("/admin/jobs", schedulerHandler.HandleGetJobStatus)
+				r.Post("/admin/jobs/{name}/run", schedulerHandler.HandleRunJob)
 
 				r.Post("/admin/mfa/setup", userHandler.HandleSetupMFA)
 				r.Post("/admin/mfa/activate", userHandler.HandleActivateMFA)
@@ -290,8 +421,25 @@ func main() {
 		IdleTimeout:  60 * time.Second,
 	}
 
-	logger.L.Info("Server starting", "address", serverAddr)
-	if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-		stdlog.Fatalf("Failed to start server: %v", err)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	go func() {
+		logger.L.Info("Server starting", "address", serverAddr)
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			stdlog.Fatalf("Failed to start server: %v", err)
+		}
+	}()
+
+	<-ctx.Done()
+	logger.L.Info("Shutting down server...")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		logger.L.Error("Server shutdown failed", "error", err)
 	}
+	// Stop cancels running jobs and waits for them to return, so none is cut off mid-transaction.
+	jobScheduler.Stop()
+	jobQueue.Stop()
+	logger.L.Info("Server stopped")
 }
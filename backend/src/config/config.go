@@ -56,6 +56,28 @@ type AppConfig struct {
 
 	// Admin Users
 	AdminEmails []string
+
+	// Background scheduler settings (times are "HH:MM" in UTC)
+	SchedulerEnabled     bool
+	PriceRefreshRunAt    string
+	SnapshotRefreshRunAt string
+	MetricsRefreshRunAt  string
+	IBKRFlexSyncRunAt    string
+
+	// Background job queue settings
+	JobWorkerCount  int
+	JobPollInterval time.Duration
+
+	// IBKR Flex Web Service (an empty base URL selects the IBKR endpoint)
+	IBKRFlexBaseURL      string
+	IBKRFlexPollInterval time.Duration
+	IBKRFlexMaxAttempts  int
+
+	// Analytics
+	RiskFreeRate float64 // Annual rate used by Sharpe/Sortino (0.02 = 2%)
+
+	// Tax
+	CapitalGainsTaxRate float64 // Flat rate used for tax estimates (0.28 = 28%)
 }
 
 // Cfg is a global instance of the AppConfig.
@@ -157,6 +179,29 @@ func LoadConfig() {
 
 		// Admin Users
 		AdminEmails: getAdminEmails("ADMIN_EMAILS"),
+
+		// Scheduler (defaults run after the US market close)
+		SchedulerEnabled:     getEnvAsBool("SCHEDULER_ENABLED", true),
+		PriceRefreshRunAt:    getEnv("SCHEDULER_PRICE_REFRESH_AT", "21:30"),
+		SnapshotRefreshRunAt: getEnv("SCHEDULER_SNAPSHOT_REFRESH_AT", "22:00"),
+		MetricsRefreshRunAt:  getEnv("SCHEDULER_METRICS_REFRESH_AT", "22:30"),
+		// IBKR publishes the previous day's statements in the early morning
+		IBKRFlexSyncRunAt: getEnv("SCHEDULER_IBKR_FLEX_SYNC_AT", "07:00"),
+
+		// Job queue
+		JobWorkerCount:  getEnvAsInt("JOB_WORKER_COUNT", 2),
+		JobPollInterval: getEnvAsDuration("JOB_POLL_INTERVAL", 2*time.Second),
+
+		// IBKR Flex Web Service
+		IBKRFlexBaseURL:      getEnv("IBKR_FLEX_BASE_URL", ""),
+		IBKRFlexPollInterval: getEnvAsDuration("IBKR_FLEX_POLL_INTERVAL", 5*time.Second),
+		IBKRFlexMaxAttempts:  getEnvAsInt("IBKR_FLEX_MAX_ATTEMPTS", 10),
+
+		// Analytics
+		RiskFreeRate: getEnvAsFloat("RISK_FREE_RATE", 0.02),
+
+		// Tax
+		CapitalGainsTaxRate: getEnvAsFloat("CAPITAL_GAINS_TAX_RATE", 0.28),
 	}
 
 	log.Printf("Configuration loaded: Port=%s, LogLevel=%s, DBPath=%s, FrontendURL=%s",
@@ -195,6 +240,32 @@ func getEnvAsInt(key string, fallback int) int {
 	return fallback
 }
 
+// getEnvAsFloat retrieves an environment variable as a float64 or returns a fallback.
+func getEnvAsFloat(key string, fallback float64) float64 {
+	valueStr := getEnv(key, "")
+	if valueStr == "" {
+		return fallback
+	}
+	if value, err := strconv.ParseFloat(valueStr, 64); err == nil {
+		return value
+	}
+	log.Printf("Invalid float value for %s ('%s'), using default: %g", key, valueStr, fallback)
+	return fallback
+}
+
+// getEnvAsBool retrieves an environment variable as a boolean or returns a fallback.
+func getEnvAsBool(key string, fallback bool) bool {
+	valueStr := getEnv(key, "")
+	if valueStr == "" {
+		return fallback
+	}
+	if value, err := strconv.ParseBool(valueStr); err == nil {
+		return value
+	}
+	log.Printf("Invalid boolean value for %s ('%s'), using default: %t", key, valueStr, fallback)
+	return fallback
+}
+
 // getEnvAsDuration retrieves an environment variable as a time.Duration or returns a fallback.
 func getEnvAsDuration(key string, fallback time.Duration) time.Duration {
 	valueStr := getEnv(key, "")
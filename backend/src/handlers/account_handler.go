@@ -57,6 +57,13 @@ func (h *UserHandler) DeleteAccountHandler(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
+	// Fund compositions have no foreign key because provider rows use user_id 0.
+	if _, err = txDB.Exec("DELETE FROM fund_compositions WHERE user_id = ?", userID); err != nil {
+		logger.L.Error("Failed to delete user fund compositions", "userID", userID, "error", err)
+		sendJSONError(w, "Failed to delete user account", http.StatusInternalServerError)
+		return
+	}
+
 	if _, err = txDB.Exec("DELETE FROM users WHERE id = ?", userID); err != nil {
 		logger.L.Error("Failed to delete user from users table", "userID", userID, "error", err)
 		sendJSONError(w, "Failed to delete user account", http.StatusInternalServerError)
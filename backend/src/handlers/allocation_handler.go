@@ -0,0 +1,121 @@
+// backend/src/handlers/allocation_handler.go
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/username/taxfolio/backend/src/logger"
+	"github.com/username/taxfolio/backend/src/model"
+	"github.com/username/taxfolio/backend/src/models"
+	"github.com/username/taxfolio/backend/src/services"
+	"github.com/username/taxfolio/backend/src/utils"
+)
+
+type AllocationHandler struct {
+	allocationService services.AllocationService
+}
+
+func NewAllocationHandler(allocationService services.AllocationService) *AllocationHandler {
+	return &AllocationHandler{allocationService: allocationService}
+}
+
+// HandleGetAllocation returns the breakdowns of a portfolio. ?look_through=true splits funds
+// by their composition.
+func (h *AllocationHandler) HandleGetAllocation(w http.ResponseWriter, r *http.Request) {
+	userID, ok := GetUserIDFromContext(r.Context())
+	if !ok {
+		utils.SendJSONError(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+	portfolioID, err := getPortfolioID(r)
+	if err != nil {
+		utils.SendJSONError(w, "Invalid portfolio_id", http.StatusBadRequest)
+		return
+	}
+	lookThrough, _ := strconv.ParseBool(r.URL.Query().Get("look_through"))
+
+	result, err := h.allocationService.GetAllocation(userID, portfolioID, lookThrough)
+	if err != nil {
+		logger.L.Error("Failed to compute allocation", "userID", userID, "portfolioID", portfolioID, "error", err)
+		utils.SendJSONError(w, "Failed to compute allocation", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+func (h *AllocationHandler) HandleGetFundComposition(w http.ResponseWriter, r *http.Request) {
+	userID, ok := GetUserIDFromContext(r.Context())
+	if !ok {
+		utils.SendJSONError(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+	compositions, err := h.allocationService.GetFundCompositions(userID, chi.URLParam(r, "isin"))
+	if err != nil {
+		logger.L.Error("Failed to get fund composition", "userID", userID, "error", err)
+		utils.SendJSONError(w, "Failed to retrieve fund composition", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(compositions)
+}
+
+// HandleSetFundComposition stores the user's own weights for one dimension of a fund,
+// e.g. {"dimension": "country", "weights": {"US": 62.5, "JP": 5.8}}.
+func (h *AllocationHandler) HandleSetFundComposition(w http.ResponseWriter, r *http.Request) {
+	userID, ok := GetUserIDFromContext(r.Context())
+	if !ok {
+		utils.SendJSONError(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+	var req struct {
+		Dimension string             `json:"dimension"`
+		Weights   map[string]float64 `json:"weights"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.SendJSONError(w, "Invalid body", http.StatusBadRequest)
+		return
+	}
+
+	composition, err := h.allocationService.SetFundComposition(userID, models.FundComposition{
+		FundISIN:  chi.URLParam(r, "isin"),
+		Dimension: req.Dimension,
+		Weights:   req.Weights,
+	})
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidFundComposition) {
+			utils.SendJSONError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		logger.L.Error("Failed to save fund composition", "userID", userID, "error", err)
+		utils.SendJSONError(w, "Failed to save fund composition", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(composition)
+}
+
+// HandleDeleteFundComposition removes the user's weights for ?dimension= (all dimensions if
+// omitted), falling back to provider data.
+func (h *AllocationHandler) HandleDeleteFundComposition(w http.ResponseWriter, r *http.Request) {
+	userID, ok := GetUserIDFromContext(r.Context())
+	if !ok {
+		utils.SendJSONError(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+	err := h.allocationService.DeleteFundComposition(userID, chi.URLParam(r, "isin"), r.URL.Query().Get("dimension"))
+	if err != nil {
+		if errors.Is(err, model.ErrFundCompositionNotFound) {
+			utils.SendJSONError(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		logger.L.Error("Failed to delete fund composition", "userID", userID, "error", err)
+		utils.SendJSONError(w, "Failed to delete fund composition", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
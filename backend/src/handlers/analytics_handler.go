@@ -0,0 +1,145 @@
+// backend/src/handlers/analytics_handler.go
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/username/taxfolio/backend/src/config"
+
+	"github.com/username/taxfolio/backend/src/logger"
+	"github.com/username/taxfolio/backend/src/model"
+	"github.com/username/taxfolio/backend/src/services"
+	"github.com/username/taxfolio/backend/src/utils"
+)
+
+type AnalyticsHandler struct {
+	performanceService services.PerformanceService
+	riskService        services.RiskService
+	fxService          services.FXAttributionService
+}
+
+func NewAnalyticsHandler(performanceService services.PerformanceService, riskService services.RiskService, fxService services.FXAttributionService) *AnalyticsHandler {
+	return &AnalyticsHandler{performanceService: performanceService, riskService: riskService, fxService: fxService}
+}
+
+// HandleGetReturns returns TWR and MWR for ?period=ytd|1y|3y|inception|custom (custom uses from/to, YYYY-MM-DD).
+func (h *AnalyticsHandler) HandleGetReturns(w http.ResponseWriter, r *http.Request) {
+	userID, ok := GetUserIDFromContext(r.Context())
+	if !ok {
+		utils.SendJSONError(w, "authentication required", http.StatusUnauthorized)
+		return
+	}
+	portfolioID, err := getPortfolioID(r)
+	if err != nil {
+		utils.SendJSONError(w, "Invalid portfolio_id", http.StatusBadRequest)
+		return
+	}
+
+	query := r.URL.Query()
+	result, err := h.performanceService.GetReturns(userID, portfolioID, query.Get("period"), query.Get("from"), query.Get("to"))
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidPeriod) {
+			utils.SendJSONError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		logger.L.Error("Error calculating returns", "userID", userID, "portfolioID", portfolioID, "error", err)
+		utils.SendJSONError(w, "Error calculating returns", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// HandleGetRisk returns drawdown, volatility, Sharpe/Sortino and beta for the same periods as
+// HandleGetReturns. Optional: benchmark (name), risk_free_rate (annual fraction), window (trading days).
+func (h *AnalyticsHandler) HandleGetRisk(w http.ResponseWriter, r *http.Request) {
+	userID, ok := GetUserIDFromContext(r.Context())
+	if !ok {
+		utils.SendJSONError(w, "authentication required", http.StatusUnauthorized)
+		return
+	}
+	portfolioID, err := getPortfolioID(r)
+	if err != nil {
+		utils.SendJSONError(w, "Invalid portfolio_id", http.StatusBadRequest)
+		return
+	}
+
+	query := r.URL.Query()
+	params := services.RiskParams{
+		Period:       query.Get("period"),
+		From:         query.Get("from"),
+		To:           query.Get("to"),
+		Benchmark:    query.Get("benchmark"),
+		RiskFreeRate: config.Cfg.RiskFreeRate,
+	}
+	if v := query.Get("risk_free_rate"); v != "" {
+		rate, err := strconv.ParseFloat(v, 64)
+		if err != nil || rate <= -1 || rate > 1 {
+			utils.SendJSONError(w, "Invalid risk_free_rate", http.StatusBadRequest)
+			return
+		}
+		params.RiskFreeRate = rate
+	}
+	if v := query.Get("window"); v != "" {
+		window, err := strconv.Atoi(v)
+		if err != nil || window <= 0 {
+			utils.SendJSONError(w, "Invalid window", http.StatusBadRequest)
+			return
+		}
+		params.RollingWindow = window
+	}
+
+	result, err := h.riskService.GetRiskMetrics(userID, portfolioID, params)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidPeriod) {
+			utils.SendJSONError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if errors.Is(err, model.ErrBenchmarkNotFound) {
+			utils.SendJSONError(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		logger.L.Error("Error calculating risk metrics", "userID", userID, "portfolioID", portfolioID, "error", err)
+		utils.SendJSONError(w, "Error calculating risk metrics", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// HandleGetFXAttribution splits realized and unrealized results into price and currency
+// effects. Optional: year restricts the realized sales to one year.
+func (h *AnalyticsHandler) HandleGetFXAttribution(w http.ResponseWriter, r *http.Request) {
+	userID, ok := GetUserIDFromContext(r.Context())
+	if !ok {
+		utils.SendJSONError(w, "authentication required", http.StatusUnauthorized)
+		return
+	}
+	portfolioID, err := getPortfolioID(r)
+	if err != nil {
+		utils.SendJSONError(w, "Invalid portfolio_id", http.StatusBadRequest)
+		return
+	}
+	year := 0
+	if v := r.URL.Query().Get("year"); v != "" {
+		if year, err = strconv.Atoi(v); err != nil || year < 1900 {
+			utils.SendJSONError(w, "Invalid year", http.StatusBadRequest)
+			return
+		}
+	}
+
+	result, err := h.fxService.GetFXAttribution(userID, portfolioID, year)
+	if err != nil {
+		logger.L.Error("Error calculating FX attribution", "userID", userID, "portfolioID", portfolioID, "error", err)
+		utils.SendJSONError(w, "Error calculating FX attribution", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
@@ -0,0 +1,159 @@
+// backend/src/handlers/benchmark_handler.go
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/username/taxfolio/backend/src/logger"
+	"github.com/username/taxfolio/backend/src/model"
+	"github.com/username/taxfolio/backend/src/models"
+	"github.com/username/taxfolio/backend/src/services"
+	"github.com/username/taxfolio/backend/src/utils"
+)
+
+type BenchmarkHandler struct {
+	benchmarkService services.BenchmarkService
+}
+
+func NewBenchmarkHandler(benchmarkService services.BenchmarkService) *BenchmarkHandler {
+	return &BenchmarkHandler{benchmarkService: benchmarkService}
+}
+
+type benchmarkRequest struct {
+	Name       string                      `json:"name"`
+	Components []models.BenchmarkComponent `json:"components"`
+	Rebalance  string                      `json:"rebalance"`
+}
+
+func urlParamInt64(r *http.Request, name string) (int64, error) {
+	return strconv.ParseInt(chi.URLParam(r, name), 10, 64)
+}
+
+func (h *BenchmarkHandler) HandleListBenchmarks(w http.ResponseWriter, r *http.Request) {
+	userID, ok := GetUserIDFromContext(r.Context())
+	if !ok {
+		utils.SendJSONError(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+	portfolioID, err := urlParamInt64(r, "id")
+	if err != nil {
+		utils.SendJSONError(w, "Invalid portfolio id", http.StatusBadRequest)
+		return
+	}
+	benchmarks, err := h.benchmarkService.ListBenchmarks(userID, portfolioID)
+	if err != nil {
+		logger.L.Error("Failed to list benchmarks", "userID", userID, "portfolioID", portfolioID, "error", err)
+		utils.SendJSONError(w, "Failed to retrieve benchmarks", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(benchmarks)
+}
+
+func (h *BenchmarkHandler) HandleCreateBenchmark(w http.ResponseWriter, r *http.Request) {
+	userID, ok := GetUserIDFromContext(r.Context())
+	if !ok {
+		utils.SendJSONError(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+	portfolioID, err := urlParamInt64(r, "id")
+	if err != nil {
+		utils.SendJSONError(w, "Invalid portfolio id", http.StatusBadRequest)
+		return
+	}
+	var req benchmarkRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.SendJSONError(w, "Invalid body", http.StatusBadRequest)
+		return
+	}
+
+	benchmark, err := h.benchmarkService.CreateBenchmark(userID, models.Benchmark{
+		PortfolioID: portfolioID,
+		Name:        req.Name,
+		Components:  req.Components,
+		Rebalance:   req.Rebalance,
+	})
+	if err != nil {
+		h.sendBenchmarkError(w, err, userID, portfolioID)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(benchmark)
+}
+
+func (h *BenchmarkHandler) HandleUpdateBenchmark(w http.ResponseWriter, r *http.Request) {
+	userID, ok := GetUserIDFromContext(r.Context())
+	if !ok {
+		utils.SendJSONError(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+	portfolioID, err := urlParamInt64(r, "id")
+	if err != nil {
+		utils.SendJSONError(w, "Invalid portfolio id", http.StatusBadRequest)
+		return
+	}
+	benchmarkID, err := urlParamInt64(r, "benchmarkID")
+	if err != nil {
+		utils.SendJSONError(w, "Invalid benchmark id", http.StatusBadRequest)
+		return
+	}
+	var req benchmarkRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.SendJSONError(w, "Invalid body", http.StatusBadRequest)
+		return
+	}
+
+	benchmark, err := h.benchmarkService.UpdateBenchmark(userID, models.Benchmark{
+		ID:          benchmarkID,
+		PortfolioID: portfolioID,
+		Name:        req.Name,
+		Components:  req.Components,
+		Rebalance:   req.Rebalance,
+	})
+	if err != nil {
+		h.sendBenchmarkError(w, err, userID, portfolioID)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(benchmark)
+}
+
+func (h *BenchmarkHandler) HandleDeleteBenchmark(w http.ResponseWriter, r *http.Request) {
+	userID, ok := GetUserIDFromContext(r.Context())
+	if !ok {
+		utils.SendJSONError(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+	portfolioID, err := urlParamInt64(r, "id")
+	if err != nil {
+		utils.SendJSONError(w, "Invalid portfolio id", http.StatusBadRequest)
+		return
+	}
+	benchmarkID, err := urlParamInt64(r, "benchmarkID")
+	if err != nil {
+		utils.SendJSONError(w, "Invalid benchmark id", http.StatusBadRequest)
+		return
+	}
+	if err := h.benchmarkService.DeleteBenchmark(userID, portfolioID, benchmarkID); err != nil {
+		h.sendBenchmarkError(w, err, userID, portfolioID)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *BenchmarkHandler) sendBenchmarkError(w http.ResponseWriter, err error, userID, portfolioID int64) {
+	switch {
+	case errors.Is(err, services.ErrInvalidBenchmark):
+		utils.SendJSONError(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, model.ErrBenchmarkNotFound), errors.Is(err, model.ErrPortfolioNotFound):
+		utils.SendJSONError(w, err.Error(), http.StatusNotFound)
+	default:
+		logger.L.Error("Benchmark operation failed", "userID", userID, "portfolioID", portfolioID, "error", err)
+		utils.SendJSONError(w, "Failed to save benchmark", http.StatusInternalServerError)
+	}
+}
@@ -12,11 +12,13 @@ import (
 )
 
 type DividendHandler struct {
-	uploadService services.UploadService
+	uploadService   services.UploadService
+	calendarService services.DividendCalendarService
+	historyService  services.DividendHistoryService
 }
 
-func NewDividendHandler(service services.UploadService) *DividendHandler {
-	return &DividendHandler{uploadService: service}
+func NewDividendHandler(service services.UploadService, calendarService services.DividendCalendarService, historyService services.DividendHistoryService) *DividendHandler {
+	return &DividendHandler{uploadService: service, calendarService: calendarService, historyService: historyService}
 }
 
 func (h *DividendHandler) HandleGetDividendTaxSummary(w http.ResponseWriter, r *http.Request) {
@@ -123,3 +125,69 @@ func (h *DividendHandler) HandleGetDividendMetrics(w http.ResponseWriter, r *htt
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(metrics)
 }
+
+// HandleGetDividendCalendar returns the upcoming dividends of held positions and checks past
+// payments. Optional: months (horizon, 1-12, default 12) and lookback_months (1-60, default 12).
+func (h *DividendHandler) HandleGetDividendCalendar(w http.ResponseWriter, r *http.Request) {
+	userID, ok := GetUserIDFromContext(r.Context())
+	if !ok {
+		utils.SendJSONError(w, "authentication required", http.StatusUnauthorized)
+		return
+	}
+	portfolioID, err := getPortfolioID(r)
+	if err != nil {
+		utils.SendJSONError(w, "Invalid portfolio_id", http.StatusBadRequest)
+		return
+	}
+
+	query := r.URL.Query()
+	horizonMonths, lookbackMonths := 12, 12
+	for name, limits := range map[string]struct {
+		target *int
+		max    int
+	}{"months": {&horizonMonths, 12}, "lookback_months": {&lookbackMonths, 60}} {
+		if v := query.Get(name); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 1 || n > limits.max {
+				utils.SendJSONError(w, "Invalid "+name, http.StatusBadRequest)
+				return
+			}
+			*limits.target = n
+		}
+	}
+
+	calendar, err := h.calendarService.GetCalendar(userID, portfolioID, horizonMonths, lookbackMonths)
+	if err != nil {
+		logger.L.Error("Error building dividend calendar", "userID", userID, "portfolioID", portfolioID, "error", err)
+		utils.SendJSONError(w, "Error building dividend calendar", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(calendar)
+}
+
+// HandleGetDividendHistory returns monthly and yearly dividend income with growth, payout
+// frequency, dividend cuts and yield on cost per position.
+func (h *DividendHandler) HandleGetDividendHistory(w http.ResponseWriter, r *http.Request) {
+	userID, ok := GetUserIDFromContext(r.Context())
+	if !ok {
+		utils.SendJSONError(w, "authentication required", http.StatusUnauthorized)
+		return
+	}
+	portfolioID, err := getPortfolioID(r)
+	if err != nil {
+		utils.SendJSONError(w, "Invalid portfolio_id", http.StatusBadRequest)
+		return
+	}
+
+	history, err := h.historyService.GetDividendHistory(userID, portfolioID)
+	if err != nil {
+		logger.L.Error("Error building dividend history", "userID", userID, "portfolioID", portfolioID, "error", err)
+		utils.SendJSONError(w, "Error building dividend history", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(history)
+}
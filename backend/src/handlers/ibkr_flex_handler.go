@@ -0,0 +1,146 @@
+// backend/src/handlers/ibkr_flex_handler.go
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/username/taxfolio/backend/src/jobs"
+	"github.com/username/taxfolio/backend/src/logger"
+	"github.com/username/taxfolio/backend/src/model"
+	"github.com/username/taxfolio/backend/src/models"
+	"github.com/username/taxfolio/backend/src/services"
+	"github.com/username/taxfolio/backend/src/utils"
+)
+
+type IBKRFlexHandler struct {
+	flexService services.IBKRFlexService
+	queue       *jobs.Queue
+}
+
+func NewIBKRFlexHandler(flexService services.IBKRFlexService, queue *jobs.Queue) *IBKRFlexHandler {
+	return &IBKRFlexHandler{flexService: flexService, queue: queue}
+}
+
+func (h *IBKRFlexHandler) HandleGetConnection(w http.ResponseWriter, r *http.Request) {
+	userID, ok := GetUserIDFromContext(r.Context())
+	if !ok {
+		utils.SendJSONError(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+	portfolioID, err := urlParamInt64(r, "id")
+	if err != nil {
+		utils.SendJSONError(w, "Invalid portfolio id", http.StatusBadRequest)
+		return
+	}
+	connection, err := h.flexService.GetConnection(userID, portfolioID)
+	if err != nil {
+		h.sendFlexError(w, err, userID, portfolioID)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(connection)
+}
+
+// HandleSaveConnection stores the Flex Web Service credentials of a portfolio, e.g.
+// {"token": "123456789012345678901234", "query_id": "987654", "enabled": true}.
+// The token may be omitted to keep the stored one; enabled defaults to true.
+func (h *IBKRFlexHandler) HandleSaveConnection(w http.ResponseWriter, r *http.Request) {
+	userID, ok := GetUserIDFromContext(r.Context())
+	if !ok {
+		utils.SendJSONError(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+	portfolioID, err := urlParamInt64(r, "id")
+	if err != nil {
+		utils.SendJSONError(w, "Invalid portfolio id", http.StatusBadRequest)
+		return
+	}
+	var req struct {
+		Token   string `json:"token"`
+		QueryID string `json:"query_id"`
+		Enabled *bool  `json:"enabled"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.SendJSONError(w, "Invalid body", http.StatusBadRequest)
+		return
+	}
+
+	connection, err := h.flexService.SaveConnection(userID, models.IBKRFlexConnection{
+		PortfolioID: portfolioID,
+		Token:       req.Token,
+		QueryID:     req.QueryID,
+		Enabled:     req.Enabled == nil || *req.Enabled,
+	})
+	if err != nil {
+		h.sendFlexError(w, err, userID, portfolioID)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(connection)
+}
+
+func (h *IBKRFlexHandler) HandleDeleteConnection(w http.ResponseWriter, r *http.Request) {
+	userID, ok := GetUserIDFromContext(r.Context())
+	if !ok {
+		utils.SendJSONError(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+	portfolioID, err := urlParamInt64(r, "id")
+	if err != nil {
+		utils.SendJSONError(w, "Invalid portfolio id", http.StatusBadRequest)
+		return
+	}
+	if err := h.flexService.DeleteConnection(userID, portfolioID); err != nil {
+		h.sendFlexError(w, err, userID, portfolioID)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// HandleSync queues an import of the portfolio's Flex query. Generating the statement can
+// take minutes, so the import runs as a background job; its outcome is also recorded on
+// the connection.
+func (h *IBKRFlexHandler) HandleSync(w http.ResponseWriter, r *http.Request) {
+	userID, ok := GetUserIDFromContext(r.Context())
+	if !ok {
+		utils.SendJSONError(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+	portfolioID, err := urlParamInt64(r, "id")
+	if err != nil {
+		utils.SendJSONError(w, "Invalid portfolio id", http.StatusBadRequest)
+		return
+	}
+	if _, err := h.flexService.GetConnection(userID, portfolioID); err != nil {
+		h.sendFlexError(w, err, userID, portfolioID)
+		return
+	}
+
+	job, err := h.queue.Enqueue(userID, portfolioID, model.JobTypeIBKRFlexSync)
+	if err != nil {
+		if errors.Is(err, jobs.ErrPortfolioNotFound) {
+			utils.SendJSONError(w, "Portfolio not found", http.StatusNotFound)
+			return
+		}
+		logger.L.Error("Failed to enqueue IBKR Flex import", "userID", userID, "portfolioID", portfolioID, "error", err)
+		utils.SendJSONError(w, "Failed to enqueue import", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(job)
+}
+
+func (h *IBKRFlexHandler) sendFlexError(w http.ResponseWriter, err error, userID, portfolioID int64) {
+	switch {
+	case errors.Is(err, services.ErrInvalidIBKRFlexConnection):
+		utils.SendJSONError(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, model.ErrIBKRFlexConnectionNotFound), errors.Is(err, model.ErrPortfolioNotFound):
+		utils.SendJSONError(w, err.Error(), http.StatusNotFound)
+	default:
+		logger.L.Error("IBKR Flex connection operation failed", "userID", userID, "portfolioID", portfolioID, "error", err)
+		utils.SendJSONError(w, "Failed to process IBKR Flex connection", http.StatusInternalServerError)
+	}
+}
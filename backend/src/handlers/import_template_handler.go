@@ -0,0 +1,123 @@
+// backend/src/handlers/import_template_handler.go
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/username/taxfolio/backend/src/logger"
+	"github.com/username/taxfolio/backend/src/model"
+	"github.com/username/taxfolio/backend/src/models"
+	"github.com/username/taxfolio/backend/src/services"
+	"github.com/username/taxfolio/backend/src/utils"
+)
+
+type ImportTemplateHandler struct {
+	importTemplateService services.ImportTemplateService
+}
+
+func NewImportTemplateHandler(importTemplateService services.ImportTemplateService) *ImportTemplateHandler {
+	return &ImportTemplateHandler{importTemplateService: importTemplateService}
+}
+
+func (h *ImportTemplateHandler) HandleListTemplates(w http.ResponseWriter, r *http.Request) {
+	userID, ok := GetUserIDFromContext(r.Context())
+	if !ok {
+		utils.SendJSONError(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+	templates, err := h.importTemplateService.ListTemplates(userID)
+	if err != nil {
+		logger.L.Error("Failed to list import templates", "userID", userID, "error", err)
+		utils.SendJSONError(w, "Failed to retrieve import templates", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(templates)
+}
+
+// HandleCreateTemplate stores a column mapping, e.g.
+// {"name": "My broker", "delimiter": ";", "decimal_separator": ",", "date_format": "DD/MM/YYYY",
+// "columns": {"date": "Data", "type": "Tipo", "isin": "ISIN", "quantity": "Qtd", "price": "Preço", "amount": "Valor"},
+// "type_mapping": {"Compra": "BUY", "Venda": "SELL", "Dividendo": "DIVIDEND"}}.
+// Files are then uploaded with source "custom:<id>".
+func (h *ImportTemplateHandler) HandleCreateTemplate(w http.ResponseWriter, r *http.Request) {
+	userID, ok := GetUserIDFromContext(r.Context())
+	if !ok {
+		utils.SendJSONError(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+	var req models.ImportTemplate
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.SendJSONError(w, "Invalid body", http.StatusBadRequest)
+		return
+	}
+	req.ID = 0
+
+	template, err := h.importTemplateService.CreateTemplate(userID, req)
+	if err != nil {
+		h.sendTemplateError(w, err, userID)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(template)
+}
+
+func (h *ImportTemplateHandler) HandleUpdateTemplate(w http.ResponseWriter, r *http.Request) {
+	userID, ok := GetUserIDFromContext(r.Context())
+	if !ok {
+		utils.SendJSONError(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+	templateID, err := urlParamInt64(r, "templateID")
+	if err != nil {
+		utils.SendJSONError(w, "Invalid template id", http.StatusBadRequest)
+		return
+	}
+	var req models.ImportTemplate
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.SendJSONError(w, "Invalid body", http.StatusBadRequest)
+		return
+	}
+	req.ID = templateID
+
+	template, err := h.importTemplateService.UpdateTemplate(userID, req)
+	if err != nil {
+		h.sendTemplateError(w, err, userID)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(template)
+}
+
+func (h *ImportTemplateHandler) HandleDeleteTemplate(w http.ResponseWriter, r *http.Request) {
+	userID, ok := GetUserIDFromContext(r.Context())
+	if !ok {
+		utils.SendJSONError(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+	templateID, err := urlParamInt64(r, "templateID")
+	if err != nil {
+		utils.SendJSONError(w, "Invalid template id", http.StatusBadRequest)
+		return
+	}
+	if err := h.importTemplateService.DeleteTemplate(userID, templateID); err != nil {
+		h.sendTemplateError(w, err, userID)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *ImportTemplateHandler) sendTemplateError(w http.ResponseWriter, err error, userID int64) {
+	switch {
+	case errors.Is(err, services.ErrInvalidImportTemplate):
+		utils.SendJSONError(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, model.ErrImportTemplateNotFound):
+		utils.SendJSONError(w, err.Error(), http.StatusNotFound)
+	default:
+		logger.L.Error("Import template operation failed", "userID", userID, "error", err)
+		utils.SendJSONError(w, "Failed to save import template", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,156 @@
+// backend/src/handlers/job_handler.go
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/username/taxfolio/backend/src/jobs"
+	"github.com/username/taxfolio/backend/src/logger"
+	"github.com/username/taxfolio/backend/src/model"
+	"github.com/username/taxfolio/backend/src/utils"
+)
+
+const defaultJobListLimit = 20
+
+type JobHandler struct {
+	queue *jobs.Queue
+}
+
+func NewJobHandler(queue *jobs.Queue) *JobHandler {
+	return &JobHandler{queue: queue}
+}
+
+type enqueueJobRequest struct {
+	JobType     string `json:"job_type"`
+	PortfolioID int64  `json:"portfolio_id"`
+}
+
+// HandleEnqueueJob queues a rebuild, re-pricing, metrics refresh or IBKR Flex import for one of the user's portfolios.
+func (h *JobHandler) HandleEnqueueJob(w http.ResponseWriter, r *http.Request) {
+	userID, ok := GetUserIDFromContext(r.Context())
+	if !ok {
+		utils.SendJSONError(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	var req enqueueJobRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.SendJSONError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.PortfolioID <= 0 {
+		utils.SendJSONError(w, "portfolio_id is required", http.StatusBadRequest)
+		return
+	}
+
+	job, err := h.queue.EnqueueRequested(userID, req.PortfolioID, req.JobType)
+	if err != nil {
+		switch {
+		case errors.Is(err, jobs.ErrInvalidJobType):
+			utils.SendJSONError(w, "Invalid job_type", http.StatusBadRequest)
+		case errors.Is(err, jobs.ErrPortfolioNotFound):
+			utils.SendJSONError(w, "Portfolio not found", http.StatusNotFound)
+		default:
+			logger.L.Error("Failed to enqueue job", "userID", userID, "error", err)
+			utils.SendJSONError(w, "Failed to enqueue job", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(job)
+}
+
+// HandleListJobs returns the user's most recent jobs. portfolio_id is optional.
+func (h *JobHandler) HandleListJobs(w http.ResponseWriter, r *http.Request) {
+	userID, ok := GetUserIDFromContext(r.Context())
+	if !ok {
+		utils.SendJSONError(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	var portfolioID int64
+	if r.URL.Query().Get("portfolio_id") != "" {
+		pid, err := getPortfolioID(r)
+		if err != nil {
+			utils.SendJSONError(w, "Invalid portfolio_id", http.StatusBadRequest)
+			return
+		}
+		portfolioID = pid
+	}
+
+	jobList, err := h.queue.List(userID, portfolioID, defaultJobListLimit)
+	if err != nil {
+		logger.L.Error("Failed to list jobs", "userID", userID, "error", err)
+		utils.SendJSONError(w, "Failed to list jobs", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(jobList)
+}
+
+// HandleGetJob returns the status and progress of a single job.
+func (h *JobHandler) HandleGetJob(w http.ResponseWriter, r *http.Request) {
+	userID, ok := GetUserIDFromContext(r.Context())
+	if !ok {
+		utils.SendJSONError(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+	jobID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		utils.SendJSONError(w, "Invalid job ID", http.StatusBadRequest)
+		return
+	}
+
+	job, err := h.queue.Get(userID, jobID)
+	if err != nil {
+		if errors.Is(err, model.ErrJobNotFound) {
+			utils.SendJSONError(w, "Job not found", http.StatusNotFound)
+			return
+		}
+		logger.L.Error("Failed to fetch job", "userID", userID, "jobID", jobID, "error", err)
+		utils.SendJSONError(w, "Failed to fetch job", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(job)
+}
+
+// HandleCancelJob cancels a queued job or asks a running job to stop.
+func (h *JobHandler) HandleCancelJob(w http.ResponseWriter, r *http.Request) {
+	userID, ok := GetUserIDFromContext(r.Context())
+	if !ok {
+		utils.SendJSONError(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+	jobID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		utils.SendJSONError(w, "Invalid job ID", http.StatusBadRequest)
+		return
+	}
+
+	job, err := h.queue.Cancel(userID, jobID)
+	if err != nil {
+		if errors.Is(err, model.ErrJobNotFound) {
+			utils.SendJSONError(w, "Job not found", http.StatusNotFound)
+			return
+		}
+		logger.L.Error("Failed to cancel job", "userID", userID, "jobID", jobID, "error", err)
+		utils.SendJSONError(w, "Failed to cancel job", http.StatusInternalServerError)
+		return
+	}
+	if job.Status != model.JobStatusCancelled && job.Status != model.JobStatusRunning {
+		utils.SendJSONError(w, "Job has already finished", http.StatusConflict)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(job)
+}
@@ -0,0 +1,86 @@
+// backend/src/handlers/lot_handler.go
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/username/taxfolio/backend/src/config"
+
+	"github.com/username/taxfolio/backend/src/logger"
+	"github.com/username/taxfolio/backend/src/services"
+	"github.com/username/taxfolio/backend/src/utils"
+)
+
+type LotHandler struct {
+	lotService     services.LotService
+	harvestService services.HarvestService
+}
+
+func NewLotHandler(lotService services.LotService, harvestService services.HarvestService) *LotHandler {
+	return &LotHandler{lotService: lotService, harvestService: harvestService}
+}
+
+// HandleGetUnrealizedLots returns each open lot with its unrealized result and holding period.
+func (h *LotHandler) HandleGetUnrealizedLots(w http.ResponseWriter, r *http.Request) {
+	userID, ok := GetUserIDFromContext(r.Context())
+	if !ok {
+		utils.SendJSONError(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+	portfolioID, err := getPortfolioID(r)
+	if err != nil {
+		utils.SendJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	summary, err := h.lotService.GetUnrealizedLots(userID, portfolioID)
+	if err != nil {
+		logger.L.Error("Failed to get unrealized lots", "userID", userID, "portfolioID", portfolioID, "error", err)
+		utils.SendJSONError(w, "Failed to retrieve unrealized lots", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(summary)
+}
+
+// HandleGetHarvestPlan suggests loss-realizing sells for the current year. Optional:
+// target_net (EUR, default 0) and tax_rate (fraction, default CAPITAL_GAINS_TAX_RATE).
+func (h *LotHandler) HandleGetHarvestPlan(w http.ResponseWriter, r *http.Request) {
+	userID, ok := GetUserIDFromContext(r.Context())
+	if !ok {
+		utils.SendJSONError(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+	portfolioID, err := getPortfolioID(r)
+	if err != nil {
+		utils.SendJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	query := r.URL.Query()
+	params := services.HarvestParams{TaxRate: config.Cfg.CapitalGainsTaxRate}
+	if v := query.Get("target_net"); v != "" {
+		if params.TargetNetEUR, err = strconv.ParseFloat(v, 64); err != nil {
+			utils.SendJSONError(w, "Invalid target_net", http.StatusBadRequest)
+			return
+		}
+	}
+	if v := query.Get("tax_rate"); v != "" {
+		rate, err := strconv.ParseFloat(v, 64)
+		if err != nil || rate < 0 || rate > 1 {
+			utils.SendJSONError(w, "Invalid tax_rate", http.StatusBadRequest)
+			return
+		}
+		params.TaxRate = rate
+	}
+
+	plan, err := h.harvestService.GetHarvestPlan(userID, portfolioID, params)
+	if err != nil {
+		logger.L.Error("Failed to build harvest plan", "userID", userID, "portfolioID", portfolioID, "error", err)
+		utils.SendJSONError(w, "Failed to build harvest plan", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(plan)
+}
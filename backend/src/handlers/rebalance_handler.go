@@ -0,0 +1,151 @@
+// backend/src/handlers/rebalance_handler.go
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/username/taxfolio/backend/src/logger"
+	"github.com/username/taxfolio/backend/src/model"
+	"github.com/username/taxfolio/backend/src/models"
+	"github.com/username/taxfolio/backend/src/services"
+	"github.com/username/taxfolio/backend/src/utils"
+)
+
+type RebalanceHandler struct {
+	rebalanceService services.RebalanceService
+}
+
+func NewRebalanceHandler(rebalanceService services.RebalanceService) *RebalanceHandler {
+	return &RebalanceHandler{rebalanceService: rebalanceService}
+}
+
+func (h *RebalanceHandler) HandleGetTargets(w http.ResponseWriter, r *http.Request) {
+	userID, ok := GetUserIDFromContext(r.Context())
+	if !ok {
+		utils.SendJSONError(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+	portfolioID, err := urlParamInt64(r, "id")
+	if err != nil {
+		utils.SendJSONError(w, "Invalid portfolio id", http.StatusBadRequest)
+		return
+	}
+	targets, err := h.rebalanceService.GetTargets(userID, portfolioID)
+	if err != nil {
+		logger.L.Error("Failed to get targets", "userID", userID, "portfolioID", portfolioID, "error", err)
+		utils.SendJSONError(w, "Failed to retrieve targets", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(targets)
+}
+
+// HandleSetTargets replaces the targets of a portfolio, e.g.
+// {"dimension": "isin", "targets": [{"key": "IE00B4L5Y983", "weight": 80}, {"key": "IE00B3F81409", "weight": 20}]}.
+func (h *RebalanceHandler) HandleSetTargets(w http.ResponseWriter, r *http.Request) {
+	userID, ok := GetUserIDFromContext(r.Context())
+	if !ok {
+		utils.SendJSONError(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+	portfolioID, err := urlParamInt64(r, "id")
+	if err != nil {
+		utils.SendJSONError(w, "Invalid portfolio id", http.StatusBadRequest)
+		return
+	}
+	var req struct {
+		Dimension string                `json:"dimension"`
+		Targets   []models.TargetWeight `json:"targets"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.SendJSONError(w, "Invalid body", http.StatusBadRequest)
+		return
+	}
+
+	targets, err := h.rebalanceService.SetTargets(userID, models.TargetAllocation{
+		PortfolioID: portfolioID,
+		Dimension:   req.Dimension,
+		Targets:     req.Targets,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidTargets):
+			utils.SendJSONError(w, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, model.ErrPortfolioNotFound):
+			utils.SendJSONError(w, err.Error(), http.StatusNotFound)
+		default:
+			logger.L.Error("Failed to save targets", "userID", userID, "portfolioID", portfolioID, "error", err)
+			utils.SendJSONError(w, "Failed to save targets", http.StatusInternalServerError)
+		}
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(targets)
+}
+
+func (h *RebalanceHandler) HandleDeleteTargets(w http.ResponseWriter, r *http.Request) {
+	userID, ok := GetUserIDFromContext(r.Context())
+	if !ok {
+		utils.SendJSONError(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+	portfolioID, err := urlParamInt64(r, "id")
+	if err != nil {
+		utils.SendJSONError(w, "Invalid portfolio id", http.StatusBadRequest)
+		return
+	}
+	if err := h.rebalanceService.DeleteTargets(userID, portfolioID); err != nil {
+		logger.L.Error("Failed to delete targets", "userID", userID, "portfolioID", portfolioID, "error", err)
+		utils.SendJSONError(w, "Failed to delete targets", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// HandleGetRebalancePlan returns drift and suggested trades. Optional query parameters:
+// new_cash, min_trade (EUR) and new_cash_only, no_sells, whole_shares (booleans).
+func (h *RebalanceHandler) HandleGetRebalancePlan(w http.ResponseWriter, r *http.Request) {
+	userID, ok := GetUserIDFromContext(r.Context())
+	if !ok {
+		utils.SendJSONError(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+	portfolioID, err := urlParamInt64(r, "id")
+	if err != nil {
+		utils.SendJSONError(w, "Invalid portfolio id", http.StatusBadRequest)
+		return
+	}
+
+	query := r.URL.Query()
+	var constraints models.RebalanceConstraints
+	for name, target := range map[string]*float64{"new_cash": &constraints.NewCashEUR, "min_trade": &constraints.MinTradeEUR} {
+		if v := query.Get(name); v != "" {
+			if *target, err = strconv.ParseFloat(v, 64); err != nil {
+				utils.SendJSONError(w, "Invalid "+name, http.StatusBadRequest)
+				return
+			}
+		}
+	}
+	constraints.NewCashOnly, _ = strconv.ParseBool(query.Get("new_cash_only"))
+	constraints.NoSells, _ = strconv.ParseBool(query.Get("no_sells"))
+	constraints.WholeShares, _ = strconv.ParseBool(query.Get("whole_shares"))
+
+	plan, err := h.rebalanceService.GetRebalancePlan(userID, portfolioID, constraints)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidRebalanceInput):
+			utils.SendJSONError(w, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, services.ErrNoTargets):
+			utils.SendJSONError(w, err.Error(), http.StatusNotFound)
+		default:
+			logger.L.Error("Failed to build rebalance plan", "userID", userID, "portfolioID", portfolioID, "error", err)
+			utils.SendJSONError(w, "Failed to build rebalance plan", http.StatusInternalServerError)
+		}
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(plan)
+}
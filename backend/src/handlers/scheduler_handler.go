@@ -0,0 +1,52 @@
+// backend/src/handlers/scheduler_handler.go
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/username/taxfolio/backend/src/logger"
+	"github.com/username/taxfolio/backend/src/scheduler"
+	"github.com/username/taxfolio/backend/src/utils"
+)
+
+type SchedulerHandler struct {
+	scheduler *scheduler.Scheduler
+}
+
+func NewSchedulerHandler(s *scheduler.Scheduler) *SchedulerHandler {
+	return &SchedulerHandler{scheduler: s}
+}
+
+// HandleGetJobStatus lists every scheduled job with its last run and next run times.
+func (h *SchedulerHandler) HandleGetJobStatus(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.scheduler.Status())
+}
+
+// HandleRunJob triggers a scheduled job immediately.
+func (h *SchedulerHandler) HandleRunJob(w http.ResponseWriter, r *http.Request) {
+	jobName := chi.URLParam(r, "name")
+
+	err := h.scheduler.RunNow(jobName)
+	if err != nil {
+		switch {
+		case errors.Is(err, scheduler.ErrJobNotFound):
+			utils.SendJSONError(w, "Job not found", http.StatusNotFound)
+		case errors.Is(err, scheduler.ErrJobAlreadyRunning):
+			utils.SendJSONError(w, "Job is already running", http.StatusConflict)
+		case errors.Is(err, scheduler.ErrStopped):
+			utils.SendJSONError(w, "Server is shutting down", http.StatusServiceUnavailable)
+		default:
+			utils.SendJSONError(w, "Failed to start job", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	logger.L.Info("Admin triggered scheduler job", "job", jobName)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]string{"status": "started", "job": jobName})
+}
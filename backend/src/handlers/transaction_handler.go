@@ -14,7 +14,9 @@ import (
 	"time"
 
 	"github.com/username/taxfolio/backend/src/database"
+	"github.com/username/taxfolio/backend/src/jobs"
 	"github.com/username/taxfolio/backend/src/logger"
+	"github.com/username/taxfolio/backend/src/model"
 	"github.com/username/taxfolio/backend/src/models"
 	"github.com/username/taxfolio/backend/src/processors"
 	"github.com/username/taxfolio/backend/src/security/validation"
@@ -24,11 +26,13 @@ import (
 
 type TransactionHandler struct {
 	uploadService services.UploadService
+	queue         *jobs.Queue
 }
 
-func NewTransactionHandler(uploadService services.UploadService) *TransactionHandler {
+func NewTransactionHandler(uploadService services.UploadService, queue *jobs.Queue) *TransactionHandler {
 	return &TransactionHandler{
 		uploadService: uploadService,
+		queue:         queue,
 	}
 }
 
@@ -134,6 +138,7 @@ func (h *TransactionHandler) HandleDeleteAllProcessedTransactions(w http.Respons
 	defer txDB.Rollback()
 
 	var result sql.Result
+	rebuildHistory := false
 	switch req.Type {
 	case "all":
 		result, err = txDB.Exec("DELETE FROM processed_transactions WHERE user_id = ? AND portfolio_id = ?", userID, req.PortfolioID)
@@ -159,10 +164,21 @@ func (h *TransactionHandler) HandleDeleteAllProcessedTransactions(w http.Respons
 		for i, v := range req.Values {
 			args[i+2] = v
 		}
+
+		// Snapshots are only stale from the earliest removed transaction onward.
+		var earliest sql.NullString
+		err = txDB.QueryRow(
+			"SELECT MIN(SUBSTR(date, 7, 4) || '-' || SUBSTR(date, 4, 2) || '-' || SUBSTR(date, 1, 2)) FROM processed_transactions WHERE user_id = ? AND portfolio_id = ? AND source IN (?"+strings.Repeat(",?", len(req.Values)-1)+")",
+			args...,
+		).Scan(&earliest)
+		if err != nil {
+			break
+		}
 		result, err = txDB.Exec(query, args...)
 
-		if err == nil {
-			_, err = txDB.Exec("DELETE FROM portfolio_snapshots WHERE user_id = ? AND portfolio_id = ?", userID, req.PortfolioID)
+		if err == nil && earliest.Valid {
+			err = model.MarkHistoryDirty(txDB, req.PortfolioID, earliest.String)
+			rebuildHistory = true
 		}
 
 	case "year":
@@ -171,10 +187,15 @@ func (h *TransactionHandler) HandleDeleteAllProcessedTransactions(w http.Respons
 			utils.SendJSONError(w, err.Error(), http.StatusBadRequest)
 			return
 		}
+		if _, convErr := strconv.Atoi(req.Values[0]); convErr != nil || len(req.Values[0]) != 4 {
+			utils.SendJSONError(w, "invalid year value", http.StatusBadRequest)
+			return
+		}
 		result, err = txDB.Exec("DELETE FROM processed_transactions WHERE user_id = ? AND portfolio_id = ? AND SUBSTR(date, 7, 4) = ?", userID, req.PortfolioID, req.Values[0])
 
 		if err == nil {
-			_, err = txDB.Exec("DELETE FROM portfolio_snapshots WHERE user_id = ? AND portfolio_id = ?", userID, req.PortfolioID)
+			err = model.MarkHistoryDirty(txDB, req.PortfolioID, req.Values[0]+"-01-01")
+			rebuildHistory = true
 		}
 
 	default:
@@ -205,6 +226,11 @@ func (h *TransactionHandler) HandleDeleteAllProcessedTransactions(w http.Respons
 	logger.L.Info("Successfully deleted transactions", "rows", rowsAffected)
 
 	h.uploadService.InvalidateUserCache(userID, req.PortfolioID)
+	if rebuildHistory {
+		if _, err := h.queue.Enqueue(userID, req.PortfolioID, model.JobTypeRebuildHistory); err != nil {
+			logger.L.Error("Failed to enqueue history rebuild after deletion", "userID", userID, "portfolioID", req.PortfolioID, "error", err)
+		}
+	}
 	w.WriteHeader(http.StatusNoContent)
 }
 
@@ -300,8 +326,8 @@ func (h *TransactionHandler) HandleAddManualTransaction(w http.ResponseWriter, r
         INSERT INTO processed_transactions 
         (user_id, portfolio_id, date, source, product_name, isin, quantity, original_quantity, price, 
         transaction_type, transaction_subtype, buy_sell, description, amount, currency, 
-        commission, order_id, exchange_rate, amount_eur, country_code, input_string, hash_id) 
-        VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+        commission, order_id, exchange_rate, amount_eur, country_code, input_string, hash_id, units) 
+        VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
     `)
 	if err != nil {
 		logger.L.Error("Failed to prepare statement", "error", err)
@@ -333,6 +359,7 @@ func (h *TransactionHandler) HandleAddManualTransaction(w http.ResponseWriter, r
 		countryCode,
 		sanitizedDescription,
 		hashId,
+		req.Quantity,
 	)
 
 	if err != nil {
@@ -344,6 +371,12 @@ func (h *TransactionHandler) HandleAddManualTransaction(w http.ResponseWriter, r
 	h.uploadService.InvalidateUserCache(userID, req.PortfolioID)
 	logger.L.Info("Manual transaction added", "userID", userID, "portfolioID", req.PortfolioID)
 
+	if err := model.MarkHistoryDirty(database.DB, req.PortfolioID, transactionDate.Format("2006-01-02")); err != nil {
+		logger.L.Error("Failed to mark portfolio history as stale", "portfolioID", req.PortfolioID, "error", err)
+	} else if _, err := h.queue.Enqueue(userID, req.PortfolioID, model.JobTypeRebuildHistory); err != nil {
+		logger.L.Error("Failed to enqueue history rebuild after manual transaction", "userID", userID, "portfolioID", req.PortfolioID, "error", err)
+	}
+
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(map[string]string{"message": "Transação adicionada com sucesso"})
 }
@@ -4,6 +4,7 @@ package handlers
 import (
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"strconv"
 	"strings"
@@ -17,6 +18,9 @@ import (
 	"github.com/username/taxfolio/backend/src/utils"
 )
 
+// maxFilesPerUpload caps the number of files of a single upload.
+const maxFilesPerUpload = 200
+
 type UploadHandler struct {
 	uploadService services.UploadService
 }
@@ -104,24 +108,48 @@ func (h *UploadHandler) HandleUpload(w http.ResponseWriter, r *http.Request) {
 	// Commit is technically not needed here if we don't write, but good practice to close the read transaction cleanly
 	tx.Commit()
 
-	file, fileHeader, err := r.FormFile("file")
-	if err != nil {
+	// Brokers that issue one document per event are uploaded as several "file" parts.
+	fileHeaders := r.MultipartForm.File["file"]
+	if len(fileHeaders) == 0 {
 		utils.SendJSONError(w, "File not found", http.StatusBadRequest)
 		return
 	}
-	defer file.Close()
-
-	// (Validate Content-Type and Magic Bytes here - existing code)
-	detectedContentType, err := validation.ValidateFileContentByMagicBytes(file)
-	if err != nil {
-		utils.SendJSONError(w, err.Error(), http.StatusBadRequest)
+	if len(fileHeaders) > maxFilesPerUpload {
+		utils.SendJSONError(w, fmt.Sprintf("Too many files (max %d per upload)", maxFilesPerUpload), http.StatusBadRequest)
 		return
 	}
-	logger.L.Debug("Content type validated", "type", detectedContentType)
 
-	result, err := h.uploadService.ProcessUpload(file, userID, portfolioID, source, fileHeader.Filename, fileHeader.Size)
+	var readers []io.Reader
+	var filenames []string
+	var totalSize int64
+	for _, fileHeader := range fileHeaders {
+		file, err := fileHeader.Open()
+		if err != nil {
+			utils.SendJSONError(w, "File not found", http.StatusBadRequest)
+			return
+		}
+		defer file.Close()
+
+		// Validate the content of every file by its magic bytes.
+		detectedContentType, err := validation.ValidateFileContentByMagicBytes(file)
+		if err != nil {
+			utils.SendJSONError(w, fmt.Sprintf("%s: %s", fileHeader.Filename, err.Error()), http.StatusBadRequest)
+			return
+		}
+		logger.L.Debug("Content type validated", "type", detectedContentType, "filename", fileHeader.Filename)
+
+		readers = append(readers, file)
+		filenames = append(filenames, fileHeader.Filename)
+		totalSize += fileHeader.Size
+	}
+	filename := filenames[0]
+	if len(filenames) > 1 {
+		filename = fmt.Sprintf("%s (+%d)", filenames[0], len(filenames)-1)
+	}
+
+	result, err := h.uploadService.ProcessUpload(readers, userID, portfolioID, source, filename, totalSize)
 	if err != nil {
-		go logUploadFailure(userID, source, fileHeader.Filename, err.Error())
+		go logUploadFailure(userID, source, filename, err.Error())
 		utils.SendJSONError(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -184,6 +212,15 @@ func (h *UploadHandler) HandleGetRealizedGainsData(w http.ResponseWriter, r *htt
 	if realizedgainsData.DividendTransactionsList == nil {
 		realizedgainsData.DividendTransactionsList = []models.ProcessedTransaction{}
 	}
+	if realizedgainsData.CryptoSaleDetails == nil {
+		realizedgainsData.CryptoSaleDetails = []models.CryptoSaleDetail{}
+	}
+	if realizedgainsData.CryptoHoldings == nil {
+		realizedgainsData.CryptoHoldings = []models.CryptoLot{}
+	}
+	if realizedgainsData.CryptoIncome == nil {
+		realizedgainsData.CryptoIncome = []models.CryptoIncome{}
+	}
 
 	currentETag, etagErr := utils.GenerateETag(realizedgainsData)
 	if etagErr != nil {
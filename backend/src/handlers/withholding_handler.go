@@ -0,0 +1,171 @@
+// backend/src/handlers/withholding_handler.go
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/username/taxfolio/backend/src/logger"
+	"github.com/username/taxfolio/backend/src/model"
+	"github.com/username/taxfolio/backend/src/models"
+	"github.com/username/taxfolio/backend/src/services"
+	"github.com/username/taxfolio/backend/src/utils"
+)
+
+type WithholdingHandler struct {
+	withholdingService services.WithholdingService
+}
+
+func NewWithholdingHandler(withholdingService services.WithholdingService) *WithholdingHandler {
+	return &WithholdingHandler{withholdingService: withholdingService}
+}
+
+// HandleGetReconciliation matches dividends to their withholding. Optional: year.
+func (h *WithholdingHandler) HandleGetReconciliation(w http.ResponseWriter, r *http.Request) {
+	userID, ok := GetUserIDFromContext(r.Context())
+	if !ok {
+		utils.SendJSONError(w, "authentication required", http.StatusUnauthorized)
+		return
+	}
+	portfolioID, err := getPortfolioID(r)
+	if err != nil {
+		utils.SendJSONError(w, "Invalid portfolio_id", http.StatusBadRequest)
+		return
+	}
+	year := 0
+	if v := r.URL.Query().Get("year"); v != "" {
+		if year, err = strconv.Atoi(v); err != nil || year < 1900 {
+			utils.SendJSONError(w, "Invalid year", http.StatusBadRequest)
+			return
+		}
+	}
+
+	result, err := h.withholdingService.GetReconciliation(userID, portfolioID, year)
+	if err != nil {
+		logger.L.Error("Error reconciling dividend withholding", "userID", userID, "portfolioID", portfolioID, "error", err)
+		utils.SendJSONError(w, "Error reconciling dividend withholding", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+func (h *WithholdingHandler) HandleListReclaims(w http.ResponseWriter, r *http.Request) {
+	userID, ok := GetUserIDFromContext(r.Context())
+	if !ok {
+		utils.SendJSONError(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+	portfolioID, err := urlParamInt64(r, "id")
+	if err != nil {
+		utils.SendJSONError(w, "Invalid portfolio id", http.StatusBadRequest)
+		return
+	}
+	reclaims, err := h.withholdingService.ListReclaims(userID, portfolioID)
+	if err != nil {
+		logger.L.Error("Failed to list reclaims", "userID", userID, "portfolioID", portfolioID, "error", err)
+		utils.SendJSONError(w, "Failed to retrieve reclaims", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(reclaims)
+}
+
+func (h *WithholdingHandler) HandleCreateReclaim(w http.ResponseWriter, r *http.Request) {
+	userID, ok := GetUserIDFromContext(r.Context())
+	if !ok {
+		utils.SendJSONError(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+	portfolioID, err := urlParamInt64(r, "id")
+	if err != nil {
+		utils.SendJSONError(w, "Invalid portfolio id", http.StatusBadRequest)
+		return
+	}
+	var req models.DividendReclaim
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.SendJSONError(w, "Invalid body", http.StatusBadRequest)
+		return
+	}
+	req.ID = 0
+	req.PortfolioID = portfolioID
+
+	reclaim, err := h.withholdingService.CreateReclaim(userID, req)
+	if err != nil {
+		h.sendReclaimError(w, err, userID, portfolioID)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(reclaim)
+}
+
+func (h *WithholdingHandler) HandleUpdateReclaim(w http.ResponseWriter, r *http.Request) {
+	userID, ok := GetUserIDFromContext(r.Context())
+	if !ok {
+		utils.SendJSONError(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+	portfolioID, err := urlParamInt64(r, "id")
+	if err != nil {
+		utils.SendJSONError(w, "Invalid portfolio id", http.StatusBadRequest)
+		return
+	}
+	reclaimID, err := urlParamInt64(r, "reclaimID")
+	if err != nil {
+		utils.SendJSONError(w, "Invalid reclaim id", http.StatusBadRequest)
+		return
+	}
+	var req models.DividendReclaim
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.SendJSONError(w, "Invalid body", http.StatusBadRequest)
+		return
+	}
+	req.ID = reclaimID
+	req.PortfolioID = portfolioID
+
+	reclaim, err := h.withholdingService.UpdateReclaim(userID, req)
+	if err != nil {
+		h.sendReclaimError(w, err, userID, portfolioID)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(reclaim)
+}
+
+func (h *WithholdingHandler) HandleDeleteReclaim(w http.ResponseWriter, r *http.Request) {
+	userID, ok := GetUserIDFromContext(r.Context())
+	if !ok {
+		utils.SendJSONError(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+	portfolioID, err := urlParamInt64(r, "id")
+	if err != nil {
+		utils.SendJSONError(w, "Invalid portfolio id", http.StatusBadRequest)
+		return
+	}
+	reclaimID, err := urlParamInt64(r, "reclaimID")
+	if err != nil {
+		utils.SendJSONError(w, "Invalid reclaim id", http.StatusBadRequest)
+		return
+	}
+	if err := h.withholdingService.DeleteReclaim(userID, portfolioID, reclaimID); err != nil {
+		h.sendReclaimError(w, err, userID, portfolioID)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *WithholdingHandler) sendReclaimError(w http.ResponseWriter, err error, userID, portfolioID int64) {
+	switch {
+	case errors.Is(err, services.ErrInvalidReclaim):
+		utils.SendJSONError(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, model.ErrReclaimNotFound), errors.Is(err, model.ErrPortfolioNotFound):
+		utils.SendJSONError(w, err.Error(), http.StatusNotFound)
+	default:
+		logger.L.Error("Reclaim operation failed", "userID", userID, "portfolioID", portfolioID, "error", err)
+		utils.SendJSONError(w, "Failed to save reclaim", http.StatusInternalServerError)
+	}
+}
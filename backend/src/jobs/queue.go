@@ -0,0 +1,270 @@
+// backend/src/jobs/queue.go
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/username/taxfolio/backend/src/database"
+	"github.com/username/taxfolio/backend/src/logger"
+	"github.com/username/taxfolio/backend/src/model"
+	"github.com/username/taxfolio/backend/src/services"
+)
+
+var (
+	ErrInvalidJobType    = errors.New("invalid job type")
+	ErrPortfolioNotFound = errors.New("portfolio not found")
+)
+
+// cancelCheckInterval controls how often a running job looks for a cancel request.
+const cancelCheckInterval = 2 * time.Second
+
+// Handler executes a single job. It must stop promptly when ctx is cancelled.
+type Handler func(ctx context.Context, job *model.BackgroundJob, progress services.ProgressFunc) error
+
+// Queue is a database-backed job queue drained by a fixed pool of workers.
+// Jobs survive restarts: anything left running is put back in the queue on Start.
+type Queue struct {
+	handlers     map[string]Handler
+	workers      int
+	pollInterval time.Duration
+	wake         chan struct{}
+	ctx          context.Context
+	cancel       context.CancelFunc
+	wg           sync.WaitGroup
+}
+
+// NewQueue creates a queue with the standard handlers for rebuilds, re-pricing, metric refreshes
+// and IBKR Flex imports.
+func NewQueue(uploadService services.UploadService, flexService services.IBKRFlexService, workers int, pollInterval time.Duration) *Queue {
+	if workers < 1 {
+		workers = 1
+	}
+	if pollInterval <= 0 {
+		pollInterval = 2 * time.Second
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	q := &Queue{
+		handlers:     make(map[string]Handler),
+		workers:      workers,
+		pollInterval: pollInterval,
+		wake:         make(chan struct{}, workers),
+		ctx:          ctx,
+		cancel:       cancel,
+	}
+
+	q.handlers[model.JobTypeRebuildHistory] = func(ctx context.Context, job *model.BackgroundJob, progress services.ProgressFunc) error {
+		progress(0, "Updating portfolio metrics")
+		if err := uploadService.UpdateUserPortfolioMetrics(job.UserID, job.PortfolioID); err != nil {
+			// Metrics are only used by the admin dashboard; the rebuild can still proceed.
+			logger.L.Warn("Failed to update user portfolio metrics before rebuild", "userID", job.UserID, "error", err)
+		}
+		return uploadService.RebuildUserHistoryWithProgress(ctx, job.UserID, job.PortfolioID, progress)
+	}
+	q.handlers[model.JobTypeReprice] = func(ctx context.Context, job *model.BackgroundJob, progress services.ProgressFunc) error {
+		progress(10, "Refreshing prices")
+		return uploadService.RepricePortfolio(ctx, job.UserID, job.PortfolioID)
+	}
+	q.handlers[model.JobTypeRefreshMetrics] = func(ctx context.Context, job *model.BackgroundJob, progress services.ProgressFunc) error {
+		progress(10, "Updating portfolio metrics")
+		return uploadService.UpdateUserPortfolioMetrics(job.UserID, job.PortfolioID)
+	}
+	q.handlers[model.JobTypeIBKRFlexSync] = func(ctx context.Context, job *model.BackgroundJob, progress services.ProgressFunc) error {
+		progress(10, "Downloading the IBKR Flex statement")
+		_, err := flexService.Sync(ctx, job.UserID, job.PortfolioID)
+		return err
+	}
+	return q
+}
+
+// Start requeues interrupted jobs and launches the worker pool.
+func (q *Queue) Start() {
+	if n, err := model.RequeueInterruptedJobs(database.DB); err != nil {
+		logger.L.Error("Failed to requeue interrupted background jobs", "error", err)
+	} else if n > 0 {
+		logger.L.Info("Requeued interrupted background jobs", "count", n)
+	}
+
+	for i := 0; i < q.workers; i++ {
+		q.wg.Add(1)
+		go q.worker(i)
+	}
+	logger.L.Info("Job queue started", "workers", q.workers)
+}
+
+// Stop cancels running jobs and waits for the workers to exit. Cancelled jobs are
+// requeued on the next Start.
+func (q *Queue) Stop() {
+	q.cancel()
+	q.wg.Wait()
+	logger.L.Info("Job queue stopped")
+}
+
+// Enqueue adds a job for a portfolio owned by userID and wakes an idle worker. A history
+// rebuild only rewrites the snapshots from the date its caller marked with
+// model.MarkHistoryDirty, or those after the last stored snapshot.
+func (q *Queue) Enqueue(userID, portfolioID int64, jobType string) (*model.BackgroundJob, error) {
+	if err := q.checkJob(userID, portfolioID, jobType); err != nil {
+		return nil, err
+	}
+	return q.enqueue(userID, portfolioID, jobType)
+}
+
+// EnqueueRequested adds a job the user asked for. A requested history rebuild recomputes
+// the whole history.
+func (q *Queue) EnqueueRequested(userID, portfolioID int64, jobType string) (*model.BackgroundJob, error) {
+	if err := q.checkJob(userID, portfolioID, jobType); err != nil {
+		return nil, err
+	}
+	if jobType == model.JobTypeRebuildHistory {
+		if err := model.MarkHistoryDirty(database.DB, portfolioID, model.FullHistoryRebuild); err != nil {
+			return nil, fmt.Errorf("failed to mark portfolio history as stale: %w", err)
+		}
+	}
+	return q.enqueue(userID, portfolioID, jobType)
+}
+
+// checkJob validates the job type and that the portfolio belongs to userID.
+func (q *Queue) checkJob(userID, portfolioID int64, jobType string) error {
+	if _, ok := q.handlers[jobType]; !ok {
+		return ErrInvalidJobType
+	}
+
+	var exists bool
+	err := database.DB.QueryRow("SELECT EXISTS(SELECT 1 FROM portfolios WHERE id = ? AND user_id = ?)", portfolioID, userID).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("failed to verify portfolio ownership: %w", err)
+	}
+	if !exists {
+		return ErrPortfolioNotFound
+	}
+	return nil
+}
+
+func (q *Queue) enqueue(userID, portfolioID int64, jobType string) (*model.BackgroundJob, error) {
+	job, err := model.EnqueueJob(database.DB, userID, portfolioID, jobType)
+	if err != nil {
+		return nil, fmt.Errorf("failed to enqueue job: %w", err)
+	}
+	q.notify()
+	return job, nil
+}
+
+// Get returns a job owned by userID.
+func (q *Queue) Get(userID, jobID int64) (*model.BackgroundJob, error) {
+	return model.GetJobForUser(database.DB, jobID, userID)
+}
+
+// List returns the latest jobs of a user, optionally restricted to one portfolio.
+func (q *Queue) List(userID, portfolioID int64, limit int) ([]model.BackgroundJob, error) {
+	return model.ListJobsForUser(database.DB, userID, portfolioID, limit)
+}
+
+// Cancel cancels a queued job immediately; a running job stops at its next checkpoint.
+func (q *Queue) Cancel(userID, jobID int64) (*model.BackgroundJob, error) {
+	return model.RequestJobCancel(database.DB, jobID, userID)
+}
+
+func (q *Queue) notify() {
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (q *Queue) worker(id int) {
+	defer q.wg.Done()
+	ticker := time.NewTicker(q.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		// Drain the queue before going back to sleep.
+		for q.ctx.Err() == nil {
+			job, err := model.ClaimNextJob(database.DB)
+			if err != nil {
+				logger.L.Error("Failed to claim background job", "worker", id, "error", err)
+				break
+			}
+			if job == nil {
+				break
+			}
+			q.run(job)
+		}
+
+		select {
+		case <-q.ctx.Done():
+			return
+		case <-q.wake:
+		case <-ticker.C:
+		}
+	}
+}
+
+func (q *Queue) run(job *model.BackgroundJob) {
+	handler, ok := q.handlers[job.JobType]
+	if !ok {
+		model.FinishJob(database.DB, job.ID, model.JobStatusFailed, "", ErrInvalidJobType.Error())
+		return
+	}
+
+	startedAt := time.Now()
+	logger.L.Info("Background job started", "jobID", job.ID, "type", job.JobType, "userID", job.UserID, "portfolioID", job.PortfolioID)
+
+	ctx, cancel := context.WithCancel(q.ctx)
+	defer cancel()
+	go q.watchCancellation(ctx, cancel, job.ID)
+
+	progress := func(percent int, message string) {
+		if err := model.UpdateJobProgress(database.DB, job.ID, percent, message); err != nil {
+			logger.L.Warn("Failed to record job progress", "jobID", job.ID, "error", err)
+		}
+	}
+
+	err := runSafely(ctx, handler, job, progress)
+
+	switch {
+	case err == nil:
+		model.FinishJob(database.DB, job.ID, model.JobStatusCompleted, "Done", "")
+		logger.L.Info("Background job completed", "jobID", job.ID, "type", job.JobType, "duration", time.Since(startedAt))
+	case q.ctx.Err() != nil:
+		// Server shutdown: leave the job as running so that it is requeued on restart.
+		logger.L.Info("Background job interrupted by shutdown", "jobID", job.ID, "type", job.JobType)
+	case errors.Is(err, context.Canceled):
+		model.FinishJob(database.DB, job.ID, model.JobStatusCancelled, "Cancelled", "")
+		logger.L.Info("Background job cancelled", "jobID", job.ID, "type", job.JobType)
+	default:
+		model.FinishJob(database.DB, job.ID, model.JobStatusFailed, "Failed", err.Error())
+		logger.L.Error("Background job failed", "jobID", job.ID, "type", job.JobType, "duration", time.Since(startedAt), "error", err)
+	}
+}
+
+// watchCancellation polls the job row and cancels ctx once a cancel request is recorded.
+func (q *Queue) watchCancellation(ctx context.Context, cancel context.CancelFunc, jobID int64) {
+	ticker := time.NewTicker(cancelCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			requested, err := model.IsJobCancelRequested(database.DB, jobID)
+			if err == nil && requested {
+				cancel()
+				return
+			}
+		}
+	}
+}
+
+// runSafely protects the worker from panics inside a job handler.
+func runSafely(ctx context.Context, handler Handler, job *model.BackgroundJob, progress services.ProgressFunc) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("job panicked: %v", r)
+		}
+	}()
+	return handler(ctx, job, progress)
+}
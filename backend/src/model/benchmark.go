@@ -0,0 +1,98 @@
+package model
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/username/taxfolio/backend/src/models"
+)
+
+var ErrBenchmarkNotFound = errors.New("benchmark not found")
+
+func scanBenchmark(row rowScanner) (*models.Benchmark, error) {
+	var b models.Benchmark
+	var componentsJSON string
+	if err := row.Scan(&b.ID, &b.PortfolioID, &b.Name, &componentsJSON, &b.Rebalance); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(componentsJSON), &b.Components); err != nil {
+		return nil, fmt.Errorf("invalid components for benchmark %d: %w", b.ID, err)
+	}
+	return &b, nil
+}
+
+// GetBenchmarksByPortfolio returns the benchmarks configured for a portfolio, oldest first.
+func GetBenchmarksByPortfolio(db *sql.DB, userID, portfolioID int64) ([]models.Benchmark, error) {
+	rows, err := db.Query(`
+		SELECT id, portfolio_id, name, components, rebalance
+		FROM portfolio_benchmarks
+		WHERE user_id = ? AND portfolio_id = ?
+		ORDER BY id ASC`, userID, portfolioID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	benchmarks := []models.Benchmark{}
+	for rows.Next() {
+		b, err := scanBenchmark(rows)
+		if err != nil {
+			return nil, err
+		}
+		benchmarks = append(benchmarks, *b)
+	}
+	return benchmarks, rows.Err()
+}
+
+// InsertBenchmark stores a new benchmark and sets its ID.
+func InsertBenchmark(db *sql.DB, userID int64, b *models.Benchmark) error {
+	componentsJSON, err := json.Marshal(b.Components)
+	if err != nil {
+		return err
+	}
+	res, err := db.Exec(`
+		INSERT INTO portfolio_benchmarks (user_id, portfolio_id, name, components, rebalance)
+		VALUES (?, ?, ?, ?, ?)`,
+		userID, b.PortfolioID, b.Name, string(componentsJSON), b.Rebalance,
+	)
+	if err != nil {
+		return err
+	}
+	b.ID, err = res.LastInsertId()
+	return err
+}
+
+// UpdateBenchmark replaces the definition of an existing benchmark.
+func UpdateBenchmark(db *sql.DB, userID int64, b *models.Benchmark) error {
+	componentsJSON, err := json.Marshal(b.Components)
+	if err != nil {
+		return err
+	}
+	res, err := db.Exec(`
+		UPDATE portfolio_benchmarks
+		SET name = ?, components = ?, rebalance = ?, updated_at = CURRENT_TIMESTAMP
+		WHERE id = ? AND user_id = ? AND portfolio_id = ?`,
+		b.Name, string(componentsJSON), b.Rebalance, b.ID, userID, b.PortfolioID,
+	)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrBenchmarkNotFound
+	}
+	return nil
+}
+
+// DeleteBenchmark removes a benchmark from a portfolio.
+func DeleteBenchmark(db *sql.DB, userID, portfolioID, benchmarkID int64) error {
+	res, err := db.Exec(`DELETE FROM portfolio_benchmarks WHERE id = ? AND user_id = ? AND portfolio_id = ?`, benchmarkID, userID, portfolioID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrBenchmarkNotFound
+	}
+	return nil
+}
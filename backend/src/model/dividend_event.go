@@ -0,0 +1,82 @@
+package model
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/username/taxfolio/backend/src/models"
+)
+
+// GetDividendEvents returns the stored events of a ticker with an ex-date on or after `from` (YYYY-MM-DD), oldest first.
+func GetDividendEvents(db *sql.DB, ticker, from string) ([]models.DividendEvent, error) {
+	rows, err := db.Query(`
+		SELECT ticker_symbol, ex_date, pay_date, amount, currency, source
+		FROM dividend_events
+		WHERE ticker_symbol = ? AND ex_date >= ?
+		ORDER BY ex_date ASC`, ticker, from)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := []models.DividendEvent{}
+	for rows.Next() {
+		var e models.DividendEvent
+		var payDate sql.NullString
+		if err := rows.Scan(&e.Ticker, &e.ExDate, &payDate, &e.Amount, &e.Currency, &e.Source); err != nil {
+			return nil, err
+		}
+		e.PayDate = payDate.String
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
+
+// ReplaceDividendEvents stores freshly fetched events of a ticker and records the fetch.
+// Announced events that are no longer reported are removed; a known pay date is kept
+// when the provider stops reporting it.
+func ReplaceDividendEvents(db *sql.DB, ticker string, events []models.DividendEvent) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM dividend_events WHERE ticker_symbol = ? AND source = ?`, ticker, models.DividendEventAnnounced); err != nil {
+		return err
+	}
+	stmt, err := tx.Prepare(`
+		INSERT INTO dividend_events (ticker_symbol, ex_date, pay_date, amount, currency, source, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP)
+		ON CONFLICT(ticker_symbol, ex_date) DO UPDATE SET
+			pay_date = COALESCE(excluded.pay_date, dividend_events.pay_date),
+			amount = excluded.amount,
+			currency = excluded.currency,
+			source = excluded.source,
+			updated_at = CURRENT_TIMESTAMP`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	for _, e := range events {
+		if _, err := stmt.Exec(ticker, e.ExDate, nullableString(e.PayDate), e.Amount, e.Currency, e.Source); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.Exec(`
+		INSERT INTO dividend_event_fetches (ticker_symbol, fetched_at) VALUES (?, ?)
+		ON CONFLICT(ticker_symbol) DO UPDATE SET fetched_at = excluded.fetched_at`, ticker, time.Now().UTC()); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// GetDividendEventsFetchedAt returns when the events of a ticker were last fetched (zero if never).
+func GetDividendEventsFetchedAt(db *sql.DB, ticker string) (time.Time, error) {
+	var fetchedAt time.Time
+	err := db.QueryRow(`SELECT fetched_at FROM dividend_event_fetches WHERE ticker_symbol = ?`, ticker).Scan(&fetchedAt)
+	if err == sql.ErrNoRows {
+		return time.Time{}, nil
+	}
+	return fetchedAt, err
+}
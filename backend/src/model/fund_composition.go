@@ -0,0 +1,119 @@
+package model
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/username/taxfolio/backend/src/models"
+)
+
+// ProviderUserID marks fund composition rows fetched from the market data provider.
+const ProviderUserID = 0
+
+const (
+	CompositionSourceProvider = "provider"
+	CompositionSourceUser     = "user"
+)
+
+var ErrFundCompositionNotFound = errors.New("fund composition not found")
+
+// GetFundCompositions returns the compositions of the given funds, keyed by ISIN and then
+// dimension. A user's own composition replaces the provider's for the same dimension.
+func GetFundCompositions(db *sql.DB, userID int64, isins []string) (map[string]map[string]models.FundComposition, error) {
+	result := make(map[string]map[string]models.FundComposition)
+	if len(isins) == 0 {
+		return result, nil
+	}
+	args := make([]interface{}, 0, len(isins)+2)
+	args = append(args, ProviderUserID, userID)
+	for _, isin := range isins {
+		args = append(args, isin)
+	}
+	rows, err := db.Query(`
+		SELECT fund_isin, user_id, dimension, bucket, weight, source, updated_at
+		FROM fund_compositions
+		WHERE user_id IN (?, ?) AND fund_isin IN (?`+strings.Repeat(",?", len(isins)-1)+`)
+		ORDER BY user_id ASC`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	// Provider rows come first; the first user row for a dimension discards them.
+	owner := make(map[string]int64)
+	for rows.Next() {
+		var isin, dimension, bucket, source string
+		var rowUserID int64
+		var weight float64
+		var updatedAt time.Time
+		if err := rows.Scan(&isin, &rowUserID, &dimension, &bucket, &weight, &source, &updatedAt); err != nil {
+			return nil, err
+		}
+		if result[isin] == nil {
+			result[isin] = make(map[string]models.FundComposition)
+		}
+		key := isin + "|" + dimension
+		composition, exists := result[isin][dimension]
+		if !exists || owner[key] != rowUserID {
+			composition = models.FundComposition{
+				FundISIN:  isin,
+				Dimension: dimension,
+				Source:    source,
+				Weights:   make(map[string]float64),
+				UpdatedAt: updatedAt.Format(time.RFC3339),
+			}
+			owner[key] = rowUserID
+		}
+		composition.Weights[bucket] = weight
+		result[isin][dimension] = composition
+	}
+	return result, rows.Err()
+}
+
+// ReplaceFundComposition stores the weights of one fund and dimension, replacing any
+// previous rows owned by the same user (or by the provider when userID is ProviderUserID).
+func ReplaceFundComposition(db *sql.DB, userID int64, c models.FundComposition) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM fund_compositions WHERE fund_isin = ? AND user_id = ? AND dimension = ?`, c.FundISIN, userID, c.Dimension); err != nil {
+		return err
+	}
+	stmt, err := tx.Prepare(`
+		INSERT INTO fund_compositions (fund_isin, user_id, dimension, bucket, weight, source, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP)`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	for bucket, weight := range c.Weights {
+		if _, err := stmt.Exec(c.FundISIN, userID, c.Dimension, bucket, weight, c.Source); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// DeleteFundComposition removes a user's composition for one dimension, or for all
+// dimensions when dimension is empty.
+func DeleteFundComposition(db *sql.DB, userID int64, isin, dimension string) error {
+	query := `DELETE FROM fund_compositions WHERE fund_isin = ? AND user_id = ?`
+	args := []interface{}{isin, userID}
+	if dimension != "" {
+		query += ` AND dimension = ?`
+		args = append(args, dimension)
+	}
+	res, err := db.Exec(query, args...)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrFundCompositionNotFound
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package model
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/username/taxfolio/backend/src/models"
+)
+
+var ErrIBKRFlexConnectionNotFound = errors.New("ibkr flex connection not found")
+
+const ibkrFlexConnectionColumns = `portfolio_id, user_id, token, query_id, enabled, last_sync_at, last_success_at, last_error`
+
+func scanIBKRFlexConnection(row rowScanner) (*models.IBKRFlexConnection, error) {
+	var c models.IBKRFlexConnection
+	var lastSyncAt, lastSuccessAt sql.NullTime
+	if err := row.Scan(&c.PortfolioID, &c.UserID, &c.Token, &c.QueryID, &c.Enabled, &lastSyncAt, &lastSuccessAt, &c.LastError); err != nil {
+		return nil, err
+	}
+	if lastSyncAt.Valid {
+		t := lastSyncAt.Time
+		c.LastSyncAt = &t
+	}
+	if lastSuccessAt.Valid {
+		t := lastSuccessAt.Time
+		c.LastSuccessAt = &t
+	}
+	return &c, nil
+}
+
+// GetIBKRFlexConnection returns the Flex connection of a portfolio.
+func GetIBKRFlexConnection(db *sql.DB, userID, portfolioID int64) (*models.IBKRFlexConnection, error) {
+	row := db.QueryRow(`SELECT `+ibkrFlexConnectionColumns+`
+		FROM ibkr_flex_connections
+		WHERE user_id = ? AND portfolio_id = ?`, userID, portfolioID)
+	c, err := scanIBKRFlexConnection(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrIBKRFlexConnectionNotFound
+	}
+	return c, err
+}
+
+// GetEnabledIBKRFlexConnections returns the connections included in the scheduled import.
+func GetEnabledIBKRFlexConnections(db *sql.DB) ([]models.IBKRFlexConnection, error) {
+	rows, err := db.Query(`SELECT ` + ibkrFlexConnectionColumns + `
+		FROM ibkr_flex_connections
+		WHERE enabled = 1
+		ORDER BY user_id, portfolio_id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var connections []models.IBKRFlexConnection
+	for rows.Next() {
+		c, err := scanIBKRFlexConnection(rows)
+		if err != nil {
+			return nil, err
+		}
+		connections = append(connections, *c)
+	}
+	return connections, rows.Err()
+}
+
+// UpsertIBKRFlexConnection stores the credentials of a portfolio, replacing any previous
+// ones. The sync status is cleared as it belonged to the old credentials.
+func UpsertIBKRFlexConnection(db *sql.DB, c *models.IBKRFlexConnection) error {
+	_, err := db.Exec(`
+		INSERT INTO ibkr_flex_connections (portfolio_id, user_id, token, query_id, enabled)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(portfolio_id) DO UPDATE SET
+			token = excluded.token,
+			query_id = excluded.query_id,
+			enabled = excluded.enabled,
+			last_sync_at = NULL,
+			last_success_at = NULL,
+			last_error = '',
+			updated_at = CURRENT_TIMESTAMP`,
+		c.PortfolioID, c.UserID, c.Token, c.QueryID, c.Enabled,
+	)
+	if err != nil {
+		return err
+	}
+	c.LastSyncAt, c.LastSuccessAt, c.LastError = nil, nil, ""
+	return nil
+}
+
+// RecordIBKRFlexSync stores the outcome of an import attempt. An empty syncErr marks it
+// as successful.
+func RecordIBKRFlexSync(db *sql.DB, portfolioID int64, at time.Time, syncErr string) error {
+	if syncErr == "" {
+		_, err := db.Exec(`
+			UPDATE ibkr_flex_connections
+			SET last_sync_at = ?, last_success_at = ?, last_error = ''
+			WHERE portfolio_id = ?`, at, at, portfolioID)
+		return err
+	}
+	_, err := db.Exec(`
+		UPDATE ibkr_flex_connections
+		SET last_sync_at = ?, last_error = ?
+		WHERE portfolio_id = ?`, at, syncErr, portfolioID)
+	return err
+}
+
+// DeleteIBKRFlexConnection removes the credentials of a portfolio.
+func DeleteIBKRFlexConnection(db *sql.DB, userID, portfolioID int64) error {
+	res, err := db.Exec(`DELETE FROM ibkr_flex_connections WHERE user_id = ? AND portfolio_id = ?`, userID, portfolioID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrIBKRFlexConnectionNotFound
+	}
+	return nil
+}
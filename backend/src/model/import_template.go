@@ -0,0 +1,127 @@
+package model
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/username/taxfolio/backend/src/models"
+)
+
+var ErrImportTemplateNotFound = errors.New("import template not found")
+
+func scanImportTemplate(row rowScanner) (*models.ImportTemplate, error) {
+	var t models.ImportTemplate
+	var columnsJSON, typeMappingJSON string
+	if err := row.Scan(&t.ID, &t.Name, &t.Delimiter, &t.DecimalSeparator, &t.DateFormat, &t.DefaultCurrency,
+		&columnsJSON, &typeMappingJSON); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(columnsJSON), &t.Columns); err != nil {
+		return nil, fmt.Errorf("invalid columns for import template %d: %w", t.ID, err)
+	}
+	if err := json.Unmarshal([]byte(typeMappingJSON), &t.TypeMapping); err != nil {
+		return nil, fmt.Errorf("invalid type mapping for import template %d: %w", t.ID, err)
+	}
+	return &t, nil
+}
+
+func marshalImportTemplate(t *models.ImportTemplate) (string, string, error) {
+	columnsJSON, err := json.Marshal(t.Columns)
+	if err != nil {
+		return "", "", err
+	}
+	typeMappingJSON, err := json.Marshal(t.TypeMapping)
+	if err != nil {
+		return "", "", err
+	}
+	return string(columnsJSON), string(typeMappingJSON), nil
+}
+
+// GetImportTemplates returns the import templates of a user, by name.
+func GetImportTemplates(db *sql.DB, userID int64) ([]models.ImportTemplate, error) {
+	rows, err := db.Query(`
+		SELECT id, name, delimiter, decimal_separator, date_format, default_currency, columns, type_mapping
+		FROM import_templates
+		WHERE user_id = ?
+		ORDER BY name ASC`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	templates := []models.ImportTemplate{}
+	for rows.Next() {
+		t, err := scanImportTemplate(rows)
+		if err != nil {
+			return nil, err
+		}
+		templates = append(templates, *t)
+	}
+	return templates, rows.Err()
+}
+
+// GetImportTemplate returns one import template of a user.
+func GetImportTemplate(db *sql.DB, userID, templateID int64) (*models.ImportTemplate, error) {
+	row := db.QueryRow(`
+		SELECT id, name, delimiter, decimal_separator, date_format, default_currency, columns, type_mapping
+		FROM import_templates
+		WHERE id = ? AND user_id = ?`, templateID, userID)
+	t, err := scanImportTemplate(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrImportTemplateNotFound
+	}
+	return t, err
+}
+
+// InsertImportTemplate stores a new import template and sets its ID.
+func InsertImportTemplate(db *sql.DB, userID int64, t *models.ImportTemplate) error {
+	columnsJSON, typeMappingJSON, err := marshalImportTemplate(t)
+	if err != nil {
+		return err
+	}
+	res, err := db.Exec(`
+		INSERT INTO import_templates (user_id, name, delimiter, decimal_separator, date_format, default_currency, columns, type_mapping)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		userID, t.Name, t.Delimiter, t.DecimalSeparator, t.DateFormat, t.DefaultCurrency, columnsJSON, typeMappingJSON,
+	)
+	if err != nil {
+		return err
+	}
+	t.ID, err = res.LastInsertId()
+	return err
+}
+
+// UpdateImportTemplate replaces the definition of an existing import template.
+func UpdateImportTemplate(db *sql.DB, userID int64, t *models.ImportTemplate) error {
+	columnsJSON, typeMappingJSON, err := marshalImportTemplate(t)
+	if err != nil {
+		return err
+	}
+	res, err := db.Exec(`
+		UPDATE import_templates
+		SET name = ?, delimiter = ?, decimal_separator = ?, date_format = ?, default_currency = ?, columns = ?, type_mapping = ?, updated_at = CURRENT_TIMESTAMP
+		WHERE id = ? AND user_id = ?`,
+		t.Name, t.Delimiter, t.DecimalSeparator, t.DateFormat, t.DefaultCurrency, columnsJSON, typeMappingJSON, t.ID, userID,
+	)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrImportTemplateNotFound
+	}
+	return nil
+}
+
+// DeleteImportTemplate removes an import template. Transactions imported with it are kept.
+func DeleteImportTemplate(db *sql.DB, userID, templateID int64) error {
+	res, err := db.Exec(`DELETE FROM import_templates WHERE id = ? AND user_id = ?`, templateID, userID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrImportTemplateNotFound
+	}
+	return nil
+}
@@ -0,0 +1,269 @@
+package model
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// Background job types processed by the worker pool.
+const (
+	JobTypeRebuildHistory = "rebuild_history"
+	JobTypeReprice        = "reprice"
+	JobTypeRefreshMetrics = "refresh_metrics"
+	JobTypeIBKRFlexSync   = "ibkr_flex_sync"
+)
+
+// Background job statuses.
+const (
+	JobStatusQueued    = "queued"
+	JobStatusRunning   = "running"
+	JobStatusCompleted = "completed"
+	JobStatusFailed    = "failed"
+	JobStatusCancelled = "cancelled"
+)
+
+var ErrJobNotFound = errors.New("background job not found")
+
+// BackgroundJob represents a row in the background_jobs table.
+type BackgroundJob struct {
+	ID              int64      `json:"id"`
+	UserID          int64      `json:"user_id"`
+	PortfolioID     int64      `json:"portfolio_id"`
+	JobType         string     `json:"job_type"`
+	Status          string     `json:"status"`
+	Progress        int        `json:"progress"`
+	Message         string     `json:"message"`
+	Error           string     `json:"error"`
+	CancelRequested bool       `json:"cancel_requested"`
+	CreatedAt       time.Time  `json:"created_at"`
+	StartedAt       *time.Time `json:"started_at"`
+	FinishedAt      *time.Time `json:"finished_at"`
+}
+
+// IsValidJobType reports whether jobType is one of the known job types.
+func IsValidJobType(jobType string) bool {
+	switch jobType {
+	case JobTypeRebuildHistory, JobTypeReprice, JobTypeRefreshMetrics, JobTypeIBKRFlexSync:
+		return true
+	}
+	return false
+}
+
+const backgroundJobColumns = `id, user_id, portfolio_id, job_type, status, progress, message, error,
+	cancel_requested, created_at, started_at, finished_at`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanBackgroundJob(row rowScanner) (*BackgroundJob, error) {
+	var job BackgroundJob
+	var message, errMsg sql.NullString
+	var startedAt, finishedAt sql.NullTime
+	err := row.Scan(&job.ID, &job.UserID, &job.PortfolioID, &job.JobType, &job.Status, &job.Progress,
+		&message, &errMsg, &job.CancelRequested, &job.CreatedAt, &startedAt, &finishedAt)
+	if err != nil {
+		return nil, err
+	}
+	job.Message = message.String
+	job.Error = errMsg.String
+	if startedAt.Valid {
+		t := startedAt.Time
+		job.StartedAt = &t
+	}
+	if finishedAt.Valid {
+		t := finishedAt.Time
+		job.FinishedAt = &t
+	}
+	return &job, nil
+}
+
+// EnqueueJob adds a job to the queue. If an identical job for the same portfolio is
+// still queued, that job is returned instead of creating a duplicate. A running job does
+// not count as a duplicate: it may have read the portfolio before the change that asks for
+// the new job, so exactly one follow-up is queued behind it.
+func EnqueueJob(db *sql.DB, userID, portfolioID int64, jobType string) (*BackgroundJob, error) {
+	// Checking and inserting in one statement keeps concurrent requests from queueing twice.
+	res, err := db.Exec(`
+		INSERT INTO background_jobs (user_id, portfolio_id, job_type, status, message)
+		SELECT ?, ?, ?, ?, ?
+		WHERE NOT EXISTS (
+			SELECT 1 FROM background_jobs
+			WHERE user_id = ? AND portfolio_id = ? AND job_type = ? AND status = ?
+		)`,
+		userID, portfolioID, jobType, JobStatusQueued, "Waiting for a worker",
+		userID, portfolioID, jobType, JobStatusQueued,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return nil, err
+	} else if n == 0 {
+		existing, err := scanBackgroundJob(db.QueryRow(`
+			SELECT `+backgroundJobColumns+` FROM background_jobs
+			WHERE user_id = ? AND portfolio_id = ? AND job_type = ? AND status = ?
+			ORDER BY id DESC LIMIT 1`,
+			userID, portfolioID, jobType, JobStatusQueued,
+		))
+		if err == sql.ErrNoRows {
+			// Claimed by a worker in between: queue the follow-up after all.
+			return EnqueueJob(db, userID, portfolioID, jobType)
+		}
+		return existing, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+	return GetJobByID(db, id)
+}
+
+// GetJobByID fetches a single job regardless of owner.
+func GetJobByID(db *sql.DB, id int64) (*BackgroundJob, error) {
+	job, err := scanBackgroundJob(db.QueryRow(`SELECT `+backgroundJobColumns+` FROM background_jobs WHERE id = ?`, id))
+	if err == sql.ErrNoRows {
+		return nil, ErrJobNotFound
+	}
+	return job, err
+}
+
+// GetJobForUser fetches a job only if it belongs to userID.
+func GetJobForUser(db *sql.DB, id, userID int64) (*BackgroundJob, error) {
+	job, err := scanBackgroundJob(db.QueryRow(`SELECT `+backgroundJobColumns+` FROM background_jobs WHERE id = ? AND user_id = ?`, id, userID))
+	if err == sql.ErrNoRows {
+		return nil, ErrJobNotFound
+	}
+	return job, err
+}
+
+// ListJobsForUser returns the most recent jobs of a user, optionally filtered by portfolio (portfolioID > 0).
+func ListJobsForUser(db *sql.DB, userID, portfolioID int64, limit int) ([]BackgroundJob, error) {
+	query := `SELECT ` + backgroundJobColumns + ` FROM background_jobs WHERE user_id = ?`
+	args := []interface{}{userID}
+	if portfolioID > 0 {
+		query += ` AND portfolio_id = ?`
+		args = append(args, portfolioID)
+	}
+	query += ` ORDER BY id DESC LIMIT ?`
+	args = append(args, limit)
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	jobs := []BackgroundJob{}
+	for rows.Next() {
+		job, err := scanBackgroundJob(rows)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, *job)
+	}
+	return jobs, rows.Err()
+}
+
+// ClaimNextJob atomically marks the oldest queued job as running and returns it.
+// Jobs of a portfolio run one at a time, so portfolios with a running job are skipped.
+// It returns (nil, nil) when no job can be started.
+func ClaimNextJob(db *sql.DB) (*BackgroundJob, error) {
+	job, err := scanBackgroundJob(db.QueryRow(`
+		UPDATE background_jobs
+		SET status = ?, started_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP, message = 'Started'
+		WHERE id = (
+			SELECT id FROM background_jobs
+			WHERE status = ?
+			  AND portfolio_id NOT IN (SELECT portfolio_id FROM background_jobs WHERE status = ?)
+			ORDER BY id LIMIT 1)
+		  AND status = ?
+		RETURNING `+backgroundJobColumns,
+		JobStatusRunning, JobStatusQueued, JobStatusRunning, JobStatusQueued,
+	))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return job, err
+}
+
+// UpdateJobProgress records the progress (0-100) and a short message for a running job.
+func UpdateJobProgress(db *sql.DB, id int64, progress int, message string) error {
+	_, err := db.Exec(`
+		UPDATE background_jobs SET progress = ?, message = ?, updated_at = CURRENT_TIMESTAMP
+		WHERE id = ? AND status = ?`,
+		progress, message, id, JobStatusRunning,
+	)
+	return err
+}
+
+// FinishJob moves a job into a terminal state.
+func FinishJob(db *sql.DB, id int64, status, message, errMsg string) error {
+	progressClause := "progress"
+	if status == JobStatusCompleted {
+		progressClause = "100"
+	}
+	_, err := db.Exec(`
+		UPDATE background_jobs
+		SET status = ?, message = ?, error = ?, progress = `+progressClause+`,
+			finished_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+		WHERE id = ?`,
+		status, message, errMsg, id,
+	)
+	return err
+}
+
+// RequestJobCancel cancels a queued job immediately or flags a running job for cancellation.
+// It returns the updated job, or ErrJobNotFound if the job does not belong to userID.
+func RequestJobCancel(db *sql.DB, id, userID int64) (*BackgroundJob, error) {
+	_, err := db.Exec(`
+		UPDATE background_jobs
+		SET status = ?, message = 'Cancelled before start', finished_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+		WHERE id = ? AND user_id = ? AND status = ?`,
+		JobStatusCancelled, id, userID, JobStatusQueued,
+	)
+	if err != nil {
+		return nil, err
+	}
+	_, err = db.Exec(`
+		UPDATE background_jobs SET cancel_requested = TRUE, updated_at = CURRENT_TIMESTAMP
+		WHERE id = ? AND user_id = ? AND status = ?`,
+		id, userID, JobStatusRunning,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return GetJobForUser(db, id, userID)
+}
+
+// IsJobCancelRequested reports whether a cancellation was requested for a job.
+func IsJobCancelRequested(db *sql.DB, id int64) (bool, error) {
+	var requested bool
+	err := db.QueryRow(`SELECT cancel_requested FROM background_jobs WHERE id = ?`, id).Scan(&requested)
+	return requested, err
+}
+
+// RequeueInterruptedJobs puts jobs left "running" by a previous process back in the queue.
+func RequeueInterruptedJobs(db *sql.DB) (int64, error) {
+	// Jobs the user already asked to cancel are not worth restarting.
+	_, err := db.Exec(`
+		UPDATE background_jobs
+		SET status = ?, message = 'Cancelled', finished_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+		WHERE status = ? AND cancel_requested = TRUE`,
+		JobStatusCancelled, JobStatusRunning,
+	)
+	if err != nil {
+		return 0, err
+	}
+	res, err := db.Exec(`
+		UPDATE background_jobs
+		SET status = ?, progress = 0, message = 'Requeued after server restart', started_at = NULL, updated_at = CURRENT_TIMESTAMP
+		WHERE status = ?`,
+		JobStatusQueued, JobStatusRunning,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
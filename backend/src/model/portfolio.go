@@ -0,0 +1,63 @@
+package model
+
+import (
+	"database/sql"
+	"errors"
+)
+
+// FullHistoryRebuild can be passed to MarkHistoryDirty to force every snapshot to be recomputed.
+const FullHistoryRebuild = "0001-01-01"
+
+var ErrPortfolioNotFound = errors.New("portfolio not found")
+
+// Execer is satisfied by both *sql.DB and *sql.Tx so that callers can mark a portfolio
+// dirty inside the same transaction that changes its transactions.
+type Execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+// MarkHistoryDirty records that snapshots from fromDate (YYYY-MM-DD) onward must be recomputed.
+// An earlier dirty date already stored on the portfolio is kept.
+func MarkHistoryDirty(db Execer, portfolioID int64, fromDate string) error {
+	_, err := db.Exec(`
+		UPDATE portfolios
+		SET history_dirty_from = CASE
+			WHEN history_dirty_from IS NULL OR history_dirty_from > ? THEN ?
+			ELSE history_dirty_from
+		END
+		WHERE id = ?`,
+		fromDate, fromDate, portfolioID,
+	)
+	return err
+}
+
+// GetHistoryDirtyFrom returns the earliest stale snapshot date of a portfolio, or "" if none.
+func GetHistoryDirtyFrom(db *sql.DB, portfolioID int64) (string, error) {
+	var dirtyFrom sql.NullString
+	err := db.QueryRow("SELECT history_dirty_from FROM portfolios WHERE id = ?", portfolioID).Scan(&dirtyFrom)
+	if err != nil {
+		return "", err
+	}
+	return dirtyFrom.String, nil
+}
+
+// ClearHistoryDirty resets the dirty marker, but only if it still equals the value the
+// rebuild started from. A newer mark set while the rebuild was running is preserved.
+func ClearHistoryDirty(db Execer, portfolioID int64, expected string) error {
+	_, err := db.Exec(`
+		UPDATE portfolios SET history_dirty_from = NULL
+		WHERE id = ? AND history_dirty_from IS ?`,
+		portfolioID, sql.NullString{String: expected, Valid: expected != ""},
+	)
+	return err
+}
+
+// CheckPortfolioOwnership returns ErrPortfolioNotFound unless the portfolio belongs to the user.
+func CheckPortfolioOwnership(db *sql.DB, userID, portfolioID int64) error {
+	var exists int
+	err := db.QueryRow("SELECT 1 FROM portfolios WHERE id = ? AND user_id = ?", portfolioID, userID).Scan(&exists)
+	if err == sql.ErrNoRows {
+		return ErrPortfolioNotFound
+	}
+	return err
+}
@@ -153,3 +153,36 @@ func InsertOrUpdatePrice(db *sql.DB, price DailyPrice) error {
 	}
 	return err
 }
+
+// GetAllMappedTickers returns every distinct ticker symbol known in isin_ticker_map.
+func GetAllMappedTickers(db *sql.DB) ([]string, error) {
+	rows, err := db.Query(`SELECT DISTINCT ticker_symbol FROM isin_ticker_map WHERE ticker_symbol != '' ORDER BY ticker_symbol`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var tickers []string
+	for rows.Next() {
+		var ticker string
+		if err := rows.Scan(&ticker); err != nil {
+			return nil, err
+		}
+		tickers = append(tickers, ticker)
+	}
+	return tickers, rows.Err()
+}
+
+// GetPriceHistory returns every stored price of a ticker (Date YYYY-MM-DD -> Price)
+// together with the currency of the most recent row.
+func GetPriceHistory(db *sql.DB, ticker string) (map[string]float64, string, error) {
+	prices, err := GetPricesByTicker(db, ticker)
+	if err != nil {
+		return nil, "", err
+	}
+	var currency string
+	err = db.QueryRow(`SELECT currency FROM daily_prices WHERE ticker_symbol = ? ORDER BY date DESC LIMIT 1`, ticker).Scan(&currency)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, "", err
+	}
+	return prices, currency, nil
+}
@@ -0,0 +1,97 @@
+package model
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/username/taxfolio/backend/src/models"
+)
+
+var ErrReclaimNotFound = errors.New("reclaim not found")
+
+func scanReclaim(row rowScanner) (*models.DividendReclaim, error) {
+	var c models.DividendReclaim
+	var filedDate, refundedDate sql.NullString
+	if err := row.Scan(&c.ID, &c.PortfolioID, &c.Year, &c.CountryCode, &c.Status, &c.ClaimedEUR,
+		&c.RefundedEUR, &filedDate, &refundedDate, &c.Notes); err != nil {
+		return nil, err
+	}
+	c.FiledDate = filedDate.String
+	c.RefundedDate = refundedDate.String
+	return &c, nil
+}
+
+func nullableString(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+// GetDividendReclaims returns the reclaims of a portfolio, by year and oldest first.
+func GetDividendReclaims(db *sql.DB, userID, portfolioID int64) ([]models.DividendReclaim, error) {
+	rows, err := db.Query(`
+		SELECT id, portfolio_id, year, country_code, status, claimed_eur, refunded_eur, filed_date, refunded_date, notes
+		FROM dividend_reclaims
+		WHERE user_id = ? AND portfolio_id = ?
+		ORDER BY year ASC, id ASC`, userID, portfolioID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	reclaims := []models.DividendReclaim{}
+	for rows.Next() {
+		c, err := scanReclaim(rows)
+		if err != nil {
+			return nil, err
+		}
+		reclaims = append(reclaims, *c)
+	}
+	return reclaims, rows.Err()
+}
+
+// InsertDividendReclaim stores a new reclaim and sets its ID.
+func InsertDividendReclaim(db *sql.DB, userID int64, c *models.DividendReclaim) error {
+	res, err := db.Exec(`
+		INSERT INTO dividend_reclaims (user_id, portfolio_id, year, country_code, status, claimed_eur, refunded_eur, filed_date, refunded_date, notes)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		userID, c.PortfolioID, c.Year, c.CountryCode, c.Status, c.ClaimedEUR, c.RefundedEUR,
+		nullableString(c.FiledDate), nullableString(c.RefundedDate), c.Notes,
+	)
+	if err != nil {
+		return err
+	}
+	c.ID, err = res.LastInsertId()
+	return err
+}
+
+// UpdateDividendReclaim replaces an existing reclaim.
+func UpdateDividendReclaim(db *sql.DB, userID int64, c *models.DividendReclaim) error {
+	res, err := db.Exec(`
+		UPDATE dividend_reclaims
+		SET year = ?, country_code = ?, status = ?, claimed_eur = ?, refunded_eur = ?, filed_date = ?, refunded_date = ?, notes = ?, updated_at = CURRENT_TIMESTAMP
+		WHERE id = ? AND user_id = ? AND portfolio_id = ?`,
+		c.Year, c.CountryCode, c.Status, c.ClaimedEUR, c.RefundedEUR,
+		nullableString(c.FiledDate), nullableString(c.RefundedDate), c.Notes, c.ID, userID, c.PortfolioID,
+	)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrReclaimNotFound
+	}
+	return nil
+}
+
+// DeleteDividendReclaim removes a reclaim from a portfolio.
+func DeleteDividendReclaim(db *sql.DB, userID, portfolioID, reclaimID int64) error {
+	res, err := db.Exec(`DELETE FROM dividend_reclaims WHERE id = ? AND user_id = ? AND portfolio_id = ?`, reclaimID, userID, portfolioID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrReclaimNotFound
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package model
+
+import (
+	"database/sql"
+
+	"github.com/username/taxfolio/backend/src/models"
+)
+
+// GetPortfolioTargets returns the targets of a portfolio. Dimension is empty when none are set.
+func GetPortfolioTargets(db *sql.DB, userID, portfolioID int64) (*models.TargetAllocation, error) {
+	rows, err := db.Query(`
+		SELECT dimension, target_key, weight
+		FROM portfolio_targets
+		WHERE user_id = ? AND portfolio_id = ?
+		ORDER BY weight DESC, target_key ASC`, userID, portfolioID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	allocation := &models.TargetAllocation{PortfolioID: portfolioID, Targets: []models.TargetWeight{}}
+	for rows.Next() {
+		var t models.TargetWeight
+		if err := rows.Scan(&allocation.Dimension, &t.Key, &t.Weight); err != nil {
+			return nil, err
+		}
+		allocation.Targets = append(allocation.Targets, t)
+	}
+	return allocation, rows.Err()
+}
+
+// ReplacePortfolioTargets swaps all targets of a portfolio for the given ones.
+func ReplacePortfolioTargets(db *sql.DB, userID int64, allocation models.TargetAllocation) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM portfolio_targets WHERE user_id = ? AND portfolio_id = ?`, userID, allocation.PortfolioID); err != nil {
+		return err
+	}
+	stmt, err := tx.Prepare(`
+		INSERT INTO portfolio_targets (user_id, portfolio_id, dimension, target_key, weight)
+		VALUES (?, ?, ?, ?, ?)`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	for _, t := range allocation.Targets {
+		if _, err := stmt.Exec(userID, allocation.PortfolioID, allocation.Dimension, t.Key, t.Weight); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// DeletePortfolioTargets removes all targets of a portfolio.
+func DeletePortfolioTargets(db *sql.DB, userID, portfolioID int64) error {
+	_, err := db.Exec(`DELETE FROM portfolio_targets WHERE user_id = ? AND portfolio_id = ?`, userID, portfolioID)
+	return err
+}
@@ -0,0 +1,40 @@
+package models
+
+// Allocation dimensions. They are also the dimension values stored in fund_compositions.
+const (
+	DimensionSector    = "sector"
+	DimensionCountry   = "country"
+	DimensionCurrency  = "currency"
+	DimensionAssetType = "asset_type"
+)
+
+// Bucket names shared by all dimensions.
+const (
+	AllocationUnknown = "Unknown"
+	AllocationCash    = "Cash"
+)
+
+// AllocationBucket is one slice of a breakdown.
+type AllocationBucket struct {
+	Name       string  `json:"name"`
+	ValueEUR   float64 `json:"value_eur"`
+	Percentage float64 `json:"percentage"` // 0-100
+}
+
+// AllocationResult holds the market value of a portfolio broken down by each dimension,
+// largest bucket first. Cash is included in every breakdown.
+type AllocationResult struct {
+	TotalValueEUR float64                       `json:"total_value_eur"`
+	CashEUR       float64                       `json:"cash_eur"`
+	LookThrough   bool                          `json:"look_through"`
+	Breakdowns    map[string][]AllocationBucket `json:"breakdowns"`
+}
+
+// FundComposition is the look-through breakdown of a fund for one dimension.
+type FundComposition struct {
+	FundISIN  string             `json:"fund_isin"`
+	Dimension string             `json:"dimension"`
+	Source    string             `json:"source"` // "provider" or "user"
+	Weights   map[string]float64 `json:"weights"`
+	UpdatedAt string             `json:"updated_at"`
+}
@@ -0,0 +1,26 @@
+package models
+
+// Benchmark rebalancing frequencies.
+const (
+	RebalanceNone      = "none"
+	RebalanceMonthly   = "monthly"
+	RebalanceQuarterly = "quarterly"
+	RebalanceYearly    = "yearly"
+)
+
+// BenchmarkComponent is one instrument of a benchmark blend.
+type BenchmarkComponent struct {
+	Symbol string  `json:"symbol"` // As entered by the user: ticker or ISIN
+	Ticker string  `json:"ticker"` // Resolved Yahoo ticker
+	Weight float64 `json:"weight"` // Fraction of the blend; weights sum to 1
+}
+
+// Benchmark is a shadow portfolio the user compares their portfolio against.
+// Every deposit and withdrawal of the real portfolio is mirrored into it.
+type Benchmark struct {
+	ID          int64                `json:"id"`
+	PortfolioID int64                `json:"portfolio_id"`
+	Name        string               `json:"name"`
+	Components  []BenchmarkComponent `json:"components"`
+	Rebalance   string               `json:"rebalance"`
+}
@@ -6,12 +6,15 @@ import "time"
 // CanonicalTransaction is the unified, intermediate representation of a transaction.
 // Each parser is responsible for populating as many of these fields as possible
 // directly from the source file, including the initial classification and the final signed amount.
+// Instruments the source identifies only by ticker keep the uppercase ticker in ISIN, which
+// resolves to a price through the Yahoo search like an ISIN; a security row with neither an
+// ISIN nor a ticker is rejected by its parser.
 type CanonicalTransaction struct {
 	// --- Fields to be populated by the Parser ---
 	Source             string    `json:"source"`
 	TransactionDate    time.Time `json:"transaction_date"`
 	ProductName        string    `json:"product_name"`
-	ISIN               string    `json:"isin"`
+	ISIN               string    `json:"isin"` // The ISIN, or the uppercase ticker when the source has none; never a product name
 	Quantity           float64   `json:"quantity"`
 	Price              float64   `json:"price"`
 	Commission         float64   `json:"commission"`
@@ -31,3 +34,17 @@ type CanonicalTransaction struct {
 	CountryCode        string    `json:"country_code"`
 	HashId             string    `json:"hash_id"`
 }
+
+// Sub-types of STOCK buys that do not come from an order.
+const (
+	// SubTypeDividendReinvestment is a buy paid with a dividend (DRIP or scrip). The parser
+	// also emits the DIVIDEND income, so the cash movements of both cancel out.
+	SubTypeDividendReinvestment = "REINVESTMENT"
+	// SubTypeStockDividend is a lot of shares distributed without a cash alternative. It has
+	// no cost basis: the cost of the original position is unchanged.
+	SubTypeStockDividend = "STOCK_DIVIDEND"
+	// SubTypeStockSplit is a BUY of the shares a split adds, or, with a negative quantity, of
+	// those a reverse split removes. It moves no cash: the lots of the position keep their cost
+	// and purchase date, and the stock processor spreads the shares over them.
+	SubTypeStockSplit = "STOCK_SPLIT"
+)
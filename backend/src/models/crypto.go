@@ -0,0 +1,46 @@
+// backend/src/models/crypto.go
+package models
+
+// Crypto assets have TransactionType "CRYPTO" and carry their symbol (e.g. "BTC") in the ISIN
+// field. Their fractional quantity is kept in ProcessedTransaction.Units.
+const (
+	// SubTypeStaking marks staking and earn rewards. The parser emits a BUY of the units
+	// received, with their market value as cost, and the matching income row.
+	SubTypeStaking = "STAKING"
+	// SubTypeCryptoFee marks units of an asset given up to pay a trading fee.
+	SubTypeCryptoFee = "FEE"
+)
+
+// CryptoSaleDetail is the part of a crypto disposal matched against one purchase lot (FIFO).
+// A crypto-to-crypto trade is a disposal valued at the EUR price of the trade.
+type CryptoSaleDetail struct {
+	SaleDate      string  `json:"sale_date"`
+	BuyDate       string  `json:"buy_date"`
+	Asset         string  `json:"asset"`
+	Source        string  `json:"source"`
+	Units         float64 `json:"units"`
+	SaleAmountEUR float64 `json:"sale_amount_eur"`
+	BuyAmountEUR  float64 `json:"buy_amount_eur"` // Negative, like SaleDetail.BuyAmountEUR
+	Commission    float64 `json:"commission"`
+	Delta         float64 `json:"delta"` // SaleAmountEUR + BuyAmountEUR
+	DaysHeld      int     `json:"days_held"`
+	HoldingPeriod string  `json:"holding_period"` // Gains on crypto held for 365 days or more are exempt in Portugal
+}
+
+// CryptoLot is the unsold part of a crypto purchase.
+type CryptoLot struct {
+	BuyDate      string  `json:"buy_date"`
+	Asset        string  `json:"asset"`
+	Source       string  `json:"source"`
+	Units        float64 `json:"units"`
+	BuyAmountEUR float64 `json:"buy_amount_eur"`
+}
+
+// CryptoIncome is a staking or earn reward, taxed as income at its value when received.
+type CryptoIncome struct {
+	Date      string  `json:"date"`
+	Asset     string  `json:"asset"`
+	Source    string  `json:"source"`
+	Units     float64 `json:"units"`
+	AmountEUR float64 `json:"amount_eur"`
+}
@@ -0,0 +1,70 @@
+package models
+
+// Dividend event sources.
+const (
+	DividendEventHistory   = "history"   // Reported by the provider after the ex-date
+	DividendEventAnnounced = "announced" // Upcoming ex-date; the amount repeats the previous payment
+	DividendEventProjected = "projected" // Last year's event moved forward by a year
+)
+
+// Statuses of an expected dividend.
+const (
+	DividendReceived = "received"
+	DividendPartial  = "partial" // Received, but less than 90% of the expected gross amount
+	DividendPending  = "pending" // Not received yet, but still within the payment window
+	DividendMissing  = "missing"
+)
+
+// DividendEvent is one dividend of a ticker. Amount is per share in Currency.
+type DividendEvent struct {
+	Ticker   string  `json:"ticker"`
+	ExDate   string  `json:"ex_date"`            // YYYY-MM-DD
+	PayDate  string  `json:"pay_date,omitempty"` // YYYY-MM-DD
+	Amount   float64 `json:"amount"`
+	Currency string  `json:"currency"`
+	Source   string  `json:"source"`
+}
+
+// DividendCalendarEntry is an upcoming dividend of a held position.
+// PayDateEstimated is set when the pay date is derived from past payments.
+type DividendCalendarEntry struct {
+	ISIN             string  `json:"isin"`
+	ProductName      string  `json:"product_name"`
+	Ticker           string  `json:"ticker"`
+	ExDate           string  `json:"ex_date"`
+	PayDate          string  `json:"pay_date,omitempty"`
+	PayDateEstimated bool    `json:"pay_date_estimated"`
+	AmountPerShare   float64 `json:"amount_per_share"`
+	Currency         string  `json:"currency"`
+	Quantity         int     `json:"quantity"`
+	ExpectedLocal    float64 `json:"expected_local"`
+	ExpectedEUR      float64 `json:"expected_eur"`
+	Source           string  `json:"source"`
+}
+
+// ExpectedDividend compares a past dividend event with what was received for the shares
+// held on the day before the ex-date.
+type ExpectedDividend struct {
+	ISIN             string  `json:"isin"`
+	ProductName      string  `json:"product_name"`
+	Ticker           string  `json:"ticker"`
+	ExDate           string  `json:"ex_date"`
+	PayDate          string  `json:"pay_date,omitempty"`
+	AmountPerShare   float64 `json:"amount_per_share"`
+	Currency         string  `json:"currency"`
+	QuantityAtExDate int     `json:"quantity_at_ex_date"`
+	ExpectedEUR      float64 `json:"expected_eur"`
+	ReceivedEUR      float64 `json:"received_eur"`
+	ReceivedDate     string  `json:"received_date,omitempty"`
+	Status           string  `json:"status"`
+}
+
+// DividendCalendar is the forward calendar of held positions and the check of past payments.
+type DividendCalendar struct {
+	Upcoming          []DividendCalendarEntry `json:"upcoming"`
+	UpcomingTotalEUR  float64                 `json:"upcoming_total_eur"`
+	Expected          []ExpectedDividend      `json:"expected"`
+	MissingCount      int                     `json:"missing_count"`
+	MissingTotalEUR   float64                 `json:"missing_total_eur"`
+	UnmappedPositions []string                `json:"unmapped_positions"`
+}
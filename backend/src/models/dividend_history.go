@@ -0,0 +1,73 @@
+package models
+
+// Payout frequencies detected from the spacing of ex-dates.
+const (
+	FrequencyMonthly    = "monthly"
+	FrequencyQuarterly  = "quarterly"
+	FrequencySemiannual = "semiannual"
+	FrequencyAnnual     = "annual"
+	FrequencyIrregular  = "irregular"
+	FrequencyUnknown    = "unknown"
+)
+
+// IncomePoint is the dividend income of a month (YYYY-MM) or a year (YYYY).
+// GrowthPercent compares a year with the previous one and is nil for the first year.
+type IncomePoint struct {
+	Period        string   `json:"period"`
+	GrossEUR      float64  `json:"gross_eur"`
+	WithheldEUR   float64  `json:"withheld_eur"`
+	NetEUR        float64  `json:"net_eur"`
+	GrowthPercent *float64 `json:"growth_percent,omitempty"`
+}
+
+// DividendGrowth holds compound annual growth rates over the last complete years, in percent.
+// A rate is nil when there is not enough history or a year without dividends.
+type DividendGrowth struct {
+	CAGR1Y *float64 `json:"cagr_1y"`
+	CAGR3Y *float64 `json:"cagr_3y"`
+	CAGR5Y *float64 `json:"cagr_5y"`
+}
+
+// DividendCut is a regular dividend at least 10% below the previous comparable one.
+type DividendCut struct {
+	ExDate         string  `json:"ex_date"`
+	Amount         float64 `json:"amount"`
+	PreviousAmount float64 `json:"previous_amount"`
+	ChangePercent  float64 `json:"change_percent"`
+	Currency       string  `json:"currency"`
+}
+
+// LotYieldOnCost is the trailing-twelve-month dividend per share of an open lot relative to its cost.
+type LotYieldOnCost struct {
+	BuyDate            string  `json:"buy_date"`
+	Quantity           int     `json:"quantity"`
+	CostEUR            float64 `json:"cost_eur"`
+	AnnualDividendEUR  float64 `json:"annual_dividend_eur"`
+	YieldOnCostPercent float64 `json:"yield_on_cost_percent"`
+}
+
+// PositionDividendHistory is the dividend history of one ISIN. Income comes from the booked
+// dividends; frequency, per-share growth and cuts come from the provider's dividend events.
+type PositionDividendHistory struct {
+	ISIN             string             `json:"isin"`
+	ProductName      string             `json:"product_name"`
+	Ticker           string             `json:"ticker"`
+	ReceivedGrossEUR float64            `json:"received_gross_eur"`
+	Yearly           []IncomePoint      `json:"yearly"`
+	IncomeGrowth     DividendGrowth     `json:"income_growth"`
+	Frequency        string             `json:"frequency"`
+	PaymentsPerYear  int                `json:"payments_per_year"`
+	PerShareByYear   map[string]float64 `json:"per_share_by_year"`
+	PerShareCurrency string             `json:"per_share_currency"`
+	PerShareGrowth   DividendGrowth     `json:"per_share_growth"`
+	Cuts             []DividendCut      `json:"cuts"`
+	YieldOnCost      []LotYieldOnCost   `json:"yield_on_cost"`
+}
+
+// DividendHistoryResult is the dividend income history of a portfolio and its positions.
+type DividendHistoryResult struct {
+	Monthly      []IncomePoint             `json:"monthly"`
+	Yearly       []IncomePoint             `json:"yearly"`
+	IncomeGrowth DividendGrowth            `json:"income_growth"`
+	Positions    []PositionDividendHistory `json:"positions"`
+}
@@ -0,0 +1,40 @@
+package models
+
+// RealizedAttribution splits the EUR result of one FIFO-matched sale. PriceEffectEUR is the
+// local-currency result converted at the sale rate; FXEffectEUR is the cost revalued at the
+// sale rate minus the EUR cost. Both are nil when the buy and sale currencies differ.
+type RealizedAttribution struct {
+	SaleDate       string   `json:"sale_date"`
+	BuyDate        string   `json:"buy_date"`
+	ISIN           string   `json:"isin"`
+	ProductName    string   `json:"product_name"`
+	Quantity       int      `json:"quantity"`
+	Currency       string   `json:"currency"`
+	BuyRate        float64  `json:"buy_rate"`  // Local units per EUR
+	SaleRate       float64  `json:"sale_rate"` // Local units per EUR
+	ResultLocal    *float64 `json:"result_local"`
+	ResultEUR      float64  `json:"result_eur"`
+	PriceEffectEUR *float64 `json:"price_effect_eur"`
+	FXEffectEUR    *float64 `json:"fx_effect_eur"`
+}
+
+// FXAttributionGroup totals the results of a year or a currency. Results that cannot be
+// split are reported in UnattributedEUR only.
+type FXAttributionGroup struct {
+	Key             string  `json:"key"`
+	Count           int     `json:"count"`
+	ResultEUR       float64 `json:"result_eur"`
+	PriceEffectEUR  float64 `json:"price_effect_eur"`
+	FXEffectEUR     float64 `json:"fx_effect_eur"`
+	UnattributedEUR float64 `json:"unattributed_eur"`
+}
+
+// FXAttributionResult is the price/currency split of realized sales and open lots.
+type FXAttributionResult struct {
+	Realized             []RealizedAttribution `json:"realized"`
+	RealizedByYear       []FXAttributionGroup  `json:"realized_by_year"`
+	RealizedByCurrency   []FXAttributionGroup  `json:"realized_by_currency"`
+	UnrealizedByCurrency []FXAttributionGroup  `json:"unrealized_by_currency"`
+	RealizedTotal        FXAttributionGroup    `json:"realized_total"`
+	UnrealizedTotal      FXAttributionGroup    `json:"unrealized_total"`
+}
@@ -0,0 +1,52 @@
+package models
+
+// HarvestCandidate is a position whose oldest lots are at a loss. MaxLossEUR is the largest
+// loss a FIFO sell can realize, reached by selling MaxQuantity shares.
+type HarvestCandidate struct {
+	ISIN            string  `json:"isin"`
+	ProductName     string  `json:"product_name"`
+	Quantity        int     `json:"quantity"`
+	CurrentPriceEUR float64 `json:"current_price_eur"`
+	UnrealizedEUR   float64 `json:"unrealized_eur"`
+	MaxQuantity     int     `json:"max_quantity"`
+	MaxLossEUR      float64 `json:"max_loss_eur"`
+}
+
+// HarvestLotMatch is the part of an open lot consumed by a simulated sell.
+type HarvestLotMatch struct {
+	BuyDate       string  `json:"buy_date"`
+	Quantity      int     `json:"quantity"`
+	CostEUR       float64 `json:"cost_eur"`
+	RealizedEUR   float64 `json:"realized_eur"`
+	HoldingPeriod string  `json:"holding_period"`
+}
+
+// HarvestSell is one suggested sell, matched against the open lots in FIFO order.
+type HarvestSell struct {
+	ISIN        string            `json:"isin"`
+	ProductName string            `json:"product_name"`
+	Quantity    int               `json:"quantity"`
+	ProceedsEUR float64           `json:"proceeds_eur"`
+	CostEUR     float64           `json:"cost_eur"`
+	RealizedEUR float64           `json:"realized_eur"`
+	Lots        []HarvestLotMatch `json:"lots"`
+}
+
+// HarvestPlan compares the realized P/L of the fiscal year so far with the result after the
+// suggested sells. Estimates apply a flat rate to a positive net result and ignore commissions.
+type HarvestPlan struct {
+	Year                  int                `json:"year"`
+	TaxRate               float64            `json:"tax_rate"`
+	TargetNetEUR          float64            `json:"target_net_eur"`
+	RealizedGainsEUR      float64            `json:"realized_gains_eur"`
+	RealizedLossesEUR     float64            `json:"realized_losses_eur"`
+	RealizedNetEUR        float64            `json:"realized_net_eur"`
+	EstimatedTaxBeforeEUR float64            `json:"estimated_tax_before_eur"`
+	Candidates            []HarvestCandidate `json:"candidates"`
+	Sells                 []HarvestSell      `json:"sells"`
+	HarvestedLossEUR      float64            `json:"harvested_loss_eur"`
+	ResultingNetEUR       float64            `json:"resulting_net_eur"`
+	EstimatedTaxAfterEUR  float64            `json:"estimated_tax_after_eur"`
+	TaxSavedEUR           float64            `json:"tax_saved_eur"`
+	UnpricedPositions     []string           `json:"unpriced_positions"`
+}
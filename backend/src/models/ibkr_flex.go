@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// IBKRFlexConnection holds the Flex Web Service credentials of a portfolio. The token is
+// never returned to the client; TokenHint shows its last characters instead.
+type IBKRFlexConnection struct {
+	PortfolioID   int64      `json:"portfolio_id"`
+	UserID        int64      `json:"-"`
+	Token         string     `json:"-"`
+	TokenHint     string     `json:"token_hint"`
+	QueryID       string     `json:"query_id"`
+	Enabled       bool       `json:"enabled"`
+	LastSyncAt    *time.Time `json:"last_sync_at"`
+	LastSuccessAt *time.Time `json:"last_success_at"`
+	LastError     string     `json:"last_error"`
+}
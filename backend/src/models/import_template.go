@@ -0,0 +1,42 @@
+package models
+
+// Transaction kinds a CSV type value can be mapped to by an import template.
+const (
+	ImportTypeBuy         = "BUY"
+	ImportTypeSell        = "SELL"
+	ImportTypeDividend    = "DIVIDEND"
+	ImportTypeDividendTax = "DIVIDEND_TAX"
+	ImportTypeFee         = "FEE"
+	ImportTypeInterest    = "INTEREST"
+	ImportTypeDeposit     = "DEPOSIT"
+	ImportTypeWithdrawal  = "WITHDRAWAL"
+	ImportTypeIgnore      = "IGNORE"
+)
+
+// ImportColumns names the CSV header of each field. Date, type and either amount or
+// quantity and price are required; trades also need quantity and ISIN, whose column may hold
+// tickers for instruments without an ISIN. The other columns are optional.
+type ImportColumns struct {
+	Date        string `json:"date"`
+	Type        string `json:"type"`
+	ISIN        string `json:"isin"`
+	ProductName string `json:"product_name"`
+	Quantity    string `json:"quantity"`
+	Price       string `json:"price"`
+	Amount      string `json:"amount"`
+	Currency    string `json:"currency"`
+	Commission  string `json:"commission"`
+	OrderID     string `json:"order_id"`
+}
+
+// ImportTemplate describes how to read the CSV export of a broker without a dedicated parser.
+type ImportTemplate struct {
+	ID               int64             `json:"id"`
+	Name             string            `json:"name"`
+	Delimiter        string            `json:"delimiter"`         // "," by default
+	DecimalSeparator string            `json:"decimal_separator"` // "." or ","
+	DateFormat       string            `json:"date_format"`       // e.g. "DD/MM/YYYY" or "YYYY-MM-DD HH:mm:ss"
+	DefaultCurrency  string            `json:"default_currency"`  // Used when no currency column is mapped or it is empty
+	Columns          ImportColumns     `json:"columns"`
+	TypeMapping      map[string]string `json:"type_mapping"` // CSV type value -> ImportType*; matched case-insensitively
+}
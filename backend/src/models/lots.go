@@ -0,0 +1,50 @@
+package models
+
+// Holding periods. Portuguese residents who opt for englobamento (or must, for assets held
+// under a year) are taxed differently on short-term gains, so every lot is classified.
+const (
+	HoldingPeriodShort  = "short"
+	HoldingPeriodLong   = "long"
+	LongTermHoldingDays = 365
+)
+
+// UnrealizedLot is an open FIFO purchase lot valued at the current price.
+// The EUR result splits into PriceEffectEUR (the move of the local price, converted at
+// today's rate) and FXEffectEUR (the cost revalued at today's rate minus the EUR cost).
+// Both are nil when the quote currency differs from the purchase currency.
+type UnrealizedLot struct {
+	ISIN              string   `json:"isin"`
+	ProductName       string   `json:"product_name"`
+	BuyDate           string   `json:"buy_date"`
+	Quantity          int      `json:"quantity"`
+	DaysHeld          int      `json:"days_held"`
+	HoldingPeriod     string   `json:"holding_period"`
+	Currency          string   `json:"currency"`
+	BuyPrice          float64  `json:"buy_price"`
+	CostLocal         float64  `json:"cost_local"`
+	CostEUR           float64  `json:"cost_eur"`
+	PriceStatus       string   `json:"price_status"`
+	CurrentPriceLocal float64  `json:"current_price_local"`
+	CurrentPriceEUR   float64  `json:"current_price_eur"`
+	CurrentValueLocal float64  `json:"current_value_local"`
+	CurrentValueEUR   float64  `json:"current_value_eur"`
+	UnrealizedLocal   *float64 `json:"unrealized_local"`
+	UnrealizedEUR     float64  `json:"unrealized_eur"`
+	UnrealizedPercent float64  `json:"unrealized_percent"`
+	PriceEffectEUR    *float64 `json:"price_effect_eur"`
+	FXEffectEUR       *float64 `json:"fx_effect_eur"`
+}
+
+// UnrealizedSummary lists the open lots of a portfolio with totals over the priced ones.
+// The price and FX effects only cover lots for which the split is known.
+type UnrealizedSummary struct {
+	Lots                   []UnrealizedLot `json:"lots"`
+	TotalCostEUR           float64         `json:"total_cost_eur"`
+	TotalValueEUR          float64         `json:"total_value_eur"`
+	TotalUnrealizedEUR     float64         `json:"total_unrealized_eur"`
+	ShortTermUnrealizedEUR float64         `json:"short_term_unrealized_eur"`
+	LongTermUnrealizedEUR  float64         `json:"long_term_unrealized_eur"`
+	PriceEffectEUR         float64         `json:"price_effect_eur"`
+	FXEffectEUR            float64         `json:"fx_effect_eur"`
+	UnpricedLots           int             `json:"unpriced_lots"`
+}
@@ -0,0 +1,28 @@
+package models
+
+// Performance periods accepted by the returns endpoint.
+const (
+	PeriodYTD       = "ytd"
+	Period1Y        = "1y"
+	Period3Y        = "3y"
+	PeriodInception = "inception"
+	PeriodCustom    = "custom"
+)
+
+// ReturnsResult holds time-weighted and money-weighted returns for a portfolio over one period.
+// Rates are fractions (0.05 = 5%). Annualised TWR is only reported for periods of at least a year.
+type ReturnsResult struct {
+	Period        string   `json:"period"`
+	StartDate     string   `json:"start_date"` // First day actually covered (clamped to inception)
+	EndDate       string   `json:"end_date"`
+	Days          int      `json:"days"`
+	StartValue    float64  `json:"start_value"`
+	EndValue      float64  `json:"end_value"`
+	NetCashFlow   float64  `json:"net_cash_flow"` // Deposits minus withdrawals within the period
+	Gain          float64  `json:"gain"`          // EndValue - StartValue - NetCashFlow
+	TWR           float64  `json:"twr"`
+	TWRAnnualized *float64 `json:"twr_annualized"`
+	MWR           *float64 `json:"mwr"`            // Return over the period implied by the XIRR
+	MWRAnnualized *float64 `json:"mwr_annualized"` // XIRR
+	HasData       bool     `json:"has_data"`
+}
@@ -90,6 +90,9 @@ type HistoricalDataPoint struct {
 	PortfolioValue     float64 `json:"portfolio_value"` // To be populated via snapshots later
 	BenchmarkValue     float64 `json:"benchmark_value"`
 	SPYPrice           float64 `json:"spy_price"`
+	// Benchmarks holds the shadow-portfolio value (EUR) of every benchmark, keyed by name.
+	// BenchmarkValue and SPYPrice mirror the first one for older clients.
+	Benchmarks map[string]float64 `json:"benchmarks,omitempty"`
 }
 
 type Portfolio struct {
@@ -0,0 +1,65 @@
+package models
+
+// DimensionISIN targets individual holdings instead of an allocation category.
+const DimensionISIN = "isin"
+
+// TargetWeight is the desired share of one ISIN or category.
+type TargetWeight struct {
+	Key    string  `json:"key"`
+	Weight float64 `json:"weight"` // Fraction of the total value, cash included (0-1)
+}
+
+// TargetAllocation holds the targets of a portfolio. Whatever the weights leave
+// unallocated is the cash target.
+type TargetAllocation struct {
+	PortfolioID int64          `json:"portfolio_id"`
+	Dimension   string         `json:"dimension"`
+	Targets     []TargetWeight `json:"targets"`
+}
+
+// DriftItem compares the current and target weight of one target key.
+type DriftItem struct {
+	Key             string  `json:"key"`
+	TargetWeight    float64 `json:"target_weight"`
+	CurrentWeight   float64 `json:"current_weight"`
+	Drift           float64 `json:"drift"` // CurrentWeight - TargetWeight
+	CurrentValueEUR float64 `json:"current_value_eur"`
+	TargetValueEUR  float64 `json:"target_value_eur"`
+	ProjectedWeight float64 `json:"projected_weight"` // Weight after the plan's trades
+}
+
+// RebalanceConstraints restrict the trades a plan may suggest.
+type RebalanceConstraints struct {
+	NewCashEUR  float64 `json:"new_cash_eur"`  // Money added with this rebalance
+	NewCashOnly bool    `json:"new_cash_only"` // Only invest NewCashEUR; no sells, existing cash untouched
+	NoSells     bool    `json:"no_sells"`      // Buys funded from existing and new cash only
+	MinTradeEUR float64 `json:"min_trade_eur"` // Trades below this amount are dropped
+	WholeShares bool    `json:"whole_shares"`  // Round quantities down to whole shares
+}
+
+// RebalanceTrade is one suggested order.
+type RebalanceTrade struct {
+	ISIN             string   `json:"isin"`
+	ProductName      string   `json:"product_name"`
+	TargetKey        string   `json:"target_key"`
+	Action           string   `json:"action"` // "BUY" or "SELL"
+	Quantity         float64  `json:"quantity"`
+	PriceEUR         float64  `json:"price_eur"`
+	AmountEUR        float64  `json:"amount_eur"`
+	EstimatedGainEUR *float64 `json:"estimated_gain_eur,omitempty"` // Sells only, from FIFO lots
+}
+
+// RebalancePlan is the drift of a portfolio against its targets and the trades that reduce it.
+type RebalancePlan struct {
+	Dimension                string               `json:"dimension"`
+	Constraints              RebalanceConstraints `json:"constraints"`
+	TotalValueEUR            float64              `json:"total_value_eur"`
+	CashEUR                  float64              `json:"cash_eur"`
+	Drift                    []DriftItem          `json:"drift"`
+	Trades                   []RebalanceTrade     `json:"trades"`
+	TotalBuyEUR              float64              `json:"total_buy_eur"`
+	TotalSellEUR             float64              `json:"total_sell_eur"`
+	CashAfterEUR             float64              `json:"cash_after_eur"`
+	EstimatedRealizedGainEUR float64              `json:"estimated_realized_gain_eur"`
+	Warnings                 []string             `json:"warnings"`
+}
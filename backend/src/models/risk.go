@@ -0,0 +1,37 @@
+package models
+
+// Drawdown describes the largest peak-to-trough fall of the time-weighted return index.
+type Drawdown struct {
+	Depth        float64 `json:"depth"` // Negative fraction (-0.25 = -25%)
+	PeakDate     string  `json:"peak_date"`
+	TroughDate   string  `json:"trough_date"`
+	RecoveryDate *string `json:"recovery_date"` // nil while the index is still below the peak
+	DurationDays int     `json:"duration_days"` // Peak to recovery, or to the end of the period if not recovered
+}
+
+// RollingVolatilityPoint is the annualised volatility of the window ending on Date.
+type RollingVolatilityPoint struct {
+	Date       string  `json:"date"`
+	Volatility float64 `json:"volatility"`
+}
+
+// RiskResult holds the risk metrics of a portfolio over one period. Statistics use
+// flow-adjusted daily returns on trading days and are annualised with 252 days.
+// Ratios are nil when there are not enough observations to compute them.
+type RiskResult struct {
+	Period            string                   `json:"period"`
+	StartDate         string                   `json:"start_date"`
+	EndDate           string                   `json:"end_date"`
+	Observations      int                      `json:"observations"`
+	RiskFreeRate      float64                  `json:"risk_free_rate"`
+	Volatility        *float64                 `json:"volatility"`
+	MaxDrawdown       *Drawdown                `json:"max_drawdown"`
+	RollingWindow     int                      `json:"rolling_window"`
+	RollingVolatility []RollingVolatilityPoint `json:"rolling_volatility"`
+	Sharpe            *float64                 `json:"sharpe"`
+	Sortino           *float64                 `json:"sortino"`
+	Benchmark         string                   `json:"benchmark"`
+	Beta              *float64                 `json:"beta"`
+	Correlation       *float64                 `json:"correlation"`
+	HasData           bool                     `json:"has_data"`
+}
@@ -23,6 +23,7 @@ type ProcessedTransaction struct {
 	ISIN               string  `json:"isin"`
 	Quantity           int     `json:"quantity"`
 	OriginalQuantity   int     `json:"original_quantity"` // Original quantity of the purchase lot before any sales
+	Units              float64 `json:"units"`             // Fractional quantity, used for crypto assets
 	Price              float64 `json:"price"`
 	TransactionType    string  `json:"transaction_type"`    // e.g., "STOCK", "OPTION", "DIVIDEND", "FEE", "CASH"
 	TransactionSubType string  `json:"transaction_subtype"` // e.g., "CALL", "PUT", "TAX", "DEPOSIT"
@@ -0,0 +1,84 @@
+package models
+
+// Reclaim statuses.
+const (
+	ReclaimPending  = "pending"
+	ReclaimFiled    = "filed"
+	ReclaimRefunded = "refunded"
+	ReclaimRejected = "rejected"
+)
+
+// Reconciliation outcomes of a single dividend.
+const (
+	WithholdingOK            = "ok"             // Withheld at or below the treaty rate
+	WithholdingExcess        = "excess"         // Withheld above the treaty rate; the difference can be reclaimed
+	WithholdingNone          = "none"           // No withholding was found for the dividend
+	WithholdingUnknownTreaty = "unknown_treaty" // No treaty rate is known for the source country
+)
+
+// WithholdingRate is the source-country withholding on dividends paid to Portuguese residents:
+// the domestic rate and the rate the double tax treaty limits it to.
+type WithholdingRate struct {
+	StatutoryRate float64 `json:"statutory_rate"`
+	TreatyRate    float64 `json:"treaty_rate"`
+	Note          string  `json:"note,omitempty"`
+}
+
+// WithholdingMatch is a dividend matched to the tax withheld on it.
+type WithholdingMatch struct {
+	Date          string   `json:"date"`
+	ISIN          string   `json:"isin"`
+	ProductName   string   `json:"product_name"`
+	CountryCode   string   `json:"country_code"`
+	Country       string   `json:"country"`
+	GrossEUR      float64  `json:"gross_eur"`
+	WithheldEUR   float64  `json:"withheld_eur"`
+	EffectiveRate float64  `json:"effective_rate"`
+	TreatyRate    *float64 `json:"treaty_rate"`
+	ExpectedEUR   *float64 `json:"expected_eur"`
+	ExcessEUR     float64  `json:"excess_eur"`
+	Status        string   `json:"status"`
+}
+
+// WithholdingCountryYear totals the withholding of one source country in one year together
+// with the reclaims filed for it. OutstandingEUR is the excess not yet refunded or rejected.
+type WithholdingCountryYear struct {
+	Year           int      `json:"year"`
+	CountryCode    string   `json:"country_code"`
+	Country        string   `json:"country"`
+	Dividends      int      `json:"dividends"`
+	GrossEUR       float64  `json:"gross_eur"`
+	WithheldEUR    float64  `json:"withheld_eur"`
+	EffectiveRate  float64  `json:"effective_rate"`
+	TreatyRate     *float64 `json:"treaty_rate"`
+	ExcessEUR      float64  `json:"excess_eur"`
+	ClaimedEUR     float64  `json:"claimed_eur"`
+	RefundedEUR    float64  `json:"refunded_eur"`
+	OutstandingEUR float64  `json:"outstanding_eur"`
+	ReclaimStatus  string   `json:"reclaim_status"` // Status of the latest reclaim; empty when none was recorded
+}
+
+// DividendReclaim is a withholding tax refund claim filed with a source country.
+type DividendReclaim struct {
+	ID           int64   `json:"id"`
+	PortfolioID  int64   `json:"portfolio_id"`
+	Year         int     `json:"year"`
+	CountryCode  string  `json:"country_code"`
+	Status       string  `json:"status"`
+	ClaimedEUR   float64 `json:"claimed_eur"`
+	RefundedEUR  float64 `json:"refunded_eur"`
+	FiledDate    string  `json:"filed_date,omitempty"`    // YYYY-MM-DD
+	RefundedDate string  `json:"refunded_date,omitempty"` // YYYY-MM-DD
+	Notes        string  `json:"notes"`
+}
+
+// WithholdingReconciliation is the result of matching dividends to their withholding.
+// Withholding that could not be matched to any dividend is reported in UnmatchedTaxEUR.
+type WithholdingReconciliation struct {
+	Dividends        []WithholdingMatch       `json:"dividends"`
+	ByYearCountry    []WithholdingCountryYear `json:"by_year_country"`
+	Reclaims         []DividendReclaim        `json:"reclaims"`
+	TotalExcessEUR   float64                  `json:"total_excess_eur"`
+	TotalRefundedEUR float64                  `json:"total_refunded_eur"`
+	UnmatchedTaxEUR  float64                  `json:"unmatched_tax_eur"`
+}
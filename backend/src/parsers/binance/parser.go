@@ -0,0 +1,270 @@
+// backend/src/parsers/binance/parser.go
+package binance
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/username/taxfolio/backend/src/logger"
+	"github.com/username/taxfolio/backend/src/models"
+	"github.com/username/taxfolio/backend/src/parsers/exchange"
+	"github.com/username/taxfolio/backend/src/utils"
+)
+
+// quoteAssets are the assets Binance quotes pairs in, longest first, to split a market such
+// as "ETHBTC" into base and quote.
+var quoteAssets = []string{"FDUSD", "USDT", "USDC", "BUSD", "TUSD", "EUR", "USD", "GBP", "TRY", "BRL", "BTC", "ETH", "BNB", "DAI"}
+
+// amountAssetRe splits the amounts of the trade history, such as "0.01000000BTC".
+var amountAssetRe = regexp.MustCompile(`^(-?[\d.,]+)\s*([A-Za-z0-9]+)$`)
+
+// tradeOperations are the operations of the transaction history that make up a trade. The
+// rows of one trade share their time.
+var tradeOperations = map[string]bool{
+	"buy": true, "sell": true, "fee": true, "binance convert": true, "small assets exchange bnb": true,
+	"transaction buy": true, "transaction spend": true, "transaction revenue": true, "transaction sold": true, "transaction fee": true,
+}
+
+// BinanceParser implements the parsers.Parser interface for Binance. It reads the spot trade
+// history export and the transaction history export, which also lists staking rewards.
+type BinanceParser struct{}
+
+// NewParser creates a new instance of the BinanceParser.
+func NewParser() *BinanceParser {
+	return &BinanceParser{}
+}
+
+// table is a CSV export with its columns indexed by lower-case name.
+type table struct {
+	cols    map[string]int
+	records [][]string
+}
+
+func (t table) has(name string) bool {
+	_, ok := t.cols[name]
+	return ok
+}
+
+func (t table) get(record []string, name string) string {
+	idx, ok := t.cols[name]
+	if !ok || idx >= len(record) {
+		return ""
+	}
+	return strings.TrimSpace(record[idx])
+}
+
+// Parse detects the kind of export from its header.
+func (p *BinanceParser) Parse(file io.Reader) ([]models.CanonicalTransaction, error) {
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return nil, fmt.Errorf("binance parser: failed to read file: %w", err)
+	}
+	reader := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))))
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("binance parser: failed to read CSV records: %w", err)
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("binance parser: empty file")
+	}
+	t := table{cols: make(map[string]int, len(records[0])), records: records[1:]}
+	for i, name := range records[0] {
+		t.cols[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+
+	var canonicalTxs []models.CanonicalTransaction
+	switch {
+	case t.has("operation") && t.has("coin") && t.has("change"):
+		canonicalTxs = parseTransactionHistory(t)
+	case (t.has("pair") || t.has("market")) && (t.has("side") || t.has("type")):
+		canonicalTxs = parseTradeHistory(t)
+	default:
+		return nil, fmt.Errorf("binance parser: unrecognised export")
+	}
+
+	sort.SliceStable(canonicalTxs, func(i, j int) bool {
+		return canonicalTxs[i].TransactionDate.Before(canonicalTxs[j].TransactionDate)
+	})
+	return canonicalTxs, nil
+}
+
+// parseTradeHistory reads the spot trade history, in its current layout
+// Date(UTC),Pair,Side,Price,Executed,Amount,Fee (amounts suffixed with the asset) and its
+// older layout Date(UTC),Market,Type,Price,Amount,Total,Fee,Fee Coin.
+func parseTradeHistory(t table) []models.CanonicalTransaction {
+	var canonicalTxs []models.CanonicalTransaction
+	for _, record := range t.records {
+		date, err := parseDate(t.get(record, "date(utc)"))
+		if err != nil {
+			logger.L.Warn("Binance Parser: Skipping trade with invalid date", "date", t.get(record, "date(utc)"))
+			continue
+		}
+
+		var base, quote, fee exchange.Asset
+		var side string
+		if t.has("executed") {
+			side = t.get(record, "side")
+			base, err = parseAmountAsset(t.get(record, "executed"))
+			if err == nil {
+				quote, err = parseAmountAsset(t.get(record, "amount"))
+			}
+			if err == nil && t.get(record, "fee") != "" {
+				fee, err = parseAmountAsset(t.get(record, "fee"))
+			}
+		} else {
+			side = t.get(record, "type")
+			baseSymbol, quoteSymbol, ok := splitMarket(t.get(record, "market"))
+			if !ok {
+				err = fmt.Errorf("unknown market %q", t.get(record, "market"))
+			}
+			base = exchange.Asset{Symbol: baseSymbol, Units: parseNumber(t.get(record, "amount"))}
+			quote = exchange.Asset{Symbol: quoteSymbol, Units: parseNumber(t.get(record, "total"))}
+			fee = exchange.Asset{Symbol: strings.ToUpper(t.get(record, "fee coin")), Units: parseNumber(t.get(record, "fee"))}
+		}
+		if err != nil {
+			logger.L.Warn("Binance Parser: Skipping trade", "date", date, "error", err)
+			continue
+		}
+
+		trade := exchange.Trade{
+			Source:  "binance",
+			Date:    date,
+			Fee:     fee,
+			RawText: "binance|" + strings.Join(record, ","),
+		}
+		switch strings.ToUpper(side) {
+		case "BUY":
+			trade.Acquired, trade.Disposed = base, quote
+		case "SELL":
+			trade.Acquired, trade.Disposed = quote, base
+		default:
+			logger.L.Warn("Binance Parser: Skipping trade with unknown side", "side", side, "date", date)
+			continue
+		}
+		txs, err := trade.Transactions()
+		if err != nil {
+			logger.L.Warn("Binance Parser: Skipping trade", "date", date, "error", err)
+			continue
+		}
+		canonicalTxs = append(canonicalTxs, txs...)
+	}
+	return canonicalTxs
+}
+
+// parseTransactionHistory reads the transaction history,
+// User_ID,UTC_Time,Account,Operation,Coin,Change,Remark. Trades are listed as one row per
+// asset (and one for the fee); rewards and fiat deposits and withdrawals as single rows.
+// Crypto deposits and withdrawals are transfers between wallets and are not recorded.
+func parseTransactionHistory(t table) []models.CanonicalTransaction {
+	type tradeRows struct {
+		changes map[string]float64
+		fee     exchange.Asset
+		raw     []string
+	}
+	var canonicalTxs []models.CanonicalTransaction
+	trades := make(map[string]*tradeRows)
+	var tradeTimes []string
+
+	for _, record := range t.records {
+		timestamp := t.get(record, "utc_time")
+		date, err := parseDate(timestamp)
+		if err != nil {
+			logger.L.Warn("Binance Parser: Skipping row with invalid date", "date", timestamp)
+			continue
+		}
+		operation := strings.ToLower(t.get(record, "operation"))
+		asset := exchange.Asset{Symbol: strings.ToUpper(t.get(record, "coin")), Units: parseNumber(t.get(record, "change"))}
+		rawText := "binance|" + strings.Join(record, ",")
+
+		switch {
+		case tradeOperations[operation]:
+			tr, ok := trades[timestamp]
+			if !ok {
+				tr = &tradeRows{changes: make(map[string]float64)}
+				trades[timestamp] = tr
+				tradeTimes = append(tradeTimes, timestamp)
+			}
+			if strings.Contains(operation, "fee") {
+				tr.fee = exchange.Asset{Symbol: asset.Symbol, Units: tr.fee.Units - asset.Units}
+			} else {
+				tr.changes[asset.Symbol] += asset.Units
+			}
+			tr.raw = append(tr.raw, strings.Join(record, ","))
+		case asset.Units > 0 && (strings.Contains(operation, "reward") || strings.Contains(operation, "interest")):
+			canonicalTxs = append(canonicalTxs, exchange.Reward("binance", date, asset, rawText)...)
+		case strings.Contains(operation, "deposit") || strings.Contains(operation, "withdraw"):
+			if utils.IsFiatCurrency(asset.Symbol) && asset.Units != 0 {
+				canonicalTxs = append(canonicalTxs, exchange.CashMovement("binance", date, asset, rawText))
+			}
+		default:
+			logger.L.Debug("Binance Parser: Skipping operation", "operation", operation, "date", timestamp)
+		}
+	}
+
+	for _, timestamp := range tradeTimes {
+		tr := trades[timestamp]
+		date, _ := parseDate(timestamp)
+		trade := exchange.Trade{Source: "binance", Date: date, Fee: tr.fee, RawText: "binance|" + strings.Join(tr.raw, "|")}
+		var acquired, disposed []exchange.Asset
+		for symbol, change := range tr.changes {
+			if change > 0 {
+				acquired = append(acquired, exchange.Asset{Symbol: symbol, Units: change})
+			} else if change < 0 {
+				disposed = append(disposed, exchange.Asset{Symbol: symbol, Units: -change})
+			}
+		}
+		if len(acquired) != 1 || len(disposed) != 1 {
+			logger.L.Warn("Binance Parser: Skipping trade rows that do not pair two assets", "date", timestamp)
+			continue
+		}
+		trade.Acquired, trade.Disposed = acquired[0], disposed[0]
+		txs, err := trade.Transactions()
+		if err != nil {
+			logger.L.Warn("Binance Parser: Skipping trade", "date", timestamp, "error", err)
+			continue
+		}
+		canonicalTxs = append(canonicalTxs, txs...)
+	}
+	return canonicalTxs
+}
+
+// splitMarket splits a market such as "BTCEUR" into its base and quote assets.
+func splitMarket(market string) (string, string, bool) {
+	market = strings.ToUpper(strings.ReplaceAll(market, "/", ""))
+	for _, quote := range quoteAssets {
+		if strings.HasSuffix(market, quote) && len(market) > len(quote) {
+			return strings.TrimSuffix(market, quote), quote, true
+		}
+	}
+	return "", "", false
+}
+
+func parseAmountAsset(s string) (exchange.Asset, error) {
+	m := amountAssetRe.FindStringSubmatch(strings.TrimSpace(s))
+	if m == nil {
+		return exchange.Asset{}, fmt.Errorf("could not parse amount '%s'", s)
+	}
+	return exchange.Asset{Symbol: strings.ToUpper(m[2]), Units: parseNumber(m[1])}, nil
+}
+
+func parseNumber(s string) float64 {
+	v, _ := strconv.ParseFloat(strings.ReplaceAll(strings.TrimSpace(s), ",", ""), 64)
+	return v
+}
+
+func parseDate(s string) (time.Time, error) {
+	for _, layout := range []string{"2006-01-02 15:04:05", "06-01-02 15:04:05", "2006-01-02T15:04:05Z"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("could not parse binance date '%s'", s)
+}
@@ -0,0 +1,187 @@
+// backend/src/parsers/degiro/locale.go
+package degiro
+
+import (
+	"regexp"
+	"strings"
+)
+
+// matcher recognises a description by its full text or by a fragment of it. Both are lowercase.
+type matcher struct {
+	exact, contains []string
+}
+
+func (m matcher) match(lowerDesc string) bool {
+	for _, s := range m.exact {
+		if lowerDesc == s {
+			return true
+		}
+	}
+	return containsAny(lowerDesc, m.contains)
+}
+
+// locale holds the descriptions DeGiro writes in one language of its exports.
+type locale struct {
+	code string
+	// header is the name of the date and time columns, which tell the languages apart.
+	header [2]string
+	// decimalComma is set when the quantities and prices of the descriptions use a decimal comma.
+	decimalComma bool
+	// trade matches "<buy|sell> <quantity> <product>@<price> <currency>"; buyWord is the buy verb.
+	trade   *regexp.Regexp
+	buyWord string
+
+	interest, commission, fx, connectivity, dividend, dividendTax matcher
+	deposit, withdrawal, productChange                            matcher
+	// stockDividend marks shares distributed without a cash alternative (as opposed to a
+	// scrip dividend, where the shares replace a cash dividend).
+	stockDividend []string
+}
+
+func tradePattern(buy, sell string) *regexp.Regexp {
+	return regexp.MustCompile(`(?i)\b(` + buy + `|` + sell + `)\s+([\d\s.,]+)\s+(.+?)\s*@([\d,.]+)`)
+}
+
+// locales lists the export languages. Portuguese comes first: it is the language the parser
+// was written for and the fallback when nothing else matches.
+var locales = []*locale{
+	{
+		code: "pt", header: [2]string{"data", "hora"}, decimalComma: true,
+		trade: tradePattern("compra", "venda"), buyWord: "compra",
+		interest:      matcher{exact: []string{"juros"}},
+		commission:    matcher{contains: []string{"comissões de transação", "custos de transação"}},
+		fx:            matcher{contains: []string{"crédito de divisa", "levantamento de divisa", "mudança de divisa"}},
+		connectivity:  matcher{contains: []string{"custo de conectividade"}},
+		dividend:      matcher{contains: []string{"dividendo"}},
+		dividendTax:   matcher{contains: []string{"imposto sobre dividendo"}},
+		deposit:       matcher{exact: []string{"depósito"}},
+		withdrawal:    matcher{exact: []string{"levantamento"}},
+		productChange: matcher{contains: []string{"mudança de produto"}},
+		stockDividend: []string{"dividendo em ações", "dividendo em acções", "stock dividend", "bónus", "bonus", "gratuitas"},
+	},
+	{
+		code: "en", header: [2]string{"date", "time"},
+		trade: tradePattern("buy", "sell"), buyWord: "buy",
+		interest:      matcher{exact: []string{"interest", "flatex interest", "flatex interest income"}},
+		commission:    matcher{contains: []string{"transaction fee", "transaction and/or third"}},
+		fx:            matcher{contains: []string{"fx credit", "fx debit", "fx withdrawal"}},
+		connectivity:  matcher{contains: []string{"connection fee", "connectivity fee"}},
+		dividend:      matcher{contains: []string{"dividend"}},
+		dividendTax:   matcher{contains: []string{"dividend tax"}},
+		deposit:       matcher{exact: []string{"deposit", "ideal deposit", "sofort deposit", "ideal / sofort deposit"}},
+		withdrawal:    matcher{exact: []string{"withdrawal"}},
+		productChange: matcher{contains: []string{"product change"}},
+		stockDividend: []string{"stock dividend", "bonus"},
+	},
+	{
+		code: "es", header: [2]string{"fecha", "hora"}, decimalComma: true,
+		trade: tradePattern("compra", "venta"), buyWord: "compra",
+		interest:      matcher{exact: []string{"interés", "intereses"}},
+		commission:    matcher{contains: []string{"comisión de transacción", "costes de transacción"}},
+		fx:            matcher{contains: []string{"cambio de divisa"}},
+		connectivity:  matcher{contains: []string{"conectividad"}},
+		dividend:      matcher{contains: []string{"dividendo"}},
+		dividendTax:   matcher{contains: []string{"retención del dividendo", "retención de dividendo"}},
+		deposit:       matcher{exact: []string{"ingreso", "depósito"}},
+		withdrawal:    matcher{exact: []string{"retirada"}},
+		productChange: matcher{contains: []string{"cambio de producto"}},
+		stockDividend: []string{"dividendo en acciones", "acciones liberadas", "bonus"},
+	},
+	{
+		code: "nl", header: [2]string{"datum", "tijd"}, decimalComma: true,
+		trade: tradePattern("koop", "verkoop"), buyWord: "koop",
+		interest:      matcher{exact: []string{"rente", "flatex rente"}},
+		commission:    matcher{contains: []string{"transactiekosten"}},
+		fx:            matcher{contains: []string{"valuta creditering", "valuta debitering"}},
+		connectivity:  matcher{contains: []string{"aansluitingskosten"}},
+		dividend:      matcher{contains: []string{"dividend"}},
+		dividendTax:   matcher{contains: []string{"dividendbelasting"}},
+		deposit:       matcher{exact: []string{"storting", "ideal storting", "ideal / sofort storting"}},
+		withdrawal:    matcher{exact: []string{"terugstorting", "opname"}},
+		productChange: matcher{contains: []string{"productwijziging"}},
+		stockDividend: []string{"stockdividend", "bonusaandelen"},
+	},
+	{
+		code: "de", header: [2]string{"datum", "uhrzeit"}, decimalComma: true,
+		trade: tradePattern("kauf", "verkauf"), buyWord: "kauf",
+		interest:      matcher{exact: []string{"zinsen", "flatex zinsen"}},
+		commission:    matcher{contains: []string{"transaktionsgebühr", "transaktionskosten"}},
+		fx:            matcher{contains: []string{"währungswechsel"}},
+		connectivity:  matcher{contains: []string{"verbindungsgebühr", "börsenanbindung"}},
+		dividend:      matcher{contains: []string{"dividende"}},
+		dividendTax:   matcher{contains: []string{"dividendensteuer"}},
+		deposit:       matcher{exact: []string{"einzahlung", "sofort einzahlung", "ideal / sofort einzahlung"}},
+		withdrawal:    matcher{exact: []string{"auszahlung"}},
+		productChange: matcher{contains: []string{"produktänderung"}},
+		stockDividend: []string{"stockdividende", "gratisaktien", "bonusaktien"},
+	},
+	{
+		code: "fr", header: [2]string{"date", "heure"}, decimalComma: true,
+		trade: tradePattern("achat", "vente"), buyWord: "achat",
+		interest:      matcher{exact: []string{"intérêts", "intérêt"}},
+		commission:    matcher{contains: []string{"frais de transaction", "frais de courtage"}},
+		fx:            matcher{contains: []string{"opération de change"}},
+		connectivity:  matcher{contains: []string{"frais de connexion"}},
+		dividend:      matcher{contains: []string{"dividende"}},
+		dividendTax:   matcher{contains: []string{"impôts sur dividende", "impôt sur dividende"}},
+		deposit:       matcher{exact: []string{"dépôt", "versement de fonds"}},
+		withdrawal:    matcher{exact: []string{"retrait"}},
+		productChange: matcher{contains: []string{"changement de produit"}},
+		stockDividend: []string{"dividende en actions", "actions gratuites"},
+	},
+	{
+		code: "it", header: [2]string{"data", "ora"}, decimalComma: true,
+		trade: tradePattern("acquisto", "vendita"), buyWord: "acquisto",
+		interest:      matcher{exact: []string{"interessi"}},
+		commission:    matcher{contains: []string{"commissione di transazione", "commissioni di transazione"}},
+		fx:            matcher{contains: []string{"cambio valuta"}},
+		connectivity:  matcher{contains: []string{"costo di connessione", "commissione di connessione"}},
+		dividend:      matcher{contains: []string{"dividendo"}},
+		dividendTax:   matcher{contains: []string{"ritenuta sul dividendo", "ritenuta su dividendo"}},
+		deposit:       matcher{exact: []string{"versamento", "versamento fondi", "deposito"}},
+		withdrawal:    matcher{exact: []string{"prelievo"}},
+		productChange: matcher{contains: []string{"cambio prodotto", "cambio di prodotto"}},
+		stockDividend: []string{"dividendo in azioni", "azioni gratuite"},
+	},
+}
+
+// detectLocale picks the language that recognises the most rows of an Account export. The
+// header decides ties, since the column names differ per language; Portuguese is the default.
+func detectLocale(header []string, rawTxs []RawTransaction) *locale {
+	preferred := locales[0]
+	if len(header) >= 2 {
+		date, clock := normalizeHeader(header[0]), normalizeHeader(header[1])
+		for _, loc := range locales {
+			if loc.header == [2]string{date, clock} {
+				preferred = loc
+				break
+			}
+		}
+	}
+
+	best, bestScore := preferred, countRecognised(preferred, rawTxs)
+	for _, loc := range locales {
+		if loc == preferred {
+			continue
+		}
+		if score := countRecognised(loc, rawTxs); score > bestScore {
+			best, bestScore = loc, score
+		}
+	}
+	return best
+}
+
+func countRecognised(loc *locale, rawTxs []RawTransaction) int {
+	count := 0
+	for _, raw := range rawTxs {
+		if txType, _, _, _, _, _ := classifyDeGiroTransaction(raw, loc); txType != "UNKNOWN" {
+			count++
+		}
+	}
+	return count
+}
+
+// normalizeHeader lowercases a column name and drops the byte order mark of the first column.
+func normalizeHeader(s string) string {
+	return strings.ToLower(strings.TrimSpace(strings.TrimPrefix(s, "\ufeff")))
+}
@@ -21,7 +21,8 @@ type RawTransaction struct {
 	RawLine                                                                                                                           string
 }
 
-// DeGiroParser implements the parsers.Parser interface for DeGiro files.
+// DeGiroParser implements the parsers.Parser interface for DeGiro files: the Account
+// statement (Account.csv) and the trade list (Transactions.csv), in any export language.
 type DeGiroParser struct{}
 
 // NewParser creates a new instance of the DeGiroParser.
@@ -29,33 +30,95 @@ func NewParser() *DeGiroParser {
 	return &DeGiroParser{}
 }
 
+var (
+	tradeCurrencyRe = regexp.MustCompile(`@[\d,.]+\s+([A-Z]{3})\b`)
+	optionPatternRe = regexp.MustCompile(`\s+[CP]\d+(\.\d+)?\s+\d{2}[A-Z]{3}\d{2}$`)
+)
+
+// normalizeDecimalString prepares an amount for strconv.ParseFloat. The decimal separator is
+// the last of "," and "."; the other one separates thousands.
 func normalizeDecimalString(s string) string {
 	// 1. Trim whitespace and quotes
 	cleaned := strings.TrimSpace(s)
 	cleaned = strings.Trim(cleaned, "\"")
 
-	// 2. Replace comma with a period for the decimal point
+	// 2. Drop the thousands separator, then use a period for the decimal point
+	if strings.LastIndex(cleaned, ",") > strings.LastIndex(cleaned, ".") {
+		cleaned = strings.ReplaceAll(cleaned, ".", "")
+	} else {
+		cleaned = strings.ReplaceAll(cleaned, ",", "")
+	}
 	cleaned = strings.ReplaceAll(cleaned, ",", ".")
 
 	return cleaned
 }
 
-// Parse reads a DeGiro CSV file and converts its rows into a slice of CanonicalTransaction.
+// Parse reads a DeGiro CSV file, telling the Account and Transactions exports apart by their header.
 func (p *DeGiroParser) Parse(file io.Reader) ([]models.CanonicalTransaction, error) {
+	txs, _, err := parseFile(file)
+	return txs, err
+}
+
+// ParseFiles reads the files of one upload together. When both exports are uploaded, the
+// trades of Transactions.csv, which state quantity, price and costs in their own columns,
+// replace the Account.csv trades of the same order, and the two are cross-checked.
+func (p *DeGiroParser) ParseFiles(files []io.Reader) ([]models.CanonicalTransaction, error) {
+	var accountTxs, tradeTxs []models.CanonicalTransaction
+	var firstErr error
+	parsed := 0
+	for i, file := range files {
+		txs, isTransactions, err := parseFile(file)
+		if err != nil {
+			log.Printf("DeGiro Parser: Skipping file %d of multi-file upload: %v", i+1, err)
+			if firstErr == nil {
+				firstErr = fmt.Errorf("file %d: %w", i+1, err)
+			}
+			continue
+		}
+		parsed++
+		if isTransactions {
+			tradeTxs = append(tradeTxs, txs...)
+		} else {
+			accountTxs = append(accountTxs, txs...)
+		}
+	}
+	if parsed == 0 && firstErr != nil {
+		return nil, firstErr
+	}
+	if len(accountTxs) == 0 || len(tradeTxs) == 0 {
+		return append(accountTxs, tradeTxs...), nil
+	}
+	return mergeExports(accountTxs, tradeTxs), nil
+}
+
+// parseFile reads one export and reports whether it was a Transactions export.
+func parseFile(file io.Reader) ([]models.CanonicalTransaction, bool, error) {
 	// --- CSV Reading Logic ---
 	reader := csv.NewReader(file)
 	reader.FieldsPerRecord = -1 // Allow variable number of fields per record
 
-	// Read and discard the header row
-	if _, err := reader.Read(); err != nil {
-		return nil, fmt.Errorf("degiro parser: failed to read CSV header: %w", err)
+	header, err := reader.Read()
+	if err != nil {
+		return nil, false, fmt.Errorf("degiro parser: failed to read CSV header: %w", err)
 	}
 
 	records, err := reader.ReadAll() // Read all records at once
 	if err != nil {
-		return nil, fmt.Errorf("degiro parser: failed to read all CSV records: %w", err)
+		return nil, false, fmt.Errorf("degiro parser: failed to read all CSV records: %w", err)
 	}
 
+	if cols, ok := findTransactionColumns(header); ok {
+		txs := parseTransactions(records, cols)
+		keyTrades(txs)
+		return txs, true, nil
+	}
+	txs := parseAccount(header, records)
+	keyTrades(txs)
+	return txs, false, nil
+}
+
+// parseAccount converts the rows of an Account export into a slice of CanonicalTransaction.
+func parseAccount(header []string, records [][]string) []models.CanonicalTransaction {
 	// --- Raw Transaction Mapping ---
 	var rawTxs []RawTransaction
 	for _, record := range records {
@@ -86,6 +149,11 @@ func (p *DeGiroParser) Parse(file io.Reader) ([]models.CanonicalTransaction, err
 		rawTxs[i], rawTxs[j] = rawTxs[j], rawTxs[i]
 	}
 
+	loc := detectLocale(header, rawTxs)
+	if loc != locales[0] {
+		log.Printf("DeGiro Parser: Reading account statement in language '%s'", loc.code)
+	}
+
 	// --- Canonical Transaction Conversion ---
 	var canonicalTxs []models.CanonicalTransaction
 	for _, raw := range rawTxs {
@@ -95,7 +163,7 @@ func (p *DeGiroParser) Parse(file io.Reader) ([]models.CanonicalTransaction, err
 			continue
 		}
 
-		txType, subType, buySell, productName, quantity, price := classifyDeGiroTransaction(raw)
+		txType, subType, buySell, productName, quantity, price := classifyDeGiroTransaction(raw, loc)
 
 		/*	if txType == "COMMISSION_IGNORE" {
 				continue
@@ -114,7 +182,20 @@ func (p *DeGiroParser) Parse(file io.Reader) ([]models.CanonicalTransaction, err
 			finalAmount = -math.Abs(sourceAmt)
 		}
 
-		commission, _ := findCommissionForOrder(raw.OrderID, rawTxs)
+		currency := raw.Currency
+		if subType == models.SubTypeDividendReinvestment || subType == models.SubTypeStockDividend {
+			// Shares received as a dividend come without a cash movement: the cost basis is
+			// the reinvestment value, or nothing for a stock dividend.
+			if currency == "" {
+				currency = tradeCurrency(raw.Description)
+			}
+			finalAmount = 0
+			if subType == models.SubTypeDividendReinvestment {
+				finalAmount = -quantity * price
+			}
+		}
+
+		commission, _ := findCommissionForOrder(raw.OrderID, rawTxs, loc)
 
 		// --- Extract Balance ---
 		var cashBalance float64
@@ -135,7 +216,7 @@ func (p *DeGiroParser) Parse(file io.Reader) ([]models.CanonicalTransaction, err
 			ISIN:               strings.TrimSpace(raw.ISIN),
 			Quantity:           quantity,
 			Price:              price,
-			Currency:           raw.Currency,
+			Currency:           currency,
 			OrderID:            raw.OrderID,
 			RawText:            raw.RawLine,
 			SourceAmount:       sourceAmt,
@@ -149,73 +230,70 @@ func (p *DeGiroParser) Parse(file io.Reader) ([]models.CanonicalTransaction, err
 			BalanceCurrency: raw.BalanceCurrency,
 			HasBalance:      hasBalance,
 		}
+		if subType == models.SubTypeDividendReinvestment {
+			canonicalTxs = append(canonicalTxs, reinvestedDividend(tx, raw))
+		}
 		canonicalTxs = append(canonicalTxs, tx)
 	}
 
-	return canonicalTxs, nil
+	return canonicalTxs
 }
 
-func classifyDeGiroTransaction(raw RawTransaction) (txType, subType, buySell, productName string, quantity, price float64) {
+// classifyDeGiroTransaction reads the type of a row from its description, written in the
+// language of the export.
+func classifyDeGiroTransaction(raw RawTransaction, loc *locale) (txType, subType, buySell, productName string, quantity, price float64) {
 	desc := strings.TrimSpace(strings.ReplaceAll(raw.Description, "\u00A0", " "))
 	lowerDesc := strings.ToLower(desc)
 
-	if strings.EqualFold(lowerDesc, "juros") {
+	if loc.interest.match(lowerDesc) {
 		return "FEE", "INTEREST", "", desc, 0, 0
 	}
 
-	if strings.Contains(lowerDesc, "comissões de transação") {
+	if loc.commission.match(lowerDesc) {
 		return "COMMISSION_DETAIL", "", "", desc, 0, 0
 	}
 
-	if strings.Contains(lowerDesc, "crédito de divisa") || strings.Contains(lowerDesc, "levantamento de divisa") || strings.Contains(lowerDesc, "mudança de divisa") {
+	if loc.fx.match(lowerDesc) {
 		return "FX", "", "", desc, 0, 0
 	}
 
-	if strings.Contains(lowerDesc, "custo de conectividade") {
+	if loc.connectivity.match(lowerDesc) {
 		return "FEE", "", "", desc, 0, 0
 	}
 
-	if strings.Contains(lowerDesc, "dividendo") {
+	if loc.dividend.match(lowerDesc) && !loc.trade.MatchString(desc) {
 		productName = strings.TrimSpace(raw.Name)
-		if strings.Contains(lowerDesc, "imposto sobre dividendo") {
+		if loc.dividendTax.match(lowerDesc) {
 			return "DIVIDEND", "TAX", "", productName, 0, 0
 		}
 		return "DIVIDEND", "", "", productName, 0, 0
 	}
-	if strings.EqualFold(lowerDesc, "depósito") || strings.Contains(lowerDesc, "flatex deposit") {
+	// The flatex cash account writes its movements in English in every language.
+	if loc.deposit.match(lowerDesc) || strings.Contains(lowerDesc, "flatex deposit") {
 		return "CASH", "DEPOSIT", "", "Cash Deposit", 0, 0
 	}
-	if strings.EqualFold(lowerDesc, "levantamento") || strings.Contains(lowerDesc, "flatex withdrawal") {
+	if loc.withdrawal.match(lowerDesc) || strings.Contains(lowerDesc, "flatex withdrawal") {
 		return "CASH", "WITHDRAWAL", "", "Cash Withdrawal", 0, 0
 	}
 
-	if strings.Contains(lowerDesc, "mudança de produto") {
+	if loc.productChange.match(lowerDesc) {
 		return "PRODUCT_CHANGE", "", "", "Product Change", 0, 0
 	}
 
-	stockOrOptionRe := regexp.MustCompile(`(?i)\s*(compra|venda)\s+([\d\s.,]+)\s+(.+?)\s*@([\d,.]+)`)
-	matches := stockOrOptionRe.FindStringSubmatch(desc)
+	matches := loc.trade.FindStringSubmatch(desc)
 	if matches == nil {
 		return "UNKNOWN", "", "", "", 0, 0
 	}
 
-	buySellRaw := strings.ToLower(matches[1])
-	if buySellRaw == "compra" {
+	if strings.EqualFold(matches[1], loc.buyWord) {
 		buySell = "BUY"
-	} else if buySellRaw == "venda" {
+	} else {
 		buySell = "SELL"
 	}
 
 	productName = strings.TrimSpace(matches[3])
+	quantity, price = parseTradeNumbers(matches[2], matches[4], loc.decimalComma)
 
-	quantityStr := strings.ReplaceAll(strings.ReplaceAll(matches[2], " ", ""), ".", "")
-	quantityStr = strings.ReplaceAll(quantityStr, ",", ".")
-	quantity, _ = strconv.ParseFloat(quantityStr, 64)
-
-	priceStr := strings.ReplaceAll(matches[4], ",", ".")
-	price, _ = strconv.ParseFloat(priceStr, 64)
-
-	optionPatternRe := regexp.MustCompile(`\s+[CP]\d+(\.\d+)?\s+\d{2}[A-Z]{3}\d{2}$`)
 	if optionPatternRe.MatchString(productName) {
 		txType = "OPTION"
 		if strings.Contains(productName, " C") {
@@ -227,16 +305,82 @@ func classifyDeGiroTransaction(raw RawTransaction) (txType, subType, buySell, pr
 		txType = "STOCK"
 	}
 
+	if txType == "STOCK" && buySell == "BUY" && isZeroCash(raw.Amount) && !strings.Contains(lowerDesc, "isin") {
+		if price == 0 || containsAny(lowerDesc, loc.stockDividend) {
+			subType = models.SubTypeStockDividend
+		} else {
+			subType = models.SubTypeDividendReinvestment
+		}
+	}
+
 	return
 }
 
-func findCommissionForOrder(orderId string, transactions []RawTransaction) (float64, error) {
+// parseTradeNumbers reads the quantity and price of a trade description. With a decimal
+// comma, dots in the quantity separate thousands ("1.000"); otherwise commas do ("1,000").
+func parseTradeNumbers(quantityRaw, priceRaw string, decimalComma bool) (quantity, price float64) {
+	quantityStr := strings.ReplaceAll(quantityRaw, " ", "")
+	priceStr := priceRaw
+	if decimalComma {
+		quantityStr = strings.ReplaceAll(strings.ReplaceAll(quantityStr, ".", ""), ",", ".")
+		priceStr = strings.ReplaceAll(priceStr, ",", ".")
+	} else {
+		quantityStr = strings.ReplaceAll(quantityStr, ",", "")
+		priceStr = strings.ReplaceAll(priceStr, ",", "")
+	}
+	quantity, _ = strconv.ParseFloat(quantityStr, 64)
+	price, _ = strconv.ParseFloat(priceStr, 64)
+	return quantity, price
+}
+
+// reinvestedDividend builds the DIVIDEND income paid out as the shares of a reinvestment buy.
+func reinvestedDividend(buy models.CanonicalTransaction, raw RawTransaction) models.CanonicalTransaction {
+	return models.CanonicalTransaction{
+		Source:          buy.Source,
+		TransactionDate: buy.TransactionDate,
+		ProductName:     strings.TrimSpace(raw.Name),
+		ISIN:            buy.ISIN,
+		Currency:        buy.Currency,
+		RawText:         raw.RawLine + ",REINVESTED_DIVIDEND",
+		Amount:          -buy.Amount,
+		TransactionType: "DIVIDEND",
+	}
+}
+
+// isZeroCash reports whether a row moved no cash, as DeGiro books shares received as a dividend.
+func isZeroCash(amount string) bool {
+	normalized := normalizeDecimalString(amount)
+	if normalized == "" {
+		return true
+	}
+	value, err := strconv.ParseFloat(normalized, 64)
+	return err == nil && value == 0
+}
+
+// tradeCurrency returns the currency quoted after the price of a trade description, e.g. "@12,5 USD".
+func tradeCurrency(desc string) string {
+	if matches := tradeCurrencyRe.FindStringSubmatch(desc); matches != nil {
+		return matches[1]
+	}
+	return "EUR"
+}
+
+func containsAny(s string, keywords []string) bool {
+	for _, keyword := range keywords {
+		if strings.Contains(s, keyword) {
+			return true
+		}
+	}
+	return false
+}
+
+func findCommissionForOrder(orderId string, transactions []RawTransaction, loc *locale) (float64, error) {
 	if orderId == "" {
 		return 0, nil
 	}
 	var totalCommission float64
 	for _, transaction := range transactions {
-		if transaction.OrderID == orderId && strings.Contains(transaction.Description, "Comissões de transação") {
+		if transaction.OrderID == orderId && loc.commission.match(strings.ToLower(transaction.Description)) {
 			normalizedAmount := normalizeDecimalString(transaction.Amount)
 			amount, err := strconv.ParseFloat(normalizedAmount, 64)
 			if err != nil {
@@ -0,0 +1,293 @@
+// backend/src/parsers/degiro/transactions.go
+package degiro
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/username/taxfolio/backend/src/models"
+)
+
+// currencyCodeRe matches the currency column that follows an amount column of Transactions.csv.
+var currencyCodeRe = regexp.MustCompile(`^[A-Z]{3}$`)
+
+// Column names of Transactions.csv in the export languages, lowercase. Names are matched in
+// full, except the costs and order ID columns, which vary between export versions.
+var (
+	productHeaders    = []string{"product", "produto", "producto", "produkt", "produit", "prodotto"}
+	quantityHeaders   = []string{"quantity", "quantidade", "cantidad", "número", "aantal", "anzahl", "quantité", "quantità"}
+	priceHeaders      = []string{"price", "preço", "preços", "precio", "koers", "kurs", "cours", "prezzo"}
+	localValueHeaders = []string{"local value", "valor local", "lokale waarde", "lokaler wert", "valeur locale", "montant devise locale", "valore locale"}
+	valueHeaders      = []string{"value", "value eur", "valor", "valor eur", "waarde", "waarde eur", "wert", "wert eur", "valeur", "valeur eur", "valore", "valore eur"}
+	costsHeaders      = []string{"transaction", "transação", "transacción", "transactiekosten", "transaktion", "frais", "costi", "commissioni"}
+	orderIDHeaders    = []string{"order id", "order-id", "id da ordem", "id orden", "id ordre", "id ordine"}
+)
+
+// transactionColumns holds the column index of each field of Transactions.csv; -1 when absent.
+// Amount columns are followed by an unnamed column with their currency.
+type transactionColumns struct {
+	product, isin, quantity, price, localValue, value, costs, orderID int
+}
+
+// findTransactionColumns locates the columns of a Transactions.csv header. It reports false for
+// other files, such as the Account statement, which have no quantity and price columns.
+func findTransactionColumns(header []string) (transactionColumns, bool) {
+	cols := transactionColumns{product: -1, isin: -1, quantity: -1, price: -1, localValue: -1, value: -1, costs: -1, orderID: -1}
+	for i, name := range header {
+		name = normalizeHeader(name)
+		switch {
+		case name == "":
+		case containsExact(productHeaders, name):
+			cols.product = i
+		case strings.Contains(name, "isin"):
+			cols.isin = i
+		case containsExact(quantityHeaders, name):
+			cols.quantity = i
+		case containsExact(priceHeaders, name):
+			cols.price = i
+		case containsExact(localValueHeaders, name):
+			cols.localValue = i
+		case containsExact(valueHeaders, name):
+			cols.value = i
+		case containsAny(name, orderIDHeaders):
+			cols.orderID = i
+		case containsAny(name, costsHeaders) && cols.costs == -1:
+			cols.costs = i
+		}
+	}
+	ok := cols.isin >= 0 && cols.quantity >= 0 && cols.price >= 0 && cols.localValue >= 0
+	return cols, ok
+}
+
+// parseTransactions converts the rows of a Transactions export. Quantities are negative for
+// sales and local values negative for purchases.
+func parseTransactions(records [][]string, cols transactionColumns) []models.CanonicalTransaction {
+	var canonicalTxs []models.CanonicalTransaction
+	// Like Account.csv, the export is newest-first.
+	for i := len(records) - 1; i >= 0; i-- {
+		record := records[i]
+		get := func(idx int) string {
+			if idx < 0 || idx >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[idx])
+		}
+		nextCurrency := func(idx int) string {
+			if c := get(idx + 1); idx >= 0 && currencyCodeRe.MatchString(c) {
+				return c
+			}
+			return ""
+		}
+
+		date, err := time.Parse("02-01-2006", get(0))
+		if err != nil {
+			log.Printf("DeGiro Parser: Skipping trade due to invalid date: %s (OrderID: %s)", get(0), get(cols.orderID))
+			continue
+		}
+		quantity := parseTransactionNumber(get(cols.quantity))
+		price := parseTransactionNumber(get(cols.price))
+		localValue := parseTransactionNumber(get(cols.localValue))
+		if quantity == 0 {
+			continue
+		}
+		if localValue == 0 {
+			// Shares received in corporate actions; Account.csv books them with their context.
+			log.Printf("DeGiro Parser: Skipping trade without value in transactions export (OrderID: %s)", get(cols.orderID))
+			continue
+		}
+
+		currency := firstNonEmpty(nextCurrency(cols.localValue), nextCurrency(cols.price), "EUR")
+		productName := get(cols.product)
+		tx := models.CanonicalTransaction{
+			Source:          "degiro",
+			TransactionDate: date,
+			ProductName:     productName,
+			ISIN:            get(cols.isin),
+			Quantity:        math.Abs(quantity),
+			Price:           price,
+			Currency:        currency,
+			OrderID:         get(cols.orderID),
+			RawText:         "Transactions," + strings.Join(record, ","),
+			SourceAmount:    localValue,
+			Amount:          localValue,
+			TransactionType: "STOCK",
+			BuySell:         "BUY",
+			Commission:      tradeCommission(get(cols.costs), localValue, parseTransactionNumber(get(cols.value))),
+		}
+		if quantity < 0 {
+			tx.BuySell = "SELL"
+		}
+		if optionPatternRe.MatchString(productName) {
+			tx.TransactionType = "OPTION"
+			if strings.Contains(productName, " C") {
+				tx.TransactionSubType = "CALL"
+			} else if strings.Contains(productName, " P") {
+				tx.TransactionSubType = "PUT"
+			}
+		}
+		canonicalTxs = append(canonicalTxs, tx)
+	}
+	return canonicalTxs
+}
+
+// tradeCommission converts the EUR costs of a trade to its currency, using the ratio of the
+// local value to the EUR value.
+func tradeCommission(costsRaw string, localValue, eurValue float64) float64 {
+	costs := math.Abs(parseTransactionNumber(costsRaw))
+	if costs == 0 || eurValue == 0 {
+		return costs
+	}
+	return costs * math.Abs(localValue/eurValue)
+}
+
+// TradeRawText identifies a fill of a DeGiro order by what Account.csv and Transactions.csv
+// both report, so that a trade uploaded from one export is recognised when the other is
+// uploaded. fill numbers, from 1, the fills of the order with the same date and quantity.
+func TradeRawText(orderID, isin string, date time.Time, quantity float64, fill int) string {
+	return fmt.Sprintf("DeGiroTrade|%s|%s|%s|%s|%d", orderID, isin, date.Format("2006-01-02"),
+		strconv.FormatFloat(quantity, 'f', -1, 64), fill)
+}
+
+// IsOrderTrade reports whether tx is the fill of an order, which both exports list. Shares
+// received as dividends are only in Account.csv.
+func IsOrderTrade(orderID, txType, subType string) bool {
+	return orderID != "" && (txType == "STOCK" || txType == "OPTION") &&
+		subType != models.SubTypeDividendReinvestment && subType != models.SubTypeStockDividend
+}
+
+// keyTrades sets the raw text of the order fills of one export to their TradeRawText.
+func keyTrades(txs []models.CanonicalTransaction) {
+	fills := make(map[string]int)
+	for i := range txs {
+		tx := &txs[i]
+		if !IsOrderTrade(tx.OrderID, tx.TransactionType, tx.TransactionSubType) {
+			continue
+		}
+		key := TradeRawText(tx.OrderID, tx.ISIN, tx.TransactionDate, tx.Quantity, 0)
+		fills[key]++
+		tx.RawText = TradeRawText(tx.OrderID, tx.ISIN, tx.TransactionDate, tx.Quantity, fills[key])
+	}
+}
+
+// mergeExports replaces the Account.csv trades whose order is in Transactions.csv with the
+// trades of Transactions.csv, and logs the orders where both disagree on quantity or value.
+// A trade takes the place and the cash balance of the Account.csv row of the same fill, or of
+// the last row of its order when the fills differ. Shares received as dividends stay as
+// booked in Account.csv.
+func mergeExports(accountTxs, tradeTxs []models.CanonicalTransaction) []models.CanonicalTransaction {
+	type orderTotals struct{ quantity, amount float64 }
+	fromTrades := make(map[string]*orderTotals)
+	tradesByOrder := make(map[string][]int)
+	for i, tx := range tradeTxs {
+		if tx.OrderID == "" {
+			continue
+		}
+		totals := fromTrades[tx.OrderID]
+		if totals == nil {
+			totals = &orderTotals{}
+			fromTrades[tx.OrderID] = totals
+		}
+		totals.quantity += tx.Quantity
+		totals.amount += tx.Amount
+		tradesByOrder[tx.OrderID] = append(tradesByOrder[tx.OrderID], i)
+	}
+
+	tradeByRawText := make(map[string]int)
+	for i, tx := range tradeTxs {
+		tradeByRawText[tx.RawText] = i
+	}
+	accountRawTexts := make(map[string]bool)
+	for _, tx := range accountTxs {
+		accountRawTexts[tx.RawText] = true
+	}
+
+	fromAccount := make(map[string]*orderTotals)
+	merged := make([]models.CanonicalTransaction, 0, len(accountTxs)+len(tradeTxs))
+	placed := make([]bool, len(tradeTxs))
+	lastOfOrder := make(map[string]int)
+	place := func(i int, account models.CanonicalTransaction) {
+		trade := tradeTxs[i]
+		trade.CashBalance, trade.BalanceCurrency, trade.HasBalance = account.CashBalance, account.BalanceCurrency, account.HasBalance
+		placed[i] = true
+		lastOfOrder[trade.OrderID] = len(merged)
+		merged = append(merged, trade)
+	}
+	for _, tx := range accountTxs {
+		if !IsOrderTrade(tx.OrderID, tx.TransactionType, tx.TransactionSubType) || fromTrades[tx.OrderID] == nil {
+			merged = append(merged, tx)
+			continue
+		}
+		totals := fromAccount[tx.OrderID]
+		if totals == nil {
+			totals = &orderTotals{}
+			fromAccount[tx.OrderID] = totals
+		}
+		totals.quantity += tx.Quantity
+		totals.amount += tx.Amount
+
+		if i, ok := tradeByRawText[tx.RawText]; ok {
+			place(i, tx)
+			continue
+		}
+		for _, i := range tradesByOrder[tx.OrderID] {
+			if !placed[i] && !accountRawTexts[tradeTxs[i].RawText] {
+				place(i, models.CanonicalTransaction{})
+			}
+		}
+		if last, ok := lastOfOrder[tx.OrderID]; ok && tx.HasBalance {
+			merged[last].CashBalance, merged[last].BalanceCurrency, merged[last].HasBalance = tx.CashBalance, tx.BalanceCurrency, true
+		}
+	}
+
+	mismatches := 0
+	for orderID, account := range fromAccount {
+		trades := fromTrades[orderID]
+		if math.Abs(account.quantity-trades.quantity) > 1e-6 || math.Abs(account.amount-trades.amount) > 0.01 {
+			log.Printf("DeGiro Parser: Order %s differs between exports: account %g units for %.2f, transactions %g units for %.2f",
+				orderID, account.quantity, account.amount, trades.quantity, trades.amount)
+			mismatches++
+		}
+	}
+	log.Printf("DeGiro Parser: Cross-checked %d orders between Account.csv and Transactions.csv, %d differ", len(fromAccount), mismatches)
+
+	for i, tx := range tradeTxs {
+		if !placed[i] {
+			merged = append(merged, tx)
+		}
+	}
+	sort.SliceStable(merged, func(i, j int) bool {
+		return merged[i].TransactionDate.Before(merged[j].TransactionDate)
+	})
+	return merged
+}
+
+// parseTransactionNumber reads an amount of Transactions.csv, written with a decimal comma or
+// point depending on the export language.
+func parseTransactionNumber(s string) float64 {
+	value, _ := strconv.ParseFloat(normalizeDecimalString(s), 64)
+	return value
+}
+
+func containsExact(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,38 @@
+// backend/src/parsers/detect.go
+package parsers
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+)
+
+// SourceAuto is the upload source that lets the file content choose the parser.
+const SourceAuto = "auto"
+
+// detectPeekSize is how much of a file is read to recognise its format.
+const detectPeekSize = 4096
+
+// DetectSource recognises the format of a file from its first bytes and returns the source
+// of its parser, with a reader that still yields the whole file.
+func DetectSource(file io.Reader) (string, io.Reader, error) {
+	reader := bufio.NewReaderSize(file, detectPeekSize)
+	head, err := reader.Peek(detectPeekSize)
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		return "", nil, fmt.Errorf("failed to read file: %w", err)
+	}
+	head = bytes.ToUpper(bytes.TrimSpace(bytes.TrimPrefix(head, []byte("\xef\xbb\xbf"))))
+
+	switch {
+	case bytes.HasPrefix(head, []byte("OFXHEADER")), bytes.Contains(head, []byte("<OFX>")):
+		return "ofx", reader, nil
+	case bytes.HasPrefix(head, []byte("!TYPE:")), bytes.HasPrefix(head, []byte("!OPTION:")), bytes.HasPrefix(head, []byte("!ACCOUNT")):
+		return "qif", reader, nil
+	case bytes.Contains(head, []byte("<FLEXQUERYRESPONSE")):
+		return "ibkr", reader, nil
+	case bytes.HasPrefix(head, []byte("STATEMENT,HEADER,FIELD NAME")):
+		return "ibkr_csv", reader, nil
+	}
+	return "", nil, fmt.Errorf("unrecognised file format, please choose the broker")
+}
@@ -0,0 +1,469 @@
+// backend/src/parsers/etoro/parser.go
+package etoro
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/username/taxfolio/backend/src/logger"
+	"github.com/username/taxfolio/backend/src/models"
+	"github.com/username/taxfolio/backend/src/parsers/xlsx"
+)
+
+// eToro accounts are kept in USD; every amount of the statement is in USD.
+const accountCurrency = "USD"
+
+// table is a sheet with its header row indexed by lower-case column name.
+type table struct {
+	header []string
+	rows   [][]string
+}
+
+func newTable(sheet *xlsx.Sheet) table {
+	if sheet == nil || len(sheet.Rows) == 0 {
+		return table{}
+	}
+	header := make([]string, len(sheet.Rows[0]))
+	for i, name := range sheet.Rows[0] {
+		header[i] = strings.ToLower(strings.TrimSpace(name))
+	}
+	return table{header: header, rows: sheet.Rows[1:]}
+}
+
+// get returns the cell of the first column whose name starts with one of the prefixes,
+// since eToro adds the currency to some names, e.g. "Profit(USD)".
+func (t table) get(row []string, prefixes ...string) string {
+	for _, prefix := range prefixes {
+		for i, name := range t.header {
+			if strings.HasPrefix(name, prefix) {
+				if i < len(row) {
+					return strings.TrimSpace(row[i])
+				}
+				return ""
+			}
+		}
+	}
+	return ""
+}
+
+// has reports whether the sheet has a column whose name starts with prefix.
+func (t table) has(prefix string) bool {
+	for _, name := range t.header {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func (t table) number(row []string, prefixes ...string) float64 {
+	v, _ := parseNumber(t.get(row, prefixes...))
+	return v
+}
+
+// EToroParser implements the parsers.Parser interface for the eToro account statement workbook.
+type EToroParser struct{}
+
+// NewParser creates a new instance of the EToroParser.
+func NewParser() *EToroParser {
+	return &EToroParser{}
+}
+
+// Parse reads the "Closed Positions", "Account Activity" and "Dividends" sheets of an account
+// statement. eToro has no buy rows: every position becomes a BUY at its open date and, once
+// closed, a SELL at its close date. Short positions open with the SELL. CFD positions are
+// reported with the derivatives, as one contract per position.
+func (p *EToroParser) Parse(file io.Reader) ([]models.CanonicalTransaction, error) {
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return nil, fmt.Errorf("etoro parser: failed to read file: %w", err)
+	}
+	if !xlsx.IsXLSX(data) {
+		return nil, fmt.Errorf("etoro parser: the account statement must be uploaded as an XLSX workbook")
+	}
+	wb, err := xlsx.Read(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("etoro parser: %w", err)
+	}
+	closed := newTable(wb.Sheet("closed positions"))
+	activity := newTable(wb.Sheet("account activity"))
+	dividends := newTable(wb.Sheet("dividends"))
+	if closed.header == nil && activity.header == nil && dividends.header == nil {
+		return nil, fmt.Errorf("etoro parser: no Closed Positions, Account Activity or Dividends sheet found")
+	}
+	if len(closed.rows) > 0 && !closed.has("isin") {
+		return nil, fmt.Errorf("etoro parser: the Closed Positions sheet has no ISIN column; download a recent account statement")
+	}
+
+	var canonicalTxs []models.CanonicalTransaction
+	closedIDs := make(map[string]bool)
+	for _, row := range closed.rows {
+		txs, err := closedPosition(closed, row)
+		if err != nil {
+			logger.L.Warn("eToro Parser: Skipping closed position", "positionID", closed.get(row, "position id"), "error", err)
+			continue
+		}
+		closedIDs[closed.get(row, "position id")] = true
+		canonicalTxs = append(canonicalTxs, txs...)
+	}
+	canonicalTxs = append(canonicalTxs, accountActivity(activity, closedIDs, positionISINs(closed, dividends))...)
+	canonicalTxs = append(canonicalTxs, dividendRows(dividends)...)
+
+	sort.SliceStable(canonicalTxs, func(i, j int) bool {
+		return canonicalTxs[i].TransactionDate.Before(canonicalTxs[j].TransactionDate)
+	})
+	return canonicalTxs, nil
+}
+
+// position is an eToro position, closed or still open.
+type position struct {
+	id        string
+	name      string
+	isin      string
+	cfd       bool
+	short     bool
+	units     float64
+	invested  float64 // USD put into the position
+	openDate  time.Time
+	openRate  float64
+	closeDate time.Time // Zero while open
+	closeRate float64
+	proceeds  float64 // USD returned when closed: invested + profit
+}
+
+// closedPosition synthesises the opening and closing trades of a closed position.
+func closedPosition(t table, row []string) ([]models.CanonicalTransaction, error) {
+	id := t.get(row, "position id")
+	if id == "" {
+		return nil, fmt.Errorf("row without a position id")
+	}
+	openDate, err := parseDate(t.get(row, "open date"))
+	if err != nil {
+		return nil, err
+	}
+	closeDate, err := parseDate(t.get(row, "close date"))
+	if err != nil {
+		return nil, err
+	}
+	pos := position{
+		id:        id,
+		name:      instrumentName(t.get(row, "action")),
+		isin:      t.get(row, "isin"),
+		short:     strings.EqualFold(t.get(row, "long / short", "long"), "short"),
+		units:     math.Abs(t.number(row, "units")),
+		invested:  math.Abs(t.number(row, "amount")),
+		openDate:  openDate,
+		openRate:  t.number(row, "open rate"),
+		closeDate: closeDate,
+		closeRate: t.number(row, "close rate"),
+	}
+	pos.proceeds = pos.invested + t.number(row, "profit(usd)", "profit")
+	pos.cfd = isCFD(t.get(row, "type"), t.number(row, "leverage"), pos.short)
+	if !pos.cfd && !isStockType(t.get(row, "type")) {
+		return nil, fmt.Errorf("unsupported asset type %q", t.get(row, "type"))
+	}
+	if pos.units <= 0 {
+		return nil, fmt.Errorf("position without units")
+	}
+	if !pos.cfd && pos.isin == "" {
+		return nil, fmt.Errorf("position without an ISIN")
+	}
+	return pos.transactions(), nil
+}
+
+// positionISINs maps position IDs to the ISINs of the Closed Positions and Dividends sheets.
+// The Account Activity sheet has no ISIN column, so they are the only source of the ISIN of a
+// position still open.
+func positionISINs(sheets ...table) map[string]string {
+	isins := make(map[string]string)
+	for _, t := range sheets {
+		for _, row := range t.rows {
+			if id, isin := t.get(row, "position id"), t.get(row, "isin"); id != "" && isin != "" {
+				isins[id] = isin
+			}
+		}
+	}
+	return isins
+}
+
+// tickerISINs maps the tickers of the Account Activity sheet, e.g. "AAPL" in "AAPL/USD", to
+// the ISIN of any of their positions, so that a position still open without a dividend takes
+// the ISIN of another position in the same instrument.
+func tickerISINs(t table, isins map[string]string) map[string]string {
+	tickers := make(map[string]string)
+	for _, row := range t.rows {
+		if isin := isins[t.get(row, "position id")]; isin != "" {
+			tickers[instrumentName(t.get(row, "details"))] = isin
+		}
+	}
+	return tickers
+}
+
+// accountActivity maps cash movements, fees and interest, and opens the positions that are
+// not closed yet. Dividends are read from their own sheet, which has the tax withheld.
+func accountActivity(t table, closedIDs map[string]bool, isins map[string]string) []models.CanonicalTransaction {
+	var txs []models.CanonicalTransaction
+	tickers := tickerISINs(t, isins)
+	for _, row := range t.rows {
+		activity := strings.ToLower(t.get(row, "type"))
+		if activity == "" {
+			continue
+		}
+		date, err := parseDate(t.get(row, "date"))
+		if err != nil {
+			logger.L.Warn("eToro Parser: Skipping activity with invalid date", "date", t.get(row, "date"), "type", activity)
+			continue
+		}
+		amount := t.number(row, "amount")
+		tx := models.CanonicalTransaction{
+			Source:          "etoro",
+			TransactionDate: date,
+			ProductName:     t.get(row, "details"),
+			Currency:        accountCurrency,
+			OrderID:         t.get(row, "position id"),
+			RawText:         "etoro|activity|" + strings.Join(row, "|"),
+			SourceAmount:    amount,
+		}
+
+		switch {
+		case activity == "open position":
+			id := t.get(row, "position id")
+			if id == "" || closedIDs[id] {
+				continue
+			}
+			assetType := t.get(row, "asset type")
+			pos := position{
+				id:       id,
+				name:     instrumentName(t.get(row, "details")),
+				isin:     isins[id],
+				units:    math.Abs(t.number(row, "units")),
+				invested: math.Abs(amount),
+				openDate: date,
+				cfd:      isCFD(assetType, 1, false),
+			}
+			if pos.units <= 0 || (!pos.cfd && !isStockType(assetType)) {
+				continue
+			}
+			if pos.isin == "" {
+				pos.isin = tickers[pos.name]
+			}
+			if pos.isin == "" && !pos.cfd {
+				logger.L.Warn("eToro Parser: Skipping open position without an ISIN", "positionID", id, "instrument", pos.name)
+				continue
+			}
+			pos.openRate = pos.invested / pos.units
+			txs = append(txs, pos.transactions()...)
+			continue
+		case strings.Contains(activity, "deposit"):
+			tx.TransactionType, tx.TransactionSubType = "CASH", "DEPOSIT"
+			tx.ProductName = "Cash Deposit"
+			tx.Amount = math.Abs(amount)
+		case strings.Contains(activity, "withdraw") && strings.Contains(activity, "fee"):
+			tx.TransactionType = "FEE"
+			tx.ProductName = "Withdrawal Fee"
+			tx.Amount = -math.Abs(amount)
+		case strings.Contains(activity, "withdraw"):
+			tx.TransactionType, tx.TransactionSubType = "CASH", "WITHDRAWAL"
+			tx.ProductName = "Cash Withdrawal"
+			tx.Amount = -math.Abs(amount)
+		case strings.Contains(activity, "interest"):
+			tx.TransactionType, tx.TransactionSubType = "FEE", "INTEREST"
+			tx.ProductName = "Interest"
+			tx.Amount = amount
+		case strings.Contains(activity, "fee"):
+			// Overnight fees are signed: refunds on short positions are positive.
+			tx.TransactionType = "FEE"
+			tx.Amount = amount
+		default:
+			continue
+		}
+		txs = append(txs, tx)
+	}
+	return txs
+}
+
+// dividendRows maps the Dividends sheet, which reports the net dividend and the tax withheld
+// per position. Dividend adjustments of CFD positions are not dividends and are skipped.
+func dividendRows(t table) []models.CanonicalTransaction {
+	var txs []models.CanonicalTransaction
+	for _, row := range t.rows {
+		if strings.EqualFold(t.get(row, "type"), "cfd") {
+			continue
+		}
+		date, err := parseDate(t.get(row, "date of payment", "date"))
+		if err != nil {
+			continue
+		}
+		net := t.number(row, "net dividend received (usd)", "net dividend received")
+		tax := math.Abs(t.number(row, "withholding tax amount (usd)", "withholding tax amount"))
+		if net <= 0 && tax == 0 {
+			continue
+		}
+		tx := models.CanonicalTransaction{
+			Source:          "etoro",
+			TransactionDate: date,
+			ProductName:     t.get(row, "instrument name"),
+			ISIN:            t.get(row, "isin"),
+			Currency:        accountCurrency,
+			OrderID:         t.get(row, "position id"),
+			RawText:         "etoro|dividend|" + strings.Join(row, "|"),
+			SourceAmount:    net,
+			Amount:          math.Round((net+tax)*100) / 100,
+			TransactionType: "DIVIDEND",
+		}
+		txs = append(txs, tx)
+		if tax > 0 {
+			taxTx := tx
+			taxTx.TransactionSubType = "TAX"
+			taxTx.Amount = -tax
+			taxTx.SourceAmount = tax
+			taxTx.RawText = tx.RawText + "|TAX"
+			txs = append(txs, taxTx)
+		}
+	}
+	return txs
+}
+
+// transactions returns the opening trade and, for a closed position, the closing one. Both are
+// keyed on the position ID alone, so that the opening trade of a position imported while open
+// is recognised once the position is closed and rebuilt from the Closed Positions sheet. Real
+// assets are STOCK trades that feed the FIFO. A CFD becomes an OPTION contract of its own,
+// bought for the amount invested and sold for the amount returned, so that the derivatives
+// report shows its profit whether it was long or short.
+func (p position) transactions() []models.CanonicalTransaction {
+	if p.cfd {
+		return p.cfdTransactions()
+	}
+	openSide, closeSide := "BUY", "SELL"
+	if p.short {
+		openSide, closeSide = "SELL", "BUY"
+	}
+
+	base := models.CanonicalTransaction{
+		Source:          "etoro",
+		ProductName:     p.name,
+		ISIN:            p.isin,
+		Quantity:        p.units,
+		Currency:        accountCurrency,
+		OrderID:         p.id,
+		TransactionType: "STOCK",
+	}
+
+	open := base
+	open.TransactionDate = p.openDate
+	open.BuySell = openSide
+	open.Price = p.openRate
+	open.SourceAmount = p.invested
+	open.Amount = -p.invested
+	open.RawText = "etoro|open|" + p.id
+	if p.closeDate.IsZero() {
+		return []models.CanonicalTransaction{open}
+	}
+
+	closeTx := base
+	closeTx.TransactionDate = p.closeDate
+	closeTx.BuySell = closeSide
+	closeTx.Price = p.closeRate
+	closeTx.SourceAmount = p.proceeds
+	closeTx.Amount = p.proceeds
+	closeTx.RawText = "etoro|close|" + p.id
+	return []models.CanonicalTransaction{open, closeTx}
+}
+
+func (p position) cfdTransactions() []models.CanonicalTransaction {
+	base := models.CanonicalTransaction{
+		Source: "etoro",
+		// The option processor matches contracts by product name.
+		ProductName:        fmt.Sprintf("%s CFD %s", p.name, p.id),
+		ISIN:               p.isin,
+		Quantity:           1,
+		Currency:           accountCurrency,
+		OrderID:            p.id,
+		TransactionType:    "OPTION",
+		TransactionSubType: "CFD",
+	}
+
+	open := base
+	open.TransactionDate = p.openDate
+	open.BuySell = "BUY"
+	open.Price = p.invested
+	open.SourceAmount = p.invested
+	open.Amount = -p.invested
+	open.RawText = "etoro|open|" + p.id
+	if p.closeDate.IsZero() {
+		return []models.CanonicalTransaction{open}
+	}
+
+	closeTx := base
+	closeTx.TransactionDate = p.closeDate
+	closeTx.BuySell = "SELL"
+	closeTx.Price = p.proceeds
+	closeTx.SourceAmount = p.proceeds
+	closeTx.Amount = p.proceeds
+	closeTx.RawText = "etoro|close|" + p.id
+	return []models.CanonicalTransaction{open, closeTx}
+}
+
+// isCFD reports whether a position is a CFD: eToro marks them in the type column, and any
+// leveraged or short position is one.
+func isCFD(assetType string, leverage float64, short bool) bool {
+	return strings.EqualFold(strings.TrimSpace(assetType), "cfd") || leverage > 1 || short
+}
+
+// isStockType reports whether a real asset is a stock or an ETF; crypto and commodities are not supported.
+func isStockType(assetType string) bool {
+	switch strings.ToLower(strings.TrimSpace(assetType)) {
+	case "stocks", "stock", "etf", "etfs", "":
+		return true
+	}
+	return false
+}
+
+// instrumentName strips the direction from "Buy Apple" and the quote currency from "AAPL/USD".
+func instrumentName(action string) string {
+	name := strings.TrimSpace(action)
+	for _, prefix := range []string{"Buy ", "Sell "} {
+		name = strings.TrimPrefix(name, prefix)
+	}
+	if idx := strings.Index(name, "/"); idx > 0 {
+		name = name[:idx]
+	}
+	return name
+}
+
+var dateLayouts = []string{
+	"02/01/2006 15:04:05", "02/01/2006 15:04", "02/01/2006",
+	"02.01.2006 15:04:05", "2006-01-02 15:04:05", "2006-01-02",
+}
+
+// parseDate accepts eToro's text dates and the date serials of the workbook.
+func parseDate(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	if t, ok := xlsx.ParseSerialDate(s); ok {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("could not parse etoro date '%s'", s)
+}
+
+// parseNumber parses numbers written as "1,234.56" or "(12.30)" for negatives.
+func parseNumber(s string) (float64, error) {
+	s = strings.ReplaceAll(strings.TrimSpace(s), ",", "")
+	negative := strings.HasPrefix(s, "(") && strings.HasSuffix(s, ")")
+	s = strings.Trim(s, "()")
+	v, err := strconv.ParseFloat(s, 64)
+	if negative {
+		v = -v
+	}
+	return v, err
+}
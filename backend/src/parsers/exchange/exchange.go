@@ -0,0 +1,192 @@
+// backend/src/parsers/exchange/exchange.go
+
+// Package exchange converts the trades and rewards of crypto exchanges into canonical
+// transactions. Crypto assets have TransactionType "CRYPTO" and are identified by their
+// symbol, which takes the place of the ISIN. Legs priced in another crypto asset keep that
+// asset as their currency; the upload service values them in EUR before processing.
+package exchange
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/username/taxfolio/backend/src/models"
+	"github.com/username/taxfolio/backend/src/utils"
+)
+
+// valuationAssets are the assets pairs are quoted in, which always have an EUR price.
+var valuationAssets = map[string]bool{
+	"BTC": true, "ETH": true, "BNB": true, "USDT": true, "USDC": true, "BUSD": true, "FDUSD": true, "DAI": true, "EURC": true,
+}
+
+// Asset is an amount of a crypto asset or fiat currency.
+type Asset struct {
+	Symbol string
+	Units  float64
+}
+
+// Trade is the exchange of one asset for another, with the fee charged in any asset.
+type Trade struct {
+	Source   string
+	Date     time.Time
+	Acquired Asset // Gross of the fee, when the fee is charged in the acquired asset
+	Disposed Asset
+	Fee      Asset
+	OrderID  string
+	RawText  string
+}
+
+// Transactions converts the trade. A purchase or sale against fiat is one leg priced in that
+// currency. A crypto-to-crypto trade is a sale of the disposed asset and a purchase of the
+// acquired one, both valued in the quote asset of the pair. A fee charged in a third asset
+// is a disposal of those units at their market value, recorded as commission.
+func (t Trade) Transactions() ([]models.CanonicalTransaction, error) {
+	acquiredFiat := utils.IsFiatCurrency(t.Acquired.Symbol)
+	disposedFiat := utils.IsFiatCurrency(t.Disposed.Symbol)
+	if t.Acquired.Units <= 0 || t.Disposed.Units <= 0 {
+		return nil, fmt.Errorf("trade without units")
+	}
+	if acquiredFiat && disposedFiat {
+		return nil, fmt.Errorf("currency conversion %s to %s is not a crypto trade", t.Disposed.Symbol, t.Acquired.Symbol)
+	}
+
+	acquiredUnits := t.Acquired.Units
+	var commission float64
+	var feeTxs []models.CanonicalTransaction
+	switch {
+	case t.Fee.Units <= 0:
+	case t.Fee.Symbol == t.Acquired.Symbol && !acquiredFiat:
+		acquiredUnits -= t.Fee.Units
+	case utils.IsFiatCurrency(t.Fee.Symbol) && (t.Fee.Symbol == t.Acquired.Symbol || t.Fee.Symbol == t.Disposed.Symbol):
+		commission = t.Fee.Units
+	default:
+		feeTxs = append(feeTxs, t.feeTransaction())
+	}
+
+	var txs []models.CanonicalTransaction
+	switch {
+	case disposedFiat:
+		buy := t.leg("BUY", t.Acquired.Symbol, acquiredUnits, -t.Disposed.Units, t.Disposed.Symbol)
+		buy.Commission = commission
+		txs = append(txs, buy)
+	case acquiredFiat:
+		sell := t.leg("SELL", t.Disposed.Symbol, t.Disposed.Units, t.Acquired.Units, t.Acquired.Symbol)
+		sell.Commission = commission
+		txs = append(txs, sell)
+	default:
+		value := t.Disposed
+		if valuationAssets[t.Acquired.Symbol] && !valuationAssets[t.Disposed.Symbol] {
+			value = t.Acquired
+		}
+		txs = append(txs,
+			t.leg("SELL", t.Disposed.Symbol, t.Disposed.Units, value.Units, value.Symbol),
+			t.leg("BUY", t.Acquired.Symbol, acquiredUnits, -value.Units, value.Symbol),
+		)
+	}
+	return append(txs, feeTxs...), nil
+}
+
+func (t Trade) leg(buySell, symbol string, units, amount float64, currency string) models.CanonicalTransaction {
+	tx := models.CanonicalTransaction{
+		Source:          t.Source,
+		TransactionDate: t.Date,
+		ProductName:     symbol,
+		ISIN:            symbol,
+		TransactionType: "CRYPTO",
+		BuySell:         buySell,
+		Quantity:        units,
+		Amount:          amount,
+		SourceAmount:    amount,
+		Currency:        currency,
+		OrderID:         t.OrderID,
+		RawText:         t.RawText + "|" + buySell + "|" + symbol,
+	}
+	if units > 0 {
+		tx.Price = math.Abs(amount) / units
+	}
+	return tx
+}
+
+// feeTransaction is the fee paid in an asset other than those traded: a trading fee when paid
+// in fiat, or otherwise a disposal of the units used to pay it, such as BNB on Binance. The
+// disposal is priced in the fee asset itself, so that the upload service values it at the
+// market price, and the same value is its commission.
+func (t Trade) feeTransaction() models.CanonicalTransaction {
+	if utils.IsFiatCurrency(t.Fee.Symbol) {
+		return models.CanonicalTransaction{
+			Source:          t.Source,
+			TransactionDate: t.Date,
+			ProductName:     "Trading Fee",
+			TransactionType: "FEE",
+			Amount:          -t.Fee.Units,
+			SourceAmount:    t.Fee.Units,
+			Currency:        t.Fee.Symbol,
+			OrderID:         t.OrderID,
+			RawText:         t.RawText + "|FEE",
+		}
+	}
+	tx := t.leg("SELL", t.Fee.Symbol, t.Fee.Units, t.Fee.Units, t.Fee.Symbol)
+	tx.TransactionSubType = models.SubTypeCryptoFee
+	tx.Commission = t.Fee.Units
+	tx.RawText = t.RawText + "|FEE"
+	return tx
+}
+
+// Reward converts a staking or earn reward into a purchase of the units received at their
+// market value and the income of the same value, so that the cash movements cancel out.
+// Interest paid in fiat is recorded like broker interest.
+func Reward(source string, date time.Time, reward Asset, rawText string) []models.CanonicalTransaction {
+	if utils.IsFiatCurrency(reward.Symbol) {
+		return []models.CanonicalTransaction{{
+			Source:             source,
+			TransactionDate:    date,
+			ProductName:        "Interest",
+			TransactionType:    "FEE",
+			TransactionSubType: "INTEREST",
+			Amount:             reward.Units,
+			SourceAmount:       reward.Units,
+			Currency:           reward.Symbol,
+			RawText:            rawText,
+		}}
+	}
+	buy := models.CanonicalTransaction{
+		Source:             source,
+		TransactionDate:    date,
+		ProductName:        reward.Symbol,
+		ISIN:               reward.Symbol,
+		TransactionType:    "CRYPTO",
+		TransactionSubType: models.SubTypeStaking,
+		BuySell:            "BUY",
+		Quantity:           reward.Units,
+		Price:              1,
+		Amount:             -reward.Units,
+		SourceAmount:       reward.Units,
+		Currency:           reward.Symbol,
+		RawText:            rawText,
+	}
+	income := buy
+	income.BuySell = ""
+	income.Amount = reward.Units
+	income.RawText = rawText + "|INCOME"
+	return []models.CanonicalTransaction{buy, income}
+}
+
+// CashMovement converts a fiat deposit (positive units) or withdrawal (negative units).
+func CashMovement(source string, date time.Time, cash Asset, rawText string) models.CanonicalTransaction {
+	tx := models.CanonicalTransaction{
+		Source:             source,
+		TransactionDate:    date,
+		ProductName:        "Cash Deposit",
+		TransactionType:    "CASH",
+		TransactionSubType: "DEPOSIT",
+		Amount:             cash.Units,
+		SourceAmount:       math.Abs(cash.Units),
+		Currency:           cash.Symbol,
+		RawText:            rawText,
+	}
+	if cash.Units < 0 {
+		tx.ProductName, tx.TransactionSubType = "Cash Withdrawal", "WITHDRAWAL"
+	}
+	return tx
+}
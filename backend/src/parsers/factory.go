@@ -4,8 +4,17 @@ package parsers
 import (
 	"fmt"
 
+	"github.com/username/taxfolio/backend/src/parsers/binance"
 	"github.com/username/taxfolio/backend/src/parsers/degiro"
+	"github.com/username/taxfolio/backend/src/parsers/etoro"
 	"github.com/username/taxfolio/backend/src/parsers/ibkr"
+	"github.com/username/taxfolio/backend/src/parsers/kraken"
+	"github.com/username/taxfolio/backend/src/parsers/ofx"
+	"github.com/username/taxfolio/backend/src/parsers/qif"
+	"github.com/username/taxfolio/backend/src/parsers/revolut"
+	"github.com/username/taxfolio/backend/src/parsers/scalable"
+	"github.com/username/taxfolio/backend/src/parsers/traderepublic"
+	"github.com/username/taxfolio/backend/src/parsers/xtb"
 )
 
 func GetParser(source string) (Parser, error) {
@@ -14,6 +23,26 @@ func GetParser(source string) (Parser, error) {
 		return degiro.NewParser(), nil
 	case "ibkr":
 		return ibkr.NewParser(), nil
+	case "ibkr_csv":
+		return ibkr.NewActivityStatementParser(), nil
+	case "revolut":
+		return revolut.NewParser(), nil
+	case "traderepublic":
+		return traderepublic.NewParser(), nil
+	case "scalable":
+		return scalable.NewParser(), nil
+	case "etoro":
+		return etoro.NewParser(), nil
+	case "xtb":
+		return xtb.NewParser(), nil
+	case "binance":
+		return binance.NewParser(), nil
+	case "kraken":
+		return kraken.NewParser(), nil
+	case "ofx":
+		return ofx.NewParser(), nil
+	case "qif":
+		return qif.NewParser(), nil
 	default:
 		return nil, fmt.Errorf("no parser available for source: %s", source)
 	}
@@ -0,0 +1,302 @@
+// backend/src/parsers/generic/parser.go
+
+// Package generic reads the CSV export of any broker through a user-defined import template,
+// which names the column of each field and maps the broker's type values to transaction kinds.
+package generic
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/username/taxfolio/backend/src/logger"
+	"github.com/username/taxfolio/backend/src/models"
+)
+
+// dateTokens translates the tokens of a template date format into Go layout elements,
+// longest first so that "YYYY" is not read as two "YY".
+var dateTokens = []struct{ token, layout string }{
+	{"YYYY", "2006"}, {"YY", "06"}, {"MMM", "Jan"}, {"MM", "01"}, {"DD", "02"},
+	{"HH", "15"}, {"mm", "04"}, {"ss", "05"},
+}
+
+// DateLayout converts a template date format such as "DD/MM/YYYY HH:mm" into a Go time layout.
+// The format must contain a year, a month and a day.
+func DateLayout(format string) (string, error) {
+	var layout strings.Builder
+	var seen []string
+	for i := 0; i < len(format); {
+		matched := false
+		for _, t := range dateTokens {
+			if strings.HasPrefix(format[i:], t.token) {
+				layout.WriteString(t.layout)
+				seen = append(seen, t.token)
+				i += len(t.token)
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			layout.WriteByte(format[i])
+			i++
+		}
+	}
+	has := func(tokens ...string) bool {
+		for _, s := range seen {
+			for _, t := range tokens {
+				if s == t {
+					return true
+				}
+			}
+		}
+		return false
+	}
+	if !has("YYYY", "YY") || !has("MMM", "MM") || !has("DD") {
+		return "", fmt.Errorf("date format %q needs a year (YYYY), a month (MM) and a day (DD)", format)
+	}
+	return layout.String(), nil
+}
+
+// GenericParser implements the parsers.Parser interface for an import template.
+type GenericParser struct {
+	source   string
+	template models.ImportTemplate
+}
+
+// NewParser creates a parser for the template. Transactions are recorded under source.
+func NewParser(source string, template models.ImportTemplate) *GenericParser {
+	return &GenericParser{source: source, template: template}
+}
+
+// row is one CSV record with the template's columns resolved.
+type row struct {
+	date                                   time.Time
+	kind, isin, productName, currency, ref string
+	quantity, price, amount, commission    float64
+	raw                                    string
+}
+
+// Parse reads the CSV with the template's delimiter and maps its rows into CanonicalTransactions.
+// Rows whose type value is not mapped, or which cannot be read, are skipped.
+func (p *GenericParser) Parse(file io.Reader) ([]models.CanonicalTransaction, error) {
+	t := p.template
+	layout, err := DateLayout(t.DateFormat)
+	if err != nil {
+		return nil, fmt.Errorf("generic parser: %w", err)
+	}
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return nil, fmt.Errorf("generic parser: failed to read file: %w", err)
+	}
+	reader := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))))
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	if t.Delimiter != "" {
+		reader.Comma, _ = utf8.DecodeRuneInString(t.Delimiter)
+	}
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("generic parser: failed to read CSV records: %w", err)
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("generic parser: empty file")
+	}
+
+	cols := make(map[string]int, len(records[0]))
+	for i, name := range records[0] {
+		cols[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	mapped := map[string]string{
+		"date": t.Columns.Date, "type": t.Columns.Type, "isin": t.Columns.ISIN, "product_name": t.Columns.ProductName,
+		"quantity": t.Columns.Quantity, "price": t.Columns.Price, "amount": t.Columns.Amount,
+		"currency": t.Columns.Currency, "commission": t.Columns.Commission, "order_id": t.Columns.OrderID,
+	}
+	for field, header := range mapped {
+		if header == "" {
+			continue
+		}
+		if _, ok := cols[strings.ToLower(strings.TrimSpace(header))]; !ok {
+			return nil, fmt.Errorf("generic parser: column %q mapped to %s not found in file", header, field)
+		}
+	}
+	get := func(record []string, field string) string {
+		header := mapped[field]
+		if header == "" {
+			return ""
+		}
+		idx := cols[strings.ToLower(strings.TrimSpace(header))]
+		if idx >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[idx])
+	}
+
+	typeMapping := make(map[string]string, len(t.TypeMapping))
+	for value, kind := range t.TypeMapping {
+		typeMapping[strings.ToUpper(strings.TrimSpace(value))] = kind
+	}
+
+	var canonicalTxs []models.CanonicalTransaction
+	for line, record := range records[1:] {
+		if len(record) == 1 && strings.TrimSpace(record[0]) == "" {
+			continue
+		}
+		typeValue := get(record, "type")
+		kind, ok := typeMapping[strings.ToUpper(typeValue)]
+		if !ok {
+			logger.L.Warn("Generic Parser: Skipping row with unmapped type", "template", t.Name, "line", line+2, "type", typeValue)
+			continue
+		}
+		if kind == models.ImportTypeIgnore {
+			continue
+		}
+		date, err := parseDate(get(record, "date"), layout)
+		if err != nil {
+			logger.L.Warn("Generic Parser: Skipping row with invalid date", "template", t.Name, "line", line+2, "error", err)
+			continue
+		}
+		r := row{
+			date:        date,
+			kind:        kind,
+			isin:        strings.ToUpper(get(record, "isin")),
+			productName: get(record, "product_name"),
+			currency:    strings.ToUpper(get(record, "currency")),
+			ref:         get(record, "order_id"),
+			quantity:    math.Abs(p.parseNumber(get(record, "quantity"))),
+			price:       math.Abs(p.parseNumber(get(record, "price"))),
+			amount:      math.Abs(p.parseNumber(get(record, "amount"))),
+			commission:  math.Abs(p.parseNumber(get(record, "commission"))),
+			raw:         strings.Join(record, string(reader.Comma)),
+		}
+		if r.currency == "" {
+			r.currency = t.DefaultCurrency
+		}
+		tx, err := p.convertRow(r)
+		if err != nil {
+			logger.L.Warn("Generic Parser: Skipping row", "template", t.Name, "line", line+2, "error", err)
+			continue
+		}
+		canonicalTxs = append(canonicalTxs, tx)
+	}
+
+	sort.SliceStable(canonicalTxs, func(i, j int) bool {
+		return canonicalTxs[i].TransactionDate.Before(canonicalTxs[j].TransactionDate)
+	})
+	return canonicalTxs, nil
+}
+
+// convertRow signs the amount of a row by its kind. When the amount column is missing or
+// empty, the amount of a trade is its quantity times its price.
+func (p *GenericParser) convertRow(r row) (models.CanonicalTransaction, error) {
+	if r.amount == 0 && r.quantity > 0 {
+		r.amount = r.quantity * r.price
+	}
+	if r.price == 0 && r.quantity > 0 {
+		r.price = r.amount / r.quantity
+	}
+	productName := r.productName
+	if productName == "" {
+		productName = r.isin
+	}
+	tx := models.CanonicalTransaction{
+		Source:          p.source,
+		TransactionDate: r.date,
+		ProductName:     productName,
+		ISIN:            r.isin,
+		Currency:        r.currency,
+		OrderID:         r.ref,
+		SourceAmount:    r.amount,
+		RawText:         p.source + "|" + r.raw,
+	}
+
+	switch r.kind {
+	case models.ImportTypeBuy, models.ImportTypeSell:
+		// The ISIN column may hold a ticker for instruments without an ISIN. A product name
+		// does not resolve to a price, so it never stands in for either.
+		if r.isin == "" {
+			return tx, fmt.Errorf("trade without ISIN or ticker")
+		}
+		if r.quantity <= 0 {
+			return tx, fmt.Errorf("trade without quantity")
+		}
+		tx.TransactionType = "STOCK"
+		tx.BuySell = r.kind
+		tx.Quantity = r.quantity
+		tx.Price = r.price
+		tx.Commission = r.commission
+		tx.Amount = r.amount
+		if r.kind == models.ImportTypeBuy {
+			tx.Amount = -r.amount
+		}
+	case models.ImportTypeDividend:
+		tx.TransactionType = "DIVIDEND"
+		tx.Amount = r.amount
+	case models.ImportTypeDividendTax:
+		tx.TransactionType, tx.TransactionSubType = "DIVIDEND", "TAX"
+		tx.Amount = -r.amount
+	case models.ImportTypeFee:
+		tx.TransactionType = "FEE"
+		if tx.ProductName == "" {
+			tx.ProductName = "Fee"
+		}
+		tx.Amount = -r.amount
+	case models.ImportTypeInterest:
+		tx.TransactionType, tx.TransactionSubType = "FEE", "INTEREST"
+		tx.ProductName = "Interest"
+		tx.Amount = r.amount
+	case models.ImportTypeDeposit:
+		tx.TransactionType, tx.TransactionSubType = "CASH", "DEPOSIT"
+		tx.ProductName, tx.ISIN = "Cash Deposit", ""
+		tx.Amount = r.amount
+	case models.ImportTypeWithdrawal:
+		tx.TransactionType, tx.TransactionSubType = "CASH", "WITHDRAWAL"
+		tx.ProductName, tx.ISIN = "Cash Withdrawal", ""
+		tx.Amount = -r.amount
+	default:
+		return tx, fmt.Errorf("unknown transaction kind %q", r.kind)
+	}
+	if tx.Amount == 0 {
+		return tx, fmt.Errorf("row without amount")
+	}
+	return tx, nil
+}
+
+// parseDate parses a date with the template layout. Values with a time after a date-only
+// layout, such as "2024-03-01 10:00" for "YYYY-MM-DD", are read by their date part.
+func parseDate(s, layout string) (time.Time, error) {
+	if t, err := time.Parse(layout, s); err == nil {
+		return t, nil
+	}
+	if len(s) > len(layout) {
+		if t, err := time.Parse(layout, s[:len(layout)]); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("could not parse date '%s' with layout '%s'", s, layout)
+}
+
+// parseNumber parses an amount with the template's decimal separator, ignoring currency
+// symbols and thousands separators. Empty or unreadable values are zero.
+func (p *GenericParser) parseNumber(s string) float64 {
+	if p.template.DecimalSeparator == "," {
+		s = strings.ReplaceAll(s, ".", "")
+		s = strings.ReplaceAll(s, ",", ".")
+	} else {
+		s = strings.ReplaceAll(s, ",", "")
+	}
+	cleaned := strings.Map(func(r rune) rune {
+		if (r >= '0' && r <= '9') || r == '.' || r == '-' {
+			return r
+		}
+		return -1
+	}, s)
+	v, _ := strconv.ParseFloat(cleaned, 64)
+	return v
+}
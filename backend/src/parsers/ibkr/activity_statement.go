@@ -0,0 +1,292 @@
+package ibkr
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/username/taxfolio/backend/src/logger"
+	"github.com/username/taxfolio/backend/src/models"
+)
+
+// descriptionSecurityRe reads the symbol and ISIN that start the description of dividends,
+// withholding and corporate actions, e.g. "AAPL(US0378331005) Cash Dividend USD 0.24 per Share".
+var descriptionSecurityRe = regexp.MustCompile(`^\s*([^(\s]+)\s*\(([A-Z]{2}[A-Z0-9]{9}[0-9])\)`)
+
+// assetCategories maps the asset categories of the Activity Statement to their Flex codes.
+var assetCategories = map[string]string{
+	"Stocks":                   "STK",
+	"Equity and Index Options": "OPT",
+	"Forex":                    "CASH",
+	"Bonds":                    "BOND",
+	"Warrants":                 "WAR",
+	"Futures":                  "FUT",
+}
+
+// cashSections maps the cash sections of the Activity Statement to the Flex cash transaction
+// types. Interest takes the type of its sign.
+var cashSections = []struct{ section, flexType string }{
+	{"Deposits & Withdrawals", "Deposits/Withdrawals"},
+	{"Dividends", "Dividends"},
+	{"Payment In Lieu Of Dividends", "Payment In Lieu Of Dividends"},
+	{"Withholding Tax", "Withholding Tax"},
+	{"Fees", "Other Fees"},
+	{"Interest", ""},
+}
+
+// ActivityStatementParser implements the parsers.Parser interface for the default IBKR Activity
+// Statement CSV. Its sections are converted into the records of a Flex statement and classified
+// by the IBKRParser.
+type ActivityStatementParser struct {
+	flex *IBKRParser
+}
+
+// NewActivityStatementParser creates a new instance of the ActivityStatementParser.
+func NewActivityStatementParser() *ActivityStatementParser {
+	return &ActivityStatementParser{flex: NewParser()}
+}
+
+// instrument is a row of the Financial Instrument Information section.
+type instrument struct {
+	description, isin, underlying string
+}
+
+// sectionRow is a row of a section, with the columns of the header that precedes it.
+type sectionRow struct {
+	cols   map[string]int
+	record []string
+}
+
+func (r sectionRow) get(name string) string {
+	idx, ok := r.cols[name]
+	if !ok || idx >= len(r.record) {
+		return ""
+	}
+	return strings.TrimSpace(r.record[idx])
+}
+
+// Parse reads the sections of the statement. Every line starts with the section name and the
+// row kind ("Header", "Data", "SubTotal", "Total"); a section may repeat its header with
+// different columns for each asset category.
+func (p *ActivityStatementParser) Parse(file io.Reader) ([]models.CanonicalTransaction, error) {
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return nil, fmt.Errorf("ibkr activity statement parser: failed to read file: %w", err)
+	}
+	reader := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))))
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("ibkr activity statement parser: failed to read CSV records: %w", err)
+	}
+
+	sections := make(map[string][]sectionRow)
+	headers := make(map[string]map[string]int)
+	for _, record := range records {
+		if len(record) < 3 {
+			continue
+		}
+		name, kind := strings.TrimSpace(record[0]), strings.TrimSpace(record[1])
+		switch kind {
+		case "Header":
+			cols := make(map[string]int, len(record)-2)
+			for i, col := range record[2:] {
+				cols[strings.TrimSpace(col)] = i
+			}
+			headers[name] = cols
+		case "Data":
+			if cols, ok := headers[name]; ok {
+				sections[name] = append(sections[name], sectionRow{cols: cols, record: record[2:]})
+			}
+		}
+	}
+	if _, ok := headers["Statement"]; !ok {
+		return nil, fmt.Errorf("ibkr activity statement parser: not an activity statement")
+	}
+
+	instruments := readInstruments(sections["Financial Instrument Information"])
+	stmt := FlexStatement{}
+	for _, row := range sections["Trades"] {
+		if trade, ok := convertTradeRow(row, instruments); ok {
+			stmt.Trades = append(stmt.Trades, trade)
+		}
+	}
+	for _, cs := range cashSections {
+		for _, row := range sections[cs.section] {
+			if cashTx, ok := convertCashRow(row, cs.flexType); ok {
+				stmt.CashTransactions = append(stmt.CashTransactions, cashTx)
+			}
+		}
+	}
+	for _, row := range sections["Corporate Actions"] {
+		if action, ok := convertCorporateActionRow(row); ok {
+			stmt.CorporateActions = append(stmt.CorporateActions, action)
+		}
+	}
+
+	canonicalTxs := p.flex.convertStatement(stmt)
+	sort.SliceStable(canonicalTxs, func(i, j int) bool {
+		return canonicalTxs[i].TransactionDate.Before(canonicalTxs[j].TransactionDate)
+	})
+	return canonicalTxs, nil
+}
+
+// readInstruments indexes the Financial Instrument Information section by symbol.
+func readInstruments(rows []sectionRow) map[string]instrument {
+	instruments := make(map[string]instrument)
+	for _, row := range rows {
+		instruments[row.get("Symbol")] = instrument{
+			description: row.get("Description"),
+			isin:        row.get("Security ID"),
+			underlying:  row.get("Underlying"),
+		}
+	}
+	return instruments
+}
+
+// convertTradeRow converts an order of the Trades section. Proceeds are negative for buys, the
+// opposite sign of the Flex tradeMoney; commissions are negative.
+func convertTradeRow(row sectionRow, instruments map[string]instrument) (Trade, bool) {
+	if d := row.get("DataDiscriminator"); d != "" && d != "Order" && d != "Trade" {
+		return Trade{}, false
+	}
+	dateTime, err := toFlexDateTime(row.get("Date/Time"))
+	if err != nil {
+		logger.L.Warn("IBKR Activity Statement Parser: Skipping trade with invalid date", "symbol", row.get("Symbol"), "error", err)
+		return Trade{}, false
+	}
+	category := assetCategories[row.get("Asset Category")]
+	if category == "" {
+		category = strings.ToUpper(row.get("Asset Category"))
+	}
+	if category == "CASH" {
+		// Currency conversions, like the IDEALFX trades of a Flex statement.
+		return Trade{}, false
+	}
+	quantity := parseStatementNumber(row.get("Quantity"))
+	symbol := row.get("Symbol")
+	info := instruments[symbol]
+
+	trade := Trade{
+		AssetCategory: category,
+		Symbol:        symbol,
+		Description:   info.description,
+		ISIN:          info.isin,
+		DateTime:      dateTime,
+		TradeDate:     dateTime[:8],
+		Quantity:      quantity,
+		TradePrice:    parseStatementNumber(row.get("T. Price")),
+		TradeMoney:    -parseStatementNumber(row.get("Proceeds")),
+		Currency:      row.get("Currency"),
+		IBCommission:  parseStatementNumber(row.get("Comm/Fee")),
+		Notes:         row.get("Code"),
+		BuySell:       "BUY",
+	}
+	if trade.Description == "" {
+		trade.Description = symbol
+	}
+	if quantity < 0 {
+		trade.BuySell = "SELL"
+	}
+	if category == "OPT" {
+		// Option symbols end with the right, e.g. "AAPL 19JAN24 190 C".
+		if fields := strings.Fields(symbol); len(fields) > 0 {
+			trade.PutCall = fields[len(fields)-1]
+		}
+		if underlying, ok := instruments[info.underlying]; ok {
+			trade.UnderlyingSecurityID = underlying.isin
+		}
+	}
+	return trade, true
+}
+
+// convertCashRow converts a row of a cash section. Interest has no type of its own in the
+// statement and takes the Flex type of its sign.
+func convertCashRow(row sectionRow, flexType string) (CashTransaction, bool) {
+	currency := row.get("Currency")
+	if currency == "" || strings.HasPrefix(currency, "Total") {
+		return CashTransaction{}, false
+	}
+	date := row.get("Date")
+	if date == "" {
+		date = row.get("Settle Date")
+	}
+	dateTime, err := toFlexDateTime(date)
+	if err != nil {
+		logger.L.Warn("IBKR Activity Statement Parser: Skipping cash row with invalid date", "description", row.get("Description"), "error", err)
+		return CashTransaction{}, false
+	}
+	amount := parseStatementNumber(row.get("Amount"))
+	if flexType == "" {
+		flexType = "Broker Interest Received"
+		if amount < 0 {
+			flexType = "Broker Interest Paid"
+		}
+	}
+
+	cashTx := CashTransaction{
+		Type:          flexType,
+		Description:   row.get("Description"),
+		DateTime:      dateTime,
+		Amount:        amount,
+		Currency:      currency,
+		LevelOfDetail: "DETAIL",
+	}
+	if m := descriptionSecurityRe.FindStringSubmatch(cashTx.Description); m != nil {
+		cashTx.Symbol, cashTx.ISIN = m[1], m[2]
+	}
+	return cashTx, true
+}
+
+// convertCorporateActionRow converts a stock dividend of the Corporate Actions section. The
+// statement describes the action instead of giving its Flex type code.
+func convertCorporateActionRow(row sectionRow) (CorporateAction, bool) {
+	description := row.get("Description")
+	if !strings.Contains(strings.ToLower(description), "stock dividend") {
+		return CorporateAction{}, false
+	}
+	dateTime, err := toFlexDateTime(row.get("Date/Time"))
+	if err != nil {
+		logger.L.Warn("IBKR Activity Statement Parser: Skipping corporate action with invalid date", "description", description, "error", err)
+		return CorporateAction{}, false
+	}
+	action := CorporateAction{
+		Type:          "SD",
+		AssetCategory: assetCategories[row.get("Asset Category")],
+		Description:   description,
+		DateTime:      dateTime,
+		Quantity:      parseStatementNumber(row.get("Quantity")),
+		Currency:      row.get("Currency"),
+		LevelOfDetail: "DETAIL",
+	}
+	if m := descriptionSecurityRe.FindStringSubmatch(description); m != nil {
+		action.Symbol, action.ISIN = m[1], m[2]
+	}
+	return action, true
+}
+
+// toFlexDateTime converts the statement dates, "2024-01-15, 10:30:00" or "2024-01-15", to
+// the Flex format "20240115;103000" or "20240115".
+func toFlexDateTime(s string) (string, error) {
+	s = strings.TrimSpace(s)
+	for _, layout := range []string{"2006-01-02, 15:04:05", "2006-01-02 15:04:05", "2006-01-02;15:04:05"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t.Format("20060102;150405"), nil
+		}
+	}
+	if t, err := time.Parse("2006-01-02", s); err == nil {
+		return t.Format("20060102"), nil
+	}
+	return "", fmt.Errorf("could not parse ibkr statement date '%s'", s)
+}
+
+// parseStatementNumber reads a statement amount, which may have thousands separators ("1,234.5").
+func parseStatementNumber(s string) float64 {
+	return parseFloat(strings.ReplaceAll(s, ",", ""))
+}
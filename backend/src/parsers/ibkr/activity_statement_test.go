@@ -0,0 +1,130 @@
+package ibkr
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/username/taxfolio/backend/src/models"
+)
+
+const flexStatement = `<FlexQueryResponse queryName="Trades" type="AF">
+<FlexStatements count="1">
+<FlexStatement accountId="U1234567" fromDate="20240101" toDate="20240131">
+<Trades>
+<Trade assetCategory="STK" symbol="AAPL" description="APPLE INC" conid="265598" isin="US0378331005" dateTime="20240115;103000" tradeDate="20240115" quantity="10" tradePrice="185.5" tradeMoney="1855" currency="USD" exchange="NASDAQ" ibCommission="-1" ibCommissionCurrency="USD" buySell="BUY" ibOrderID="987654321" notes="" />
+<Trade assetCategory="OPT" symbol="AAPL  240119C00190000" description="AAPL 19JAN24 190 C" conid="123" isin="" underlyingSecurityID="US0378331005" multiplier="100" dateTime="20240116;153000" tradeDate="20240116" quantity="-1" tradePrice="2.1" tradeMoney="-210" currency="USD" exchange="CBOE" ibCommission="-0.65" ibCommissionCurrency="USD" buySell="SELL" ibOrderID="987654322" putCall="C" notes="" />
+</Trades>
+</FlexStatement>
+</FlexStatements>
+</FlexQueryResponse>`
+
+const activityStatement = `Statement,Header,Field Name,Field Value
+Statement,Data,Title,Activity Statement
+Trades,Header,DataDiscriminator,Asset Category,Currency,Symbol,Date/Time,Quantity,T. Price,C. Price,Proceeds,Comm/Fee,Basis,Realized P/L,MTM P/L,Code
+Trades,Data,Order,Stocks,USD,AAPL,"2024-01-15, 10:30:00",10,185.5,186,-1855,-1,1856,0,5,O
+Trades,Data,Order,Equity and Index Options,USD,AAPL 19JAN24 190 C,"2024-01-16, 15:30:00",-1,2.1,2.5,210,-0.65,-209.35,0,-40,O
+Financial Instrument Information,Header,Asset Category,Symbol,Description,Conid,Security ID,Underlying,Listing Exch,Multiplier,Type,Code
+Financial Instrument Information,Data,Stocks,AAPL,APPLE INC,265598,US0378331005,AAPL,NASDAQ,1,COMMON,
+Financial Instrument Information,Data,Equity and Index Options,AAPL 19JAN24 190 C,AAPL 19JAN24 190 C,123,,AAPL,CBOE,100,,
+`
+
+func TestTradesMatchAcrossFormats(t *testing.T) {
+	flexTxs, err := NewParser().Parse(strings.NewReader(flexStatement))
+	if err != nil {
+		t.Fatalf("flex statement: %v", err)
+	}
+	activityTxs, err := NewActivityStatementParser().Parse(strings.NewReader(activityStatement))
+	if err != nil {
+		t.Fatalf("activity statement: %v", err)
+	}
+	if len(flexTxs) != 2 || len(activityTxs) != 2 {
+		t.Fatalf("got %d flex and %d activity transactions, want 2 of each", len(flexTxs), len(activityTxs))
+	}
+
+	for i := range flexTxs {
+		flex, activity := flexTxs[i], activityTxs[i]
+		if flex.RawText != activity.RawText {
+			t.Errorf("raw text differs:\nflex:     %s\nactivity: %s", flex.RawText, activity.RawText)
+		}
+		// The order ID is only in the Flex statement.
+		flex.OrderID, activity.OrderID = "", ""
+		if flex != activity {
+			t.Errorf("transactions differ:\nflex:     %+v\nactivity: %+v", flex, activity)
+		}
+	}
+}
+
+func TestUpgradeLegacyTradeRawText(t *testing.T) {
+	flexTxs, err := NewParser().Parse(strings.NewReader(flexStatement))
+	if err != nil {
+		t.Fatalf("flex statement: %v", err)
+	}
+	legacy := []struct {
+		rawText string
+		tx      models.CanonicalTransaction
+	}{
+		{"Trade|STK|987654321|20240115;103000|APPLE INC|BUY|10.000000|185.500000|1855.000000|USD|-1.000000|AAPL", flexTxs[0]},
+		{"Trade|OPT|987654322|20240116;153000|AAPL 19JAN24 190 C|SELL|-1.000000|2.100000|-210.000000|USD|-0.650000|AAPL  240119C00190000", flexTxs[1]},
+	}
+	for _, l := range legacy {
+		got, ok := UpgradeLegacyTradeRawText(l.rawText, l.tx.ISIN)
+		if !ok {
+			t.Fatalf("legacy raw text %q not recognised", l.rawText)
+		}
+		if got = TradeFillRawText(got, 1); got != l.tx.RawText {
+			t.Errorf("upgraded raw text = %s, want %s", got, l.tx.RawText)
+		}
+	}
+
+	if _, ok := UpgradeLegacyTradeRawText(flexTxs[0].RawText, flexTxs[0].ISIN); ok {
+		t.Errorf("current raw text reported as legacy")
+	}
+}
+
+func TestIdenticalFillsStayDistinct(t *testing.T) {
+	const fill = `<Trade assetCategory="STK" symbol="AAPL" description="APPLE INC" conid="265598" isin="US0378331005" dateTime="20240115;103000" tradeDate="20240115" quantity="5" tradePrice="185.5" tradeMoney="927.5" currency="USD" exchange="NASDAQ" ibCommission="-0.5" ibCommissionCurrency="USD" buySell="BUY" ibOrderID="987654321" notes="" />`
+	statement := `<FlexQueryResponse><FlexStatements count="1"><FlexStatement accountId="U1234567"><Trades>` +
+		fill + fill + `</Trades></FlexStatement></FlexStatements></FlexQueryResponse>`
+
+	txs, err := NewParser().Parse(strings.NewReader(statement))
+	if err != nil {
+		t.Fatalf("flex statement: %v", err)
+	}
+	if len(txs) != 2 {
+		t.Fatalf("got %d transactions, want 2", len(txs))
+	}
+	if txs[0].RawText == txs[1].RawText {
+		t.Errorf("both fills have raw text %s", txs[0].RawText)
+	}
+}
+
+// TestMultiFillOrderDiffersAcrossFormats documents a limitation: the Activity Statement lists
+// an order filled in several executions as one trade, which does not match the executions of
+// a Flex statement.
+func TestMultiFillOrderDiffersAcrossFormats(t *testing.T) {
+	const statement = `<FlexQueryResponse><FlexStatements count="1"><FlexStatement accountId="U1234567"><Trades>
+<Trade assetCategory="STK" symbol="AAPL" description="APPLE INC" conid="265598" isin="US0378331005" dateTime="20240115;103000" tradeDate="20240115" quantity="4" tradePrice="185.5" tradeMoney="742" currency="USD" exchange="NASDAQ" ibCommission="-0.4" ibCommissionCurrency="USD" buySell="BUY" ibOrderID="987654321" levelOfDetail="EXECUTION" notes="" />
+<Trade assetCategory="STK" symbol="AAPL" description="APPLE INC" conid="265598" isin="US0378331005" dateTime="20240115;103000" tradeDate="20240115" quantity="6" tradePrice="185.5" tradeMoney="1113" currency="USD" exchange="NASDAQ" ibCommission="-0.6" ibCommissionCurrency="USD" buySell="BUY" ibOrderID="987654321" levelOfDetail="EXECUTION" notes="" />
+</Trades></FlexStatement></FlexStatements></FlexQueryResponse>`
+
+	flexTxs, err := NewParser().Parse(strings.NewReader(statement))
+	if err != nil {
+		t.Fatalf("flex statement: %v", err)
+	}
+	activityTxs, err := NewActivityStatementParser().Parse(strings.NewReader(activityStatement))
+	if err != nil {
+		t.Fatalf("activity statement: %v", err)
+	}
+	if len(flexTxs) != 2 {
+		t.Fatalf("got %d flex transactions, want one per execution", len(flexTxs))
+	}
+	order := activityTxs[0]
+	if flexTxs[0].Quantity+flexTxs[1].Quantity != order.Quantity {
+		t.Fatalf("executions add up to %g shares, the order has %g", flexTxs[0].Quantity+flexTxs[1].Quantity, order.Quantity)
+	}
+	for _, execution := range flexTxs {
+		if execution.RawText == order.RawText {
+			t.Errorf("execution %s matches the whole order", execution.RawText)
+		}
+	}
+}
@@ -27,6 +27,7 @@ type FlexStatement struct {
 	AccountId        string            `xml:"accountId,attr"`
 	Trades           []Trade           `xml:"Trades>Trade"`
 	CashTransactions []CashTransaction `xml:"CashTransactions>CashTransaction"`
+	CorporateActions []CorporateAction `xml:"CorporateActions>CorporateAction"`
 }
 
 // Trade represents a stock or option trade transaction.
@@ -50,6 +51,7 @@ type Trade struct {
 	BuySell              string  `xml:"buySell,attr"`
 	IBOrderID            string  `xml:"ibOrderID,attr"`
 	PutCall              string  `xml:"putCall,attr"` // For Options
+	Notes                string  `xml:"notes,attr"`   // Codes separated by ";", e.g. "R" for a dividend reinvestment
 }
 
 // CashTransaction represents dividends, withdrawals, deposits, and other cash movements.
@@ -64,6 +66,20 @@ type CashTransaction struct {
 	Symbol        string  `xml:"symbol,attr"`
 }
 
+// CorporateAction represents a corporate action such as a stock dividend or a split.
+type CorporateAction struct {
+	Type          string  `xml:"type,attr"` // e.g. "SD" for a stock dividend
+	AssetCategory string  `xml:"assetCategory,attr"`
+	Symbol        string  `xml:"symbol,attr"`
+	Description   string  `xml:"description,attr"`
+	ISIN          string  `xml:"isin,attr"`
+	DateTime      string  `xml:"dateTime,attr"`
+	Quantity      float64 `xml:"quantity,attr"`
+	Currency      string  `xml:"currency,attr"`
+	ActionID      string  `xml:"actionID,attr"`
+	LevelOfDetail string  `xml:"levelOfDetail,attr"`
+}
+
 // --- IBKR Parser Implementation ---
 
 // IBKRParser implements the parsers.Parser interface for IBKR Flex Query XML files.
@@ -83,51 +99,83 @@ func (p *IBKRParser) Parse(file io.Reader) ([]models.CanonicalTransaction, error
 	}
 
 	var canonicalTxs []models.CanonicalTransaction
-
 	for _, stmt := range response.FlexStatements {
-		// Process Trades (Stocks and Options)
-		for _, trade := range stmt.Trades {
-			// As requested, ignore internal currency exchange transactions
-			if trade.Exchange == "IDEALFX" {
-				continue
-			}
-
-			tx, err := p.processTrade(trade)
-			if err != nil {
-				logger.L.Warn("IBKR Parser: Skipping trade due to processing error", "ibOrderID", trade.IBOrderID, "error", err)
-				continue
-			}
-			canonicalTxs = append(canonicalTxs, tx)
+		canonicalTxs = append(canonicalTxs, p.convertStatement(stmt)...)
+	}
+	return canonicalTxs, nil
+}
+
+// convertStatement classifies the trades, cash transactions and corporate actions of a
+// statement. The Activity Statement CSV parser builds the same records, so that both
+// formats produce identical transactions.
+func (p *IBKRParser) convertStatement(stmt FlexStatement) []models.CanonicalTransaction {
+	var canonicalTxs []models.CanonicalTransaction
+
+	// Process Trades (Stocks and Options)
+	fills := make(map[string]int)
+	for _, trade := range stmt.Trades {
+		// As requested, ignore internal currency exchange transactions
+		if trade.Exchange == "IDEALFX" {
+			continue
 		}
 
-		// Process Cash Transactions (Dividends, Deposits, etc.)
-		for _, cashTx := range stmt.CashTransactions {
-			// Only process detailed transactions to avoid duplicates from summaries
-			if cashTx.LevelOfDetail != "DETAIL" {
-				continue
-			}
-
-			// Check transaction type
-			switch cashTx.Type {
-			case "Dividends":
-				tx, err := p.processDividend(cashTx)
-				if err != nil {
-					logger.L.Warn("IBKR Parser: Skipping dividend due to processing error", "description", cashTx.Description, "error", err)
-					continue
-				}
-				canonicalTxs = append(canonicalTxs, tx)
-			case "Deposits/Withdrawals":
-				tx, err := p.processCashMovement(cashTx)
-				if err != nil {
-					logger.L.Warn("IBKR Parser: Skipping cash movement due to processing error", "description", cashTx.Description, "error", err)
-					continue
-				}
-				canonicalTxs = append(canonicalTxs, tx)
-			}
+		tx, err := p.processTrade(trade)
+		if err != nil {
+			logger.L.Warn("IBKR Parser: Skipping trade due to processing error", "ibOrderID", trade.IBOrderID, "error", err)
+			continue
 		}
+		fills[tx.RawText]++
+		tx.RawText = TradeFillRawText(tx.RawText, fills[tx.RawText])
+		canonicalTxs = append(canonicalTxs, tx)
 	}
 
-	return canonicalTxs, nil
+	// Process Cash Transactions (Dividends, Deposits, etc.)
+	for _, cashTx := range stmt.CashTransactions {
+		// Only process detailed transactions to avoid duplicates from summaries
+		if cashTx.LevelOfDetail != "DETAIL" {
+			continue
+		}
+
+		var tx models.CanonicalTransaction
+		var err error
+		switch cashTx.Type {
+		case "Dividends", "Payment In Lieu Of Dividends":
+			tx, err = p.processDividend(cashTx)
+		case "Withholding Tax":
+			tx, err = p.processWithholdingTax(cashTx)
+		case "Deposits/Withdrawals":
+			tx, err = p.processCashMovement(cashTx)
+		case "Other Fees", "Commission Adjustments":
+			tx, err = p.processFee(cashTx)
+		case "Broker Interest Received", "Broker Interest Paid", "Bond Interest Received", "Bond Interest Paid":
+			tx, err = p.processInterest(cashTx)
+		default:
+			continue
+		}
+		if err != nil {
+			logger.L.Warn("IBKR Parser: Skipping cash transaction due to processing error", "type", cashTx.Type, "description", cashTx.Description, "error", err)
+			continue
+		}
+		canonicalTxs = append(canonicalTxs, tx)
+	}
+
+	// Process Stock Dividends. Other corporate actions are not supported yet.
+	for _, action := range stmt.CorporateActions {
+		if action.Type != "SD" || action.AssetCategory != "STK" || action.Quantity <= 0 {
+			continue
+		}
+		if action.LevelOfDetail != "" && action.LevelOfDetail != "DETAIL" {
+			continue
+		}
+		tx, err := p.processStockDividend(action)
+		if err != nil {
+			logger.L.Warn("IBKR Parser: Skipping stock dividend due to processing error", "description", action.Description, "error", err)
+			continue
+		}
+		canonicalTxs = append(canonicalTxs, tx)
+	}
+
+	return canonicalTxs
 }
 
 // processTrade converts an IBKR Trade record to a CanonicalTransaction.
@@ -144,11 +192,8 @@ func (p *IBKRParser) processTrade(trade Trade) (models.CanonicalTransaction, err
 		finalISIN = trade.ISIN
 	}
 
-	// Construct a comprehensive raw text string for hashing and reference.
-	rawText := fmt.Sprintf("Trade|%s|%s|%s|%s|%s|%f|%f|%f|%s|%f|%s",
-		trade.AssetCategory, trade.IBOrderID, trade.DateTime, trade.Description, trade.BuySell,
-		trade.Quantity, trade.TradePrice, trade.TradeMoney, trade.Currency, trade.IBCommission, trade.Symbol,
-	)
+	rawText := tradeRawText(trade.AssetCategory, tradeContract(trade.AssetCategory, trade.ISIN, trade.Description, trade.Symbol),
+		date, trade.BuySell, trade.Quantity, trade.TradePrice, trade.TradeMoney, trade.Currency, trade.IBCommission)
 
 	tx := models.CanonicalTransaction{
 		Source:          "ibkr",
@@ -168,6 +213,10 @@ func (p *IBKRParser) processTrade(trade Trade) (models.CanonicalTransaction, err
 
 	if trade.AssetCategory == "STK" {
 		tx.TransactionType = "STOCK"
+		// A DRIP buy is paid by the dividend, which is reported as its own cash transaction.
+		if trade.BuySell == "BUY" && hasNoteCode(trade.Notes, "R") {
+			tx.TransactionSubType = models.SubTypeDividendReinvestment
+		}
 	} else if trade.AssetCategory == "OPT" {
 		tx.TransactionType = "OPTION"
 		if trade.PutCall == "P" {
@@ -182,6 +231,55 @@ func (p *IBKRParser) processTrade(trade Trade) (models.CanonicalTransaction, err
 	return tx, nil
 }
 
+// tradeRawText is the raw text of a trade, for hashing and reference. It only holds fields
+// that the Flex statement and the Activity Statement both report, and report alike, so that
+// a trade imported from one format is recognised when the other is uploaded. The Activity
+// Statement lists orders, so an order filled in several executions only matches when the
+// Flex query also reports trades by order; otherwise importing both formats books it twice.
+func tradeRawText(assetCategory, contract string, date time.Time, buySell string, quantity, price, tradeMoney float64, currency string, commission float64) string {
+	return fmt.Sprintf("IBKRTrade|%s|%s|%s|%s|%f|%f|%f|%s|%f",
+		assetCategory, contract, date.Format("20060102;150405"), buySell, quantity, price, tradeMoney, currency, commission,
+	)
+}
+
+// TradeFillRawText numbers the raw text of a trade among the trades of its statement with the
+// same raw text, from 1, so that fills alike in every field both formats report, such as two
+// executions of an order in the same second, stay distinct.
+func TradeFillRawText(rawText string, fill int) string {
+	return fmt.Sprintf("%s|%d", rawText, fill)
+}
+
+// tradeContract identifies the traded contract: the ISIN of securities, or the description of
+// options, such as "AAPL 19JAN24 190 C", since both formats write option symbols differently.
+func tradeContract(assetCategory, isin, description, symbol string) string {
+	if assetCategory == "OPT" {
+		return description
+	}
+	if isin != "" {
+		return isin
+	}
+	return symbol
+}
+
+// UpgradeLegacyTradeRawText converts the raw text that trades were stored with before it was
+// shared with the Activity Statement, "Trade|category|order|dateTime|description|side|quantity|
+// price|money|currency|commission|symbol", to the current one. isin is the ISIN stored with the
+// trade. The result still needs its TradeFillRawText number. It reports false for any other
+// raw text.
+func UpgradeLegacyTradeRawText(rawText, isin string) (string, bool) {
+	fields := strings.Split(rawText, "|")
+	if len(fields) != 12 || fields[0] != "Trade" {
+		return "", false
+	}
+	date, err := parseIBKRDateTime(fields[3])
+	if err != nil {
+		return "", false
+	}
+	category, description, symbol := fields[1], fields[4], fields[11]
+	return tradeRawText(category, tradeContract(category, isin, description, symbol), date, fields[5],
+		parseFloat(fields[6]), parseFloat(fields[7]), parseFloat(fields[8]), fields[9], parseFloat(fields[10])), true
+}
+
 // processDividend converts an IBKR Dividend CashTransaction to a CanonicalTransaction.
 func (p *IBKRParser) processDividend(cashTx CashTransaction) (models.CanonicalTransaction, error) {
 	date, err := parseIBKRDateTime(cashTx.DateTime)
@@ -194,9 +292,7 @@ func (p *IBKRParser) processDividend(cashTx CashTransaction) (models.CanonicalTr
 		cashTx.DateTime, cashTx.Description, cashTx.Symbol, cashTx.Amount, cashTx.Currency, cashTx.ISIN,
 	)
 
-	// Note: IBKR reports do not always separate withholding tax into a distinct transaction.
-	// We are treating the dividend amount as the gross amount received. If tax is withheld,
-	// it might be a negative dividend transaction or require manual adjustment based on full statements.
+	// The dividend amount is the gross amount; the tax withheld is its own "Withholding Tax" transaction.
 	tx := models.CanonicalTransaction{
 		Source:          "ibkr",
 		TransactionDate: date,
@@ -211,6 +307,107 @@ func (p *IBKRParser) processDividend(cashTx CashTransaction) (models.CanonicalTr
 	return tx, nil
 }
 
+// processWithholdingTax converts the tax withheld on a dividend. Corrections keep their sign:
+// negative is tax, positive a refund.
+func (p *IBKRParser) processWithholdingTax(cashTx CashTransaction) (models.CanonicalTransaction, error) {
+	date, err := parseIBKRDateTime(cashTx.DateTime)
+	if err != nil {
+		return models.CanonicalTransaction{}, err
+	}
+
+	rawText := fmt.Sprintf("WithholdingTax|%s|%s|%s|%f|%s|%s",
+		cashTx.DateTime, cashTx.Description, cashTx.Symbol, cashTx.Amount, cashTx.Currency, cashTx.ISIN,
+	)
+
+	return models.CanonicalTransaction{
+		Source:             "ibkr",
+		TransactionDate:    date,
+		ProductName:        cashTx.Symbol,
+		ISIN:               cashTx.ISIN,
+		Amount:             cashTx.Amount,
+		SourceAmount:       math.Abs(cashTx.Amount),
+		Currency:           cashTx.Currency,
+		RawText:            rawText,
+		TransactionType:    "DIVIDEND",
+		TransactionSubType: "TAX",
+	}, nil
+}
+
+// processFee converts an account fee, such as market data subscriptions. Fee reversals are positive.
+func (p *IBKRParser) processFee(cashTx CashTransaction) (models.CanonicalTransaction, error) {
+	date, err := parseIBKRDateTime(cashTx.DateTime)
+	if err != nil {
+		return models.CanonicalTransaction{}, err
+	}
+
+	rawText := fmt.Sprintf("Fee|%s|%s|%s|%f|%s",
+		cashTx.Type, cashTx.DateTime, cashTx.Description, cashTx.Amount, cashTx.Currency,
+	)
+
+	return models.CanonicalTransaction{
+		Source:          "ibkr",
+		TransactionDate: date,
+		ProductName:     cashTx.Description,
+		Amount:          cashTx.Amount,
+		SourceAmount:    math.Abs(cashTx.Amount),
+		Currency:        cashTx.Currency,
+		RawText:         rawText,
+		TransactionType: "FEE",
+	}, nil
+}
+
+// processInterest converts interest received on cash (positive) or paid on margin (negative).
+func (p *IBKRParser) processInterest(cashTx CashTransaction) (models.CanonicalTransaction, error) {
+	date, err := parseIBKRDateTime(cashTx.DateTime)
+	if err != nil {
+		return models.CanonicalTransaction{}, err
+	}
+
+	rawText := fmt.Sprintf("Interest|%s|%s|%s|%f|%s",
+		cashTx.Type, cashTx.DateTime, cashTx.Description, cashTx.Amount, cashTx.Currency,
+	)
+
+	tx := models.CanonicalTransaction{
+		Source:          "ibkr",
+		TransactionDate: date,
+		ProductName:     "Interest",
+		Amount:          cashTx.Amount,
+		SourceAmount:    math.Abs(cashTx.Amount),
+		Currency:        cashTx.Currency,
+		RawText:         rawText,
+		TransactionType: "FEE",
+	}
+	if cashTx.Amount > 0 {
+		tx.TransactionSubType = "INTEREST"
+	}
+	return tx, nil
+}
+
+// processStockDividend converts a stock dividend into a STOCK buy with no cost basis.
+func (p *IBKRParser) processStockDividend(action CorporateAction) (models.CanonicalTransaction, error) {
+	date, err := parseIBKRDateTime(action.DateTime)
+	if err != nil {
+		return models.CanonicalTransaction{}, err
+	}
+
+	rawText := fmt.Sprintf("CorporateAction|%s|%s|%s|%s|%f|%s|%s",
+		action.Type, action.ActionID, action.DateTime, action.Description, action.Quantity, action.Symbol, action.ISIN,
+	)
+
+	return models.CanonicalTransaction{
+		Source:             "ibkr",
+		TransactionDate:    date,
+		ProductName:        action.Description,
+		ISIN:               action.ISIN,
+		Quantity:           action.Quantity,
+		Currency:           action.Currency,
+		RawText:            rawText,
+		TransactionType:    "STOCK",
+		TransactionSubType: models.SubTypeStockDividend,
+		BuySell:            "BUY",
+	}, nil
+}
+
 // processCashMovement converts a Deposit/Withdrawal to a CanonicalTransaction.
 func (p *IBKRParser) processCashMovement(cashTx CashTransaction) (models.CanonicalTransaction, error) {
 	date, err := parseIBKRDateTime(cashTx.DateTime)
@@ -257,6 +454,16 @@ func parseIBKRDateTime(datetime string) (time.Time, error) {
 	return t, nil
 }
 
+// hasNoteCode reports whether a trade's notes contain the given code.
+func hasNoteCode(notes, code string) bool {
+	for _, c := range strings.Split(notes, ";") {
+		if strings.TrimSpace(c) == code {
+			return true
+		}
+	}
+	return false
+}
+
 // Helper to convert string to float64, returning 0 on error.
 func parseFloat(s string) float64 {
 	v, err := strconv.ParseFloat(s, 64)
@@ -0,0 +1,205 @@
+// backend/src/parsers/kraken/parser.go
+package kraken
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/username/taxfolio/backend/src/logger"
+	"github.com/username/taxfolio/backend/src/models"
+	"github.com/username/taxfolio/backend/src/parsers/exchange"
+	"github.com/username/taxfolio/backend/src/utils"
+)
+
+// assetAliases maps Kraken's legacy asset codes to the common symbols.
+var assetAliases = map[string]string{
+	"XXBT": "BTC", "XBT": "BTC", "XXDG": "DOGE", "XDG": "DOGE", "ETH2": "ETH",
+	"XETH": "ETH", "XLTC": "LTC", "XXRP": "XRP", "XXLM": "XLM", "XXMR": "XMR", "XZEC": "ZEC",
+	"XETC": "ETC", "XREP": "REP", "XMLN": "MLN",
+	"ZEUR": "EUR", "ZUSD": "USD", "ZGBP": "GBP", "ZCAD": "CAD", "ZJPY": "JPY", "ZCHF": "CHF", "ZAUD": "AUD",
+}
+
+// KrakenParser implements the parsers.Parser interface for the Kraken ledgers export:
+// "txid","refid","time","type","subtype","aclass","asset","amount","fee","balance".
+type KrakenParser struct{}
+
+// NewParser creates a new instance of the KrakenParser.
+func NewParser() *KrakenParser {
+	return &KrakenParser{}
+}
+
+// ledgerEntry is one row of the ledgers export. A trade has one entry per asset, sharing the refid.
+type ledgerEntry struct {
+	refID, entryType, subType string
+	date                      time.Time
+	asset                     exchange.Asset
+	fee                       float64
+	raw                       string
+}
+
+// Parse reads the ledgers export and converts its entries into a slice of CanonicalTransaction.
+func (p *KrakenParser) Parse(file io.Reader) ([]models.CanonicalTransaction, error) {
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return nil, fmt.Errorf("kraken parser: failed to read file: %w", err)
+	}
+	reader := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))))
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("kraken parser: failed to read CSV records: %w", err)
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("kraken parser: empty file")
+	}
+	cols := make(map[string]int, len(records[0]))
+	for i, name := range records[0] {
+		cols[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, required := range []string{"refid", "time", "type", "asset", "amount", "fee"} {
+		if _, ok := cols[required]; !ok {
+			return nil, fmt.Errorf("kraken parser: missing column %q", required)
+		}
+	}
+	get := func(record []string, name string) string {
+		idx, ok := cols[name]
+		if !ok || idx >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[idx])
+	}
+
+	var canonicalTxs []models.CanonicalTransaction
+	tradeLegs := make(map[string][]ledgerEntry)
+	var tradeRefs []string
+
+	for _, record := range records[1:] {
+		// Entries without a txid are pending duplicates of the confirmed ones.
+		if _, ok := cols["txid"]; ok && get(record, "txid") == "" {
+			continue
+		}
+		date, err := time.Parse("2006-01-02 15:04:05", get(record, "time"))
+		if err != nil {
+			logger.L.Warn("Kraken Parser: Skipping entry with invalid date", "time", get(record, "time"), "refid", get(record, "refid"))
+			continue
+		}
+		amount, _ := strconv.ParseFloat(get(record, "amount"), 64)
+		fee, _ := strconv.ParseFloat(get(record, "fee"), 64)
+		entry := ledgerEntry{
+			refID:     get(record, "refid"),
+			entryType: strings.ToLower(get(record, "type")),
+			subType:   strings.ToLower(get(record, "subtype")),
+			date:      date,
+			asset:     exchange.Asset{Symbol: normalizeAsset(get(record, "asset")), Units: amount},
+			fee:       fee,
+			raw:       "kraken|" + strings.Join(record, ","),
+		}
+
+		switch entry.entryType {
+		case "trade", "spend", "receive":
+			if _, ok := tradeLegs[entry.refID]; !ok {
+				tradeRefs = append(tradeRefs, entry.refID)
+			}
+			tradeLegs[entry.refID] = append(tradeLegs[entry.refID], entry)
+		case "staking":
+			if amount > 0 {
+				canonicalTxs = append(canonicalTxs, exchange.Reward("kraken", date, entry.asset, entry.raw)...)
+			}
+		case "earn":
+			// Other earn entries move funds between the spot and earn wallets.
+			if entry.subType == "reward" && amount > 0 {
+				canonicalTxs = append(canonicalTxs, exchange.Reward("kraken", date, entry.asset, entry.raw)...)
+			}
+		case "deposit", "withdrawal":
+			// Crypto deposits and withdrawals are transfers between wallets.
+			if !utils.IsFiatCurrency(entry.asset.Symbol) || amount == 0 {
+				continue
+			}
+			canonicalTxs = append(canonicalTxs, exchange.CashMovement("kraken", date, entry.asset, entry.raw))
+			if fee > 0 {
+				canonicalTxs = append(canonicalTxs, models.CanonicalTransaction{
+					Source:          "kraken",
+					TransactionDate: date,
+					ProductName:     "Funding Fee",
+					TransactionType: "FEE",
+					Amount:          -fee,
+					SourceAmount:    fee,
+					Currency:        entry.asset.Symbol,
+					RawText:         entry.raw + "|FEE",
+				})
+			}
+		case "transfer", "margin", "rollover", "settled", "adjustment":
+			logger.L.Debug("Kraken Parser: Skipping entry", "type", entry.entryType, "refid", entry.refID)
+		default:
+			logger.L.Warn("Kraken Parser: Skipping unknown entry type", "type", entry.entryType, "refid", entry.refID)
+		}
+	}
+
+	for _, refID := range tradeRefs {
+		trade, err := buildTrade(refID, tradeLegs[refID])
+		if err == nil {
+			var txs []models.CanonicalTransaction
+			if txs, err = trade.Transactions(); err == nil {
+				canonicalTxs = append(canonicalTxs, txs...)
+				continue
+			}
+		}
+		logger.L.Warn("Kraken Parser: Skipping trade", "refid", refID, "error", err)
+	}
+
+	sort.SliceStable(canonicalTxs, func(i, j int) bool {
+		return canonicalTxs[i].TransactionDate.Before(canonicalTxs[j].TransactionDate)
+	})
+	return canonicalTxs, nil
+}
+
+// buildTrade pairs the two entries of a trade. The fee is charged on one of them, in its asset.
+func buildTrade(refID string, legs []ledgerEntry) (exchange.Trade, error) {
+	trade := exchange.Trade{Source: "kraken", OrderID: refID}
+	var raws []string
+	for _, leg := range legs {
+		switch {
+		case leg.asset.Units > 0 && trade.Acquired.Symbol == "":
+			trade.Acquired = leg.asset
+		case leg.asset.Units < 0 && trade.Disposed.Symbol == "":
+			trade.Disposed = exchange.Asset{Symbol: leg.asset.Symbol, Units: -leg.asset.Units}
+		case leg.asset.Units == 0 && leg.fee == 0:
+		default:
+			return trade, fmt.Errorf("trade with more than two legs")
+		}
+		if leg.fee > 0 {
+			trade.Fee = exchange.Asset{Symbol: leg.asset.Symbol, Units: leg.fee}
+		}
+		trade.Date = leg.date
+		raws = append(raws, leg.raw)
+	}
+	if trade.Acquired.Symbol == "" || trade.Disposed.Symbol == "" {
+		return trade, fmt.Errorf("trade without both legs")
+	}
+	// The fee of a disposed asset is charged on top of the amount exchanged.
+	if trade.Fee.Symbol == trade.Disposed.Symbol && !utils.IsFiatCurrency(trade.Fee.Symbol) {
+		trade.Disposed.Units += trade.Fee.Units
+		trade.Fee = exchange.Asset{}
+	}
+	trade.RawText = strings.Join(raws, "|")
+	return trade, nil
+}
+
+// normalizeAsset maps a ledger asset code to its common symbol. Suffixes mark balances held
+// for staking or earn (".S", ".M", ".F", ".B") of the same asset.
+func normalizeAsset(code string) string {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if i := strings.IndexByte(code, '.'); i > 0 {
+		code = code[:i]
+	}
+	if alias, ok := assetAliases[code]; ok {
+		return alias
+	}
+	return code
+}
@@ -0,0 +1,505 @@
+// backend/src/parsers/ofx/parser.go
+package ofx
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/username/taxfolio/backend/src/logger"
+	"github.com/username/taxfolio/backend/src/models"
+)
+
+// OFXParser implements the parsers.Parser interface for OFX investment statements
+// (INVSTMTRS), in both the SGML (1.x) and XML (2.x) syntax.
+type OFXParser struct{}
+
+// NewParser creates a new instance of the OFXParser.
+func NewParser() *OFXParser {
+	return &OFXParser{}
+}
+
+// security is an entry of the SECLIST.
+type security struct {
+	isin, name, ticker string
+}
+
+// Parse reads every investment statement of the file. Securities are identified by the ISIN
+// of the SECLIST, derived from the CUSIP for US and Canadian securities.
+func (p *OFXParser) Parse(file io.Reader) ([]models.CanonicalTransaction, error) {
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return nil, fmt.Errorf("ofx parser: failed to read file: %w", err)
+	}
+	root, err := parseDocument(data)
+	if err != nil {
+		return nil, fmt.Errorf("ofx parser: %w", err)
+	}
+
+	securities := make(map[string]security)
+	for _, info := range root.findAll("SECINFO") {
+		secID := info.child("SECID")
+		if secID == nil {
+			continue
+		}
+		sec := security{name: info.value("SECNAME"), ticker: info.value("TICKER")}
+		uniqueID := strings.ToUpper(secID.value("UNIQUEID"))
+		switch strings.ToUpper(secID.value("UNIQUEIDTYPE")) {
+		case "ISIN":
+			sec.isin = uniqueID
+		case "CUSIP":
+			sec.isin = cusipToISIN(uniqueID)
+		}
+		securities[secKey(secID)] = sec
+	}
+
+	statements := root.findAll("INVSTMTRS")
+	if len(statements) == 0 {
+		return nil, fmt.Errorf("ofx parser: no investment statement found")
+	}
+	var canonicalTxs []models.CanonicalTransaction
+	for _, stmt := range statements {
+		s := statement{currency: strings.ToUpper(stmt.value("CURDEF")), securities: securities}
+		if s.currency == "" {
+			s.currency = "EUR"
+		}
+		tranList := stmt.child("INVTRANLIST")
+		if tranList == nil {
+			continue
+		}
+		for _, n := range tranList.children {
+			txs, err := s.convert(n)
+			if err != nil {
+				logger.L.Warn("OFX Parser: Skipping transaction", "type", n.name, "fitid", n.find("FITID"), "error", err)
+				continue
+			}
+			canonicalTxs = append(canonicalTxs, txs...)
+		}
+	}
+
+	sort.SliceStable(canonicalTxs, func(i, j int) bool {
+		return canonicalTxs[i].TransactionDate.Before(canonicalTxs[j].TransactionDate)
+	})
+	return canonicalTxs, nil
+}
+
+// statement holds the context of one INVSTMTRS.
+type statement struct {
+	currency   string
+	securities map[string]security
+}
+
+// convert maps one entry of the INVTRANLIST.
+func (s statement) convert(n *node) ([]models.CanonicalTransaction, error) {
+	switch n.name {
+	case "DTSTART", "DTEND":
+		return nil, nil
+	case "BUYSTOCK", "BUYMF", "BUYOTHER", "BUYDEBT":
+		return s.trade(n, n.child("INVBUY"), "BUY")
+	case "SELLSTOCK", "SELLMF", "SELLOTHER", "SELLDEBT":
+		return s.trade(n, n.child("INVSELL"), "SELL")
+	case "INCOME":
+		return s.income(n)
+	case "REINVEST":
+		return s.reinvest(n)
+	case "INVEXPENSE":
+		tx, err := s.base(n, n)
+		if err != nil {
+			return nil, err
+		}
+		total := parseNumber(n.value("TOTAL"))
+		tx.TransactionType = "FEE"
+		tx.Amount = -math.Abs(total)
+		tx.SourceAmount = math.Abs(total)
+		if tx.ProductName == "" {
+			tx.ProductName = "Fee"
+		}
+		return []models.CanonicalTransaction{tx}, nil
+	case "INVBANKTRAN":
+		return s.bankTransaction(n)
+	case "BUYOPT", "SELLOPT", "TRANSFER", "SPLIT", "JRNLFUND", "JRNLSEC", "MARGININTEREST", "RETOFCAP", "CLOSUREOPT":
+		logger.L.Debug("OFX Parser: Skipping unsupported transaction", "type", n.name, "fitid", n.find("FITID"))
+		return nil, nil
+	}
+	return nil, fmt.Errorf("unknown transaction")
+}
+
+// base fills the fields shared by every investment transaction: date, security and currency.
+func (s statement) base(n, detail *node) (models.CanonicalTransaction, error) {
+	invTran := n.child("INVTRAN")
+	if invTran == nil {
+		invTran = detail.child("INVTRAN")
+	}
+	if invTran == nil {
+		return models.CanonicalTransaction{}, fmt.Errorf("missing INVTRAN")
+	}
+	date, err := parseDate(invTran.value("DTTRADE"))
+	if err != nil {
+		return models.CanonicalTransaction{}, err
+	}
+	tx := models.CanonicalTransaction{
+		Source:          "ofx",
+		TransactionDate: date,
+		Currency:        s.currency,
+		OrderID:         invTran.value("FITID"),
+		RawText:         "ofx|" + n.name + "|" + invTran.value("FITID") + "|" + invTran.value("DTTRADE"),
+	}
+	if secID := detail.child("SECID"); secID != nil {
+		sec, ok := s.securities[secKey(secID)]
+		if !ok || sec.isin == "" {
+			return tx, fmt.Errorf("security %s not resolved to an ISIN", secID.value("UNIQUEID"))
+		}
+		tx.ISIN, tx.ProductName = sec.isin, sec.name
+		if tx.ProductName == "" {
+			tx.ProductName = sec.ticker
+		}
+		tx.RawText += "|" + sec.isin
+	}
+	for _, name := range []string{"CURRENCY", "ORIGCURRENCY"} {
+		if cur := detail.child(name); cur != nil && cur.value("CURSYM") != "" {
+			tx.Currency = strings.ToUpper(cur.value("CURSYM"))
+		}
+	}
+	return tx, nil
+}
+
+// trade maps a buy or sell. The amount is the gross value of the units; commission and fees
+// are recorded as the commission.
+func (s statement) trade(n, detail *node, buySell string) ([]models.CanonicalTransaction, error) {
+	if detail == nil {
+		return nil, fmt.Errorf("missing INVBUY/INVSELL")
+	}
+	tx, err := s.base(n, detail)
+	if err != nil {
+		return nil, err
+	}
+	units := math.Abs(parseNumber(detail.value("UNITS")))
+	if units == 0 {
+		return nil, fmt.Errorf("trade without units")
+	}
+	price := math.Abs(parseNumber(detail.value("UNITPRICE")))
+	commission := math.Abs(parseNumber(detail.value("COMMISSION"))) + math.Abs(parseNumber(detail.value("FEES"))) +
+		math.Abs(parseNumber(detail.value("TAXES")))
+	total := math.Abs(parseNumber(detail.value("TOTAL")))
+	gross := units * price
+	if gross == 0 {
+		// Buys total the value plus costs; sells the value less costs.
+		if buySell == "BUY" {
+			gross = total - commission
+		} else {
+			gross = total + commission
+		}
+		price = gross / units
+	}
+
+	tx.TransactionType = "STOCK"
+	tx.BuySell = buySell
+	tx.Quantity = units
+	tx.Price = price
+	tx.Commission = commission
+	tx.SourceAmount = gross
+	tx.Amount = gross
+	if buySell == "BUY" {
+		tx.Amount = -gross
+	}
+	return []models.CanonicalTransaction{tx}, nil
+}
+
+// income maps a distribution. Interest is recorded like broker interest; dividends and capital
+// gain distributions as dividends, with the tax withheld as a separate row.
+func (s statement) income(n *node) ([]models.CanonicalTransaction, error) {
+	tx, err := s.base(n, n)
+	if err != nil {
+		return nil, err
+	}
+	total := parseNumber(n.value("TOTAL"))
+	withholding := math.Abs(parseNumber(n.value("WITHHOLDING")))
+	tx.SourceAmount = math.Abs(total)
+
+	if strings.ToUpper(n.value("INCOMETYPE")) == "INTEREST" {
+		tx.TransactionType, tx.TransactionSubType = "FEE", "INTEREST"
+		tx.ProductName = "Interest"
+		tx.Amount = total
+		return []models.CanonicalTransaction{tx}, nil
+	}
+	if tx.ISIN == "" {
+		return nil, fmt.Errorf("dividend without security")
+	}
+	tx.TransactionType = "DIVIDEND"
+	tx.Amount = math.Abs(total)
+	txs := []models.CanonicalTransaction{tx}
+	if withholding > 0 {
+		taxTx := tx
+		taxTx.TransactionSubType = "TAX"
+		taxTx.Amount = -withholding
+		taxTx.SourceAmount = withholding
+		taxTx.RawText = tx.RawText + "|TAX"
+		txs = append(txs, taxTx)
+	}
+	return txs, nil
+}
+
+// reinvest maps a reinvested distribution into the dividend and the buy it paid for.
+func (s statement) reinvest(n *node) ([]models.CanonicalTransaction, error) {
+	tx, err := s.base(n, n)
+	if err != nil {
+		return nil, err
+	}
+	units := math.Abs(parseNumber(n.value("UNITS")))
+	total := math.Abs(parseNumber(n.value("TOTAL")))
+	if units == 0 || total == 0 || tx.ISIN == "" {
+		return nil, fmt.Errorf("reinvestment without units, amount or security")
+	}
+	dividend := tx
+	dividend.TransactionType = "DIVIDEND"
+	dividend.Amount = total
+	dividend.SourceAmount = total
+	dividend.RawText = tx.RawText + "|DIVIDEND"
+
+	buy := tx
+	buy.TransactionType = "STOCK"
+	buy.TransactionSubType = models.SubTypeDividendReinvestment
+	buy.BuySell = "BUY"
+	buy.Quantity = units
+	buy.Price = total / units
+	buy.Amount = -total
+	buy.SourceAmount = total
+	return []models.CanonicalTransaction{dividend, buy}, nil
+}
+
+// bankTransaction maps a cash movement of the investment account.
+func (s statement) bankTransaction(n *node) ([]models.CanonicalTransaction, error) {
+	stmtTrn := n.child("STMTTRN")
+	if stmtTrn == nil {
+		return nil, fmt.Errorf("missing STMTTRN")
+	}
+	date, err := parseDate(stmtTrn.value("DTPOSTED"))
+	if err != nil {
+		return nil, err
+	}
+	amount := parseNumber(stmtTrn.value("TRNAMT"))
+	if amount == 0 {
+		return nil, nil
+	}
+	tx := models.CanonicalTransaction{
+		Source:          "ofx",
+		TransactionDate: date,
+		Currency:        s.currency,
+		Amount:          amount,
+		SourceAmount:    math.Abs(amount),
+		OrderID:         stmtTrn.value("FITID"),
+		RawText:         "ofx|INVBANKTRAN|" + stmtTrn.value("FITID") + "|" + stmtTrn.value("DTPOSTED"),
+	}
+	if cur := stmtTrn.child("CURRENCY"); cur != nil && cur.value("CURSYM") != "" {
+		tx.Currency = strings.ToUpper(cur.value("CURSYM"))
+	}
+
+	switch strings.ToUpper(stmtTrn.value("TRNTYPE")) {
+	case "INT", "DIV":
+		tx.TransactionType, tx.TransactionSubType = "FEE", "INTEREST"
+		tx.ProductName = "Interest"
+	case "FEE", "SRVCHG":
+		tx.TransactionType = "FEE"
+		tx.ProductName = strings.TrimSpace(stmtTrn.value("NAME") + " " + stmtTrn.value("MEMO"))
+		if tx.ProductName == "" {
+			tx.ProductName = "Fee"
+		}
+	default:
+		tx.TransactionType = "CASH"
+		if amount > 0 {
+			tx.ProductName, tx.TransactionSubType = "Cash Deposit", "DEPOSIT"
+		} else {
+			tx.ProductName, tx.TransactionSubType = "Cash Withdrawal", "WITHDRAWAL"
+		}
+	}
+	return []models.CanonicalTransaction{tx}, nil
+}
+
+func secKey(secID *node) string {
+	return strings.ToUpper(secID.value("UNIQUEIDTYPE")) + ":" + strings.ToUpper(secID.value("UNIQUEID"))
+}
+
+// cusipToISIN builds the ISIN of a US security from its CUSIP. CUSIPs of Canadian issuers,
+// which have their own ISIN prefix, cannot be told apart and are assumed to be US.
+func cusipToISIN(cusip string) string {
+	if len(cusip) != 9 {
+		return ""
+	}
+	base := "US" + cusip
+	var digits strings.Builder
+	for _, r := range base {
+		switch {
+		case r >= '0' && r <= '9':
+			digits.WriteRune(r)
+		case r >= 'A' && r <= 'Z':
+			digits.WriteString(strconv.Itoa(int(r-'A') + 10))
+		default:
+			return ""
+		}
+	}
+	// Luhn over the digits, doubling every other digit from the rightmost.
+	s := digits.String()
+	sum := 0
+	for i := len(s) - 1; i >= 0; i-- {
+		d := int(s[i] - '0')
+		if (len(s)-1-i)%2 == 0 {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+	}
+	return base + strconv.Itoa((10-sum%10)%10)
+}
+
+// parseDate reads an OFX datetime such as "20240115", "20240115120000" or
+// "20240115120000.000[-5:EST]". Only the date is kept.
+func parseDate(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if len(s) < 8 {
+		return time.Time{}, fmt.Errorf("could not parse ofx date '%s'", s)
+	}
+	t, err := time.Parse("20060102", s[:8])
+	if err != nil {
+		return time.Time{}, fmt.Errorf("could not parse ofx date '%s'", s)
+	}
+	return t, nil
+}
+
+// parseNumber reads an OFX amount. The specification allows a comma as decimal separator.
+func parseNumber(s string) float64 {
+	s = strings.TrimSpace(s)
+	if !strings.Contains(s, ".") {
+		s = strings.ReplaceAll(s, ",", ".")
+	}
+	v, _ := strconv.ParseFloat(s, 64)
+	return v
+}
+
+// node is an OFX element: an aggregate with children or an element with a value.
+type node struct {
+	name     string
+	text     string
+	children []*node
+}
+
+func (n *node) child(name string) *node {
+	for _, c := range n.children {
+		if c.name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+// value returns the value of a direct child element, or "".
+func (n *node) value(name string) string {
+	if c := n.child(name); c != nil {
+		return c.text
+	}
+	return ""
+}
+
+// find returns the value of the first descendant element with the name, or "".
+func (n *node) find(name string) string {
+	for _, c := range n.children {
+		if c.name == name && c.text != "" {
+			return c.text
+		}
+		if v := c.find(name); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// findAll returns every descendant aggregate or element with the name.
+func (n *node) findAll(name string) []*node {
+	var found []*node
+	for _, c := range n.children {
+		if c.name == name {
+			found = append(found, c)
+		}
+		found = append(found, c.findAll(name)...)
+	}
+	return found
+}
+
+// parseDocument builds the element tree of an OFX file. The SGML syntax leaves elements
+// unclosed ("<TRNAMT>-10.00"), so an element followed by text is a leaf whether or not a
+// closing tag follows; a closing tag of an aggregate closes every element opened inside it.
+func parseDocument(data []byte) (*node, error) {
+	start := bytes.Index(bytes.ToUpper(data), []byte("<OFX>"))
+	if start < 0 {
+		return nil, fmt.Errorf("missing <OFX> element")
+	}
+	data = data[start:]
+
+	root := &node{name: "#root"}
+	stack := []*node{root}
+	for len(data) > 0 {
+		open := bytes.IndexByte(data, '<')
+		if open < 0 {
+			break
+		}
+		end := bytes.IndexByte(data[open:], '>')
+		if end < 0 {
+			return nil, fmt.Errorf("unterminated tag")
+		}
+		tag := strings.TrimSpace(string(data[open+1 : open+end]))
+		data = data[open+end+1:]
+		if tag == "" || strings.HasPrefix(tag, "?") || strings.HasPrefix(tag, "!") {
+			continue
+		}
+
+		if strings.HasPrefix(tag, "/") {
+			name := strings.ToUpper(strings.TrimSpace(tag[1:]))
+			for i := len(stack) - 1; i > 0; i-- {
+				if stack[i].name == name {
+					stack = stack[:i]
+					break
+				}
+			}
+			continue
+		}
+
+		name := strings.ToUpper(strings.Fields(tag)[0])
+		selfClosing := strings.HasSuffix(tag, "/")
+		n := &node{name: strings.TrimSuffix(name, "/")}
+		parent := stack[len(stack)-1]
+		parent.children = append(parent.children, n)
+		if selfClosing {
+			continue
+		}
+
+		next := bytes.IndexByte(data, '<')
+		content := data
+		if next >= 0 {
+			content = data[:next]
+		}
+		if value := strings.TrimSpace(string(content)); value != "" {
+			n.text = unescape(value)
+			data = data[len(content):]
+			// Skip the closing tag of an element with a value (XML syntax).
+			closing := "</" + n.name + ">"
+			if len(data) >= len(closing) && strings.EqualFold(string(data[:len(closing)]), closing) {
+				data = data[len(closing):]
+			}
+			continue
+		}
+		stack = append(stack, n)
+	}
+	return root, nil
+}
+
+var entityReplacer = strings.NewReplacer("&lt;", "<", "&gt;", ">", "&quot;", `"`, "&apos;", "'", "&nbsp;", " ", "&amp;", "&")
+
+func unescape(s string) string {
+	return entityReplacer.Replace(s)
+}
@@ -2,11 +2,50 @@
 package parsers
 
 import (
+	"fmt"
 	"io"
 
+	"github.com/username/taxfolio/backend/src/logger"
 	"github.com/username/taxfolio/backend/src/models"
 )
 
 type Parser interface {
 	Parse(file io.Reader) ([]models.CanonicalTransaction, error)
 }
+
+// MultiFileParser is implemented by parsers whose files complement each other and must be
+// read together, such as the DeGiro account statement and trade list.
+type MultiFileParser interface {
+	ParseFiles(files []io.Reader) ([]models.CanonicalTransaction, error)
+}
+
+// ParseFiles parses every file of an upload with the same parser. Brokers that issue one
+// document per event (Trade Republic, Scalable) are uploaded as many files at once, so a
+// file that cannot be parsed is skipped; the upload only fails when no file can be parsed.
+func ParseFiles(p Parser, files []io.Reader) ([]models.CanonicalTransaction, error) {
+	if len(files) == 1 {
+		return p.Parse(files[0])
+	}
+	if mp, ok := p.(MultiFileParser); ok {
+		return mp.ParseFiles(files)
+	}
+	var txs []models.CanonicalTransaction
+	var firstErr error
+	parsed := 0
+	for i, file := range files {
+		fileTxs, err := p.Parse(file)
+		if err != nil {
+			logger.L.Warn("Skipping file of multi-file upload", "index", i, "error", err)
+			if firstErr == nil {
+				firstErr = fmt.Errorf("file %d: %w", i+1, err)
+			}
+			continue
+		}
+		parsed++
+		txs = append(txs, fileTxs...)
+	}
+	if parsed == 0 && firstErr != nil {
+		return nil, firstErr
+	}
+	return txs, nil
+}
@@ -0,0 +1,315 @@
+// backend/src/parsers/qif/parser.go
+package qif
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/username/taxfolio/backend/src/logger"
+	"github.com/username/taxfolio/backend/src/models"
+)
+
+// datePattern splits QIF dates such as "1/15'24", "01/15/2024", "15.01.2024" or "2024-01-15".
+var datePattern = regexp.MustCompile(`^(\d{1,4})[/.\-](\d{1,2})[/.\-'](\d{2,4})$`)
+
+// QIFParser implements the parsers.Parser interface for QIF files with an investment
+// account (!Type:Invst). QIF carries no currency and amounts are taken to be in EUR.
+type QIFParser struct{}
+
+// NewParser creates a new instance of the QIFParser.
+func NewParser() *QIFParser {
+	return &QIFParser{}
+}
+
+// record is one entry of a QIF section, keyed by the field code. Repeated codes keep the first value.
+type record struct {
+	section string
+	fields  map[byte]string
+	raw     string
+}
+
+func (r record) get(code byte) string {
+	return r.fields[code]
+}
+
+// Parse reads the securities list and the investment entries of the file. Securities are
+// identified by their symbol, or their name when the file has no securities list.
+func (p *QIFParser) Parse(file io.Reader) ([]models.CanonicalTransaction, error) {
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return nil, fmt.Errorf("qif parser: failed to read file: %w", err)
+	}
+	records := readRecords(data)
+
+	symbols := make(map[string]string)
+	var entries []record
+	for _, r := range records {
+		switch r.section {
+		case "SECURITY":
+			if name, symbol := r.get('N'), strings.ToUpper(r.get('S')); name != "" && symbol != "" {
+				symbols[strings.ToUpper(name)] = symbol
+			}
+		case "INVST":
+			entries = append(entries, r)
+		}
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("qif parser: no investment account entries found")
+	}
+
+	dayFirst := isDayFirst(entries)
+	var canonicalTxs []models.CanonicalTransaction
+	for _, r := range entries {
+		txs, err := convertEntry(r, symbols, dayFirst)
+		if err != nil {
+			logger.L.Warn("QIF Parser: Skipping entry", "action", r.get('N'), "date", r.get('D'), "error", err)
+			continue
+		}
+		canonicalTxs = append(canonicalTxs, txs...)
+	}
+
+	sort.SliceStable(canonicalTxs, func(i, j int) bool {
+		return canonicalTxs[i].TransactionDate.Before(canonicalTxs[j].TransactionDate)
+	})
+	return canonicalTxs, nil
+}
+
+// readRecords splits the file into its entries. A "!Type:" line starts a section and "^"
+// ends an entry; "!Option" and "!Account" lines are ignored.
+func readRecords(data []byte) []record {
+	var records []record
+	section := ""
+	current := record{fields: make(map[byte]string)}
+	var rawLines []string
+
+	scanner := bufio.NewScanner(bytes.NewReader(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))))
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r ")
+		if line == "" {
+			continue
+		}
+		switch {
+		case strings.HasPrefix(line, "!"):
+			upper := strings.ToUpper(line)
+			if strings.HasPrefix(upper, "!TYPE:") {
+				section = strings.TrimSpace(strings.TrimPrefix(upper, "!TYPE:"))
+			} else if strings.HasPrefix(upper, "!ACCOUNT") {
+				section = "ACCOUNT"
+			}
+		case line == "^":
+			if len(current.fields) > 0 {
+				current.section = section
+				current.raw = strings.Join(rawLines, "|")
+				records = append(records, current)
+			}
+			current = record{fields: make(map[byte]string)}
+			rawLines = nil
+		default:
+			if _, ok := current.fields[line[0]]; !ok {
+				current.fields[line[0]] = strings.TrimSpace(line[1:])
+			}
+			rawLines = append(rawLines, line)
+		}
+	}
+	return records
+}
+
+// convertEntry maps one investment entry. The total (T or U) includes the commission (O).
+func convertEntry(r record, symbols map[string]string, dayFirst bool) ([]models.CanonicalTransaction, error) {
+	date, err := parseDate(r.get('D'), dayFirst)
+	if err != nil {
+		return nil, err
+	}
+	total := math.Abs(parseNumber(r.get('T')))
+	if total == 0 {
+		total = math.Abs(parseNumber(r.get('U')))
+	}
+	quantity := math.Abs(parseNumber(r.get('Q')))
+	price := math.Abs(parseNumber(r.get('I')))
+	commission := math.Abs(parseNumber(r.get('O')))
+	name := r.get('Y')
+
+	tx := models.CanonicalTransaction{
+		Source:          "qif",
+		TransactionDate: date,
+		ProductName:     name,
+		ISIN:            securityID(name, symbols),
+		Currency:        "EUR",
+		SourceAmount:    total,
+		RawText:         "qif|" + r.raw,
+	}
+
+	action := strings.ToUpper(r.get('N'))
+	switch {
+	case strings.HasPrefix(action, "REINV"):
+		// ReinvDiv, ReinvInt, ReinvLg, ReinvSh: a distribution paid in new units.
+		if tx.ISIN == "" || quantity == 0 || total == 0 {
+			return nil, fmt.Errorf("reinvestment without security, quantity or amount")
+		}
+		dividend := tx
+		dividend.TransactionType = "DIVIDEND"
+		dividend.Amount = total
+		dividend.RawText = tx.RawText + "|DIVIDEND"
+		buy := tx
+		buy.TransactionType = "STOCK"
+		buy.TransactionSubType = models.SubTypeDividendReinvestment
+		buy.BuySell = "BUY"
+		buy.Quantity = quantity
+		buy.Price = total / quantity
+		buy.Amount = -total
+		return []models.CanonicalTransaction{dividend, buy}, nil
+
+	case strings.HasPrefix(action, "BUY"), strings.HasPrefix(action, "SELL"), action == "SHRSIN", action == "SHRSOUT":
+		if tx.ISIN == "" || quantity == 0 {
+			return nil, fmt.Errorf("trade without security or quantity")
+		}
+		buySell := "SELL"
+		if strings.HasPrefix(action, "BUY") || action == "SHRSIN" {
+			buySell = "BUY"
+		}
+		gross := quantity * price
+		if gross == 0 {
+			if buySell == "BUY" {
+				gross = total - commission
+			} else {
+				gross = total + commission
+			}
+			price = gross / quantity
+		}
+		tx.TransactionType = "STOCK"
+		tx.BuySell = buySell
+		tx.Quantity = quantity
+		tx.Price = price
+		tx.Commission = commission
+		tx.Amount = gross
+		if buySell == "BUY" {
+			tx.Amount = -gross
+		}
+		return []models.CanonicalTransaction{tx}, nil
+
+	case strings.HasPrefix(action, "DIV"), strings.HasPrefix(action, "CGLONG"), strings.HasPrefix(action, "CGMID"), strings.HasPrefix(action, "CGSHORT"):
+		if tx.ISIN == "" {
+			return nil, fmt.Errorf("dividend without security")
+		}
+		tx.TransactionType = "DIVIDEND"
+		tx.Amount = total
+		return []models.CanonicalTransaction{tx}, nil
+
+	case strings.HasPrefix(action, "INTINC"), strings.HasPrefix(action, "MARGINT"):
+		tx.TransactionType, tx.TransactionSubType = "FEE", "INTEREST"
+		tx.ProductName, tx.ISIN = "Interest", ""
+		tx.Amount = total
+		if action == "MARGINT" {
+			tx.TransactionSubType = ""
+			tx.Amount = -total
+		}
+		return []models.CanonicalTransaction{tx}, nil
+
+	case strings.HasPrefix(action, "MISCEXP"):
+		tx.TransactionType = "FEE"
+		tx.ProductName, tx.ISIN = firstNonEmpty(r.get('M'), r.get('P'), "Fee"), ""
+		tx.Amount = -total
+		return []models.CanonicalTransaction{tx}, nil
+
+	case action == "XIN", action == "CASH" && parseNumber(r.get('T')) > 0:
+		tx.TransactionType, tx.TransactionSubType = "CASH", "DEPOSIT"
+		tx.ProductName, tx.ISIN = "Cash Deposit", ""
+		tx.Amount = total
+		return []models.CanonicalTransaction{tx}, nil
+
+	case action == "XOUT", action == "CASH":
+		tx.TransactionType, tx.TransactionSubType = "CASH", "WITHDRAWAL"
+		tx.ProductName, tx.ISIN = "Cash Withdrawal", ""
+		tx.Amount = -total
+		return []models.CanonicalTransaction{tx}, nil
+
+	case action == "STKSPLIT", action == "REMINDER", action == "":
+		return nil, nil
+	}
+	return nil, fmt.Errorf("unknown action")
+}
+
+// securityID returns the symbol of the security from the securities list, or its name.
+// Either may be an ISIN or a ticker, which resolve to a price through the Yahoo search.
+func securityID(name string, symbols map[string]string) string {
+	if name == "" {
+		return ""
+	}
+	if symbol, ok := symbols[strings.ToUpper(name)]; ok {
+		return symbol
+	}
+	return strings.ToUpper(name)
+}
+
+// isDayFirst reports whether the dates of the file put the day before the month: always
+// with dots, and with slashes when a first component above 12 shows it cannot be the month.
+func isDayFirst(entries []record) bool {
+	for _, r := range entries {
+		m := datePattern.FindStringSubmatch(strings.ReplaceAll(r.get('D'), " ", ""))
+		if m == nil || len(m[1]) == 4 {
+			continue
+		}
+		if strings.Contains(r.get('D'), ".") {
+			return true
+		}
+		if first, _ := strconv.Atoi(m[1]); first > 12 {
+			return true
+		}
+	}
+	return false
+}
+
+// parseDate reads a QIF date. An apostrophe before a two-digit year marks the 2000s.
+func parseDate(s string, dayFirst bool) (time.Time, error) {
+	m := datePattern.FindStringSubmatch(strings.ReplaceAll(strings.TrimSpace(s), " ", ""))
+	if m == nil {
+		return time.Time{}, fmt.Errorf("could not parse qif date '%s'", s)
+	}
+	a, _ := strconv.Atoi(m[1])
+	b, _ := strconv.Atoi(m[2])
+	c, _ := strconv.Atoi(m[3])
+
+	var year, month, day int
+	switch {
+	case len(m[1]) == 4:
+		year, month, day = a, b, c
+	case dayFirst:
+		day, month, year = a, b, c
+	default:
+		month, day, year = a, b, c
+	}
+	if year < 100 {
+		if year < 70 || strings.Contains(s, "'") {
+			year += 2000
+		} else {
+			year += 1900
+		}
+	}
+	if month < 1 || month > 12 || day < 1 || day > 31 {
+		return time.Time{}, fmt.Errorf("could not parse qif date '%s'", s)
+	}
+	return time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC), nil
+}
+
+// parseNumber reads a QIF amount such as "1,234.56" or "-12.5".
+func parseNumber(s string) float64 {
+	v, _ := strconv.ParseFloat(strings.ReplaceAll(strings.TrimSpace(s), ",", ""), 64)
+	return v
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,244 @@
+// backend/src/parsers/revolut/parser.go
+package revolut
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/username/taxfolio/backend/src/logger"
+	"github.com/username/taxfolio/backend/src/models"
+)
+
+// RawTransaction holds the values of a single row of a Revolut Trading statement.
+type RawTransaction struct {
+	Date, Ticker, ISIN, Type, Quantity, PricePerShare, TotalAmount, Currency string
+	GrossAmount, WithholdingTax                                              string // Only in newer statements
+	RawLine                                                                  string
+}
+
+// RevolutParser implements the parsers.Parser interface for the Revolut Trading account statement CSV.
+type RevolutParser struct{}
+
+// NewParser creates a new instance of the RevolutParser.
+func NewParser() *RevolutParser {
+	return &RevolutParser{}
+}
+
+// Parse reads a Revolut Trading statement and converts its rows into a slice of CanonicalTransaction.
+func (p *RevolutParser) Parse(file io.Reader) ([]models.CanonicalTransaction, error) {
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return nil, fmt.Errorf("revolut parser: failed to read file: %w", err)
+	}
+	reader := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))))
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("revolut parser: failed to read CSV header: %w", err)
+	}
+	cols := make(map[string]int, len(header))
+	for i, name := range header {
+		cols[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, required := range []string{"date", "ticker", "type", "total amount", "currency"} {
+		if _, ok := cols[required]; !ok {
+			return nil, fmt.Errorf("revolut parser: missing column %q", required)
+		}
+	}
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("revolut parser: failed to read all CSV records: %w", err)
+	}
+
+	get := func(record []string, name string) string {
+		idx, ok := cols[name]
+		if !ok || idx >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[idx])
+	}
+
+	var canonicalTxs []models.CanonicalTransaction
+	for _, record := range records {
+		raw := RawTransaction{
+			Date:           get(record, "date"),
+			Ticker:         get(record, "ticker"),
+			ISIN:           get(record, "isin"),
+			Type:           get(record, "type"),
+			Quantity:       get(record, "quantity"),
+			PricePerShare:  get(record, "price per share"),
+			TotalAmount:    get(record, "total amount"),
+			Currency:       get(record, "currency"),
+			GrossAmount:    get(record, "gross amount"),
+			WithholdingTax: get(record, "withholding tax"),
+			RawLine:        strings.Join(record, ","),
+		}
+		txs, err := convertRow(raw)
+		if err != nil {
+			logger.L.Warn("Revolut Parser: Skipping row", "type", raw.Type, "date", raw.Date, "error", err)
+			continue
+		}
+		canonicalTxs = append(canonicalTxs, txs...)
+	}
+
+	sort.SliceStable(canonicalTxs, func(i, j int) bool {
+		return canonicalTxs[i].TransactionDate.Before(canonicalTxs[j].TransactionDate)
+	})
+	return canonicalTxs, nil
+}
+
+// convertRow maps one statement row. A dividend may produce a second row with the tax withheld.
+func convertRow(raw RawTransaction) ([]models.CanonicalTransaction, error) {
+	date, err := parseDate(raw.Date)
+	if err != nil {
+		return nil, err
+	}
+	amount, _ := parseMoney(raw.TotalAmount)
+	quantity, _ := parseMoney(raw.Quantity)
+	price, _ := parseMoney(raw.PricePerShare)
+
+	tx := models.CanonicalTransaction{
+		Source:          "revolut",
+		TransactionDate: date,
+		ProductName:     raw.Ticker,
+		ISIN:            instrumentID(raw),
+		Currency:        strings.ToUpper(raw.Currency),
+		RawText:         "revolut|" + raw.RawLine,
+		SourceAmount:    amount,
+	}
+
+	txType := strings.ToUpper(raw.Type)
+	switch {
+	case strings.HasPrefix(txType, "BUY"), strings.HasPrefix(txType, "SELL"):
+		if quantity <= 0 {
+			return nil, fmt.Errorf("trade without quantity")
+		}
+		tx.TransactionType = "STOCK"
+		tx.Quantity = math.Abs(quantity)
+		tx.Price = price
+		if strings.HasPrefix(txType, "BUY") {
+			tx.BuySell = "BUY"
+			tx.Amount = -math.Abs(amount)
+		} else {
+			tx.BuySell = "SELL"
+			tx.Amount = math.Abs(amount)
+		}
+		return []models.CanonicalTransaction{tx}, nil
+
+	case strings.Contains(txType, "DIVIDEND") && strings.Contains(txType, "TAX"):
+		// Corrections of the tax withheld keep their sign: negative is tax, positive a refund.
+		tx.TransactionType, tx.TransactionSubType = "DIVIDEND", "TAX"
+		tx.Amount = amount
+		return []models.CanonicalTransaction{tx}, nil
+
+	case strings.Contains(txType, "DIVIDEND"):
+		return splitDividend(tx, raw, math.Abs(amount), quantity, price), nil
+
+	case strings.Contains(txType, "TOP-UP"), strings.Contains(txType, "TOPUP"):
+		tx.TransactionType, tx.TransactionSubType = "CASH", "DEPOSIT"
+		tx.ProductName, tx.ISIN = "Cash Deposit", ""
+		tx.Amount = math.Abs(amount)
+		return []models.CanonicalTransaction{tx}, nil
+
+	case strings.Contains(txType, "WITHDRAWAL"):
+		tx.TransactionType, tx.TransactionSubType = "CASH", "WITHDRAWAL"
+		tx.ProductName, tx.ISIN = "Cash Withdrawal", ""
+		tx.Amount = -math.Abs(amount)
+		return []models.CanonicalTransaction{tx}, nil
+
+	case strings.Contains(txType, "FEE"):
+		tx.TransactionType = "FEE"
+		tx.ProductName, tx.ISIN = "Custody Fee", ""
+		tx.Amount = -math.Abs(amount)
+		return []models.CanonicalTransaction{tx}, nil
+
+	case strings.Contains(txType, "SPLIT"):
+		// The row holds the shares the split adds, negative for a reverse split.
+		if quantity == 0 {
+			return nil, fmt.Errorf("stock split without quantity")
+		}
+		tx.TransactionType, tx.TransactionSubType = "STOCK", models.SubTypeStockSplit
+		tx.BuySell = "BUY"
+		tx.Quantity = quantity
+		tx.Amount, tx.SourceAmount = 0, 0
+		return []models.CanonicalTransaction{tx}, nil
+	}
+	return nil, fmt.Errorf("unknown transaction type")
+}
+
+// splitDividend turns a dividend, which Revolut reports net of tax, into the gross income
+// and the tax withheld. The gross amount comes from the reported gross and tax columns when
+// present, or from the quantity and dividend per share.
+func splitDividend(tx models.CanonicalTransaction, raw RawTransaction, net, quantity, perShare float64) []models.CanonicalTransaction {
+	gross := net
+	if v, err := parseMoney(raw.GrossAmount); err == nil && v != 0 {
+		gross = math.Abs(v)
+	} else if v, err := parseMoney(raw.WithholdingTax); err == nil && v != 0 {
+		gross = net + math.Abs(v)
+	} else if quantity > 0 && perShare > 0 && quantity*perShare > net {
+		gross = quantity * perShare
+	}
+	gross = math.Round(gross*100) / 100
+
+	tx.TransactionType = "DIVIDEND"
+	tx.Amount = gross
+	txs := []models.CanonicalTransaction{tx}
+
+	if tax := math.Round((gross-net)*100) / 100; tax > 0 {
+		taxTx := tx
+		taxTx.TransactionSubType = "TAX"
+		taxTx.Amount = -tax
+		taxTx.SourceAmount = tax
+		taxTx.RawText = tx.RawText + "|TAX"
+		txs = append(txs, taxTx)
+	}
+	return txs
+}
+
+// instrumentID returns the ISIN when the statement has one, or the ticker, which resolves
+// to a price through the Yahoo search like an ISIN.
+func instrumentID(raw RawTransaction) string {
+	if raw.ISIN != "" {
+		return raw.ISIN
+	}
+	return strings.ToUpper(raw.Ticker)
+}
+
+var dateLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+	"02/01/2006 15:04:05",
+	"02/01/2006",
+}
+
+func parseDate(s string) (time.Time, error) {
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("could not parse revolut date '%s'", s)
+}
+
+// parseMoney parses amounts such as "USD 1,234.56", "$1,234.56", "-€12" or "0.1234".
+func parseMoney(s string) (float64, error) {
+	cleaned := strings.Map(func(r rune) rune {
+		if (r >= '0' && r <= '9') || r == '.' || r == '-' {
+			return r
+		}
+		return -1
+	}, s)
+	if cleaned == "" {
+		return 0, fmt.Errorf("empty amount")
+	}
+	return strconv.ParseFloat(cleaned, 64)
+}
@@ -0,0 +1,181 @@
+// backend/src/parsers/scalable/parser.go
+package scalable
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/username/taxfolio/backend/src/logger"
+	"github.com/username/taxfolio/backend/src/models"
+	"github.com/username/taxfolio/backend/src/parsers/statement"
+)
+
+// ScalableParser implements the parsers.Parser interface for Scalable Capital. It reads the
+// transactions CSV export and, for events missing from it, the text of single documents.
+type ScalableParser struct{}
+
+// NewParser creates a new instance of the ScalableParser.
+func NewParser() *ScalableParser {
+	return &ScalableParser{}
+}
+
+// Parse reads either a transactions CSV or the text of one document.
+func (p *ScalableParser) Parse(file io.Reader) ([]models.CanonicalTransaction, error) {
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return nil, fmt.Errorf("scalable parser: failed to read file: %w", err)
+	}
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+
+	firstLine := data
+	if idx := bytes.IndexByte(data, '\n'); idx >= 0 {
+		firstLine = data[:idx]
+	}
+	if header := strings.ToLower(string(firstLine)); strings.Contains(header, ";") && strings.Contains(header, "isin") {
+		return parseCSV(data)
+	}
+
+	doc, err := statement.Parse(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("scalable parser: %w", err)
+	}
+	return doc.Transactions("scalable"), nil
+}
+
+// parseCSV maps the rows of the transactions export:
+// date;time;status;reference;description;assetType;type;isin;shares;price;amount;fee;tax;currency
+func parseCSV(data []byte) ([]models.CanonicalTransaction, error) {
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.Comma = ';'
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("scalable parser: failed to read CSV records: %w", err)
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("scalable parser: empty file")
+	}
+	cols := make(map[string]int, len(records[0]))
+	for i, name := range records[0] {
+		cols[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, required := range []string{"date", "type", "isin", "amount", "currency"} {
+		if _, ok := cols[required]; !ok {
+			return nil, fmt.Errorf("scalable parser: missing column %q", required)
+		}
+	}
+	get := func(record []string, name string) string {
+		idx, ok := cols[name]
+		if !ok || idx >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[idx])
+	}
+	number := func(record []string, name string) float64 {
+		v, _ := statement.ParseNumber(get(record, name))
+		return v
+	}
+
+	var canonicalTxs []models.CanonicalTransaction
+	for _, record := range records[1:] {
+		if status := strings.ToLower(get(record, "status")); status != "" && status != "executed" {
+			continue
+		}
+		date, err := parseDateTime(get(record, "date"), get(record, "time"))
+		if err != nil {
+			logger.L.Warn("Scalable Parser: Skipping row due to invalid date", "date", get(record, "date"), "reference", get(record, "reference"))
+			continue
+		}
+		amount := math.Abs(number(record, "amount"))
+		tx := models.CanonicalTransaction{
+			Source:          "scalable",
+			TransactionDate: date,
+			ProductName:     get(record, "description"),
+			ISIN:            get(record, "isin"),
+			Currency:        strings.ToUpper(get(record, "currency")),
+			OrderID:         get(record, "reference"),
+			RawText:         "scalable|" + strings.Join(record, ";"),
+			SourceAmount:    number(record, "amount"),
+		}
+
+		switch txType := strings.ToLower(get(record, "type")); txType {
+		case "buy", "savings plan", "sell":
+			tx.TransactionType = "STOCK"
+			tx.Quantity = math.Abs(number(record, "shares"))
+			tx.Price = number(record, "price")
+			tx.Commission = math.Abs(number(record, "fee"))
+			if tx.Quantity == 0 {
+				continue
+			}
+			if txType == "sell" {
+				tx.BuySell = "SELL"
+				tx.Amount = amount
+			} else {
+				tx.BuySell = "BUY"
+				tx.Amount = -amount
+			}
+			canonicalTxs = append(canonicalTxs, tx)
+		case "distribution", "dividend":
+			// The amount is net of the tax withheld, which has its own column.
+			tax := math.Abs(number(record, "tax"))
+			tx.TransactionType = "DIVIDEND"
+			tx.Amount = amount + tax
+			canonicalTxs = append(canonicalTxs, tx)
+			if tax > 0 {
+				taxTx := tx
+				taxTx.TransactionSubType = "TAX"
+				taxTx.Amount = -tax
+				taxTx.SourceAmount = tax
+				taxTx.RawText = tx.RawText + "|TAX"
+				canonicalTxs = append(canonicalTxs, taxTx)
+			}
+		case "interest":
+			tx.TransactionType, tx.TransactionSubType = "FEE", "INTEREST"
+			tx.ProductName, tx.ISIN = "Interest", ""
+			tx.Amount = number(record, "amount")
+			canonicalTxs = append(canonicalTxs, tx)
+		case "deposit":
+			tx.TransactionType, tx.TransactionSubType = "CASH", "DEPOSIT"
+			tx.ProductName, tx.ISIN = "Cash Deposit", ""
+			tx.Amount = amount
+			canonicalTxs = append(canonicalTxs, tx)
+		case "withdrawal":
+			tx.TransactionType, tx.TransactionSubType = "CASH", "WITHDRAWAL"
+			tx.ProductName, tx.ISIN = "Cash Withdrawal", ""
+			tx.Amount = -amount
+			canonicalTxs = append(canonicalTxs, tx)
+		case "fee":
+			tx.TransactionType = "FEE"
+			tx.Amount = -amount
+			canonicalTxs = append(canonicalTxs, tx)
+		default:
+			logger.L.Warn("Scalable Parser: Skipping unknown transaction type", "type", txType, "reference", tx.OrderID)
+		}
+	}
+
+	// The export lists the newest transactions first.
+	sort.SliceStable(canonicalTxs, func(i, j int) bool {
+		return canonicalTxs[i].TransactionDate.Before(canonicalTxs[j].TransactionDate)
+	})
+	return canonicalTxs, nil
+}
+
+func parseDateTime(date, clock string) (time.Time, error) {
+	if clock != "" {
+		if t, err := time.Parse("2006-01-02 15:04:05", date+" "+clock); err == nil {
+			return t, nil
+		}
+	}
+	for _, layout := range []string{"2006-01-02", "02.01.2006"} {
+		if t, err := time.Parse(layout, date); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("could not parse scalable date '%s'", date)
+}
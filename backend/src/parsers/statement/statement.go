@@ -0,0 +1,288 @@
+// backend/src/parsers/statement/statement.go
+
+// Package statement extracts transactions from the text of per-event broker documents
+// (trade confirmations, dividend and interest notes), as produced by a PDF-to-text tool.
+// Keywords cover the German, English and Portuguese documents of Trade Republic and Scalable.
+package statement
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/username/taxfolio/backend/src/models"
+)
+
+// Document kinds.
+const (
+	KindSell     = "SELL"
+	KindBuy      = "BUY"
+	KindDividend = "DIVIDEND"
+	KindInterest = "INTEREST"
+)
+
+// kindKeywords are checked in order: "Verkauf" contains "Kauf", and trade notes of bonds
+// mention accrued interest ("Stückzinsen"). They are not matched against the security name,
+// so that a purchase of a "Dividend" ETF stays a purchase.
+var kindKeywords = []struct {
+	kind     string
+	keywords []string
+}{
+	{KindDividend, []string{"dividende", "dividend", "ausschüttung", "distribution", "dividendo", "ertragsgutschrift"}},
+	{KindSell, []string{"verkauf", "sell", "venda"}},
+	{KindBuy, []string{"sparplan", "savings plan", "plano de poupança", "kauf", "buy", "compra"}},
+	{KindInterest, []string{"zinsen", "interest", "juros"}},
+}
+
+var (
+	isinRe     = regexp.MustCompile(`\b([A-Z]{2}[A-Z0-9]{9}\d)\b`)
+	dateRe     = regexp.MustCompile(`\b(\d{2}[./]\d{2}[./]\d{4}|\d{4}-\d{2}-\d{2})\b`)
+	amountRe   = regexp.MustCompile(`(-?\d[\d.,]*)\s*([A-Z]{3})\b`)
+	quantityRe = regexp.MustCompile(`(?i)(\d[\d.,]*)\s*(?:stk\.?|stück|shares?|pcs\.?|units?|ações|unidades)(?:\s|$)`)
+	// positionRe matches the position line: quantity, price or dividend per share, amount.
+	positionRe  = regexp.MustCompile(`(?i)(\d[\d.,]*)\s*(?:stk\.?|stück|shares?|pcs\.?|units?|ações|unidades)\s+(\d[\d.,]*)\s*([A-Z]{3})\s+(\d[\d.,]*)\s*([A-Z]{3})`)
+	referenceRe = regexp.MustCompile(`(?i)\b(?:order|auftrag|ausführung|referenz|reference|execution)\s*(?:nr\.?|no\.?|number)?\s*:?\s*([A-Z0-9][A-Z0-9-]{3,})`)
+)
+
+var (
+	totalLabels = []string{"gesamt", "total", "summe"}
+	feeLabels   = []string{"fremdkostenzuschlag", "external cost", "ordergebühr", "order fee", "provision", "commission", "comissão", "fee"}
+	taxLabels   = []string{"quellensteuer", "withholding", "retenção", "kapitalertragsteuer", "solidaritätszuschlag", "kirchensteuer"}
+)
+
+// Document is the data read from the text of one broker document.
+type Document struct {
+	Kind      string
+	Date      time.Time
+	ISIN      string
+	Name      string
+	Reference string
+	Quantity  float64
+	Price     float64 // Per share; the dividend per share for dividends
+	Amount    float64 // Gross amount of the position, before fees and taxes
+	Currency  string
+	Fees      float64
+	Tax       float64 // Tax withheld, positive
+	Total     float64 // Net amount booked on the account, as printed
+}
+
+// Parse reads a document. It fails when the kind, date or amount cannot be found.
+func Parse(text string) (Document, error) {
+	var doc Document
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	for i, line := range lines {
+		if m := isinRe.FindStringSubmatch(line); m != nil {
+			doc.ISIN = m[1]
+			doc.Name = productName(lines, i)
+			break
+		}
+	}
+
+	kindText := strings.ToLower(text)
+	if doc.Name != "" {
+		kindText = strings.ReplaceAll(kindText, strings.ToLower(doc.Name), "")
+	}
+	for _, k := range kindKeywords {
+		if containsAny(kindText, k.keywords) {
+			doc.Kind = k.kind
+			break
+		}
+	}
+	if doc.Kind == "" {
+		return doc, fmt.Errorf("unrecognised document")
+	}
+
+	if m := dateRe.FindStringSubmatch(text); m != nil {
+		doc.Date, _ = parseDate(m[1])
+	}
+	if doc.Date.IsZero() {
+		return doc, fmt.Errorf("document without a date")
+	}
+	for _, m := range referenceRe.FindAllStringSubmatch(text, -1) {
+		// Skip words following the label, as in "Market-Order Kauf".
+		if strings.ContainsAny(m[1], "0123456789") {
+			doc.Reference = m[1]
+			break
+		}
+	}
+
+	for _, line := range lines {
+		if m := positionRe.FindStringSubmatch(line); m != nil && doc.Amount == 0 {
+			doc.Quantity, _ = ParseNumber(m[1])
+			doc.Price, _ = ParseNumber(m[2])
+			doc.Amount, _ = ParseNumber(m[4])
+			doc.Currency = m[5]
+			continue
+		}
+		lowerLine := strings.ToLower(line)
+		amount, currency, ok := firstAmount(line)
+		if !ok {
+			continue
+		}
+		switch {
+		case containsAny(lowerLine, taxLabels):
+			doc.Tax += math.Abs(amount)
+		case containsAny(lowerLine, feeLabels):
+			doc.Fees += math.Abs(amount)
+		case containsAny(lowerLine, totalLabels):
+			// The last total is the amount booked; earlier ones are subtotals.
+			doc.Total = amount
+			if doc.Currency == "" {
+				doc.Currency = currency
+			}
+		}
+	}
+
+	if doc.Quantity == 0 {
+		if m := quantityRe.FindStringSubmatch(text); m != nil {
+			doc.Quantity, _ = ParseNumber(m[1])
+		}
+	}
+	if doc.Amount == 0 {
+		// Interest notes, and notes without a position line, only print the total.
+		doc.Amount = math.Abs(doc.Total)
+		switch doc.Kind {
+		case KindBuy:
+			doc.Amount -= doc.Fees
+		case KindSell:
+			doc.Amount += doc.Fees + doc.Tax
+		case KindDividend:
+			doc.Amount += doc.Tax
+		}
+		if doc.Quantity > 0 && doc.Price == 0 {
+			doc.Price = doc.Amount / doc.Quantity
+		}
+	}
+	if doc.Amount <= 0 {
+		return doc, fmt.Errorf("document without an amount")
+	}
+	if doc.Kind != KindInterest && doc.ISIN == "" {
+		return doc, fmt.Errorf("document without an ISIN")
+	}
+	if (doc.Kind == KindBuy || doc.Kind == KindSell) && doc.Quantity <= 0 {
+		return doc, fmt.Errorf("trade without a quantity")
+	}
+	if doc.Currency == "" {
+		doc.Currency = "EUR"
+	}
+	return doc, nil
+}
+
+// Transactions converts a document to canonical transactions of the given source.
+func (d Document) Transactions(source string) []models.CanonicalTransaction {
+	rawText := fmt.Sprintf("%s|%s|%s|%s|%s|%f|%f|%s",
+		source, d.Kind, d.Date.Format("2006-01-02"), d.ISIN, d.Reference, d.Quantity, d.Amount, d.Currency)
+	tx := models.CanonicalTransaction{
+		Source:          source,
+		TransactionDate: d.Date,
+		ProductName:     d.Name,
+		ISIN:            d.ISIN,
+		Currency:        d.Currency,
+		OrderID:         d.Reference,
+		RawText:         rawText,
+		SourceAmount:    d.Amount,
+	}
+
+	switch d.Kind {
+	case KindBuy, KindSell:
+		tx.TransactionType = "STOCK"
+		tx.BuySell = d.Kind
+		tx.Quantity = d.Quantity
+		tx.Price = d.Price
+		tx.Commission = d.Fees
+		tx.Amount = d.Amount
+		if d.Kind == KindBuy {
+			tx.Amount = -d.Amount
+		}
+		return []models.CanonicalTransaction{tx}
+	case KindDividend:
+		tx.TransactionType = "DIVIDEND"
+		tx.Amount = d.Amount
+		txs := []models.CanonicalTransaction{tx}
+		if d.Tax > 0 {
+			taxTx := tx
+			taxTx.TransactionSubType = "TAX"
+			taxTx.Amount = -d.Tax
+			taxTx.SourceAmount = d.Tax
+			taxTx.RawText = rawText + "|TAX"
+			txs = append(txs, taxTx)
+		}
+		return txs
+	default:
+		tx.TransactionType, tx.TransactionSubType = "FEE", "INTEREST"
+		tx.ProductName = "Interest"
+		tx.Amount = d.Amount
+		return []models.CanonicalTransaction{tx}
+	}
+}
+
+// productName returns the security name, printed before the ISIN on the same line or on the line above.
+func productName(lines []string, isinLine int) string {
+	line := lines[isinLine]
+	prefix := strings.TrimRight(line[:isinRe.FindStringIndex(line)[0]], " ,:")
+	prefix = strings.TrimRight(strings.TrimSuffix(prefix, "ISIN"), " ,:")
+	if name := strings.TrimSpace(prefix); name != "" {
+		return name
+	}
+	for i := isinLine - 1; i >= 0; i-- {
+		if name := strings.TrimSpace(lines[i]); name != "" {
+			return name
+		}
+	}
+	return ""
+}
+
+// firstAmount returns the first "amount CUR" of a line, which is in the original currency
+// when a converted EUR value follows.
+func firstAmount(line string) (float64, string, bool) {
+	matches := amountRe.FindAllStringSubmatch(line, -1)
+	if len(matches) == 0 {
+		return 0, "", false
+	}
+	m := matches[0]
+	value, err := ParseNumber(m[1])
+	if err != nil {
+		return 0, "", false
+	}
+	return value, m[2], true
+}
+
+func parseDate(s string) (time.Time, error) {
+	for _, layout := range []string{"02.01.2006", "02/01/2006", "2006-01-02"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("could not parse date '%s'", s)
+}
+
+// ParseNumber accepts both "1.234,56" and "1,234.56" styles.
+func ParseNumber(s string) (float64, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, fmt.Errorf("empty number")
+	}
+	if strings.Contains(s, ",") && strings.Contains(s, ".") {
+		if strings.LastIndex(s, ",") > strings.LastIndex(s, ".") {
+			s = strings.ReplaceAll(s, ".", "")
+			s = strings.ReplaceAll(s, ",", ".")
+		} else {
+			s = strings.ReplaceAll(s, ",", "")
+		}
+	} else {
+		s = strings.ReplaceAll(s, ",", ".")
+	}
+	return strconv.ParseFloat(s, 64)
+}
+
+func containsAny(s string, keywords []string) bool {
+	for _, keyword := range keywords {
+		if strings.Contains(s, keyword) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,33 @@
+// backend/src/parsers/traderepublic/parser.go
+package traderepublic
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/username/taxfolio/backend/src/models"
+	"github.com/username/taxfolio/backend/src/parsers/statement"
+)
+
+// TradeRepublicParser implements the parsers.Parser interface for the text of Trade Republic
+// documents: trade confirmations, savings plan executions, dividend and interest notes.
+// Trade Republic issues one document per event, so uploads usually hold many files.
+type TradeRepublicParser struct{}
+
+// NewParser creates a new instance of the TradeRepublicParser.
+func NewParser() *TradeRepublicParser {
+	return &TradeRepublicParser{}
+}
+
+// Parse reads the text of one document.
+func (p *TradeRepublicParser) Parse(file io.Reader) ([]models.CanonicalTransaction, error) {
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return nil, fmt.Errorf("trade republic parser: failed to read file: %w", err)
+	}
+	doc, err := statement.Parse(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("trade republic parser: %w", err)
+	}
+	return doc.Transactions("traderepublic"), nil
+}
@@ -0,0 +1,234 @@
+// backend/src/parsers/xlsx/reader.go
+package xlsx
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxPartSize caps the uncompressed size of a single part of the workbook, so a small
+// compressed upload cannot expand into gigabytes of XML.
+const maxPartSize = 64 << 20
+
+// Sheet holds the cell text of one worksheet. Rows are dense: empty cells are "".
+type Sheet struct {
+	Name string
+	Rows [][]string
+}
+
+// Workbook is the cell text of every worksheet of an XLSX file, in workbook order.
+type Workbook struct {
+	Sheets []Sheet
+}
+
+// IsXLSX reports whether the data starts with the ZIP signature used by XLSX files.
+func IsXLSX(data []byte) bool {
+	return bytes.HasPrefix(data, []byte("PK\x03\x04"))
+}
+
+// Sheet returns the first sheet whose name contains `name` (case-insensitive), or nil.
+func (w *Workbook) Sheet(name string) *Sheet {
+	name = strings.ToLower(name)
+	for i := range w.Sheets {
+		if strings.Contains(strings.ToLower(w.Sheets[i].Name), name) {
+			return &w.Sheets[i]
+		}
+	}
+	return nil
+}
+
+// --- XML structures of the workbook parts ---
+
+type workbookXML struct {
+	Sheets []struct {
+		Name string `xml:"name,attr"`
+		RID  string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+	} `xml:"sheets>sheet"`
+}
+
+type relationshipsXML struct {
+	Relationships []struct {
+		ID     string `xml:"Id,attr"`
+		Target string `xml:"Target,attr"`
+	} `xml:"Relationship"`
+}
+
+type richTextXML struct {
+	Text string `xml:"t"`
+	Runs []struct {
+		Text string `xml:"t"`
+	} `xml:"r"`
+}
+
+func (t richTextXML) String() string {
+	if len(t.Runs) == 0 {
+		return t.Text
+	}
+	var sb strings.Builder
+	for _, r := range t.Runs {
+		sb.WriteString(r.Text)
+	}
+	return sb.String()
+}
+
+type sharedStringsXML struct {
+	Items []richTextXML `xml:"si"`
+}
+
+type worksheetXML struct {
+	Rows []struct {
+		Index int `xml:"r,attr"`
+		Cells []struct {
+			Ref    string      `xml:"r,attr"`
+			Type   string      `xml:"t,attr"`
+			Value  string      `xml:"v"`
+			Inline richTextXML `xml:"is"`
+		} `xml:"c"`
+	} `xml:"sheetData>row"`
+}
+
+// Read parses an XLSX file. Only cell values are read: formulas yield their cached result
+// and numbers (including dates) are returned as stored, e.g. "45310.5".
+func Read(r io.Reader) (*Workbook, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("xlsx: failed to read file: %w", err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("xlsx: not a valid workbook: %w", err)
+	}
+	files := make(map[string]*zip.File, len(zr.File))
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+
+	var wb workbookXML
+	if err := decodePart(files, "xl/workbook.xml", &wb); err != nil {
+		return nil, err
+	}
+	var rels relationshipsXML
+	if err := decodePart(files, "xl/_rels/workbook.xml.rels", &rels); err != nil {
+		return nil, err
+	}
+	targets := make(map[string]string, len(rels.Relationships))
+	for _, rel := range rels.Relationships {
+		target := strings.TrimPrefix(rel.Target, "/")
+		if !strings.HasPrefix(target, "xl/") {
+			target = path.Join("xl", target)
+		}
+		targets[rel.ID] = target
+	}
+
+	var shared sharedStringsXML
+	if _, ok := files["xl/sharedStrings.xml"]; ok {
+		if err := decodePart(files, "xl/sharedStrings.xml", &shared); err != nil {
+			return nil, err
+		}
+	}
+
+	workbook := &Workbook{}
+	for _, s := range wb.Sheets {
+		target, ok := targets[s.RID]
+		if !ok {
+			continue
+		}
+		var ws worksheetXML
+		if err := decodePart(files, target, &ws); err != nil {
+			return nil, err
+		}
+		workbook.Sheets = append(workbook.Sheets, Sheet{Name: s.Name, Rows: sheetRows(ws, shared)})
+	}
+	return workbook, nil
+}
+
+// sheetRows lays the cells of a worksheet out on a dense grid.
+func sheetRows(ws worksheetXML, shared sharedStringsXML) [][]string {
+	var rows [][]string
+	for _, row := range ws.Rows {
+		rowIndex := len(rows)
+		if row.Index > 0 {
+			rowIndex = row.Index - 1
+		}
+		for len(rows) <= rowIndex {
+			rows = append(rows, nil)
+		}
+		var cells []string
+		for i, c := range row.Cells {
+			col := i
+			if c.Ref != "" {
+				col = columnIndex(c.Ref)
+			}
+			for len(cells) <= col {
+				cells = append(cells, "")
+			}
+			switch c.Type {
+			case "s":
+				if idx, err := strconv.Atoi(c.Value); err == nil && idx >= 0 && idx < len(shared.Items) {
+					cells[col] = shared.Items[idx].String()
+				}
+			case "inlineStr":
+				cells[col] = c.Inline.String()
+			default:
+				cells[col] = c.Value
+			}
+		}
+		rows[rowIndex] = cells
+	}
+	return rows
+}
+
+// columnIndex converts the letters of a cell reference ("C12") to a zero-based column.
+func columnIndex(ref string) int {
+	col := 0
+	for _, ch := range ref {
+		if ch < 'A' || ch > 'Z' {
+			break
+		}
+		col = col*26 + int(ch-'A'+1)
+	}
+	return col - 1
+}
+
+func decodePart(files map[string]*zip.File, name string, out interface{}) error {
+	f, ok := files[name]
+	if !ok {
+		return fmt.Errorf("xlsx: missing part %s", name)
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return fmt.Errorf("xlsx: failed to open %s: %w", name, err)
+	}
+	defer rc.Close()
+	data, err := io.ReadAll(io.LimitReader(rc, maxPartSize+1))
+	if err != nil {
+		return fmt.Errorf("xlsx: failed to read %s: %w", name, err)
+	}
+	if len(data) > maxPartSize {
+		return fmt.Errorf("xlsx: part %s is too large", name)
+	}
+	if err := xml.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("xlsx: failed to decode %s: %w", name, err)
+	}
+	return nil
+}
+
+// ParseSerialDate converts an Excel date serial (days since 1899-12-30, fraction = time of day).
+func ParseSerialDate(s string) (time.Time, bool) {
+	serial, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil || serial <= 0 {
+		return time.Time{}, false
+	}
+	days := math.Floor(serial)
+	seconds := math.Round((serial - days) * 86400)
+	base := time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+	return base.AddDate(0, 0, int(days)).Add(time.Duration(seconds) * time.Second), true
+}
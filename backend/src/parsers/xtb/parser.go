@@ -0,0 +1,391 @@
+// backend/src/parsers/xtb/parser.go
+package xtb
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/username/taxfolio/backend/src/logger"
+	"github.com/username/taxfolio/backend/src/models"
+	"github.com/username/taxfolio/backend/src/parsers/xlsx"
+)
+
+// tradeCommentRe matches the comment of a trade in the cash operations, e.g.
+// "OPEN BUY 0.2531 @ 182.45" or "CLOSE BUY 2/5 @ 190.10" (2 of the 5 shares of the position).
+var tradeCommentRe = regexp.MustCompile(`(?i)(OPEN|CLOSE)\s+BUY\s+([\d.,]+)(?:/[\d.,]+)?\s*@\s*([\d.,]+)`)
+
+// yahooSuffixes maps the market suffix of an XTB symbol to the Yahoo Finance one.
+var yahooSuffixes = map[string]string{
+	"US": "", "UK": ".L", "DE": ".DE", "FR": ".PA", "NL": ".AS", "ES": ".MC", "IT": ".MI",
+	"PT": ".LS", "BE": ".BR", "CH": ".SW", "DK": ".CO", "SE": ".ST", "NO": ".OL", "FI": ".HE",
+	"PL": ".WA", "CZ": ".PR", "IE": ".IR",
+}
+
+// table is a header-indexed block of rows from a sheet or CSV file.
+type table struct {
+	cols map[string]int
+	rows [][]string
+}
+
+func (t table) has(name string) bool {
+	_, ok := t.cols[name]
+	return ok
+}
+
+func (t table) get(row []string, name string) string {
+	idx, ok := t.cols[name]
+	if !ok || idx >= len(row) {
+		return ""
+	}
+	return strings.TrimSpace(row[idx])
+}
+
+// XTBParser implements the parsers.Parser interface for the XTB cash operations and
+// closed positions exports, either as the XLSX account statement or as CSV files.
+type XTBParser struct{}
+
+// NewParser creates a new instance of the XTBParser.
+func NewParser() *XTBParser {
+	return &XTBParser{}
+}
+
+// Parse reads an XTB export and converts its rows into a slice of CanonicalTransaction.
+// Trades are taken from the cash operations when present, as they carry the amounts booked
+// on the account; the closed positions are only used when uploaded on their own.
+func (p *XTBParser) Parse(file io.Reader) ([]models.CanonicalTransaction, error) {
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return nil, fmt.Errorf("xtb parser: failed to read file: %w", err)
+	}
+
+	var sheets [][][]string
+	if xlsx.IsXLSX(data) {
+		wb, err := xlsx.Read(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("xtb parser: %w", err)
+		}
+		for _, sheet := range wb.Sheets {
+			sheets = append(sheets, sheet.Rows)
+		}
+	} else {
+		rows, err := readCSV(data)
+		if err != nil {
+			return nil, fmt.Errorf("xtb parser: %w", err)
+		}
+		sheets = append(sheets, rows)
+	}
+
+	var cashOps, closed []table
+	currency := "EUR"
+	for _, rows := range sheets {
+		if c := accountCurrency(rows); c != "" {
+			currency = c
+		}
+		if t, ok := findTable(rows, "type", "time", "amount"); ok && t.has("comment") {
+			cashOps = append(cashOps, t)
+		} else if t, ok := findTable(rows, "position", "symbol", "volume", "open time", "close time"); ok {
+			closed = append(closed, t)
+		}
+	}
+	if len(cashOps) == 0 && len(closed) == 0 {
+		return nil, fmt.Errorf("xtb parser: no cash operations or closed positions found")
+	}
+
+	var canonicalTxs []models.CanonicalTransaction
+	for _, t := range cashOps {
+		canonicalTxs = append(canonicalTxs, parseCashOperations(t, currency)...)
+	}
+	if len(cashOps) == 0 {
+		for _, t := range closed {
+			canonicalTxs = append(canonicalTxs, parseClosedPositions(t, currency)...)
+		}
+	}
+
+	// Exports list the newest operations first.
+	sort.SliceStable(canonicalTxs, func(i, j int) bool {
+		return canonicalTxs[i].TransactionDate.Before(canonicalTxs[j].TransactionDate)
+	})
+	return canonicalTxs, nil
+}
+
+// parseCashOperations maps the rows of the cash operations history.
+func parseCashOperations(t table, currency string) []models.CanonicalTransaction {
+	var txs []models.CanonicalTransaction
+	for _, row := range t.rows {
+		opType := strings.ToLower(t.get(row, "type"))
+		if opType == "" {
+			continue
+		}
+		date, err := parseTime(t.get(row, "time"))
+		if err != nil {
+			logger.L.Warn("XTB Parser: Skipping row due to invalid date", "time", t.get(row, "time"), "id", t.get(row, "id"))
+			continue
+		}
+		amount, err := parseNumber(t.get(row, "amount"))
+		if err != nil {
+			logger.L.Warn("XTB Parser: Skipping row due to invalid amount", "amount", t.get(row, "amount"), "id", t.get(row, "id"))
+			continue
+		}
+		symbol := t.get(row, "symbol")
+		tx := models.CanonicalTransaction{
+			Source:          "xtb",
+			TransactionDate: date,
+			ProductName:     symbol,
+			ISIN:            instrumentID(t, row),
+			Currency:        currency,
+			OrderID:         t.get(row, "id"),
+			RawText:         "xtb|cash|" + strings.Join(row, "|"),
+			SourceAmount:    amount,
+			Amount:          amount,
+		}
+
+		switch {
+		case strings.Contains(opType, "interest"):
+			// Interest on free funds, and the tax withheld on it (negative).
+			tx.TransactionType, tx.TransactionSubType = "FEE", "INTEREST"
+			tx.ProductName = "Free-funds Interest"
+			tx.ISIN = ""
+		case strings.Contains(opType, "withholding"):
+			tx.TransactionType, tx.TransactionSubType = "DIVIDEND", "TAX"
+			tx.Amount = -math.Abs(amount)
+		case strings.Contains(opType, "divid"):
+			tx.TransactionType = "DIVIDEND"
+		case strings.Contains(opType, "purchase"), strings.Contains(opType, "sale"):
+			matches := tradeCommentRe.FindStringSubmatch(t.get(row, "comment"))
+			if matches == nil {
+				logger.L.Warn("XTB Parser: Skipping trade without a recognisable comment", "comment", t.get(row, "comment"), "id", tx.OrderID)
+				continue
+			}
+			quantity, _ := parseNumber(matches[2])
+			if quantity <= 0 {
+				continue
+			}
+			tx.TransactionType = "STOCK"
+			tx.Quantity = quantity
+			// The amount is booked in the account currency; the price is kept in the same
+			// currency so that quantity, price and amount agree.
+			tx.Price = math.Abs(amount) / quantity
+			if strings.Contains(opType, "purchase") {
+				tx.BuySell = "BUY"
+				tx.Amount = -math.Abs(amount)
+			} else {
+				tx.BuySell = "SELL"
+				tx.Amount = math.Abs(amount)
+			}
+		case strings.Contains(opType, "deposit"):
+			tx.TransactionType, tx.TransactionSubType = "CASH", "DEPOSIT"
+			tx.ProductName, tx.ISIN = "Cash Deposit", ""
+		case strings.Contains(opType, "withdraw"):
+			tx.TransactionType, tx.TransactionSubType = "CASH", "WITHDRAWAL"
+			tx.ProductName, tx.ISIN = "Cash Withdrawal", ""
+			tx.Amount = -math.Abs(amount)
+		case strings.Contains(opType, "commission"), strings.Contains(opType, "fee"):
+			tx.TransactionType = "FEE"
+			tx.Amount = -math.Abs(amount)
+			if tx.ProductName == "" {
+				tx.ProductName = t.get(row, "type")
+			}
+		default:
+			logger.L.Warn("XTB Parser: Skipping unknown operation type", "type", opType, "id", tx.OrderID)
+			continue
+		}
+		txs = append(txs, tx)
+	}
+	return txs
+}
+
+// parseClosedPositions synthesises the BUY and SELL of every closed position. Short
+// positions only exist on CFDs, which are not supported.
+func parseClosedPositions(t table, currency string) []models.CanonicalTransaction {
+	var txs []models.CanonicalTransaction
+	for _, row := range t.rows {
+		position := t.get(row, "position")
+		if position == "" {
+			continue
+		}
+		if side := strings.ToUpper(t.get(row, "type")); side != "" && side != "BUY" {
+			logger.L.Warn("XTB Parser: Skipping short position", "position", position)
+			continue
+		}
+		openDate, err1 := parseTime(t.get(row, "open time"))
+		closeDate, err2 := parseTime(t.get(row, "close time"))
+		quantity, err3 := parseNumber(t.get(row, "volume"))
+		if err1 != nil || err2 != nil || err3 != nil || quantity <= 0 {
+			logger.L.Warn("XTB Parser: Skipping closed position with invalid data", "position", position)
+			continue
+		}
+		isin := instrumentID(t, row)
+		if isin == "" {
+			logger.L.Warn("XTB Parser: Skipping closed position without ISIN or symbol", "position", position)
+			continue
+		}
+		purchaseValue := positionValue(t, row, "purchase value", "open price", quantity)
+		saleValue := positionValue(t, row, "sale value", "close price", quantity)
+		commission, _ := parseNumber(t.get(row, "commission"))
+
+		base := models.CanonicalTransaction{
+			Source:          "xtb",
+			ProductName:     t.get(row, "symbol"),
+			ISIN:            isin,
+			Quantity:        quantity,
+			Currency:        currency,
+			OrderID:         position,
+			TransactionType: "STOCK",
+		}
+		raw := strings.Join(row, "|")
+
+		buy := base
+		buy.TransactionDate = openDate
+		buy.BuySell = "BUY"
+		buy.Price = purchaseValue / quantity
+		buy.SourceAmount = purchaseValue
+		buy.Amount = -purchaseValue
+		buy.RawText = "xtb|closed|open|" + raw
+
+		sell := base
+		sell.TransactionDate = closeDate
+		sell.BuySell = "SELL"
+		sell.Price = saleValue / quantity
+		sell.SourceAmount = saleValue
+		sell.Amount = saleValue
+		sell.Commission = math.Abs(commission)
+		sell.RawText = "xtb|closed|close|" + raw
+
+		txs = append(txs, buy, sell)
+	}
+	return txs
+}
+
+// positionValue returns the reported value of one side of a position, or quantity × price.
+func positionValue(t table, row []string, valueCol, priceCol string, quantity float64) float64 {
+	if v, err := parseNumber(t.get(row, valueCol)); err == nil && v != 0 {
+		return math.Abs(v)
+	}
+	price, _ := parseNumber(t.get(row, priceCol))
+	return math.Abs(price * quantity)
+}
+
+// instrumentID returns the ISIN when the export has one. Older exports identify instruments
+// by XTB symbol only; the matching uppercase Yahoo ticker is stored instead, as documented on
+// models.CanonicalTransaction.
+func instrumentID(t table, row []string) string {
+	if isin := t.get(row, "isin"); isin != "" {
+		return strings.ToUpper(isin)
+	}
+	symbol := strings.ToUpper(t.get(row, "symbol"))
+	if symbol == "" {
+		return ""
+	}
+	if dot := strings.LastIndex(symbol, "."); dot > 0 {
+		if suffix, ok := yahooSuffixes[symbol[dot+1:]]; ok {
+			return symbol[:dot] + suffix
+		}
+	}
+	return symbol
+}
+
+// findTable locates the header row holding all the required columns and returns the rows below it.
+func findTable(rows [][]string, required ...string) (table, bool) {
+	for i, row := range rows {
+		cols := make(map[string]int)
+		for j, cell := range row {
+			name := strings.ToLower(strings.TrimSpace(cell))
+			if _, seen := cols[name]; name != "" && !seen {
+				cols[name] = j
+			}
+		}
+		found := true
+		for _, name := range required {
+			if _, ok := cols[name]; !ok {
+				found = false
+				break
+			}
+		}
+		if found {
+			return table{cols: cols, rows: rows[i+1:]}, true
+		}
+	}
+	return table{}, false
+}
+
+// accountCurrency reads the currency from the account summary above the tables of the statement.
+func accountCurrency(rows [][]string) string {
+	for i := 0; i+1 < len(rows) && i < 10; i++ {
+		for j, cell := range rows[i] {
+			if strings.EqualFold(strings.TrimSpace(cell), "currency") && j < len(rows[i+1]) {
+				if c := strings.ToUpper(strings.TrimSpace(rows[i+1][j])); len(c) == 3 {
+					return c
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// readCSV reads a CSV export, which XTB writes with either ";" or "," as separator.
+func readCSV(data []byte) ([][]string, error) {
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	firstLine := data
+	if idx := bytes.IndexByte(data, '\n'); idx >= 0 {
+		firstLine = data[:idx]
+	}
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	if bytes.Count(firstLine, []byte(";")) > bytes.Count(firstLine, []byte(",")) {
+		reader.Comma = ';'
+	}
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV records: %w", err)
+	}
+	return rows, nil
+}
+
+var timeLayouts = []string{
+	"02.01.2006 15:04:05", "02.01.2006 15:04", "02.01.2006",
+	"2006-01-02 15:04:05", "2006-01-02 15:04", "2006-01-02",
+	"02/01/2006 15:04:05", "02/01/2006 15:04", "02/01/2006",
+}
+
+// parseTime accepts the text formats of the CSV exports and the date serials of the XLSX one.
+func parseTime(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	for _, layout := range timeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	if t, ok := xlsx.ParseSerialDate(s); ok {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("could not parse xtb time '%s'", s)
+}
+
+// parseNumber accepts both "1,234.56" and "1 234,56" styles.
+func parseNumber(s string) (float64, error) {
+	s = strings.ReplaceAll(strings.TrimSpace(s), " ", "")
+	s = strings.ReplaceAll(s, " ", "")
+	if s == "" {
+		return 0, fmt.Errorf("empty number")
+	}
+	if strings.Contains(s, ",") && strings.Contains(s, ".") {
+		if strings.LastIndex(s, ",") > strings.LastIndex(s, ".") {
+			s = strings.ReplaceAll(s, ".", "")
+			s = strings.ReplaceAll(s, ",", ".")
+		} else {
+			s = strings.ReplaceAll(s, ",", "")
+		}
+	} else {
+		s = strings.ReplaceAll(s, ",", ".")
+	}
+	return strconv.ParseFloat(s, 64)
+}
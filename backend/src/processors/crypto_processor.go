@@ -0,0 +1,141 @@
+// backend/src/processors/crypto_processor.go
+package processors
+
+import (
+	"math"
+	"sort"
+
+	"github.com/username/taxfolio/backend/src/models"
+	"github.com/username/taxfolio/backend/src/utils"
+)
+
+// unitsEpsilon absorbs the rounding left over when fractional units are matched.
+const unitsEpsilon = 1e-9
+
+type cryptoProcessorImpl struct{}
+
+func NewCryptoProcessor() CryptoProcessor {
+	return &cryptoProcessorImpl{}
+}
+
+// cryptoLot is an open purchase with the units still unsold.
+type cryptoLot struct {
+	tx        models.ProcessedTransaction
+	units     float64
+	remaining float64
+}
+
+// Process matches crypto disposals against purchases of the same asset, first in first out,
+// on the fractional units of each transaction.
+func (p *cryptoProcessorImpl) Process(transactions []models.ProcessedTransaction) ([]models.CryptoSaleDetail, []models.CryptoLot, []models.CryptoIncome) {
+	var cryptoTxs []models.ProcessedTransaction
+	for _, tx := range transactions {
+		if tx.TransactionType == "CRYPTO" {
+			cryptoTxs = append(cryptoTxs, tx)
+		}
+	}
+	// Transactions come ordered by date and insertion, which keeps the legs of a
+	// crypto-to-crypto trade in the order the parser emitted them.
+	sort.SliceStable(cryptoTxs, func(i, j int) bool {
+		return utils.ParseDate(cryptoTxs[i].Date).Before(utils.ParseDate(cryptoTxs[j].Date))
+	})
+
+	saleDetails := []models.CryptoSaleDetail{}
+	income := []models.CryptoIncome{}
+	openLots := make(map[string][]*cryptoLot)
+	var assets []string
+
+	for _, tx := range cryptoTxs {
+		units := tx.Units
+		if units == 0 {
+			units = float64(tx.Quantity)
+		}
+		switch tx.BuySell {
+		case "BUY":
+			if units <= 0 {
+				continue
+			}
+			if _, ok := openLots[tx.ISIN]; !ok {
+				assets = append(assets, tx.ISIN)
+			}
+			openLots[tx.ISIN] = append(openLots[tx.ISIN], &cryptoLot{tx: tx, units: units, remaining: units})
+		case "SELL":
+			saleDetails = append(saleDetails, matchCryptoSale(tx, units, openLots)...)
+		default:
+			if tx.TransactionSubType == models.SubTypeStaking {
+				income = append(income, models.CryptoIncome{
+					Date:      tx.Date,
+					Asset:     tx.ISIN,
+					Source:    tx.Source,
+					Units:     units,
+					AmountEUR: utils.RoundFloat(tx.AmountEUR, 2),
+				})
+			}
+		}
+	}
+
+	holdings := []models.CryptoLot{}
+	for _, asset := range assets {
+		for _, lot := range openLots[asset] {
+			holdings = append(holdings, models.CryptoLot{
+				BuyDate:      lot.tx.Date,
+				Asset:        asset,
+				Source:       lot.tx.Source,
+				Units:        lot.remaining,
+				BuyAmountEUR: utils.RoundFloat(lot.tx.AmountEUR*lot.remaining/lot.units, 2),
+			})
+		}
+	}
+	return saleDetails, holdings, income
+}
+
+// matchCryptoSale consumes the oldest lots of the asset sold. Units sold beyond the open lots,
+// which happens when the purchases are in a file that was not uploaded, are not reported.
+func matchCryptoSale(tx models.ProcessedTransaction, units float64, openLots map[string][]*cryptoLot) []models.CryptoSaleDetail {
+	var details []models.CryptoSaleDetail
+	saleDate := utils.ParseDate(tx.Date)
+	remaining := units
+	lots := openLots[tx.ISIN]
+
+	for remaining > unitsEpsilon && len(lots) > 0 {
+		lot := lots[0]
+		matched := math.Min(remaining, lot.remaining)
+		saleRatio := matched / units
+		purchaseRatio := matched / lot.units
+
+		commission := tx.Commission * saleRatio
+		if lot.tx.Commission > 0 {
+			commission += lot.tx.Commission
+			lot.tx.Commission = 0
+		}
+		buyAmountEUR := utils.RoundFloat(lot.tx.AmountEUR*purchaseRatio, 2)
+		saleAmountEUR := utils.RoundFloat(tx.AmountEUR*saleRatio, 2)
+		daysHeld := int(saleDate.Sub(utils.ParseDate(lot.tx.Date)).Hours() / 24)
+		holdingPeriod := models.HoldingPeriodShort
+		if daysHeld >= models.LongTermHoldingDays {
+			holdingPeriod = models.HoldingPeriodLong
+		}
+
+		details = append(details, models.CryptoSaleDetail{
+			SaleDate:      tx.Date,
+			BuyDate:       lot.tx.Date,
+			Asset:         tx.ISIN,
+			Source:        tx.Source,
+			Units:         matched,
+			SaleAmountEUR: saleAmountEUR,
+			BuyAmountEUR:  buyAmountEUR,
+			Commission:    utils.RoundFloat(commission, 2),
+			Delta:         utils.RoundFloat(saleAmountEUR+buyAmountEUR, 2),
+			DaysHeld:      daysHeld,
+			HoldingPeriod: holdingPeriod,
+		})
+
+		remaining -= matched
+		lot.remaining -= matched
+		if lot.remaining <= unitsEpsilon {
+			lots = lots[1:]
+		}
+	}
+	openLots[tx.ISIN] = lots
+	return details
+}
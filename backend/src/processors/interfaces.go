@@ -34,3 +34,9 @@ type CashMovementProcessor interface {
 type FeeProcessor interface {
 	Process(transactions []models.ProcessedTransaction) []models.FeeDetail
 }
+
+// CryptoProcessor defines the interface for processing crypto asset transactions.
+// It returns the FIFO-matched disposals, the open lots and the staking income.
+type CryptoProcessor interface {
+	Process(transactions []models.ProcessedTransaction) ([]models.CryptoSaleDetail, []models.CryptoLot, []models.CryptoIncome)
+}
@@ -1,6 +1,7 @@
 package processors
 
 import (
+	"math"
 	"sort"
 	"strconv"
 
@@ -48,8 +49,10 @@ func calculateSalesAndYearlyHoldings(transactions []models.ProcessedTransaction)
 			}
 		}
 
-		// Process the current transaction (buy or sell).
-		if tx.TransactionType == "STOCK" && tx.BuySell == "BUY" {
+		// Process the current transaction (split, buy or sell).
+		if tx.TransactionSubType == models.SubTypeStockSplit {
+			applyStockSplit(openPurchasesByISIN[tx.ISIN], tx.Quantity)
+		} else if tx.TransactionType == "STOCK" && tx.BuySell == "BUY" {
 			purchaseCopy := tx
 			openPurchasesByISIN[tx.ISIN] = append(openPurchasesByISIN[tx.ISIN], &purchaseCopy)
 		} else if tx.TransactionType == "STOCK" && tx.BuySell == "SELL" {
@@ -114,6 +117,32 @@ func calculateSalesAndYearlyHoldings(transactions []models.ProcessedTransaction)
 	return saleDetails, holdingsByYear
 }
 
+// applyStockSplit spreads the shares added by a split, or removed by a reverse split, over the
+// open lots of a position in proportion to their size. The lots keep their cost and purchase
+// date; the last lot takes the rounding so that the position ends with the split quantity.
+func applyStockSplit(lots []*models.ProcessedTransaction, added int) {
+	held := 0
+	for _, lot := range lots {
+		held += lot.Quantity
+	}
+	if held == 0 || held+added <= 0 {
+		return
+	}
+	ratio := float64(held+added) / float64(held)
+	remaining := held + added
+	for i, lot := range lots {
+		quantity := int(math.Round(float64(lot.Quantity) * ratio))
+		if i == len(lots)-1 {
+			quantity = remaining
+		}
+		remaining -= quantity
+		// Sales take their cost from the share of the original quantity they consume.
+		lot.OriginalQuantity = int(math.Round(float64(lot.OriginalQuantity) * float64(quantity) / float64(lot.Quantity)))
+		lot.Price /= ratio
+		lot.Quantity = quantity
+	}
+}
+
 // collectAndCopyHoldings is a helper to create the PurchaseLot view model from the internal state.
 func collectAndCopyHoldings(holdingsMap map[string][]*models.ProcessedTransaction) []models.PurchaseLot {
 	var snapshot []models.PurchaseLot
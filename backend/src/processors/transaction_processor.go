@@ -5,6 +5,7 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
+	"strings"
 
 	"github.com/username/taxfolio/backend/src/logger"
 	"github.com/username/taxfolio/backend/src/models"
@@ -49,8 +50,11 @@ func (p *TransactionProcessor) Process(txs []models.CanonicalTransaction) []mode
 			commissionEUR = tx.Commission // Assume 1:1 se a taxa for 0 ou 1
 		}
 
-		// 4. Enrich with Country Code from ISIN.
-		tx.CountryCode = utils.GetCountryCodeString(tx.ISIN)
+		// 4. Enrich with Country Code from ISIN. Crypto assets are identified by their symbol,
+		// and instruments without an ISIN by their ticker, which carries no country.
+		if tx.TransactionType != "CRYPTO" && utils.IsISIN(strings.ToUpper(tx.ISIN)) {
+			tx.CountryCode = utils.GetCountryCodeString(tx.ISIN)
+		}
 
 		// 5. Enrich with a unique Hash ID.
 		tx.HashId = generateHash(tx)
@@ -64,6 +68,7 @@ func (p *TransactionProcessor) Process(txs []models.CanonicalTransaction) []mode
 			ISIN:               tx.ISIN,
 			Quantity:           int(tx.Quantity),
 			OriginalQuantity:   int(tx.Quantity),
+			Units:              tx.Quantity,
 			Price:              tx.Price,
 			TransactionType:    tx.TransactionType,
 			TransactionSubType: tx.TransactionSubType,
@@ -88,7 +93,14 @@ func (p *TransactionProcessor) Process(txs []models.CanonicalTransaction) []mode
 
 // generateHash creates a unique hash for the transaction based on key source data.
 func generateHash(tx models.CanonicalTransaction) string {
-	input := fmt.Sprintf(tx.RawText)
-	hash := sha256.Sum256([]byte(input))
+	return HashRawText(tx.RawText)
+}
+
+// HashRawText returns the hash ID of a transaction with the given raw text. The raw text has
+// always gone through fmt.Sprintf as a format string, which rewrites any "%" in it, e.g. in a
+// bond named "4.5% ...", and the stored hashes depend on it.
+func HashRawText(rawText string) string {
+	var noArgs []interface{}
+	hash := sha256.Sum256([]byte(fmt.Sprintf(rawText, noArgs...)))
 	return hex.EncodeToString(hash[:])
 }
@@ -0,0 +1,323 @@
+// backend/src/scheduler/scheduler.go
+package scheduler
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/username/taxfolio/backend/src/database"
+	"github.com/username/taxfolio/backend/src/logger"
+)
+
+var (
+	ErrJobNotFound       = errors.New("scheduler job not found")
+	ErrJobAlreadyRunning = errors.New("scheduler job is already running")
+	ErrStopped           = errors.New("scheduler is stopped")
+)
+
+// JobFunc is the unit of work executed by the scheduler.
+// Implementations should honour ctx cancellation between expensive steps.
+type JobFunc func(ctx context.Context) error
+
+// Job describes a task that runs once per day at a fixed UTC time.
+type Job struct {
+	Name        string
+	Description string
+	RunAt       string // "HH:MM" in UTC
+	Run         JobFunc
+}
+
+// JobStatus is the admin-facing view of a registered job.
+type JobStatus struct {
+	Name           string     `json:"name"`
+	Description    string     `json:"description"`
+	Schedule       string     `json:"schedule"`
+	Running        bool       `json:"running"`
+	LastStartedAt  *time.Time `json:"last_started_at"`
+	LastFinishedAt *time.Time `json:"last_finished_at"`
+	LastDuration   string     `json:"last_duration"`
+	LastStatus     string     `json:"last_status"`
+	LastError      string     `json:"last_error"`
+	RunCount       int        `json:"run_count"`
+	NextRunAt      time.Time  `json:"next_run_at"`
+}
+
+type jobEntry struct {
+	job    Job
+	hour   int
+	minute int
+	status JobStatus
+}
+
+// Scheduler runs registered jobs in-process on a daily schedule.
+type Scheduler struct {
+	mu      sync.Mutex
+	jobs    map[string]*jobEntry
+	ctx     context.Context
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+	started bool
+	stopped bool // Set under mu before Stop waits, so that no job is added to wg afterwards
+}
+
+// New creates an empty scheduler. Jobs must be registered before Start is called.
+func New() *Scheduler {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Scheduler{
+		jobs:   make(map[string]*jobEntry),
+		ctx:    ctx,
+		cancel: cancel,
+	}
+}
+
+// Register adds a job to the scheduler and restores its last run information from the database.
+func (s *Scheduler) Register(job Job) error {
+	hour, minute, err := parseRunAt(job.RunAt)
+	if err != nil {
+		return fmt.Errorf("invalid schedule for job %s: %w", job.Name, err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.jobs[job.Name]; exists {
+		return fmt.Errorf("job %s is already registered", job.Name)
+	}
+
+	entry := &jobEntry{
+		job:    job,
+		hour:   hour,
+		minute: minute,
+		status: JobStatus{
+			Name:        job.Name,
+			Description: job.Description,
+			Schedule:    fmt.Sprintf("daily at %02d:%02d UTC", hour, minute),
+		},
+	}
+	loadLastRun(&entry.status)
+	entry.status.NextRunAt = nextRun(time.Now().UTC(), hour, minute)
+	s.jobs[job.Name] = entry
+	return nil
+}
+
+// Start launches one goroutine per registered job.
+func (s *Scheduler) Start() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.started || s.stopped {
+		return
+	}
+	s.started = true
+
+	for _, entry := range s.jobs {
+		s.wg.Add(1)
+		go s.loop(entry)
+	}
+	logger.L.Info("Scheduler started", "jobs", len(s.jobs))
+}
+
+// Stop cancels running jobs and waits for the job loops to exit.
+func (s *Scheduler) Stop() {
+	s.mu.Lock()
+	s.stopped = true
+	s.mu.Unlock()
+	s.cancel()
+	s.wg.Wait()
+	logger.L.Info("Scheduler stopped")
+}
+
+// Status returns a snapshot of all registered jobs, sorted by name.
+func (s *Scheduler) Status() []JobStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	statuses := make([]JobStatus, 0, len(s.jobs))
+	for _, entry := range s.jobs {
+		statuses = append(statuses, entry.status)
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Name < statuses[j].Name
+	})
+	return statuses
+}
+
+// RunNow triggers a job immediately in the background, outside its normal schedule.
+func (s *Scheduler) RunNow(name string) error {
+	s.mu.Lock()
+	entry, ok := s.jobs[name]
+	if !ok {
+		s.mu.Unlock()
+		return ErrJobNotFound
+	}
+	if entry.status.Running {
+		s.mu.Unlock()
+		return ErrJobAlreadyRunning
+	}
+	if s.stopped {
+		s.mu.Unlock()
+		return ErrStopped
+	}
+	s.wg.Add(1)
+	s.mu.Unlock()
+
+	go func() {
+		defer s.wg.Done()
+		s.execute(entry)
+	}()
+	return nil
+}
+
+func (s *Scheduler) loop(entry *jobEntry) {
+	defer s.wg.Done()
+	for {
+		s.mu.Lock()
+		next := nextRun(time.Now().UTC(), entry.hour, entry.minute)
+		entry.status.NextRunAt = next
+		s.mu.Unlock()
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-s.ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+			s.execute(entry)
+		}
+	}
+}
+
+func (s *Scheduler) execute(entry *jobEntry) {
+	s.mu.Lock()
+	if entry.status.Running {
+		s.mu.Unlock()
+		logger.L.Warn("Scheduler job still running, skipping execution", "job", entry.job.Name)
+		return
+	}
+	startedAt := time.Now().UTC()
+	entry.status.Running = true
+	entry.status.LastStartedAt = &startedAt
+	entry.status.LastStatus = "running"
+	entry.status.LastError = ""
+	s.mu.Unlock()
+
+	persistRun(entry.job.Name, startedAt, nil, "running", "")
+	logger.L.Info("Scheduler job started", "job", entry.job.Name)
+
+	err := runSafely(s.ctx, entry.job.Run)
+
+	finishedAt := time.Now().UTC()
+	status, errMsg := "success", ""
+	if err != nil {
+		status, errMsg = "failed", err.Error()
+		logger.L.Error("Scheduler job failed", "job", entry.job.Name, "duration", finishedAt.Sub(startedAt), "error", err)
+	} else {
+		logger.L.Info("Scheduler job finished", "job", entry.job.Name, "duration", finishedAt.Sub(startedAt))
+	}
+
+	s.mu.Lock()
+	entry.status.Running = false
+	entry.status.LastFinishedAt = &finishedAt
+	entry.status.LastDuration = finishedAt.Sub(startedAt).Round(time.Second).String()
+	entry.status.LastStatus = status
+	entry.status.LastError = errMsg
+	entry.status.RunCount++
+	s.mu.Unlock()
+
+	persistRun(entry.job.Name, startedAt, &finishedAt, status, errMsg)
+}
+
+// runSafely protects the scheduler loop from panics inside a job.
+func runSafely(ctx context.Context, fn JobFunc) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("job panicked: %v", r)
+		}
+	}()
+	return fn(ctx)
+}
+
+// nextRun returns the next occurrence of hour:minute (UTC) strictly after now.
+func nextRun(now time.Time, hour, minute int) time.Time {
+	next := time.Date(now.Year(), now.Month(), now.Day(), hour, minute, 0, 0, time.UTC)
+	if !next.After(now) {
+		next = next.AddDate(0, 0, 1)
+	}
+	return next
+}
+
+func parseRunAt(runAt string) (int, int, error) {
+	t, err := time.Parse("15:04", runAt)
+	if err != nil {
+		return 0, 0, err
+	}
+	return t.Hour(), t.Minute(), nil
+}
+
+func loadLastRun(status *JobStatus) {
+	if database.DB == nil {
+		return
+	}
+	var startedAt, finishedAt sql.NullTime
+	var lastStatus, lastError sql.NullString
+	err := database.DB.QueryRow(`
+		SELECT last_started_at, last_finished_at, last_status, last_error, run_count
+		FROM scheduler_job_runs WHERE job_name = ?`, status.Name,
+	).Scan(&startedAt, &finishedAt, &lastStatus, &lastError, &status.RunCount)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			logger.L.Warn("Failed to load scheduler job history", "job", status.Name, "error", err)
+		}
+		return
+	}
+	if startedAt.Valid {
+		t := startedAt.Time
+		status.LastStartedAt = &t
+	}
+	if finishedAt.Valid {
+		t := finishedAt.Time
+		status.LastFinishedAt = &t
+		if startedAt.Valid {
+			status.LastDuration = finishedAt.Time.Sub(startedAt.Time).Round(time.Second).String()
+		}
+	}
+	status.LastStatus = lastStatus.String
+	status.LastError = lastError.String
+	// A "running" status on startup means the server stopped mid-run.
+	if status.LastStatus == "running" {
+		status.LastStatus = "interrupted"
+	}
+}
+
+func persistRun(jobName string, startedAt time.Time, finishedAt *time.Time, status, errMsg string) {
+	if database.DB == nil {
+		return
+	}
+	var finished interface{}
+	if finishedAt != nil {
+		finished = *finishedAt
+	}
+	runIncrement := 0
+	if finishedAt != nil {
+		runIncrement = 1
+	}
+	_, err := database.DB.Exec(`
+		INSERT INTO scheduler_job_runs (job_name, last_started_at, last_finished_at, last_status, last_error, run_count, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP)
+		ON CONFLICT(job_name) DO UPDATE SET
+			last_started_at = excluded.last_started_at,
+			last_finished_at = excluded.last_finished_at,
+			last_status = excluded.last_status,
+			last_error = excluded.last_error,
+			run_count = scheduler_job_runs.run_count + ?,
+			updated_at = CURRENT_TIMESTAMP`,
+		jobName, startedAt, finished, status, errMsg, runIncrement, runIncrement,
+	)
+	if err != nil {
+		logger.L.Warn("Failed to persist scheduler job run", "job", jobName, "error", err)
+	}
+}
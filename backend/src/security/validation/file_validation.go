@@ -19,7 +19,19 @@ var AllowedClientContentTypes = map[string]bool{
 	"application/csv":          true,
 	"application/vnd.ms-excel": true,
 	"text/plain":               true,
-	"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet": false,
+	"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet": true,
+}
+
+// xlsxContentType is reported for XLSX workbooks, the only binary format accepted.
+const xlsxContentType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+
+// isXLSX checks for the ZIP signature of an Office Open XML workbook. The first entries of
+// the archive are its content types and workbook parts, whose names show in the first bytes.
+func isXLSX(buf []byte) bool {
+	if !bytes.HasPrefix(buf, []byte("PK\x03\x04")) {
+		return false
+	}
+	return bytes.Contains(buf, []byte("[Content_Types].xml")) || bytes.Contains(buf, []byte("xl/"))
 }
 
 // ValidateClientContentType checks the Content-Type header provided by the client.
@@ -73,6 +85,11 @@ func ValidateFileContentByMagicBytes(file io.ReadSeeker) (string, error) {
 		return "", fmt.Errorf("file is empty")
 	}
 
+	if isXLSX(buffer[:n]) {
+		logger.L.Debug("File content type validated", "detectedContentType", xlsxContentType)
+		return xlsxContentType, nil
+	}
+
 	if isBinaryContent(buffer[:n]) {
 		logger.L.Warn("File rejected: Binary content detected in text upload")
 		return "application/octet-stream", fmt.Errorf("file appears to be binary or executable")
@@ -0,0 +1,358 @@
+// backend/src/services/allocation_service.go
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/username/taxfolio/backend/src/database"
+	"github.com/username/taxfolio/backend/src/logger"
+	"github.com/username/taxfolio/backend/src/model"
+	"github.com/username/taxfolio/backend/src/models"
+	"github.com/username/taxfolio/backend/src/utils"
+)
+
+const (
+	// providerCompositionMaxAge is how long fetched fund compositions are trusted.
+	providerCompositionMaxAge = 90 * 24 * time.Hour
+	// providerRetryInterval throttles lookups for funds the provider has no data for.
+	providerRetryInterval = 24 * time.Hour
+	maxCompositionBuckets = 300
+)
+
+var ErrInvalidFundComposition = errors.New("invalid fund composition")
+
+var allocationDimensions = []string{
+	models.DimensionSector,
+	models.DimensionCountry,
+	models.DimensionCurrency,
+	models.DimensionAssetType,
+}
+
+// AllocationService aggregates current holdings and cash into percentage breakdowns.
+type AllocationService interface {
+	// GetAllocation breaks the portfolio down by sector, country, quote currency and asset type.
+	// With lookThrough, funds are split using their stored or fetched composition.
+	GetAllocation(userID int64, portfolioID int64, lookThrough bool) (*models.AllocationResult, error)
+	// GetFundCompositions returns the compositions used for a fund, keyed by dimension.
+	GetFundCompositions(userID int64, isin string) (map[string]models.FundComposition, error)
+	// SetFundComposition stores a user's own composition for one fund and dimension.
+	SetFundComposition(userID int64, composition models.FundComposition) (*models.FundComposition, error)
+	DeleteFundComposition(userID int64, isin, dimension string) error
+}
+
+type allocationServiceImpl struct {
+	uploadService UploadService
+	priceService  PriceService
+
+	mu               sync.Mutex
+	providerAttempts map[string]time.Time
+}
+
+func NewAllocationService(uploadService UploadService, priceService PriceService) AllocationService {
+	return &allocationServiceImpl{
+		uploadService:    uploadService,
+		priceService:     priceService,
+		providerAttempts: make(map[string]time.Time),
+	}
+}
+
+func isFundAssetType(assetType string) bool {
+	switch strings.ToUpper(assetType) {
+	case "ETF", "MUTUALFUND":
+		return true
+	}
+	return false
+}
+
+func (s *allocationServiceImpl) GetAllocation(userID int64, portfolioID int64, lookThrough bool) (*models.AllocationResult, error) {
+	holdings, err := s.uploadService.GetCurrentHoldingsWithValue(userID, portfolioID)
+	if err != nil {
+		return nil, err
+	}
+
+	cash, err := latestCashBalance(userID, portfolioID)
+	if err != nil {
+		return nil, err
+	}
+
+	isins := make([]string, 0, len(holdings))
+	var fundISINs []string
+	for _, h := range holdings {
+		isins = append(isins, h.ISIN)
+		if isFundAssetType(h.AssetType) {
+			fundISINs = append(fundISINs, h.ISIN)
+		}
+	}
+	mappings, err := model.GetMappingsByISINs(database.DB, isins)
+	if err != nil {
+		logger.L.Warn("Could not load ISIN mappings for allocation", "error", err)
+		mappings = map[string]model.ISINTickerMap{}
+	}
+
+	var compositions map[string]map[string]models.FundComposition
+	if lookThrough && len(fundISINs) > 0 {
+		compositions, err = model.GetFundCompositions(database.DB, userID, fundISINs)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load fund compositions: %w", err)
+		}
+		if s.refreshProviderCompositions(fundISINs, compositions) {
+			compositions, err = model.GetFundCompositions(database.DB, userID, fundISINs)
+			if err != nil {
+				return nil, fmt.Errorf("failed to load fund compositions: %w", err)
+			}
+		}
+	}
+
+	totals := make(map[string]map[string]float64, len(allocationDimensions))
+	for _, dimension := range allocationDimensions {
+		totals[dimension] = make(map[string]float64)
+	}
+
+	total := cash
+	for _, h := range holdings {
+		value := h.MarketValueEUR
+		total += value
+
+		for _, dimension := range allocationDimensions {
+			if composition, ok := compositions[h.ISIN][dimension]; ok && len(composition.Weights) > 0 {
+				splitByWeights(totals[dimension], value, composition.Weights)
+				continue
+			}
+			totals[dimension][holdingBucket(h, dimension, mappings)] += value
+		}
+	}
+
+	if cash != 0 {
+		totals[models.DimensionSector][models.AllocationCash] += cash
+		totals[models.DimensionCountry][models.AllocationCash] += cash
+		totals[models.DimensionCurrency]["EUR"] += cash
+		totals[models.DimensionAssetType][models.AllocationCash] += cash
+	}
+
+	result := &models.AllocationResult{
+		TotalValueEUR: total,
+		CashEUR:       cash,
+		LookThrough:   lookThrough,
+		Breakdowns:    make(map[string][]models.AllocationBucket, len(allocationDimensions)),
+	}
+	for _, dimension := range allocationDimensions {
+		result.Breakdowns[dimension] = toBuckets(totals[dimension], total)
+	}
+	return result, nil
+}
+
+// latestCashBalance returns the cash balance of the most recent snapshot, or 0 without one.
+func latestCashBalance(userID int64, portfolioID int64) (float64, error) {
+	var cash float64
+	err := database.DB.QueryRow(`
+		SELECT cash_balance FROM portfolio_snapshots
+		WHERE user_id = ? AND portfolio_id = ?
+		ORDER BY date DESC LIMIT 1`, userID, portfolioID).Scan(&cash)
+	if err != nil && err != sql.ErrNoRows {
+		return 0, fmt.Errorf("failed to fetch cash balance: %w", err)
+	}
+	return cash, nil
+}
+
+// splitByWeights spreads value across the buckets of a composition. Weights that add up
+// to less than one leave the remainder in the unknown bucket.
+func splitByWeights(totals map[string]float64, value float64, weights map[string]float64) {
+	sum := 0.0
+	for _, w := range weights {
+		sum += w
+	}
+	scale := 1.0
+	if sum > 1 {
+		scale = 1 / sum
+	}
+	for bucket, w := range weights {
+		totals[bucket] += value * w * scale
+	}
+	if remainder := 1 - sum*scale; remainder > 1e-6 {
+		totals[models.AllocationUnknown] += value * remainder
+	}
+}
+
+func toBuckets(totals map[string]float64, total float64) []models.AllocationBucket {
+	buckets := make([]models.AllocationBucket, 0, len(totals))
+	for name, value := range totals {
+		if math.Abs(value) < 0.005 {
+			continue
+		}
+		bucket := models.AllocationBucket{Name: name, ValueEUR: value}
+		if total != 0 {
+			bucket.Percentage = value / total * 100
+		}
+		buckets = append(buckets, bucket)
+	}
+	sort.Slice(buckets, func(i, j int) bool {
+		if buckets[i].ValueEUR != buckets[j].ValueEUR {
+			return buckets[i].ValueEUR > buckets[j].ValueEUR
+		}
+		return buckets[i].Name < buckets[j].Name
+	})
+	return buckets
+}
+
+// holdingBucket returns the bucket a holding falls in for a dimension, without looking
+// through funds. Country is the issuer's (for funds, their domicile).
+func holdingBucket(h models.HoldingWithValue, dimension string, mappings map[string]model.ISINTickerMap) string {
+	bucket := ""
+	switch dimension {
+	case models.DimensionISIN:
+		bucket = h.ISIN
+	case models.DimensionSector:
+		bucket = h.Sector
+	case models.DimensionCountry:
+		if len(h.ISIN) >= 2 {
+			bucket = utils.GetCountryName(h.ISIN[:2])
+		}
+	case models.DimensionCurrency:
+		bucket = strings.ToUpper(mappings[h.ISIN].Currency)
+	case models.DimensionAssetType:
+		bucket = strings.ToUpper(h.AssetType)
+	}
+	if bucket == "" {
+		return models.AllocationUnknown
+	}
+	return bucket
+}
+
+// refreshProviderCompositions fetches provider data for funds that have none, or only
+// stale provider data. It reports whether anything was stored.
+func (s *allocationServiceImpl) refreshProviderCompositions(fundISINs []string, compositions map[string]map[string]models.FundComposition) bool {
+	stored := false
+	for _, isin := range fundISINs {
+		if !s.needsProviderComposition(isin, compositions[isin]) {
+			continue
+		}
+		data, err := s.priceService.FetchFundComposition(isin)
+		if err != nil {
+			logger.L.Info("No provider composition for fund", "isin", isin, "error", err)
+			continue
+		}
+		for dimension, weights := range data {
+			err := model.ReplaceFundComposition(database.DB, model.ProviderUserID, models.FundComposition{
+				FundISIN:  isin,
+				Dimension: dimension,
+				Source:    model.CompositionSourceProvider,
+				Weights:   weights,
+			})
+			if err != nil {
+				logger.L.Warn("Failed to store fund composition", "isin", isin, "dimension", dimension, "error", err)
+				continue
+			}
+			stored = true
+		}
+	}
+	return stored
+}
+
+func (s *allocationServiceImpl) needsProviderComposition(isin string, existing map[string]models.FundComposition) bool {
+	needed := false
+	for _, dimension := range []string{models.DimensionSector, models.DimensionAssetType} {
+		composition, ok := existing[dimension]
+		if !ok {
+			needed = true
+			break
+		}
+		if composition.Source == model.CompositionSourceProvider {
+			if updated, err := time.Parse(time.RFC3339, composition.UpdatedAt); err == nil && time.Since(updated) > providerCompositionMaxAge {
+				needed = true
+				break
+			}
+		}
+	}
+	if !needed {
+		return false
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if last, ok := s.providerAttempts[isin]; ok && time.Since(last) < providerRetryInterval {
+		return false
+	}
+	s.providerAttempts[isin] = time.Now()
+	return true
+}
+
+func (s *allocationServiceImpl) GetFundCompositions(userID int64, isin string) (map[string]models.FundComposition, error) {
+	isin = strings.ToUpper(strings.TrimSpace(isin))
+	compositions, err := model.GetFundCompositions(database.DB, userID, []string{isin})
+	if err != nil {
+		return nil, err
+	}
+	if compositions[isin] == nil {
+		return map[string]models.FundComposition{}, nil
+	}
+	return compositions[isin], nil
+}
+
+// SetFundComposition validates and stores user-supplied weights. Weights may be given as
+// fractions or as percentages; they must not add up to more than 100%.
+func (s *allocationServiceImpl) SetFundComposition(userID int64, c models.FundComposition) (*models.FundComposition, error) {
+	c.FundISIN = strings.ToUpper(strings.TrimSpace(c.FundISIN))
+	if !utils.IsISIN(c.FundISIN) {
+		return nil, fmt.Errorf("%w: invalid ISIN", ErrInvalidFundComposition)
+	}
+	valid := false
+	for _, dimension := range allocationDimensions {
+		if c.Dimension == dimension {
+			valid = true
+		}
+	}
+	if !valid {
+		return nil, fmt.Errorf("%w: unknown dimension %q", ErrInvalidFundComposition, c.Dimension)
+	}
+	if len(c.Weights) == 0 || len(c.Weights) > maxCompositionBuckets {
+		return nil, fmt.Errorf("%w: between 1 and %d buckets are required", ErrInvalidFundComposition, maxCompositionBuckets)
+	}
+
+	weights := make(map[string]float64, len(c.Weights))
+	sum := 0.0
+	for bucket, w := range c.Weights {
+		bucket = strings.TrimSpace(bucket)
+		if bucket == "" || w < 0 || math.IsNaN(w) || math.IsInf(w, 0) {
+			return nil, fmt.Errorf("%w: buckets need a name and a non-negative weight", ErrInvalidFundComposition)
+		}
+		switch c.Dimension {
+		case models.DimensionCountry:
+			if name := utils.GetCountryName(bucket); name != "" {
+				bucket = name
+			}
+		case models.DimensionCurrency, models.DimensionAssetType:
+			bucket = strings.ToUpper(bucket)
+		}
+		weights[bucket] += w
+		sum += w
+	}
+	if sum > 1.0001 {
+		// Percentages
+		for bucket := range weights {
+			weights[bucket] /= 100
+		}
+		sum /= 100
+	}
+	if sum > 1.0001 || sum <= 0 {
+		return nil, fmt.Errorf("%w: weights must add up to at most 100%%", ErrInvalidFundComposition)
+	}
+
+	c.Weights = weights
+	c.Source = model.CompositionSourceUser
+	if err := model.ReplaceFundComposition(database.DB, userID, c); err != nil {
+		return nil, fmt.Errorf("failed to save fund composition: %w", err)
+	}
+	c.UpdatedAt = time.Now().UTC().Format(time.RFC3339)
+	return &c, nil
+}
+
+func (s *allocationServiceImpl) DeleteFundComposition(userID int64, isin, dimension string) error {
+	return model.DeleteFundComposition(database.DB, userID, strings.ToUpper(strings.TrimSpace(isin)), dimension)
+}
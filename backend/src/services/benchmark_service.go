@@ -0,0 +1,379 @@
+// backend/src/services/benchmark_service.go
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/username/taxfolio/backend/src/database"
+	"github.com/username/taxfolio/backend/src/logger"
+	"github.com/username/taxfolio/backend/src/model"
+	"github.com/username/taxfolio/backend/src/models"
+)
+
+const (
+	maxBenchmarksPerPortfolio = 5
+	maxBenchmarkComponents    = 10
+	maxBenchmarkNameLength    = 50
+)
+
+var ErrInvalidBenchmark = errors.New("invalid benchmark")
+
+// BenchmarkService manages per-portfolio benchmarks and runs their shadow portfolios.
+type BenchmarkService interface {
+	ListBenchmarks(userID int64, portfolioID int64) ([]models.Benchmark, error)
+	CreateBenchmark(userID int64, benchmark models.Benchmark) (*models.Benchmark, error)
+	UpdateBenchmark(userID int64, benchmark models.Benchmark) (*models.Benchmark, error)
+	DeleteBenchmark(userID int64, portfolioID int64, benchmarkID int64) error
+	// ApplyBenchmarks fills the benchmark values of the chart points in place.
+	ApplyBenchmarks(userID int64, portfolioID int64, points []models.HistoricalDataPoint) error
+	// GetBenchmarkSeries returns the name and shadow-portfolio values of one benchmark.
+	GetBenchmarkSeries(userID int64, portfolioID int64, name string, points []models.HistoricalDataPoint) (string, []float64, error)
+	// GetBenchmarkPricesEUR returns the daily EUR price of a ticker, forward filled.
+	GetBenchmarkPricesEUR(ticker string) (map[string]float64, error)
+}
+
+type benchmarkServiceImpl struct {
+	priceService PriceService
+}
+
+func NewBenchmarkService(priceService PriceService) BenchmarkService {
+	return &benchmarkServiceImpl{priceService: priceService}
+}
+
+// defaultBenchmark is used for portfolios without any configured benchmark.
+func defaultBenchmark(portfolioID int64) models.Benchmark {
+	return models.Benchmark{
+		PortfolioID: portfolioID,
+		Name:        DefaultBenchmarkTicker,
+		Components:  []models.BenchmarkComponent{{Symbol: DefaultBenchmarkTicker, Ticker: DefaultBenchmarkTicker, Weight: 1}},
+		Rebalance:   models.RebalanceNone,
+	}
+}
+
+func (s *benchmarkServiceImpl) ListBenchmarks(userID int64, portfolioID int64) ([]models.Benchmark, error) {
+	return model.GetBenchmarksByPortfolio(database.DB, userID, portfolioID)
+}
+
+func (s *benchmarkServiceImpl) CreateBenchmark(userID int64, benchmark models.Benchmark) (*models.Benchmark, error) {
+	if err := model.CheckPortfolioOwnership(database.DB, userID, benchmark.PortfolioID); err != nil {
+		return nil, err
+	}
+	existing, err := model.GetBenchmarksByPortfolio(database.DB, userID, benchmark.PortfolioID)
+	if err != nil {
+		return nil, err
+	}
+	if len(existing) >= maxBenchmarksPerPortfolio {
+		return nil, fmt.Errorf("%w: at most %d benchmarks per portfolio", ErrInvalidBenchmark, maxBenchmarksPerPortfolio)
+	}
+	for _, b := range existing {
+		if strings.EqualFold(b.Name, strings.TrimSpace(benchmark.Name)) {
+			return nil, fmt.Errorf("%w: a benchmark named %q already exists", ErrInvalidBenchmark, b.Name)
+		}
+	}
+	if err := s.normalize(&benchmark); err != nil {
+		return nil, err
+	}
+	if err := model.InsertBenchmark(database.DB, userID, &benchmark); err != nil {
+		return nil, fmt.Errorf("failed to save benchmark: %w", err)
+	}
+	return &benchmark, nil
+}
+
+func (s *benchmarkServiceImpl) UpdateBenchmark(userID int64, benchmark models.Benchmark) (*models.Benchmark, error) {
+	existing, err := model.GetBenchmarksByPortfolio(database.DB, userID, benchmark.PortfolioID)
+	if err != nil {
+		return nil, err
+	}
+	for _, b := range existing {
+		if b.ID != benchmark.ID && strings.EqualFold(b.Name, strings.TrimSpace(benchmark.Name)) {
+			return nil, fmt.Errorf("%w: a benchmark named %q already exists", ErrInvalidBenchmark, b.Name)
+		}
+	}
+	if err := s.normalize(&benchmark); err != nil {
+		return nil, err
+	}
+	if err := model.UpdateBenchmark(database.DB, userID, &benchmark); err != nil {
+		return nil, err
+	}
+	return &benchmark, nil
+}
+
+func (s *benchmarkServiceImpl) DeleteBenchmark(userID int64, portfolioID int64, benchmarkID int64) error {
+	return model.DeleteBenchmark(database.DB, userID, portfolioID, benchmarkID)
+}
+
+// normalize validates a benchmark definition, resolves its symbols to tickers, checks that
+// price history exists for each of them and rescales the weights to sum to 1.
+func (s *benchmarkServiceImpl) normalize(b *models.Benchmark) error {
+	b.Name = strings.TrimSpace(b.Name)
+	if b.Name == "" || len(b.Name) > maxBenchmarkNameLength {
+		return fmt.Errorf("%w: name must be between 1 and %d characters", ErrInvalidBenchmark, maxBenchmarkNameLength)
+	}
+	if b.Rebalance == "" {
+		b.Rebalance = models.RebalanceNone
+	}
+	switch b.Rebalance {
+	case models.RebalanceNone, models.RebalanceMonthly, models.RebalanceQuarterly, models.RebalanceYearly:
+	default:
+		return fmt.Errorf("%w: unknown rebalance frequency %q", ErrInvalidBenchmark, b.Rebalance)
+	}
+	if len(b.Components) == 0 || len(b.Components) > maxBenchmarkComponents {
+		return fmt.Errorf("%w: between 1 and %d components are required", ErrInvalidBenchmark, maxBenchmarkComponents)
+	}
+
+	totalWeight := 0.0
+	seen := make(map[string]bool)
+	for i := range b.Components {
+		c := &b.Components[i]
+		if c.Weight <= 0 || math.IsNaN(c.Weight) || math.IsInf(c.Weight, 0) {
+			return fmt.Errorf("%w: component weights must be positive", ErrInvalidBenchmark)
+		}
+		ticker, err := s.priceService.ResolveTicker(c.Symbol)
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidBenchmark, err)
+		}
+		if seen[ticker] {
+			return fmt.Errorf("%w: %s appears more than once", ErrInvalidBenchmark, ticker)
+		}
+		seen[ticker] = true
+		if err := s.priceService.EnsureBenchmarkData(ticker); err != nil {
+			return fmt.Errorf("%w: no price history available for %s", ErrInvalidBenchmark, ticker)
+		}
+		c.Symbol = strings.ToUpper(strings.TrimSpace(c.Symbol))
+		c.Ticker = ticker
+		totalWeight += c.Weight
+	}
+	for i := range b.Components {
+		b.Components[i].Weight /= totalWeight
+	}
+	return nil
+}
+
+// GetBenchmarkPricesEUR loads the stored history of a ticker and converts it to EUR
+// with the daily FX history ({CUR}EUR=X, EUR per unit of foreign currency).
+func (s *benchmarkServiceImpl) GetBenchmarkPricesEUR(ticker string) (map[string]float64, error) {
+	if err := s.priceService.EnsureBenchmarkData(ticker); err != nil {
+		logger.L.Warn("Could not refresh benchmark history, using stored prices", "ticker", ticker, "error", err)
+	}
+	prices, currency, err := model.GetPriceHistory(database.DB, ticker)
+	if err != nil {
+		return nil, err
+	}
+	if len(prices) == 0 {
+		return nil, fmt.Errorf("no price history for %s", ticker)
+	}
+
+	// London listings are quoted in pence.
+	scale := 1.0
+	if currency == "GBp" || currency == "GBX" {
+		currency = "GBP"
+		scale = 0.01
+	}
+	currency = strings.ToUpper(currency)
+	if currency == "" || currency == "EUR" {
+		if scale == 1 {
+			return prices, nil
+		}
+		for date, p := range prices {
+			prices[date] = p * scale
+		}
+		return prices, nil
+	}
+
+	fxTicker := fmt.Sprintf("%sEUR=X", currency)
+	if err := s.priceService.EnsureBenchmarkData(fxTicker); err != nil {
+		logger.L.Warn("Could not refresh FX history", "ticker", fxTicker, "error", err)
+	}
+	rates, _, err := model.GetPriceHistory(database.DB, fxTicker)
+	if err != nil || len(rates) == 0 {
+		return nil, fmt.Errorf("no FX history to convert %s from %s", ticker, currency)
+	}
+
+	dates := make([]string, 0, len(prices))
+	for date := range prices {
+		dates = append(dates, date)
+	}
+	sort.Strings(dates)
+
+	converted := make(map[string]float64, len(prices))
+	lastRate := 0.0
+	for _, date := range dates {
+		if r, ok := rates[date]; ok && r > 0 {
+			lastRate = r
+		}
+		if lastRate == 0 {
+			continue // No FX data yet for this early date.
+		}
+		converted[date] = prices[date] * scale * lastRate
+	}
+	return converted, nil
+}
+
+// ApplyBenchmarks runs one shadow portfolio per benchmark configured for the portfolio
+// (or the default SPY benchmark when none is configured) and writes the values into
+// points[i].Benchmarks keyed by benchmark name. The first benchmark also fills the
+// legacy BenchmarkValue/SPYPrice fields.
+func (s *benchmarkServiceImpl) ApplyBenchmarks(userID int64, portfolioID int64, points []models.HistoricalDataPoint) error {
+	if len(points) == 0 {
+		return nil
+	}
+	benchmarks, err := model.GetBenchmarksByPortfolio(database.DB, userID, portfolioID)
+	if err != nil {
+		return err
+	}
+	if len(benchmarks) == 0 {
+		benchmarks = []models.Benchmark{defaultBenchmark(portfolioID)}
+	}
+
+	for bi, b := range benchmarks {
+		values, unitPrices, err := s.runBenchmark(b, points)
+		if err != nil {
+			logger.L.Warn("Skipping benchmark without prices", "benchmark", b.Name, "error", err)
+			continue
+		}
+		for i := range points {
+			if points[i].Benchmarks == nil {
+				points[i].Benchmarks = make(map[string]float64)
+			}
+			points[i].Benchmarks[b.Name] = values[i]
+			if bi == 0 {
+				points[i].BenchmarkValue = values[i]
+				points[i].SPYPrice = unitPrices[i]
+			}
+		}
+	}
+	return nil
+}
+
+// GetBenchmarkSeries runs the shadow portfolio of a single benchmark over the points.
+// An empty name selects the portfolio's first benchmark (or the default one).
+func (s *benchmarkServiceImpl) GetBenchmarkSeries(userID int64, portfolioID int64, name string, points []models.HistoricalDataPoint) (string, []float64, error) {
+	benchmarks, err := model.GetBenchmarksByPortfolio(database.DB, userID, portfolioID)
+	if err != nil {
+		return "", nil, err
+	}
+	if len(benchmarks) == 0 {
+		benchmarks = []models.Benchmark{defaultBenchmark(portfolioID)}
+	}
+
+	selected := benchmarks[0]
+	if name != "" {
+		found := false
+		for _, b := range benchmarks {
+			if strings.EqualFold(b.Name, name) {
+				selected, found = b, true
+				break
+			}
+		}
+		if !found {
+			return "", nil, model.ErrBenchmarkNotFound
+		}
+	}
+
+	values, _, err := s.runBenchmark(selected, points)
+	if err != nil {
+		return "", nil, err
+	}
+	return selected.Name, values, nil
+}
+
+// runBenchmark loads the EUR prices of every component and simulates the benchmark.
+func (s *benchmarkServiceImpl) runBenchmark(b models.Benchmark, points []models.HistoricalDataPoint) ([]float64, []float64, error) {
+	componentPrices := make([]map[string]float64, len(b.Components))
+	weights := make([]float64, len(b.Components))
+	for i, c := range b.Components {
+		prices, err := s.GetBenchmarkPricesEUR(c.Ticker)
+		if err != nil {
+			return nil, nil, err
+		}
+		componentPrices[i] = prices
+		weights[i] = c.Weight
+	}
+	values, unitPrices := simulateBenchmark(points, componentPrices, weights, b.Rebalance)
+	return values, unitPrices, nil
+}
+
+// simulateBenchmark mirrors every change in the portfolio's cumulative net cash flow into
+// a shadow portfolio split across the components by weight. Cash received on days with no
+// price for some component waits until all components can be bought. The blend is brought
+// back to its target weights at the start of each rebalance period.
+// It returns the shadow value per point and, for single-component benchmarks, the EUR price.
+func simulateBenchmark(points []models.HistoricalDataPoint, prices []map[string]float64, weights []float64, rebalance string) ([]float64, []float64) {
+	values := make([]float64, len(points))
+	unitPrices := make([]float64, len(points))
+	units := make([]float64, len(prices))
+	lastPrices := make([]float64, len(prices))
+	pendingCash := 0.0
+	previousCashFlow := 0.0
+	lastPeriod := ""
+	invested := false
+
+	for i, p := range points {
+		allPriced := true
+		for c := range prices {
+			if price := prices[c][p.Date]; price > 0 {
+				lastPrices[c] = price
+			}
+			if lastPrices[c] <= 0 {
+				allPriced = false
+			}
+		}
+
+		pendingCash += p.CumulativeCashFlow - previousCashFlow
+		previousCashFlow = p.CumulativeCashFlow
+
+		if allPriced {
+			period := rebalancePeriod(p.Date, rebalance)
+			if invested && rebalance != models.RebalanceNone && period != lastPeriod {
+				total := 0.0
+				for c := range units {
+					total += units[c] * lastPrices[c]
+				}
+				for c := range units {
+					units[c] = total * weights[c] / lastPrices[c]
+				}
+			}
+			lastPeriod = period
+
+			if pendingCash != 0 {
+				for c := range units {
+					units[c] += pendingCash * weights[c] / lastPrices[c]
+				}
+				pendingCash = 0
+				invested = true
+			}
+		}
+
+		value := pendingCash
+		for c := range units {
+			value += units[c] * lastPrices[c]
+		}
+		values[i] = value
+		if len(prices) == 1 {
+			unitPrices[i] = lastPrices[0]
+		}
+	}
+	return values, unitPrices
+}
+
+// rebalancePeriod returns a key that changes whenever a new rebalance period starts.
+func rebalancePeriod(date string, rebalance string) string {
+	t, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		return ""
+	}
+	switch rebalance {
+	case models.RebalanceMonthly:
+		return t.Format("2006-01")
+	case models.RebalanceQuarterly:
+		return fmt.Sprintf("%d-Q%d", t.Year(), (int(t.Month())-1)/3+1)
+	case models.RebalanceYearly:
+		return t.Format("2006")
+	}
+	return ""
+}
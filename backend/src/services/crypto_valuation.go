@@ -0,0 +1,71 @@
+// backend/src/services/crypto_valuation.go
+package services
+
+import (
+	"math"
+
+	"github.com/username/taxfolio/backend/src/logger"
+	"github.com/username/taxfolio/backend/src/models"
+	"github.com/username/taxfolio/backend/src/utils"
+)
+
+// valueCryptoTransactions converts the crypto legs priced in another crypto asset, such as both
+// legs of a BTC/ETH trade or a staking reward counted in units, to EUR at that asset's close on
+// the trade date. The TransactionProcessor only knows the exchange rates of fiat currencies.
+// Legs without an EUR price for their date are left out and returned, so that the upload result
+// shows them: a missing buy leaves the sales of the asset without a lot to match.
+func (s *uploadServiceImpl) valueCryptoTransactions(txs []models.CanonicalTransaction) ([]models.CanonicalTransaction, []SkippedTransaction) {
+	pricesByAsset := make(map[string]PriceMap)
+	valued := txs[:0]
+	var skipped []SkippedTransaction
+	for _, tx := range txs {
+		if tx.TransactionType != "CRYPTO" || tx.Currency == "" || utils.IsFiatCurrency(tx.Currency) {
+			valued = append(valued, tx)
+			continue
+		}
+		prices, ok := pricesByAsset[tx.Currency]
+		if !ok {
+			var err error
+			prices, _, err = s.priceService.GetHistoricalPrices(tx.Currency + "-EUR")
+			if err != nil {
+				logger.L.Warn("Could not fetch EUR prices of crypto asset", "asset", tx.Currency, "error", err)
+			}
+			// A failed fetch is remembered as an empty map, so it is not retried for every leg.
+			pricesByAsset[tx.Currency] = prices
+		}
+		date := tx.TransactionDate.Format("2006-01-02")
+		price, ok := prices[date]
+		if !ok || price <= 0 {
+			logger.L.Warn("Skipping crypto transaction without an EUR price", "asset", tx.ISIN, "currency", tx.Currency, "date", date, "orderID", tx.OrderID)
+			skipped = append(skipped, SkippedTransaction{
+				Date:        date,
+				ProductName: tx.ProductName,
+				Currency:    tx.Currency,
+				Reason:      "no EUR price for " + tx.Currency + " on this date",
+			})
+			continue
+		}
+
+		tx.Amount *= price
+		tx.Commission *= price
+		if tx.Quantity > 0 {
+			tx.Price = math.Abs(tx.Amount) / tx.Quantity
+		}
+		tx.Currency = "EUR"
+		valued = append(valued, tx)
+	}
+	if len(skipped) > 0 {
+		logger.L.Warn("Crypto transactions left out of the upload for lack of EUR prices", "skipped", len(skipped))
+	}
+	return valued, skipped
+}
+
+// withSkipped adds the rows left out of an upload to a copy of its result, which is cached.
+func withSkipped(result *UploadResult, skipped []SkippedTransaction) *UploadResult {
+	if result == nil || len(skipped) == 0 {
+		return result
+	}
+	withRows := *result
+	withRows.SkippedTransactions = skipped
+	return &withRows
+}
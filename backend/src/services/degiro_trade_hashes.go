@@ -0,0 +1,95 @@
+// backend/src/services/degiro_trade_hashes.go
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/username/taxfolio/backend/src/logger"
+	"github.com/username/taxfolio/backend/src/parsers/degiro"
+	"github.com/username/taxfolio/backend/src/processors"
+)
+
+// UpgradeDeGiroTradeHashes rewrites the raw text and hash of the DeGiro trades stored before
+// Account.csv and Transactions.csv shared a trade format, so that uploading either export
+// again still recognises them. A stored trade whose new hash is already taken, usually the
+// same fill imported from the other export, is logged and left as it is for the user to
+// review. It returns the number of trades upgraded.
+func UpgradeDeGiroTradeHashes(db *sql.DB) (int, error) {
+	type legacyTrade struct {
+		id, portfolioID           int64
+		orderID, isin, date, kind string
+		units                     float64
+	}
+	rows, err := db.Query(`
+		SELECT id, portfolio_id, order_id, COALESCE(isin, ''), date, COALESCE(units, original_quantity),
+		       transaction_type, COALESCE(transaction_subtype, ''), input_string
+		FROM processed_transactions
+		WHERE source = 'degiro' AND COALESCE(order_id, '') != '' AND input_string NOT LIKE 'DeGiroTrade|%'
+		ORDER BY id`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list degiro trades: %w", err)
+	}
+	var trades []legacyTrade
+	for rows.Next() {
+		var t legacyTrade
+		var txType, subType, rawText string
+		if err := rows.Scan(&t.id, &t.portfolioID, &t.orderID, &t.isin, &t.date, &t.units, &txType, &subType, &rawText); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to read degiro trade: %w", err)
+		}
+		if !degiro.IsOrderTrade(t.orderID, txType, subType) {
+			continue
+		}
+		// Fills are numbered within the export they were imported from.
+		t.kind = "account"
+		if strings.HasPrefix(rawText, "Transactions,") {
+			t.kind = "transactions"
+		}
+		trades = append(trades, t)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed to list degiro trades: %w", err)
+	}
+	if len(trades) == 0 {
+		return 0, nil
+	}
+
+	dbTx, err := db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("error beginning database transaction: %w", err)
+	}
+	defer dbTx.Rollback()
+
+	upgraded := 0
+	fills := make(map[string]int)
+	for _, t := range trades {
+		date, err := time.Parse("02-01-2006", t.date)
+		if err != nil {
+			logger.L.Warn("Could not upgrade the raw text of a DeGiro trade", "transactionID", t.id, "date", t.date)
+			continue
+		}
+		key := fmt.Sprintf("%d|%s|%s", t.portfolioID, t.kind, degiro.TradeRawText(t.orderID, t.isin, date, t.units, 0))
+		fills[key]++
+		rawText := degiro.TradeRawText(t.orderID, t.isin, date, t.units, fills[key])
+
+		_, err = dbTx.Exec(`UPDATE processed_transactions SET input_string = ?, hash_id = ? WHERE id = ?`,
+			rawText, processors.HashRawText(rawText), t.id)
+		if err != nil && strings.Contains(strings.ToLower(err.Error()), "unique constraint failed") {
+			logger.L.Warn("DeGiro trade collides with a stored trade, leaving its hash unchanged",
+				"transactionID", t.id, "portfolioID", t.portfolioID, "rawText", rawText)
+			continue
+		}
+		if err != nil {
+			return 0, fmt.Errorf("failed to upgrade degiro trade %d: %w", t.id, err)
+		}
+		upgraded++
+	}
+	if err := dbTx.Commit(); err != nil {
+		return 0, fmt.Errorf("error committing transaction: %w", err)
+	}
+	return upgraded, nil
+}
@@ -0,0 +1,376 @@
+// backend/src/services/dividend_calendar_service.go
+package services
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/username/taxfolio/backend/src/database"
+	"github.com/username/taxfolio/backend/src/logger"
+	"github.com/username/taxfolio/backend/src/model"
+	"github.com/username/taxfolio/backend/src/models"
+	"github.com/username/taxfolio/backend/src/processors"
+	"github.com/username/taxfolio/backend/src/utils"
+)
+
+const (
+	// defaultPayLagDays is assumed between ex-date and pay date when no payment history exists.
+	defaultPayLagDays = 30
+	// dividendPaymentWindowDays is how long after the ex-date a payment is matched to an event.
+	dividendPaymentWindowDays = 90
+	// dividendGraceDays is added to the (estimated) pay date before a payment counts as missing.
+	dividendGraceDays = 10
+	// announcedMatchDays is how close a projected ex-date may be to an announced one before
+	// the projection is considered covered by the announcement.
+	announcedMatchDays = 30
+)
+
+// DividendCalendarService builds the forward dividend calendar and checks past payments.
+type DividendCalendarService interface {
+	GetCalendar(userID int64, portfolioID int64, horizonMonths int, lookbackMonths int) (*models.DividendCalendar, error)
+}
+
+type dividendCalendarServiceImpl struct {
+	uploadService UploadService
+	priceService  PriceService
+}
+
+func NewDividendCalendarService(uploadService UploadService, priceService PriceService) DividendCalendarService {
+	return &dividendCalendarServiceImpl{uploadService: uploadService, priceService: priceService}
+}
+
+// quantityChange is a change of the number of shares held, effective on date.
+type quantityChange struct {
+	date     time.Time
+	quantity int
+}
+
+// receivedDividend is the gross dividend booked for an ISIN on one date.
+type receivedDividend struct {
+	date     time.Time
+	grossEUR float64
+	used     bool
+}
+
+// quantityBefore returns the number of shares held at the end of the day before `date`,
+// which is what entitles to a dividend with that ex-date.
+func quantityBefore(changes []quantityChange, date time.Time) int {
+	quantity := 0
+	for _, c := range changes {
+		if !c.date.Before(date) {
+			break
+		}
+		quantity += c.quantity
+	}
+	return quantity
+}
+
+// dividendAmountEUR converts a dividend amount quoted in `currency` to EUR at the rate of `date`.
+func dividendAmountEUR(amount float64, currency string, date time.Time) (float64, bool) {
+	if currency == "GBp" || strings.EqualFold(currency, "GBX") {
+		amount, currency = amount/100, "GBP"
+	}
+	currency = strings.ToUpper(currency)
+	if currency == "" || currency == "EUR" {
+		return amount, true
+	}
+	rate, err := processors.GetExchangeRate(currency, date)
+	if err != nil || rate <= 0 {
+		return 0, false
+	}
+	return amount / rate, true
+}
+
+// medianPayLag returns the median number of days between ex-date and payment of past events
+// that were matched to a payment, or 0 when there are none.
+func medianPayLag(expected []models.ExpectedDividend) int {
+	var lags []int
+	for _, e := range expected {
+		if e.ReceivedDate == "" {
+			continue
+		}
+		exDate, _ := time.Parse("2006-01-02", e.ExDate)
+		received, _ := time.Parse("2006-01-02", e.ReceivedDate)
+		lags = append(lags, int(received.Sub(exDate).Hours()/24))
+	}
+	if len(lags) == 0 {
+		return 0
+	}
+	sort.Ints(lags)
+	return lags[len(lags)/2]
+}
+
+func (s *dividendCalendarServiceImpl) GetCalendar(userID int64, portfolioID int64, horizonMonths int, lookbackMonths int) (*models.DividendCalendar, error) {
+	transactions, err := fetchUserProcessedTransactions(userID, portfolioID)
+	if err != nil {
+		return nil, err
+	}
+	holdings, err := s.uploadService.GetCurrentHoldingsWithValue(userID, portfolioID)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := make(map[string][]quantityChange)
+	received := make(map[string][]*receivedDividend)
+	productNames := make(map[string]string)
+	for _, tx := range transactions {
+		date := utils.ParseDate(tx.Date)
+		switch {
+		case tx.TransactionType == "STOCK" && (tx.BuySell == "BUY" || tx.BuySell == "SELL"):
+			quantity := int(math.Abs(float64(tx.Quantity)))
+			if tx.BuySell == "SELL" {
+				quantity = -quantity
+			}
+			changes[tx.ISIN] = append(changes[tx.ISIN], quantityChange{date: date, quantity: quantity})
+			productNames[tx.ISIN] = tx.ProductName
+		case tx.TransactionType == "DIVIDEND" && tx.TransactionSubType != "TAX":
+			list := received[tx.ISIN]
+			if n := len(list); n > 0 && list[n-1].date.Equal(date) {
+				list[n-1].grossEUR += tx.AmountEUR
+			} else {
+				received[tx.ISIN] = append(list, &receivedDividend{date: date, grossEUR: tx.AmountEUR})
+			}
+		}
+	}
+	for isin := range changes {
+		sort.SliceStable(changes[isin], func(i, j int) bool { return changes[isin][i].date.Before(changes[isin][j].date) })
+	}
+	for isin := range received {
+		sort.SliceStable(received[isin], func(i, j int) bool { return received[isin][i].date.Before(received[isin][j].date) })
+	}
+
+	heldQuantity := make(map[string]int)
+	for _, h := range holdings {
+		if h.Quantity > 0 {
+			heldQuantity[h.ISIN] = h.Quantity
+			productNames[h.ISIN] = h.ProductName
+		}
+	}
+
+	isins := make([]string, 0, len(changes))
+	for isin := range changes {
+		isins = append(isins, isin)
+	}
+	sort.Strings(isins)
+	mappings, err := model.GetMappingsByISINs(database.DB, isins)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	lookbackStart := today.AddDate(0, -lookbackMonths, 0)
+	horizonEnd := today.AddDate(0, horizonMonths, 0)
+	// Projections repeat last year's events, so a year before the horizon is needed as well.
+	eventsFrom := lookbackStart
+	if projectionStart := today.AddDate(-1, 0, 0); projectionStart.Before(eventsFrom) {
+		eventsFrom = projectionStart
+	}
+
+	calendar := &models.DividendCalendar{
+		Upcoming:          []models.DividendCalendarEntry{},
+		Expected:          []models.ExpectedDividend{},
+		UnmappedPositions: []string{},
+	}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, 5) // Limit concurrent requests to Yahoo
+
+	for _, isin := range isins {
+		mapping, ok := mappings[isin]
+		if !ok || mapping.TickerSymbol == "" {
+			if heldQuantity[isin] > 0 {
+				calendar.UnmappedPositions = append(calendar.UnmappedPositions, isin)
+			}
+			continue
+		}
+
+		wg.Add(1)
+		go func(isin, ticker string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			events, err := s.priceService.GetDividendEvents(ticker, eventsFrom)
+			if err != nil {
+				logger.L.Warn("Failed to get dividend events", "ticker", ticker, "error", err)
+				return
+			}
+			expected := checkPastDividends(isin, productNames[isin], events, changes[isin], received[isin], lookbackStart, today)
+			upcoming := projectDividends(isin, productNames[isin], heldQuantity[isin], events, medianPayLag(expected), today, horizonEnd)
+
+			mu.Lock()
+			calendar.Expected = append(calendar.Expected, expected...)
+			calendar.Upcoming = append(calendar.Upcoming, upcoming...)
+			mu.Unlock()
+		}(isin, mapping.TickerSymbol)
+	}
+	wg.Wait()
+
+	sort.Slice(calendar.Upcoming, func(i, j int) bool {
+		if calendar.Upcoming[i].ExDate != calendar.Upcoming[j].ExDate {
+			return calendar.Upcoming[i].ExDate < calendar.Upcoming[j].ExDate
+		}
+		return calendar.Upcoming[i].ISIN < calendar.Upcoming[j].ISIN
+	})
+	sort.Slice(calendar.Expected, func(i, j int) bool {
+		if calendar.Expected[i].ExDate != calendar.Expected[j].ExDate {
+			return calendar.Expected[i].ExDate > calendar.Expected[j].ExDate
+		}
+		return calendar.Expected[i].ISIN < calendar.Expected[j].ISIN
+	})
+	for _, entry := range calendar.Upcoming {
+		calendar.UpcomingTotalEUR += entry.ExpectedEUR
+	}
+	for _, e := range calendar.Expected {
+		if e.Status == models.DividendMissing {
+			calendar.MissingCount++
+			calendar.MissingTotalEUR += e.ExpectedEUR
+		}
+	}
+	calendar.UpcomingTotalEUR = utils.RoundFloat(calendar.UpcomingTotalEUR, 2)
+	calendar.MissingTotalEUR = utils.RoundFloat(calendar.MissingTotalEUR, 2)
+	return calendar, nil
+}
+
+// checkPastDividends compares the paid events since lookbackStart with the dividends received.
+func checkPastDividends(isin, productName string, events []models.DividendEvent, changes []quantityChange, received []*receivedDividend, lookbackStart, today time.Time) []models.ExpectedDividend {
+	var expected []models.ExpectedDividend
+	for _, event := range events {
+		exDate, err := time.Parse("2006-01-02", event.ExDate)
+		if err != nil || event.Source != models.DividendEventHistory || exDate.Before(lookbackStart) || exDate.After(today) {
+			continue
+		}
+		quantity := quantityBefore(changes, exDate)
+		if quantity <= 0 {
+			continue
+		}
+
+		payDate, hasPayDate := time.Time{}, false
+		if event.PayDate != "" {
+			payDate, err = time.Parse("2006-01-02", event.PayDate)
+			hasPayDate = err == nil
+		}
+		rateDate := exDate
+		if hasPayDate && !payDate.After(today) {
+			rateDate = payDate
+		}
+		expectedEUR, _ := dividendAmountEUR(event.Amount*float64(quantity), event.Currency, rateDate)
+
+		check := models.ExpectedDividend{
+			ISIN:             isin,
+			ProductName:      productName,
+			Ticker:           event.Ticker,
+			ExDate:           event.ExDate,
+			PayDate:          event.PayDate,
+			AmountPerShare:   event.Amount,
+			Currency:         event.Currency,
+			QuantityAtExDate: quantity,
+			ExpectedEUR:      utils.RoundFloat(expectedEUR, 2),
+		}
+		windowEnd := exDate.AddDate(0, 0, dividendPaymentWindowDays)
+		for _, r := range received {
+			if r.used || r.date.Before(exDate) || r.date.After(windowEnd) {
+				continue
+			}
+			r.used = true
+			check.ReceivedEUR = utils.RoundFloat(r.grossEUR, 2)
+			check.ReceivedDate = r.date.Format("2006-01-02")
+			break
+		}
+
+		deadline := exDate.AddDate(0, 0, defaultPayLagDays+dividendGraceDays)
+		if hasPayDate {
+			deadline = payDate.AddDate(0, 0, dividendGraceDays)
+		}
+		switch {
+		case check.ReceivedDate != "" && expectedEUR > 0 && check.ReceivedEUR < 0.9*expectedEUR:
+			check.Status = models.DividendPartial
+		case check.ReceivedDate != "":
+			check.Status = models.DividendReceived
+		case !today.After(deadline):
+			check.Status = models.DividendPending
+		default:
+			check.Status = models.DividendMissing
+		}
+		expected = append(expected, check)
+	}
+	return expected
+}
+
+// projectDividends lists the events expected for a held position until horizonEnd: announced
+// events and, where none is announced yet, last year's events moved forward by a year.
+// The horizon is therefore limited to a year.
+func projectDividends(isin, productName string, quantity int, events []models.DividendEvent, payLagDays int, today, horizonEnd time.Time) []models.DividendCalendarEntry {
+	if quantity <= 0 {
+		return nil
+	}
+	var announced []time.Time
+	var entries []models.DividendCalendarEntry
+	add := func(event models.DividendEvent, exDate time.Time, payDate string, source string) {
+		entry := models.DividendCalendarEntry{
+			ISIN:           isin,
+			ProductName:    productName,
+			Ticker:         event.Ticker,
+			ExDate:         exDate.Format("2006-01-02"),
+			PayDate:        payDate,
+			AmountPerShare: event.Amount,
+			Currency:       event.Currency,
+			Quantity:       quantity,
+			ExpectedLocal:  utils.RoundFloat(event.Amount*float64(quantity), 2),
+			Source:         source,
+		}
+		if entry.PayDate == "" {
+			lag := payLagDays
+			if lag <= 0 {
+				lag = defaultPayLagDays
+			}
+			entry.PayDate = exDate.AddDate(0, 0, lag).Format("2006-01-02")
+			entry.PayDateEstimated = true
+		}
+		expectedEUR, _ := dividendAmountEUR(event.Amount*float64(quantity), event.Currency, today)
+		entry.ExpectedEUR = utils.RoundFloat(expectedEUR, 2)
+		entries = append(entries, entry)
+	}
+
+	for _, event := range events {
+		exDate, err := time.Parse("2006-01-02", event.ExDate)
+		if err != nil || exDate.Before(today) || exDate.After(horizonEnd) {
+			continue
+		}
+		announced = append(announced, exDate)
+		add(event, exDate, event.PayDate, event.Source)
+	}
+
+	for _, event := range events {
+		exDate, err := time.Parse("2006-01-02", event.ExDate)
+		if err != nil || event.Source != models.DividendEventHistory {
+			continue
+		}
+		projected := exDate.AddDate(1, 0, 0)
+		if projected.Before(today) || projected.After(horizonEnd) {
+			continue
+		}
+		covered := false
+		for _, a := range announced {
+			if math.Abs(a.Sub(projected).Hours()/24) <= announcedMatchDays {
+				covered = true
+				break
+			}
+		}
+		if covered {
+			continue
+		}
+		payDate := ""
+		if event.PayDate != "" {
+			if p, err := time.Parse("2006-01-02", event.PayDate); err == nil {
+				payDate = p.AddDate(1, 0, 0).Format("2006-01-02")
+			}
+		}
+		add(event, projected, payDate, models.DividendEventProjected)
+	}
+	return entries
+}
@@ -0,0 +1,348 @@
+// backend/src/services/dividend_history_service.go
+package services
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/username/taxfolio/backend/src/database"
+	"github.com/username/taxfolio/backend/src/logger"
+	"github.com/username/taxfolio/backend/src/model"
+	"github.com/username/taxfolio/backend/src/models"
+	"github.com/username/taxfolio/backend/src/utils"
+)
+
+const (
+	// frequencyWindowYears is the period of ex-dates (or payments) used to detect the payout frequency.
+	frequencyWindowYears = 3
+	// cutThreshold flags a dividend below this fraction of the previous comparable one.
+	cutThreshold = 0.9
+	// cutReferenceEvents is the number of preceding dividends used to recognise special ones.
+	cutReferenceEvents = 4
+)
+
+// DividendHistoryService computes dividend income series, growth and payout analytics.
+type DividendHistoryService interface {
+	GetDividendHistory(userID int64, portfolioID int64) (*models.DividendHistoryResult, error)
+}
+
+type dividendHistoryServiceImpl struct {
+	uploadService UploadService
+	priceService  PriceService
+}
+
+func NewDividendHistoryService(uploadService UploadService, priceService PriceService) DividendHistoryService {
+	return &dividendHistoryServiceImpl{uploadService: uploadService, priceService: priceService}
+}
+
+// incomeTotals accumulates gross and withheld dividend income per period.
+type incomeTotals map[string]*models.IncomePoint
+
+func (t incomeTotals) add(period string, tx models.ProcessedTransaction) {
+	point, ok := t[period]
+	if !ok {
+		point = &models.IncomePoint{Period: period}
+		t[period] = point
+	}
+	if tx.TransactionSubType == "TAX" {
+		point.WithheldEUR -= tx.AmountEUR
+	} else {
+		point.GrossEUR += tx.AmountEUR
+	}
+}
+
+// series returns the periods in order with net amounts and, when withGrowth is set, the
+// growth over the previous period.
+func (t incomeTotals) series(withGrowth bool) []models.IncomePoint {
+	points := make([]models.IncomePoint, 0, len(t))
+	for _, p := range t {
+		points = append(points, *p)
+	}
+	sort.Slice(points, func(i, j int) bool { return points[i].Period < points[j].Period })
+	for i := range points {
+		p := &points[i]
+		if withGrowth && i > 0 && points[i-1].GrossEUR > 0 {
+			growth := utils.RoundFloat((p.GrossEUR/points[i-1].GrossEUR-1)*100, 2)
+			p.GrowthPercent = &growth
+		}
+		p.NetEUR = utils.RoundFloat(p.GrossEUR-p.WithheldEUR, 2)
+		p.GrossEUR = utils.RoundFloat(p.GrossEUR, 2)
+		p.WithheldEUR = utils.RoundFloat(p.WithheldEUR, 2)
+	}
+	return points
+}
+
+// dividendGrowth returns the 1, 3 and 5-year CAGR of yearly values ending in lastYear.
+func dividendGrowth(yearly map[int]float64, lastYear int) models.DividendGrowth {
+	rate := func(years int) *float64 {
+		cagr, ok := utils.CAGR(yearly[lastYear-years], yearly[lastYear], years)
+		if !ok {
+			return nil
+		}
+		percent := utils.RoundFloat(cagr*100, 2)
+		return &percent
+	}
+	return models.DividendGrowth{CAGR1Y: rate(1), CAGR3Y: rate(3), CAGR5Y: rate(5)}
+}
+
+// detectFrequency classifies the payout frequency from the median gap between the dates of
+// the last frequencyWindowYears.
+func detectFrequency(dates []time.Time, now time.Time) (string, int) {
+	windowStart := now.AddDate(-frequencyWindowYears, 0, 0)
+	var recent []time.Time
+	for _, d := range dates {
+		if d.After(windowStart) && !d.After(now) {
+			recent = append(recent, d)
+		}
+	}
+	if len(recent) < 2 {
+		return models.FrequencyUnknown, 0
+	}
+	sort.Slice(recent, func(i, j int) bool { return recent[i].Before(recent[j]) })
+	gaps := make([]float64, 0, len(recent)-1)
+	for i := 1; i < len(recent); i++ {
+		gaps = append(gaps, recent[i].Sub(recent[i-1]).Hours()/24)
+	}
+	sort.Float64s(gaps)
+	median := gaps[len(gaps)/2]
+	switch {
+	case median <= 45:
+		return models.FrequencyMonthly, 12
+	case median <= 135:
+		return models.FrequencyQuarterly, 4
+	case median <= 250:
+		return models.FrequencySemiannual, 2
+	case median <= 450:
+		return models.FrequencyAnnual, 1
+	default:
+		return models.FrequencyIrregular, 0
+	}
+}
+
+// detectCuts compares every regular dividend with the previous comparable one: the last
+// one for monthly and quarterly payers, the same period a year earlier for semiannual
+// payers (whose interim and final dividends differ). Special dividends, more than twice
+// the median of the preceding cutReferenceEvents, are skipped.
+func detectCuts(events []models.DividendEvent, paymentsPerYear int) []models.DividendCut {
+	lag := 1
+	if paymentsPerYear == 2 {
+		lag = 2
+	}
+	cuts := []models.DividendCut{}
+	var regular []float64
+	for _, e := range events {
+		if len(regular) > 0 {
+			window := regular
+			if len(window) > cutReferenceEvents {
+				window = window[len(window)-cutReferenceEvents:]
+			}
+			sorted := append([]float64(nil), window...)
+			sort.Float64s(sorted)
+			if e.Amount > 2*sorted[len(sorted)/2] {
+				continue
+			}
+		}
+		if len(regular) >= lag {
+			previous := regular[len(regular)-lag]
+			if e.Amount < cutThreshold*previous {
+				cuts = append(cuts, models.DividendCut{
+					ExDate:         e.ExDate,
+					Amount:         e.Amount,
+					PreviousAmount: previous,
+					ChangePercent:  utils.RoundFloat((e.Amount/previous-1)*100, 2),
+					Currency:       e.Currency,
+				})
+			}
+		}
+		regular = append(regular, e.Amount)
+	}
+	return cuts
+}
+
+func (s *dividendHistoryServiceImpl) GetDividendHistory(userID int64, portfolioID int64) (*models.DividendHistoryResult, error) {
+	transactions, err := s.uploadService.GetDividendTransactions(userID, portfolioID)
+	if err != nil {
+		return nil, err
+	}
+	lotsByISIN, err := latestOpenLots(s.uploadService, userID, portfolioID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	lastCompleteYear := now.Year() - 1
+	portfolioMonthly, portfolioYearly := incomeTotals{}, incomeTotals{}
+	positionYearly := make(map[string]incomeTotals)
+	paymentDates := make(map[string][]time.Time)
+	productNames := make(map[string]string)
+	for _, tx := range transactions {
+		date := utils.ParseDate(tx.Date)
+		if date.IsZero() {
+			continue
+		}
+		month, year := date.Format("2006-01"), date.Format("2006")
+		portfolioMonthly.add(month, tx)
+		portfolioYearly.add(year, tx)
+		if positionYearly[tx.ISIN] == nil {
+			positionYearly[tx.ISIN] = incomeTotals{}
+		}
+		positionYearly[tx.ISIN].add(year, tx)
+		if tx.TransactionSubType != "TAX" && tx.AmountEUR > 0 {
+			paymentDates[tx.ISIN] = append(paymentDates[tx.ISIN], date)
+		}
+		productNames[tx.ISIN] = tx.ProductName
+	}
+
+	result := &models.DividendHistoryResult{
+		Monthly:   portfolioMonthly.series(false),
+		Yearly:    portfolioYearly.series(true),
+		Positions: []models.PositionDividendHistory{},
+	}
+	result.IncomeGrowth = dividendGrowth(grossByYear(result.Yearly), lastCompleteYear)
+
+	isinSet := make(map[string]bool)
+	for isin := range positionYearly {
+		isinSet[isin] = true
+	}
+	for isin, lots := range lotsByISIN {
+		isinSet[isin] = true
+		if productNames[isin] == "" && len(lots) > 0 {
+			productNames[isin] = lots[0].ProductName
+		}
+	}
+	isins := make([]string, 0, len(isinSet))
+	for isin := range isinSet {
+		isins = append(isins, isin)
+	}
+	sort.Strings(isins)
+	mappings, err := model.GetMappingsByISINs(database.DB, isins)
+	if err != nil {
+		return nil, err
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, 5) // Limit concurrent requests to Yahoo
+	for _, isin := range isins {
+		position := models.PositionDividendHistory{
+			ISIN:           isin,
+			ProductName:    productNames[isin],
+			Yearly:         []models.IncomePoint{},
+			PerShareByYear: map[string]float64{},
+			Cuts:           []models.DividendCut{},
+			YieldOnCost:    []models.LotYieldOnCost{},
+		}
+		if totals, ok := positionYearly[isin]; ok {
+			position.Yearly = totals.series(true)
+		}
+		for _, p := range position.Yearly {
+			position.ReceivedGrossEUR += p.GrossEUR
+		}
+		position.ReceivedGrossEUR = utils.RoundFloat(position.ReceivedGrossEUR, 2)
+		position.IncomeGrowth = dividendGrowth(grossByYear(position.Yearly), lastCompleteYear)
+		position.Frequency, position.PaymentsPerYear = detectFrequency(paymentDates[isin], now)
+
+		mapping, ok := mappings[isin]
+		if !ok || mapping.TickerSymbol == "" {
+			result.Positions = append(result.Positions, position)
+			continue
+		}
+		position.Ticker = mapping.TickerSymbol
+
+		wg.Add(1)
+		go func(position models.PositionDividendHistory) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			events, err := s.priceService.GetDividendEvents(position.Ticker, now.AddDate(-dividendHistoryYears, 0, 0))
+			if err != nil {
+				logger.L.Warn("Failed to get dividend events", "ticker", position.Ticker, "error", err)
+			} else {
+				applyDividendEvents(&position, events, lotsByISIN[position.ISIN], now)
+			}
+			mu.Lock()
+			result.Positions = append(result.Positions, position)
+			mu.Unlock()
+		}(position)
+	}
+	wg.Wait()
+
+	sort.Slice(result.Positions, func(i, j int) bool {
+		if result.Positions[i].ReceivedGrossEUR != result.Positions[j].ReceivedGrossEUR {
+			return result.Positions[i].ReceivedGrossEUR > result.Positions[j].ReceivedGrossEUR
+		}
+		return result.Positions[i].ISIN < result.Positions[j].ISIN
+	})
+	return result, nil
+}
+
+// grossByYear indexes yearly income points by year.
+func grossByYear(points []models.IncomePoint) map[int]float64 {
+	byYear := make(map[int]float64, len(points))
+	for _, p := range points {
+		if year, err := strconv.Atoi(p.Period); err == nil {
+			byYear[year] = p.GrossEUR
+		}
+	}
+	return byYear
+}
+
+// applyDividendEvents fills the provider-based analytics of a position: payout frequency,
+// dividend per share by year and its growth, cuts and the yield on cost of the open lots.
+func applyDividendEvents(position *models.PositionDividendHistory, events []models.DividendEvent, lots []models.PurchaseLot, now time.Time) {
+	var history []models.DividendEvent
+	var exDates []time.Time
+	perShareByYear := make(map[int]float64)
+	trailing := 0.0
+	yearAgo := now.AddDate(-1, 0, 0)
+	for _, e := range events {
+		exDate, err := time.Parse("2006-01-02", e.ExDate)
+		if err != nil || e.Source != models.DividendEventHistory || exDate.After(now) {
+			continue
+		}
+		history = append(history, e)
+		exDates = append(exDates, exDate)
+		perShareByYear[exDate.Year()] += e.Amount
+		position.PerShareCurrency = e.Currency
+		if exDate.After(yearAgo) {
+			trailing += e.Amount
+		}
+	}
+	if len(history) == 0 {
+		return
+	}
+
+	if frequency, perYear := detectFrequency(exDates, now); frequency != models.FrequencyUnknown {
+		position.Frequency, position.PaymentsPerYear = frequency, perYear
+	}
+	for year, amount := range perShareByYear {
+		position.PerShareByYear[strconv.Itoa(year)] = math.Round(amount*10000) / 10000
+	}
+	position.PerShareGrowth = dividendGrowth(perShareByYear, now.Year()-1)
+	if position.Frequency != models.FrequencyIrregular {
+		position.Cuts = detectCuts(history, position.PaymentsPerYear)
+	}
+
+	trailingEUR, ok := dividendAmountEUR(trailing, position.PerShareCurrency, now)
+	if !ok {
+		return
+	}
+	for _, lot := range lots {
+		cost := math.Abs(lot.BuyAmountEUR)
+		if lot.Quantity <= 0 || cost <= 0 {
+			continue
+		}
+		annual := trailingEUR * float64(lot.Quantity)
+		position.YieldOnCost = append(position.YieldOnCost, models.LotYieldOnCost{
+			BuyDate:            lot.BuyDate,
+			Quantity:           lot.Quantity,
+			CostEUR:            utils.RoundFloat(cost, 2),
+			AnnualDividendEUR:  utils.RoundFloat(annual, 2),
+			YieldOnCostPercent: utils.RoundFloat(annual/cost*100, 2),
+		})
+	}
+}
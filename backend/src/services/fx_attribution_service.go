@@ -0,0 +1,143 @@
+// backend/src/services/fx_attribution_service.go
+package services
+
+import (
+	"math"
+	"sort"
+	"strconv"
+
+	"github.com/username/taxfolio/backend/src/models"
+	"github.com/username/taxfolio/backend/src/utils"
+)
+
+// FXAttributionService splits realized and unrealized results into price and currency effects.
+type FXAttributionService interface {
+	GetFXAttribution(userID int64, portfolioID int64, year int) (*models.FXAttributionResult, error)
+}
+
+type fxAttributionServiceImpl struct {
+	uploadService UploadService
+	lotService    LotService
+}
+
+func NewFXAttributionService(uploadService UploadService, lotService LotService) FXAttributionService {
+	return &fxAttributionServiceImpl{uploadService: uploadService, lotService: lotService}
+}
+
+// attributeSale splits a sale's Delta. The rates are implied by the local and EUR amounts so
+// that both effects add up to Delta exactly.
+func attributeSale(sale models.SaleDetail) models.RealizedAttribution {
+	result := models.RealizedAttribution{
+		SaleDate:    sale.SaleDate,
+		BuyDate:     sale.BuyDate,
+		ISIN:        sale.ISIN,
+		ProductName: sale.ProductName,
+		Quantity:    sale.Quantity,
+		Currency:    sale.SaleCurrency,
+		ResultEUR:   sale.Delta,
+	}
+	buyLocal, buyEUR := math.Abs(sale.BuyAmount), math.Abs(sale.BuyAmountEUR)
+	saleLocal, saleEUR := math.Abs(sale.SaleAmount), math.Abs(sale.SaleAmountEUR)
+	if buyEUR > 0 {
+		result.BuyRate = buyLocal / buyEUR
+	}
+	if saleEUR > 0 {
+		result.SaleRate = saleLocal / saleEUR
+	}
+	if result.SaleRate <= 0 || normalizeCurrency(sale.BuyCurrency) != normalizeCurrency(sale.SaleCurrency) {
+		return result
+	}
+
+	resultLocal := utils.RoundFloat(saleLocal-buyLocal, 2)
+	priceEffect := utils.RoundFloat((saleLocal-buyLocal)/result.SaleRate, 2)
+	fxEffect := utils.RoundFloat(sale.Delta-priceEffect, 2)
+	result.ResultLocal = &resultLocal
+	result.PriceEffectEUR = &priceEffect
+	result.FXEffectEUR = &fxEffect
+	return result
+}
+
+// fxGroups accumulates attribution groups by key.
+type fxGroups map[string]*models.FXAttributionGroup
+
+func (g fxGroups) add(key string, resultEUR float64, priceEffect, fxEffect *float64) {
+	group, ok := g[key]
+	if !ok {
+		group = &models.FXAttributionGroup{Key: key}
+		g[key] = group
+	}
+	group.Count++
+	group.ResultEUR += resultEUR
+	if priceEffect != nil && fxEffect != nil {
+		group.PriceEffectEUR += *priceEffect
+		group.FXEffectEUR += *fxEffect
+	} else {
+		group.UnattributedEUR += resultEUR
+	}
+}
+
+// sorted returns the groups ordered by key with rounded totals.
+func (g fxGroups) sorted() []models.FXAttributionGroup {
+	groups := make([]models.FXAttributionGroup, 0, len(g))
+	for _, group := range g {
+		groups = append(groups, roundFXGroup(*group))
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i].Key < groups[j].Key })
+	return groups
+}
+
+func roundFXGroup(group models.FXAttributionGroup) models.FXAttributionGroup {
+	group.ResultEUR = utils.RoundFloat(group.ResultEUR, 2)
+	group.PriceEffectEUR = utils.RoundFloat(group.PriceEffectEUR, 2)
+	group.FXEffectEUR = utils.RoundFloat(group.FXEffectEUR, 2)
+	group.UnattributedEUR = utils.RoundFloat(group.UnattributedEUR, 2)
+	return group
+}
+
+// GetFXAttribution attributes every stock sale (only those of `year` when it is not 0) and
+// the open lots valued at current prices.
+func (s *fxAttributionServiceImpl) GetFXAttribution(userID int64, portfolioID int64, year int) (*models.FXAttributionResult, error) {
+	sales, err := s.uploadService.GetStockSaleDetails(userID, portfolioID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &models.FXAttributionResult{Realized: []models.RealizedAttribution{}}
+	byYear, byCurrency, total := fxGroups{}, fxGroups{}, fxGroups{}
+	for _, sale := range sales {
+		saleYear := utils.ParseDate(sale.SaleDate).Year()
+		if year != 0 && saleYear != year {
+			continue
+		}
+		attribution := attributeSale(sale)
+		result.Realized = append(result.Realized, attribution)
+		byYear.add(strconv.Itoa(saleYear), attribution.ResultEUR, attribution.PriceEffectEUR, attribution.FXEffectEUR)
+		byCurrency.add(normalizeCurrency(attribution.Currency), attribution.ResultEUR, attribution.PriceEffectEUR, attribution.FXEffectEUR)
+		total.add("total", attribution.ResultEUR, attribution.PriceEffectEUR, attribution.FXEffectEUR)
+	}
+	result.RealizedByYear = byYear.sorted()
+	result.RealizedByCurrency = byCurrency.sorted()
+	result.RealizedTotal = models.FXAttributionGroup{Key: "total"}
+	if t, ok := total["total"]; ok {
+		result.RealizedTotal = roundFXGroup(*t)
+	}
+
+	unrealized, err := s.lotService.GetUnrealizedLots(userID, portfolioID)
+	if err != nil {
+		return nil, err
+	}
+	unrealizedByCurrency, unrealizedTotal := fxGroups{}, fxGroups{}
+	for _, lot := range unrealized.Lots {
+		if lot.PriceStatus != "OK" {
+			continue
+		}
+		unrealizedByCurrency.add(normalizeCurrency(lot.Currency), lot.UnrealizedEUR, lot.PriceEffectEUR, lot.FXEffectEUR)
+		unrealizedTotal.add("total", lot.UnrealizedEUR, lot.PriceEffectEUR, lot.FXEffectEUR)
+	}
+	result.UnrealizedByCurrency = unrealizedByCurrency.sorted()
+	result.UnrealizedTotal = models.FXAttributionGroup{Key: "total"}
+	if t, ok := unrealizedTotal["total"]; ok {
+		result.UnrealizedTotal = roundFXGroup(*t)
+	}
+	return result, nil
+}
@@ -0,0 +1,212 @@
+// backend/src/services/harvest_service.go
+package services
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/username/taxfolio/backend/src/logger"
+	"github.com/username/taxfolio/backend/src/models"
+	"github.com/username/taxfolio/backend/src/utils"
+)
+
+// HarvestParams sets the goal of GetHarvestPlan. Sells are suggested until the net realized
+// P/L of the year falls to TargetNetEUR (0 offsets all gains realized so far).
+type HarvestParams struct {
+	TargetNetEUR float64
+	TaxRate      float64
+}
+
+// HarvestService suggests loss-realizing sells that offset the gains of the current fiscal year.
+type HarvestService interface {
+	GetHarvestPlan(userID int64, portfolioID int64, params HarvestParams) (*models.HarvestPlan, error)
+}
+
+type harvestServiceImpl struct {
+	uploadService UploadService
+	priceService  PriceService
+}
+
+func NewHarvestService(uploadService UploadService, priceService PriceService) HarvestService {
+	return &harvestServiceImpl{uploadService: uploadService, priceService: priceService}
+}
+
+// harvestPosition is a candidate together with its valued lots, oldest first.
+type harvestPosition struct {
+	candidate models.HarvestCandidate
+	lots      []models.UnrealizedLot
+}
+
+func (s *harvestServiceImpl) GetHarvestPlan(userID int64, portfolioID int64, params HarvestParams) (*models.HarvestPlan, error) {
+	now := time.Now().UTC()
+	asOf := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	plan := &models.HarvestPlan{
+		Year:              asOf.Year(),
+		TaxRate:           params.TaxRate,
+		TargetNetEUR:      params.TargetNetEUR,
+		Candidates:        []models.HarvestCandidate{},
+		Sells:             []models.HarvestSell{},
+		UnpricedPositions: []string{},
+	}
+
+	// Realized P/L of the fiscal (calendar) year so far.
+	sales, err := s.uploadService.GetStockSaleDetails(userID, portfolioID)
+	if err != nil {
+		return nil, err
+	}
+	for _, sale := range sales {
+		if utils.ParseDate(sale.SaleDate).Year() != plan.Year {
+			continue
+		}
+		if sale.Delta > 0 {
+			plan.RealizedGainsEUR += sale.Delta
+		} else {
+			plan.RealizedLossesEUR += sale.Delta
+		}
+	}
+	realizedNet := plan.RealizedGainsEUR + plan.RealizedLossesEUR
+
+	positions, err := s.harvestPositions(userID, portfolioID, asOf, plan)
+	if err != nil {
+		return nil, err
+	}
+
+	// Every euro of loss lowers the estimate by the same amount, so the positions that
+	// realize the most loss per euro sold go first to keep the sells small.
+	sort.SliceStable(positions, func(i, j int) bool {
+		return lossPerEUR(positions[i].candidate) > lossPerEUR(positions[j].candidate)
+	})
+	needed := realizedNet - params.TargetNetEUR
+	for _, position := range positions {
+		if needed <= 0.005 {
+			break
+		}
+		sell := simulateHarvestSell(position, needed)
+		if sell.Quantity == 0 {
+			continue
+		}
+		plan.Sells = append(plan.Sells, sell)
+		plan.HarvestedLossEUR += sell.RealizedEUR
+		needed += sell.RealizedEUR
+	}
+
+	resultingNet := realizedNet + plan.HarvestedLossEUR
+	plan.EstimatedTaxBeforeEUR = utils.RoundFloat(math.Max(realizedNet, 0)*params.TaxRate, 2)
+	plan.EstimatedTaxAfterEUR = utils.RoundFloat(math.Max(resultingNet, 0)*params.TaxRate, 2)
+	plan.TaxSavedEUR = utils.RoundFloat(plan.EstimatedTaxBeforeEUR-plan.EstimatedTaxAfterEUR, 2)
+	plan.RealizedGainsEUR = utils.RoundFloat(plan.RealizedGainsEUR, 2)
+	plan.RealizedLossesEUR = utils.RoundFloat(plan.RealizedLossesEUR, 2)
+	plan.RealizedNetEUR = utils.RoundFloat(realizedNet, 2)
+	plan.HarvestedLossEUR = utils.RoundFloat(plan.HarvestedLossEUR, 2)
+	plan.ResultingNetEUR = utils.RoundFloat(resultingNet, 2)
+	return plan, nil
+}
+
+// harvestPositions values the open lots and keeps the positions where selling in FIFO order
+// realizes a loss. Positions without a price are recorded on the plan.
+func (s *harvestServiceImpl) harvestPositions(userID int64, portfolioID int64, asOf time.Time, plan *models.HarvestPlan) ([]harvestPosition, error) {
+	lotsByISIN, err := latestOpenLots(s.uploadService, userID, portfolioID)
+	if err != nil {
+		return nil, err
+	}
+	isins := make([]string, 0, len(lotsByISIN))
+	for isin := range lotsByISIN {
+		if !strings.HasPrefix(strings.ToLower(isin), "unknown") {
+			isins = append(isins, isin)
+		}
+	}
+	sort.Strings(isins)
+	prices, err := s.priceService.GetCurrentPrices(isins)
+	if err != nil {
+		logger.L.Warn("Could not fetch some or all current prices", "error", err)
+	}
+
+	var positions []harvestPosition
+	for _, isin := range isins {
+		price := prices[isin]
+		if price.Status != "OK" || price.Price <= 0 {
+			plan.UnpricedPositions = append(plan.UnpricedPositions, isin)
+			continue
+		}
+		position := harvestPosition{candidate: models.HarvestCandidate{ISIN: isin, CurrentPriceEUR: price.Price}}
+		cumulative, quantity := 0.0, 0
+		for _, lot := range lotsByISIN[isin] {
+			if lot.Quantity <= 0 {
+				continue
+			}
+			valued := valueLot(lot, price, asOf)
+			position.lots = append(position.lots, valued)
+			position.candidate.ProductName = lot.ProductName
+			position.candidate.UnrealizedEUR += valued.UnrealizedEUR
+
+			// The realized result of a FIFO sell can only bottom out at a lot boundary.
+			cumulative += float64(lot.Quantity)*price.Price - math.Abs(lot.BuyAmountEUR)
+			quantity += lot.Quantity
+			if cumulative < position.candidate.MaxLossEUR {
+				position.candidate.MaxLossEUR = cumulative
+				position.candidate.MaxQuantity = quantity
+			}
+		}
+		position.candidate.Quantity = quantity
+		if position.candidate.MaxLossEUR > -0.005 {
+			continue
+		}
+		position.candidate.UnrealizedEUR = utils.RoundFloat(position.candidate.UnrealizedEUR, 2)
+		position.candidate.MaxLossEUR = utils.RoundFloat(position.candidate.MaxLossEUR, 2)
+		plan.Candidates = append(plan.Candidates, position.candidate)
+		positions = append(positions, position)
+	}
+	sort.SliceStable(plan.Candidates, func(i, j int) bool {
+		return plan.Candidates[i].MaxLossEUR < plan.Candidates[j].MaxLossEUR
+	})
+	return positions, nil
+}
+
+// lossPerEUR is the loss realized per euro of proceeds when selling the candidate's MaxQuantity.
+func lossPerEUR(c models.HarvestCandidate) float64 {
+	proceeds := float64(c.MaxQuantity) * c.CurrentPriceEUR
+	if proceeds <= 0 {
+		return 0
+	}
+	return -c.MaxLossEUR / proceeds
+}
+
+// simulateHarvestSell sells the position's shares in FIFO order until `needed` euros of loss
+// are realized or the sell reaches the candidate's most loss-making quantity.
+func simulateHarvestSell(position harvestPosition, needed float64) models.HarvestSell {
+	c := position.candidate
+	sell := models.HarvestSell{ISIN: c.ISIN, ProductName: c.ProductName, Lots: []models.HarvestLotMatch{}}
+	realized := 0.0
+	for _, lot := range position.lots {
+		if sell.Quantity >= c.MaxQuantity || realized <= -needed {
+			break
+		}
+		costPerShare := lot.CostEUR / float64(lot.Quantity)
+		perShare := c.CurrentPriceEUR - costPerShare
+		take := lot.Quantity
+		if perShare < 0 && realized+float64(take)*perShare < -needed {
+			take = int(math.Ceil((needed+realized)/-perShare - 1e-9))
+			if take > lot.Quantity {
+				take = lot.Quantity
+			}
+		}
+		cost := costPerShare * float64(take)
+		result := float64(take) * perShare
+		sell.Lots = append(sell.Lots, models.HarvestLotMatch{
+			BuyDate:       lot.BuyDate,
+			Quantity:      take,
+			CostEUR:       utils.RoundFloat(cost, 2),
+			RealizedEUR:   utils.RoundFloat(result, 2),
+			HoldingPeriod: lot.HoldingPeriod,
+		})
+		sell.Quantity += take
+		sell.CostEUR += cost
+		realized += result
+	}
+	sell.ProceedsEUR = utils.RoundFloat(float64(sell.Quantity)*c.CurrentPriceEUR, 2)
+	sell.CostEUR = utils.RoundFloat(sell.CostEUR, 2)
+	sell.RealizedEUR = utils.RoundFloat(realized, 2)
+	return sell
+}
@@ -0,0 +1,241 @@
+// backend/src/services/ibkr_flex_client.go
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/username/taxfolio/backend/src/logger"
+)
+
+const (
+	// DefaultIBKRFlexBaseURL is the endpoint of version 3 of the Flex Web Service.
+	DefaultIBKRFlexBaseURL = "https://ndcdyn.interactivebrokers.com/AccountManagement/FlexWebService"
+
+	ibkrFlexVersion       = "3"
+	ibkrFlexUserAgent     = "VisorFinanceiro/1.0"
+	maxIBKRFlexStatement  = 50 * 1024 * 1024
+	maxIBKRFlexRetryDelay = time.Minute
+)
+
+// ibkrFlexRetryableCodes are the error codes after which IBKR asks to try again later,
+// e.g. 1019 "Statement generation in progress" and 1018 "Too many requests".
+var ibkrFlexRetryableCodes = map[int]bool{
+	1001: true, 1004: true, 1005: true, 1006: true, 1007: true,
+	1008: true, 1009: true, 1018: true, 1019: true, 1021: true,
+}
+
+var errIBKRFlexTooLarge = fmt.Errorf("ibkr flex: statement exceeds %d MB", maxIBKRFlexStatement/(1024*1024))
+
+// HTTPDoer sends HTTP requests. *http.Client satisfies it; tests can swap in a client
+// pointed at a local stand-in server.
+type HTTPDoer interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// IBKRFlexError is an error reported by the Flex Web Service, e.g. 1012 "Token has expired".
+type IBKRFlexError struct {
+	Code    int
+	Message string
+}
+
+func (e *IBKRFlexError) Error() string {
+	return fmt.Sprintf("ibkr flex error %d: %s", e.Code, e.Message)
+}
+
+// Retryable reports whether the request may succeed when repeated later.
+func (e *IBKRFlexError) Retryable() bool {
+	return ibkrFlexRetryableCodes[e.Code]
+}
+
+// flexStatementResponse is the envelope returned by SendRequest, and by GetStatement
+// while the statement is not ready.
+type flexStatementResponse struct {
+	XMLName       xml.Name `xml:"FlexStatementResponse"`
+	Status        string   `xml:"Status"`
+	ReferenceCode string   `xml:"ReferenceCode"`
+	URL           string   `xml:"Url"`
+	ErrorCode     int      `xml:"ErrorCode"`
+	ErrorMessage  string   `xml:"ErrorMessage"`
+}
+
+// IBKRFlexClient downloads Flex query statements in two steps: SendRequest starts the
+// generation and returns a reference code, then GetStatement is polled with that code
+// until the statement is ready.
+type IBKRFlexClient struct {
+	httpClient   HTTPDoer
+	baseURL      string
+	pollInterval time.Duration
+	maxAttempts  int
+}
+
+// NewIBKRFlexClient creates a client for the Flex Web Service at baseURL. Each step is
+// tried up to maxAttempts times, waiting pollInterval longer after every attempt.
+func NewIBKRFlexClient(httpClient HTTPDoer, baseURL string, pollInterval time.Duration, maxAttempts int) *IBKRFlexClient {
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 60 * time.Second}
+	}
+	if baseURL == "" {
+		baseURL = DefaultIBKRFlexBaseURL
+	}
+	if pollInterval <= 0 {
+		pollInterval = 5 * time.Second
+	}
+	if maxAttempts < 1 {
+		maxAttempts = 10
+	}
+	return &IBKRFlexClient{
+		httpClient:   httpClient,
+		baseURL:      strings.TrimRight(baseURL, "/"),
+		pollInterval: pollInterval,
+		maxAttempts:  maxAttempts,
+	}
+}
+
+// FetchStatement runs the Flex query and returns the FlexQueryResponse XML.
+func (c *IBKRFlexClient) FetchStatement(ctx context.Context, token, queryID string) ([]byte, error) {
+	var envelope *flexStatementResponse
+	err := c.retry(ctx, "SendRequest", func() error {
+		body, err := c.get(ctx, c.baseURL+"/SendRequest", url.Values{"t": {token}, "q": {queryID}, "v": {ibkrFlexVersion}})
+		if err != nil {
+			return err
+		}
+		envelope, err = parseFlexStatementResponse(body)
+		if err != nil {
+			return err
+		}
+		return envelope.err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	if envelope.ReferenceCode == "" {
+		return nil, fmt.Errorf("ibkr flex: SendRequest returned no reference code")
+	}
+
+	statementURL := envelope.URL
+	if statementURL == "" {
+		statementURL = c.baseURL + "/GetStatement"
+	}
+	var statement []byte
+	err = c.retry(ctx, "GetStatement", func() error {
+		body, err := c.get(ctx, statementURL, url.Values{"t": {token}, "q": {envelope.ReferenceCode}, "v": {ibkrFlexVersion}})
+		if err != nil {
+			return err
+		}
+		if !isFlexStatementResponse(body) {
+			statement = body
+			return nil
+		}
+		pending, err := parseFlexStatementResponse(body)
+		if err != nil {
+			return err
+		}
+		if err := pending.err(); err != nil {
+			return err
+		}
+		return fmt.Errorf("ibkr flex: GetStatement returned status %q without a statement", pending.Status)
+	})
+	return statement, err
+}
+
+// retry calls step until it succeeds, fails with a permanent error or runs out of attempts.
+// Everything but a Flex error with a permanent code, such as an invalid token, is tried again.
+func (c *IBKRFlexClient) retry(ctx context.Context, stepName string, step func() error) error {
+	var err error
+	for attempt := 1; attempt <= c.maxAttempts; attempt++ {
+		if err = step(); err == nil {
+			return nil
+		}
+		var flexErr *IBKRFlexError
+		if (errors.As(err, &flexErr) && !flexErr.Retryable()) || errors.Is(err, errIBKRFlexTooLarge) {
+			return err
+		}
+		if attempt == c.maxAttempts {
+			break
+		}
+
+		delay := time.Duration(attempt) * c.pollInterval
+		if delay > maxIBKRFlexRetryDelay {
+			delay = maxIBKRFlexRetryDelay
+		}
+		logger.L.Debug("IBKR Flex request not ready, retrying", "step", stepName, "attempt", attempt, "delay", delay, "error", err)
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+	return fmt.Errorf("ibkr flex: %s failed after %d attempts: %w", stepName, c.maxAttempts, err)
+}
+
+func (c *IBKRFlexClient) get(ctx context.Context, endpoint string, params url.Values) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint+"?"+params.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("ibkr flex: invalid request: %w", err)
+	}
+	// The service rejects requests without a User-Agent.
+	req.Header.Set("User-Agent", ibkrFlexUserAgent)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("ibkr flex: request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("ibkr flex: unexpected status %d", resp.StatusCode)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxIBKRFlexStatement+1))
+	if err != nil {
+		return nil, fmt.Errorf("ibkr flex: failed to read response: %w", err)
+	}
+	if len(body) > maxIBKRFlexStatement {
+		return nil, errIBKRFlexTooLarge
+	}
+	return body, nil
+}
+
+// isFlexStatementResponse reports whether the root element of body is the status envelope
+// rather than a statement.
+func isFlexStatementResponse(body []byte) bool {
+	decoder := xml.NewDecoder(bytes.NewReader(body))
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return false
+		}
+		if start, ok := token.(xml.StartElement); ok {
+			return start.Name.Local == "FlexStatementResponse"
+		}
+	}
+}
+
+func parseFlexStatementResponse(body []byte) (*flexStatementResponse, error) {
+	var envelope flexStatementResponse
+	if err := xml.Unmarshal(body, &envelope); err != nil {
+		return nil, fmt.Errorf("ibkr flex: unexpected response: %w", err)
+	}
+	return &envelope, nil
+}
+
+// err returns the error carried by the envelope, if its status is not "Success".
+func (r *flexStatementResponse) err() error {
+	if strings.EqualFold(r.Status, "Success") {
+		return nil
+	}
+	message := strings.TrimSpace(r.ErrorMessage)
+	if message == "" {
+		message = "request " + strings.ToLower(r.Status)
+	}
+	return &IBKRFlexError{Code: r.ErrorCode, Message: message}
+}
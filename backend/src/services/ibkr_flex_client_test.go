@@ -0,0 +1,178 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/username/taxfolio/backend/src/logger"
+)
+
+const flexStatementXML = `<FlexQueryResponse queryName="Trades" type="AF"><FlexStatements count="1"></FlexStatements></FlexQueryResponse>`
+
+func flexEnvelope(status string, code int, message string) string {
+	if status == "Success" {
+		return `<FlexStatementResponse timestamp="15 January, 2024 10:30 AM EST"><Status>Success</Status>` +
+			`<ReferenceCode>1234567890</ReferenceCode><Url>` + message + `</Url></FlexStatementResponse>`
+	}
+	return fmt.Sprintf(`<FlexStatementResponse timestamp="15 January, 2024 10:30 AM EST"><Status>%s</Status>`+
+		`<ErrorCode>%d</ErrorCode><ErrorMessage>%s</ErrorMessage></FlexStatementResponse>`, status, code, message)
+}
+
+// flexServer stands in for the Flex Web Service. getStatement answers the nth GetStatement call.
+type flexServer struct {
+	*httptest.Server
+	mu            sync.Mutex
+	sendRequest   func(r *http.Request) string
+	getStatement  func(n int, r *http.Request) string
+	statementHits int
+}
+
+func newFlexServer(t *testing.T) *flexServer {
+	s := &flexServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("User-Agent") == "" {
+			http.Error(w, "missing user agent", http.StatusForbidden)
+			return
+		}
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		switch r.URL.Path {
+		case "/SendRequest":
+			fmt.Fprint(w, s.sendRequest(r))
+		case "/GetStatement":
+			s.statementHits++
+			fmt.Fprint(w, s.getStatement(s.statementHits, r))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func TestMain(m *testing.M) {
+	logger.InitLogger("error")
+	m.Run()
+}
+
+func TestFetchStatementPollsUntilReady(t *testing.T) {
+	server := newFlexServer(t)
+	server.sendRequest = func(r *http.Request) string {
+		if r.URL.Query().Get("t") != "token1234" || r.URL.Query().Get("q") != "987654" || r.URL.Query().Get("v") != "3" {
+			return flexEnvelope("Fail", 1015, "Token is invalid.")
+		}
+		return flexEnvelope("Success", 0, server.URL+"/GetStatement")
+	}
+	server.getStatement = func(n int, r *http.Request) string {
+		if r.URL.Query().Get("q") != "1234567890" {
+			return flexEnvelope("Fail", 1014, "Query is invalid.")
+		}
+		if n < 3 {
+			return flexEnvelope("Warn", 1019, "Statement generation in progress. Please try again shortly.")
+		}
+		return flexStatementXML
+	}
+
+	client := NewIBKRFlexClient(server.Client(), server.URL, time.Millisecond, 5)
+	statement, err := client.FetchStatement(context.Background(), "token1234", "987654")
+	if err != nil {
+		t.Fatalf("FetchStatement: %v", err)
+	}
+	if string(statement) != flexStatementXML {
+		t.Errorf("statement = %q, want %q", statement, flexStatementXML)
+	}
+	if server.statementHits != 3 {
+		t.Errorf("GetStatement called %d times, want 3", server.statementHits)
+	}
+}
+
+func TestFetchStatementStopsOnPermanentError(t *testing.T) {
+	server := newFlexServer(t)
+	var sendHits int
+	server.sendRequest = func(r *http.Request) string {
+		sendHits++
+		return flexEnvelope("Fail", 1012, "Token has expired.")
+	}
+
+	client := NewIBKRFlexClient(server.Client(), server.URL, time.Millisecond, 5)
+	_, err := client.FetchStatement(context.Background(), "token1234", "987654")
+	var flexErr *IBKRFlexError
+	if !errors.As(err, &flexErr) || flexErr.Code != 1012 || flexErr.Retryable() {
+		t.Fatalf("error = %v, want the permanent Flex error 1012", err)
+	}
+	if sendHits != 1 {
+		t.Errorf("SendRequest called %d times, want 1", sendHits)
+	}
+}
+
+func TestFetchStatementGivesUpAfterMaxAttempts(t *testing.T) {
+	server := newFlexServer(t)
+	server.sendRequest = func(r *http.Request) string {
+		return flexEnvelope("Success", 0, "")
+	}
+	server.getStatement = func(n int, r *http.Request) string {
+		return flexEnvelope("Warn", 1019, "Statement generation in progress. Please try again shortly.")
+	}
+
+	client := NewIBKRFlexClient(server.Client(), server.URL, time.Millisecond, 3)
+	_, err := client.FetchStatement(context.Background(), "token1234", "987654")
+	var flexErr *IBKRFlexError
+	if !errors.As(err, &flexErr) || flexErr.Code != 1019 {
+		t.Fatalf("error = %v, want the last Flex error 1019", err)
+	}
+	if !strings.Contains(err.Error(), "after 3 attempts") {
+		t.Errorf("error = %v, want it to report the attempts", err)
+	}
+	if server.statementHits != 3 {
+		t.Errorf("GetStatement called %d times, want 3", server.statementHits)
+	}
+}
+
+// failingDoer stands in for the HTTP client when the service cannot be reached.
+type failingDoer struct{ calls int }
+
+func (d *failingDoer) Do(req *http.Request) (*http.Response, error) {
+	d.calls++
+	return nil, errors.New("connection refused")
+}
+
+func TestFetchStatementRetriesNetworkErrors(t *testing.T) {
+	doer := &failingDoer{}
+	client := NewIBKRFlexClient(doer, "http://flex.invalid", time.Millisecond, 2)
+	_, err := client.FetchStatement(context.Background(), "token1234", "987654")
+	if err == nil || !strings.Contains(err.Error(), "connection refused") {
+		t.Fatalf("error = %v, want the network error", err)
+	}
+	if doer.calls != 2 {
+		t.Errorf("requests sent = %d, want 2", doer.calls)
+	}
+}
+
+func TestFlexErrorRetryable(t *testing.T) {
+	for code, want := range map[int]bool{1001: true, 1018: true, 1019: true, 1003: false, 1012: false, 1015: false, 1020: false} {
+		if got := (&IBKRFlexError{Code: code}).Retryable(); got != want {
+			t.Errorf("Retryable() for %d = %v, want %v", code, got, want)
+		}
+	}
+}
+
+func TestIBKRFlexTokenHint(t *testing.T) {
+	for token, want := range map[string]string{
+		"123456789012345678901234": "****1234",
+		"abcdefgh":                 "****efgh",
+		"abcd":                     "****",
+		"ab":                       "**",
+		"":                         "",
+	} {
+		if got := ibkrFlexTokenHint(token); got != want {
+			t.Errorf("ibkrFlexTokenHint(%q) = %q, want %q", token, got, want)
+		}
+	}
+}
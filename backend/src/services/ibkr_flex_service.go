@@ -0,0 +1,186 @@
+// backend/src/services/ibkr_flex_service.go
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/username/taxfolio/backend/src/database"
+	"github.com/username/taxfolio/backend/src/logger"
+	"github.com/username/taxfolio/backend/src/model"
+	"github.com/username/taxfolio/backend/src/models"
+)
+
+var (
+	ErrInvalidIBKRFlexConnection = errors.New("invalid ibkr flex connection")
+	ErrIBKRFlexSyncInProgress    = errors.New("an ibkr flex import is already running for this portfolio")
+
+	ibkrFlexTokenPattern   = regexp.MustCompile(`^[A-Za-z0-9]{8,64}$`)
+	ibkrFlexQueryIDPattern = regexp.MustCompile(`^[0-9]{1,20}$`)
+)
+
+// IBKRFlexService imports the statements of a Flex query directly from IBKR, replacing the
+// manual download and upload of the XML. Imports overlap freely: transactions already
+// stored are recognised by their hash, so every import only adds what is new.
+type IBKRFlexService interface {
+	GetConnection(userID, portfolioID int64) (*models.IBKRFlexConnection, error)
+	SaveConnection(userID int64, connection models.IBKRFlexConnection) (*models.IBKRFlexConnection, error)
+	DeleteConnection(userID, portfolioID int64) error
+	Sync(ctx context.Context, userID, portfolioID int64) (*UploadResult, error)
+	SyncAll(ctx context.Context) error
+}
+
+type ibkrFlexServiceImpl struct {
+	uploadService UploadService
+	client        *IBKRFlexClient
+
+	mu      sync.Mutex
+	running map[int64]bool
+}
+
+func NewIBKRFlexService(uploadService UploadService, client *IBKRFlexClient) IBKRFlexService {
+	return &ibkrFlexServiceImpl{
+		uploadService: uploadService,
+		client:        client,
+		running:       make(map[int64]bool),
+	}
+}
+
+func (s *ibkrFlexServiceImpl) GetConnection(userID, portfolioID int64) (*models.IBKRFlexConnection, error) {
+	c, err := model.GetIBKRFlexConnection(database.DB, userID, portfolioID)
+	if err != nil {
+		return nil, err
+	}
+	c.TokenHint = ibkrFlexTokenHint(c.Token)
+	return c, nil
+}
+
+// SaveConnection stores the token and query ID of a portfolio. The token may be left empty
+// to keep the stored one, e.g. when only the query ID or the schedule changes.
+func (s *ibkrFlexServiceImpl) SaveConnection(userID int64, connection models.IBKRFlexConnection) (*models.IBKRFlexConnection, error) {
+	if err := model.CheckPortfolioOwnership(database.DB, userID, connection.PortfolioID); err != nil {
+		return nil, err
+	}
+	connection.UserID = userID
+	connection.Token = strings.TrimSpace(connection.Token)
+	connection.QueryID = strings.TrimSpace(connection.QueryID)
+
+	if connection.Token == "" {
+		existing, err := model.GetIBKRFlexConnection(database.DB, userID, connection.PortfolioID)
+		if err != nil && !errors.Is(err, model.ErrIBKRFlexConnectionNotFound) {
+			return nil, err
+		}
+		if existing != nil {
+			connection.Token = existing.Token
+		}
+	}
+	if !ibkrFlexTokenPattern.MatchString(connection.Token) {
+		return nil, fmt.Errorf("%w: token must be the alphanumeric Flex Web Service token", ErrInvalidIBKRFlexConnection)
+	}
+	if !ibkrFlexQueryIDPattern.MatchString(connection.QueryID) {
+		return nil, fmt.Errorf("%w: query_id must be the numeric ID of the Flex query", ErrInvalidIBKRFlexConnection)
+	}
+
+	if err := model.UpsertIBKRFlexConnection(database.DB, &connection); err != nil {
+		return nil, fmt.Errorf("failed to save ibkr flex connection: %w", err)
+	}
+	connection.TokenHint = ibkrFlexTokenHint(connection.Token)
+	return &connection, nil
+}
+
+func (s *ibkrFlexServiceImpl) DeleteConnection(userID, portfolioID int64) error {
+	return model.DeleteIBKRFlexConnection(database.DB, userID, portfolioID)
+}
+
+// Sync downloads the statement of the portfolio's Flex query and imports it as an
+// upload with source "ibkr". The outcome is recorded on the connection.
+func (s *ibkrFlexServiceImpl) Sync(ctx context.Context, userID, portfolioID int64) (*UploadResult, error) {
+	connection, err := model.GetIBKRFlexConnection(database.DB, userID, portfolioID)
+	if err != nil {
+		return nil, err
+	}
+	return s.syncConnection(ctx, connection)
+}
+
+// SyncAll imports the statements of every enabled connection, one at a time so that the
+// Flex Web Service rate limits are respected.
+func (s *ibkrFlexServiceImpl) SyncAll(ctx context.Context) error {
+	connections, err := model.GetEnabledIBKRFlexConnections(database.DB)
+	if err != nil {
+		return fmt.Errorf("failed to list ibkr flex connections: %w", err)
+	}
+
+	var failed int
+	for i := range connections {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		c := &connections[i]
+		if _, err := s.syncConnection(ctx, c); err != nil {
+			logger.L.Error("Scheduled IBKR Flex import failed", "userID", c.UserID, "portfolioID", c.PortfolioID, "error", err)
+			failed++
+		}
+	}
+
+	logger.L.Info("Scheduled IBKR Flex import complete", "connections", len(connections), "failed", failed)
+	if failed > 0 {
+		return fmt.Errorf("%d of %d ibkr flex imports failed", failed, len(connections))
+	}
+	return nil
+}
+
+func (s *ibkrFlexServiceImpl) syncConnection(ctx context.Context, c *models.IBKRFlexConnection) (*UploadResult, error) {
+	s.mu.Lock()
+	if s.running[c.PortfolioID] {
+		s.mu.Unlock()
+		return nil, ErrIBKRFlexSyncInProgress
+	}
+	s.running[c.PortfolioID] = true
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.running, c.PortfolioID)
+		s.mu.Unlock()
+	}()
+
+	result, err := s.importStatement(ctx, c)
+	if errors.Is(err, context.Canceled) {
+		// Shutting down or cancelled by the caller: not a problem of the connection.
+		return nil, err
+	}
+	syncErr := ""
+	if err != nil {
+		syncErr = err.Error()
+	}
+	if recordErr := model.RecordIBKRFlexSync(database.DB, c.PortfolioID, time.Now().UTC(), syncErr); recordErr != nil {
+		logger.L.Error("Failed to record IBKR Flex import", "portfolioID", c.PortfolioID, "error", recordErr)
+	}
+	return result, err
+}
+
+func (s *ibkrFlexServiceImpl) importStatement(ctx context.Context, c *models.IBKRFlexConnection) (*UploadResult, error) {
+	startedAt := time.Now()
+	statement, err := s.client.FetchStatement(ctx, c.Token, c.QueryID)
+	if err != nil {
+		return nil, err
+	}
+	logger.L.Info("IBKR Flex statement downloaded", "userID", c.UserID, "portfolioID", c.PortfolioID, "bytes", len(statement), "duration", time.Since(startedAt))
+
+	filename := fmt.Sprintf("IBKR Flex query %s", c.QueryID)
+	return s.uploadService.ProcessUpload([]io.Reader{bytes.NewReader(statement)}, c.UserID, c.PortfolioID, "ibkr", filename, int64(len(statement)))
+}
+
+// ibkrFlexTokenHint masks all but the last four characters of a token.
+func ibkrFlexTokenHint(token string) string {
+	if len(token) <= 4 {
+		return strings.Repeat("*", len(token))
+	}
+	return strings.Repeat("*", 4) + token[len(token)-4:]
+}
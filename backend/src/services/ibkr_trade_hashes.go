@@ -0,0 +1,82 @@
+// backend/src/services/ibkr_trade_hashes.go
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/username/taxfolio/backend/src/logger"
+	"github.com/username/taxfolio/backend/src/parsers/ibkr"
+	"github.com/username/taxfolio/backend/src/processors"
+)
+
+// UpgradeIBKRTradeHashes rewrites the raw text and hash of the IBKR trades stored before the
+// Flex statement and the Activity Statement shared a trade format, so that uploading either
+// format again still recognises them. Stored trades were all imported from Flex statements, so
+// they are distinct fills: a trade whose new hash is already taken is logged and left as it is.
+// It returns the number of trades upgraded.
+func UpgradeIBKRTradeHashes(db *sql.DB) (int, error) {
+	type legacyTrade struct {
+		id, portfolioID int64
+		isin, rawText   string
+	}
+	rows, err := db.Query(`
+		SELECT id, portfolio_id, COALESCE(isin, ''), input_string FROM processed_transactions
+		WHERE source = 'ibkr' AND input_string LIKE 'Trade|%'
+		ORDER BY id`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list ibkr trades: %w", err)
+	}
+	var trades []legacyTrade
+	for rows.Next() {
+		var t legacyTrade
+		if err := rows.Scan(&t.id, &t.portfolioID, &t.isin, &t.rawText); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to read ibkr trade: %w", err)
+		}
+		trades = append(trades, t)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed to list ibkr trades: %w", err)
+	}
+	if len(trades) == 0 {
+		return 0, nil
+	}
+
+	dbTx, err := db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("error beginning database transaction: %w", err)
+	}
+	defer dbTx.Rollback()
+
+	upgraded := 0
+	fills := make(map[string]int)
+	for _, t := range trades {
+		rawText, ok := ibkr.UpgradeLegacyTradeRawText(t.rawText, t.isin)
+		if !ok {
+			logger.L.Warn("Could not upgrade the raw text of an IBKR trade", "transactionID", t.id)
+			continue
+		}
+		key := fmt.Sprintf("%d|%s", t.portfolioID, rawText)
+		fills[key]++
+		rawText = ibkr.TradeFillRawText(rawText, fills[key])
+
+		_, err := dbTx.Exec(`UPDATE processed_transactions SET input_string = ?, hash_id = ? WHERE id = ?`,
+			rawText, processors.HashRawText(rawText), t.id)
+		if err != nil && strings.Contains(strings.ToLower(err.Error()), "unique constraint failed") {
+			logger.L.Warn("IBKR trade collides with a stored trade, leaving its hash unchanged",
+				"transactionID", t.id, "portfolioID", t.portfolioID, "rawText", rawText)
+			continue
+		}
+		if err != nil {
+			return 0, fmt.Errorf("failed to upgrade ibkr trade %d: %w", t.id, err)
+		}
+		upgraded++
+	}
+	if err := dbTx.Commit(); err != nil {
+		return 0, fmt.Errorf("error committing transaction: %w", err)
+	}
+	return upgraded, nil
+}
@@ -0,0 +1,176 @@
+// backend/src/services/import_template_service.go
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/username/taxfolio/backend/src/database"
+	"github.com/username/taxfolio/backend/src/model"
+	"github.com/username/taxfolio/backend/src/models"
+	"github.com/username/taxfolio/backend/src/parsers"
+	"github.com/username/taxfolio/backend/src/parsers/generic"
+)
+
+const (
+	// CustomSourcePrefix marks an upload read with an import template: "custom:<template id>".
+	CustomSourcePrefix = "custom:"
+
+	maxImportTemplates        = 50
+	maxImportTemplateNameLen  = 100
+	maxImportTypeMappingItems = 100
+)
+
+var ErrInvalidImportTemplate = errors.New("invalid import template")
+
+// ImportTemplateService manages the user-defined column mappings used to import the CSV
+// exports of brokers without a dedicated parser.
+type ImportTemplateService interface {
+	ListTemplates(userID int64) ([]models.ImportTemplate, error)
+	CreateTemplate(userID int64, template models.ImportTemplate) (*models.ImportTemplate, error)
+	UpdateTemplate(userID int64, template models.ImportTemplate) (*models.ImportTemplate, error)
+	DeleteTemplate(userID int64, templateID int64) error
+}
+
+type importTemplateServiceImpl struct{}
+
+func NewImportTemplateService() ImportTemplateService {
+	return &importTemplateServiceImpl{}
+}
+
+func (s *importTemplateServiceImpl) ListTemplates(userID int64) ([]models.ImportTemplate, error) {
+	return model.GetImportTemplates(database.DB, userID)
+}
+
+func (s *importTemplateServiceImpl) CreateTemplate(userID int64, template models.ImportTemplate) (*models.ImportTemplate, error) {
+	if err := normalizeImportTemplate(&template); err != nil {
+		return nil, err
+	}
+	existing, err := model.GetImportTemplates(database.DB, userID)
+	if err != nil {
+		return nil, err
+	}
+	if len(existing) >= maxImportTemplates {
+		return nil, fmt.Errorf("%w: at most %d templates per user", ErrInvalidImportTemplate, maxImportTemplates)
+	}
+	if err := model.InsertImportTemplate(database.DB, userID, &template); err != nil {
+		if strings.Contains(strings.ToLower(err.Error()), "unique constraint failed") {
+			return nil, fmt.Errorf("%w: a template named %q already exists", ErrInvalidImportTemplate, template.Name)
+		}
+		return nil, fmt.Errorf("failed to save import template: %w", err)
+	}
+	return &template, nil
+}
+
+func (s *importTemplateServiceImpl) UpdateTemplate(userID int64, template models.ImportTemplate) (*models.ImportTemplate, error) {
+	if err := normalizeImportTemplate(&template); err != nil {
+		return nil, err
+	}
+	if err := model.UpdateImportTemplate(database.DB, userID, &template); err != nil {
+		if strings.Contains(strings.ToLower(err.Error()), "unique constraint failed") {
+			return nil, fmt.Errorf("%w: a template named %q already exists", ErrInvalidImportTemplate, template.Name)
+		}
+		return nil, err
+	}
+	return &template, nil
+}
+
+func (s *importTemplateServiceImpl) DeleteTemplate(userID int64, templateID int64) error {
+	return model.DeleteImportTemplate(database.DB, userID, templateID)
+}
+
+// normalizeImportTemplate validates a template and fills in its defaults: a comma delimiter,
+// a dot decimal separator and EUR as the currency.
+func normalizeImportTemplate(t *models.ImportTemplate) error {
+	t.Name = strings.TrimSpace(t.Name)
+	if t.Name == "" || len(t.Name) > maxImportTemplateNameLen {
+		return fmt.Errorf("%w: name must have between 1 and %d characters", ErrInvalidImportTemplate, maxImportTemplateNameLen)
+	}
+
+	switch strings.ToLower(t.Delimiter) {
+	case "":
+		t.Delimiter = ","
+	case "tab", `\t`:
+		t.Delimiter = "\t"
+	}
+	if r, size := utf8.DecodeRuneInString(t.Delimiter); size != len(t.Delimiter) || r == '"' || r == '\r' || r == '\n' || r == utf8.RuneError {
+		return fmt.Errorf("%w: delimiter must be a single character", ErrInvalidImportTemplate)
+	}
+	switch t.DecimalSeparator {
+	case "":
+		t.DecimalSeparator = "."
+	case ".", ",":
+	default:
+		return fmt.Errorf("%w: decimal_separator must be \".\" or \",\"", ErrInvalidImportTemplate)
+	}
+	if t.DecimalSeparator == t.Delimiter {
+		return fmt.Errorf("%w: delimiter and decimal_separator must differ", ErrInvalidImportTemplate)
+	}
+	t.DateFormat = strings.TrimSpace(t.DateFormat)
+	if _, err := generic.DateLayout(t.DateFormat); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidImportTemplate, err)
+	}
+	t.DefaultCurrency = strings.ToUpper(strings.TrimSpace(t.DefaultCurrency))
+	if t.DefaultCurrency == "" {
+		t.DefaultCurrency = "EUR"
+	}
+	if len(t.DefaultCurrency) != 3 {
+		return fmt.Errorf("%w: default_currency must be an ISO 4217 code", ErrInvalidImportTemplate)
+	}
+
+	c := &t.Columns
+	for _, col := range []*string{&c.Date, &c.Type, &c.ISIN, &c.ProductName, &c.Quantity, &c.Price, &c.Amount, &c.Currency, &c.Commission, &c.OrderID} {
+		*col = strings.TrimSpace(*col)
+	}
+	if c.Date == "" || c.Type == "" {
+		return fmt.Errorf("%w: the date and type columns are required", ErrInvalidImportTemplate)
+	}
+	if c.Amount == "" && (c.Quantity == "" || c.Price == "") {
+		return fmt.Errorf("%w: an amount column, or quantity and price columns, are required", ErrInvalidImportTemplate)
+	}
+
+	if len(t.TypeMapping) == 0 || len(t.TypeMapping) > maxImportTypeMappingItems {
+		return fmt.Errorf("%w: between 1 and %d type mappings are required", ErrInvalidImportTemplate, maxImportTypeMappingItems)
+	}
+	mapping := make(map[string]string, len(t.TypeMapping))
+	for value, kind := range t.TypeMapping {
+		value = strings.TrimSpace(value)
+		kind = strings.ToUpper(strings.TrimSpace(kind))
+		switch kind {
+		case models.ImportTypeBuy, models.ImportTypeSell:
+			if c.Quantity == "" || c.ISIN == "" {
+				return fmt.Errorf("%w: trades need a quantity column and an isin column, which may hold tickers", ErrInvalidImportTemplate)
+			}
+		case models.ImportTypeDividend, models.ImportTypeDividendTax, models.ImportTypeFee, models.ImportTypeInterest,
+			models.ImportTypeDeposit, models.ImportTypeWithdrawal, models.ImportTypeIgnore:
+		default:
+			return fmt.Errorf("%w: unknown transaction kind %q for type %q", ErrInvalidImportTemplate, kind, value)
+		}
+		if value == "" {
+			return fmt.Errorf("%w: type values cannot be empty", ErrInvalidImportTemplate)
+		}
+		mapping[value] = kind
+	}
+	t.TypeMapping = mapping
+	return nil
+}
+
+// getParser returns the parser of an upload source: a broker parser, or the generic parser
+// with the user's import template for "custom:<template id>".
+func getParser(userID int64, source string) (parsers.Parser, error) {
+	if !strings.HasPrefix(source, CustomSourcePrefix) {
+		return parsers.GetParser(source)
+	}
+	templateID, err := strconv.ParseInt(strings.TrimPrefix(source, CustomSourcePrefix), 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid import template in source: %s", source)
+	}
+	template, err := model.GetImportTemplate(database.DB, userID, templateID)
+	if err != nil {
+		return nil, err
+	}
+	return generic.NewParser(source, *template), nil
+}
@@ -2,10 +2,12 @@
 package services
 
 import (
+	"context"
 	"errors"
 	"io"
 	"time" // Adicionado para time.Month
 
+	"github.com/username/taxfolio/backend/src/model"
 	"github.com/username/taxfolio/backend/src/models"
 )
 
@@ -18,16 +20,41 @@ type UploadResult struct {
 	CashMovements            []models.CashMovement           `json:"CashMovements"`
 	DividendTransactionsList []models.ProcessedTransaction   `json:"DividendTransactionsList"`
 	FeeDetails               []models.FeeDetail              `json:"FeeDetails"`
+	CryptoSaleDetails        []models.CryptoSaleDetail       `json:"CryptoSaleDetails"`
+	CryptoHoldings           []models.CryptoLot              `json:"CryptoHoldings"`
+	CryptoIncome             []models.CryptoIncome           `json:"CryptoIncome"`
+	// SkippedTransactions lists the rows of an upload that were left out. It is only set on
+	// the result returned by ProcessUpload.
+	SkippedTransactions []SkippedTransaction `json:"SkippedTransactions,omitempty"`
+}
+
+// SkippedTransaction is a row of an upload that could not be imported.
+type SkippedTransaction struct {
+	Date        string `json:"date"`
+	ProductName string `json:"product_name"`
+	Currency    string `json:"currency"`
+	Reason      string `json:"reason"`
+}
+
+// ProgressFunc receives progress updates (0-100) from long-running operations.
+type ProgressFunc func(percent int, message string)
+
+// JobEnqueuer queues background jobs and wakes the workers that run them. It is satisfied
+// by jobs.Queue, which depends on the services and is therefore attached after creation.
+type JobEnqueuer interface {
+	Enqueue(userID, portfolioID int64, jobType string) (*model.BackgroundJob, error)
 }
 
 var (
 	ErrParsingFailed    = errors.New("csv parsing failed")
 	ErrProcessingFailed = errors.New("transaction processing failed")
+	ErrNoJobQueue       = errors.New("no job queue attached")
 )
 
 // UploadService defines the interface for the core upload processing logic.
 type UploadService interface {
-	ProcessUpload(fileReader io.Reader, userID int64, portfolioID int64, source string, filename string, filesize int64) (*UploadResult, error)
+	// ProcessUpload imports the files of one upload, all from the same source.
+	ProcessUpload(files []io.Reader, userID int64, portfolioID int64, source string, filename string, filesize int64) (*UploadResult, error)
 	GetLatestUploadResult(userID int64, portfolioID int64) (*UploadResult, error)
 	GetDividendTaxSummary(userID int64, portfolioID int64) (models.DividendTaxResult, error)
 	GetDividendTransactions(userID int64, portfolioID int64) ([]models.ProcessedTransaction, error)
@@ -43,13 +70,19 @@ type UploadService interface {
 
 	GetDividendMetrics(userID int64, portfolioID int64) (*models.DividendMetricsResult, error)
 	RebuildUserHistory(userID int64, portfolioID int64) error
+	RebuildUserHistoryWithProgress(ctx context.Context, userID int64, portfolioID int64, progress ProgressFunc) error
 	RefreshDailySnapshot(userID int64, portfolioID int64) error
+	RepricePortfolio(ctx context.Context, userID int64, portfolioID int64) error
+	// SetJobQueue attaches the queue that runs the history rebuilds triggered by uploads.
+	SetJobQueue(queue JobEnqueuer)
 }
 
 type PriceInfo struct {
-	Status   string
-	Price    float64
-	Currency string
+	Status        string
+	Price         float64 // In EUR
+	Currency      string
+	LocalPrice    float64 // As quoted, before conversion to EUR
+	LocalCurrency string
 }
 
 type PriceMap map[string]float64
@@ -58,7 +91,17 @@ type PriceMap map[string]float64
 type PriceService interface {
 	GetCurrentPrices(isins []string) (map[string]PriceInfo, error)
 	GetHistoricalPrices(ticker string) (PriceMap, string, error)
-	EnsureBenchmarkData() error
+	EnsureBenchmarkData(tickers ...string) error
+	// ResolveTicker maps a ticker or ISIN entered by a user to a Yahoo ticker.
+	ResolveTicker(symbol string) (string, error)
 	// NOVO MÉTODO ADICIONADO AQUI
 	GetLastYearDividends(ticker string) (map[time.Month]float64, string, error)
+	// GetDividendEvents returns the stored dividend events of a ticker from a date on, refreshing them daily.
+	GetDividendEvents(ticker string, from time.Time) ([]models.DividendEvent, error)
+	// RefreshAllPrices re-fetches today's price for every mapped ticker, overwriting the cache.
+	RefreshAllPrices(ctx context.Context) (int, error)
+	// RefreshPricesForISINs does the same for the tickers mapped to the given ISINs.
+	RefreshPricesForISINs(ctx context.Context, isins []string) (int, error)
+	// FetchFundComposition returns provider look-through weights (dimension -> bucket -> weight) of a fund.
+	FetchFundComposition(isin string) (map[string]map[string]float64, error)
 }
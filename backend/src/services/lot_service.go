@@ -0,0 +1,172 @@
+// backend/src/services/lot_service.go
+package services
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/username/taxfolio/backend/src/logger"
+	"github.com/username/taxfolio/backend/src/models"
+	"github.com/username/taxfolio/backend/src/utils"
+)
+
+// LotService values open FIFO purchase lots at current prices.
+type LotService interface {
+	GetUnrealizedLots(userID int64, portfolioID int64) (*models.UnrealizedSummary, error)
+}
+
+type lotServiceImpl struct {
+	uploadService UploadService
+	priceService  PriceService
+}
+
+func NewLotService(uploadService UploadService, priceService PriceService) LotService {
+	return &lotServiceImpl{uploadService: uploadService, priceService: priceService}
+}
+
+// latestOpenLots returns the currently open purchase lots per ISIN, oldest first.
+func latestOpenLots(uploadService UploadService, userID int64, portfolioID int64) (map[string][]models.PurchaseLot, error) {
+	holdingsByYear, err := uploadService.GetStockHoldings(userID, portfolioID)
+	if err != nil {
+		return nil, err
+	}
+	latestYear := ""
+	for year := range holdingsByYear {
+		if year > latestYear {
+			latestYear = year
+		}
+	}
+	lots := make(map[string][]models.PurchaseLot)
+	for _, lot := range holdingsByYear[latestYear] {
+		lots[lot.ISIN] = append(lots[lot.ISIN], lot)
+	}
+	for isin := range lots {
+		sort.SliceStable(lots[isin], func(i, j int) bool {
+			return utils.ParseDate(lots[isin][i].BuyDate).Before(utils.ParseDate(lots[isin][j].BuyDate))
+		})
+	}
+	return lots, nil
+}
+
+// normalizeCurrency treats the pence quotes of London listings (GBp, GBX) as one currency.
+func normalizeCurrency(currency string) string {
+	if currency == "GBp" || strings.EqualFold(currency, "GBX") {
+		return "GBX"
+	}
+	return strings.ToUpper(currency)
+}
+
+// holdingPeriod classifies a lot by the days between purchase and asOf.
+func holdingPeriod(buyDate string, asOf time.Time) (int, string) {
+	bought := utils.ParseDate(buyDate)
+	if bought.IsZero() {
+		return 0, models.HoldingPeriodShort
+	}
+	days := int(asOf.Sub(bought).Hours() / 24)
+	if days >= models.LongTermHoldingDays {
+		return days, models.HoldingPeriodLong
+	}
+	return days, models.HoldingPeriodShort
+}
+
+// valueLot prices one open lot. The lot's exchange rate is implied by its local and EUR
+// cost, today's by the local and EUR price.
+func valueLot(lot models.PurchaseLot, price PriceInfo, asOf time.Time) models.UnrealizedLot {
+	result := models.UnrealizedLot{
+		ISIN:        lot.ISIN,
+		ProductName: lot.ProductName,
+		BuyDate:     lot.BuyDate,
+		Quantity:    lot.Quantity,
+		Currency:    lot.BuyCurrency,
+		BuyPrice:    lot.BuyPrice,
+		CostLocal:   utils.RoundFloat(math.Abs(lot.BuyAmount), 2),
+		CostEUR:     utils.RoundFloat(math.Abs(lot.BuyAmountEUR), 2),
+		PriceStatus: price.Status,
+	}
+	result.DaysHeld, result.HoldingPeriod = holdingPeriod(lot.BuyDate, asOf)
+	if price.Status != "OK" || price.Price <= 0 {
+		result.PriceStatus = "UNAVAILABLE"
+		return result
+	}
+
+	quantity := float64(lot.Quantity)
+	costLocal := math.Abs(lot.BuyAmount)
+	costEUR := math.Abs(lot.BuyAmountEUR)
+	result.CurrentPriceEUR = price.Price
+	result.CurrentValueEUR = utils.RoundFloat(quantity*price.Price, 2)
+	result.UnrealizedEUR = utils.RoundFloat(quantity*price.Price-costEUR, 2)
+	if costEUR > 0 {
+		result.UnrealizedPercent = (quantity*price.Price - costEUR) / costEUR * 100
+	}
+
+	if price.LocalPrice > 0 && normalizeCurrency(price.LocalCurrency) == normalizeCurrency(lot.BuyCurrency) {
+		currentRate := price.LocalPrice / price.Price // Local units per EUR today
+		valueLocal := quantity * price.LocalPrice
+		unrealizedLocal := utils.RoundFloat(valueLocal-costLocal, 2)
+		priceEffect := utils.RoundFloat((valueLocal-costLocal)/currentRate, 2)
+		fxEffect := utils.RoundFloat(costLocal/currentRate-costEUR, 2)
+		result.CurrentPriceLocal = price.LocalPrice
+		result.CurrentValueLocal = utils.RoundFloat(valueLocal, 2)
+		result.UnrealizedLocal = &unrealizedLocal
+		result.PriceEffectEUR = &priceEffect
+		result.FXEffectEUR = &fxEffect
+	}
+	return result
+}
+
+// GetUnrealizedLots returns every open lot with its unrealized result and holding period.
+func (s *lotServiceImpl) GetUnrealizedLots(userID int64, portfolioID int64) (*models.UnrealizedSummary, error) {
+	lotsByISIN, err := latestOpenLots(s.uploadService, userID, portfolioID)
+	if err != nil {
+		return nil, err
+	}
+
+	isins := make([]string, 0, len(lotsByISIN))
+	for isin := range lotsByISIN {
+		if !strings.HasPrefix(strings.ToLower(isin), "unknown") {
+			isins = append(isins, isin)
+		}
+	}
+	sort.Strings(isins)
+	prices, err := s.priceService.GetCurrentPrices(isins)
+	if err != nil {
+		logger.L.Warn("Could not fetch some or all current prices", "error", err)
+	}
+
+	now := time.Now().UTC()
+	asOf := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	summary := &models.UnrealizedSummary{Lots: []models.UnrealizedLot{}}
+	for _, isin := range isins {
+		for _, lot := range lotsByISIN[isin] {
+			valued := valueLot(lot, prices[isin], asOf)
+			summary.Lots = append(summary.Lots, valued)
+			if valued.PriceStatus != "OK" {
+				summary.UnpricedLots++
+				continue
+			}
+			summary.TotalCostEUR += valued.CostEUR
+			summary.TotalValueEUR += valued.CurrentValueEUR
+			summary.TotalUnrealizedEUR += valued.UnrealizedEUR
+			if valued.HoldingPeriod == models.HoldingPeriodLong {
+				summary.LongTermUnrealizedEUR += valued.UnrealizedEUR
+			} else {
+				summary.ShortTermUnrealizedEUR += valued.UnrealizedEUR
+			}
+			if valued.PriceEffectEUR != nil {
+				summary.PriceEffectEUR += *valued.PriceEffectEUR
+				summary.FXEffectEUR += *valued.FXEffectEUR
+			}
+		}
+	}
+
+	summary.TotalCostEUR = utils.RoundFloat(summary.TotalCostEUR, 2)
+	summary.TotalValueEUR = utils.RoundFloat(summary.TotalValueEUR, 2)
+	summary.TotalUnrealizedEUR = utils.RoundFloat(summary.TotalUnrealizedEUR, 2)
+	summary.ShortTermUnrealizedEUR = utils.RoundFloat(summary.ShortTermUnrealizedEUR, 2)
+	summary.LongTermUnrealizedEUR = utils.RoundFloat(summary.LongTermUnrealizedEUR, 2)
+	summary.PriceEffectEUR = utils.RoundFloat(summary.PriceEffectEUR, 2)
+	summary.FXEffectEUR = utils.RoundFloat(summary.FXEffectEUR, 2)
+	return summary, nil
+}
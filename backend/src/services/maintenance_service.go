@@ -0,0 +1,123 @@
+// backend/src/services/maintenance_service.go
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/username/taxfolio/backend/src/database"
+	"github.com/username/taxfolio/backend/src/logger"
+)
+
+// MaintenanceService groups the system-wide tasks executed by the background scheduler.
+type MaintenanceService interface {
+	RefreshMarketPrices(ctx context.Context) error
+	RefreshAllSnapshots(ctx context.Context) error
+	RefreshAllUserMetrics(ctx context.Context) error
+}
+
+type maintenanceServiceImpl struct {
+	uploadService UploadService
+	priceService  PriceService
+}
+
+func NewMaintenanceService(uploadService UploadService, priceService PriceService) MaintenanceService {
+	return &maintenanceServiceImpl{
+		uploadService: uploadService,
+		priceService:  priceService,
+	}
+}
+
+type portfolioRef struct {
+	UserID      int64
+	PortfolioID int64
+}
+
+// RefreshMarketPrices updates today's cached price for every mapped ticker.
+func (s *maintenanceServiceImpl) RefreshMarketPrices(ctx context.Context) error {
+	_, err := s.priceService.RefreshAllPrices(ctx)
+	return err
+}
+
+// RefreshAllSnapshots writes today's snapshot for every portfolio that has transactions,
+// backfilling any gap in the history along the way.
+func (s *maintenanceServiceImpl) RefreshAllSnapshots(ctx context.Context) error {
+	portfolios, err := listActivePortfolios()
+	if err != nil {
+		return err
+	}
+
+	var failed int
+	for _, pf := range portfolios {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := s.uploadService.RefreshDailySnapshot(pf.UserID, pf.PortfolioID); err != nil {
+			logger.L.Error("Scheduled snapshot refresh failed", "userID", pf.UserID, "portfolioID", pf.PortfolioID, "error", err)
+			failed++
+		}
+	}
+
+	logger.L.Info("Scheduled snapshot refresh complete", "portfolios", len(portfolios), "failed", failed)
+	if failed > 0 {
+		return fmt.Errorf("%d of %d portfolio snapshots failed", failed, len(portfolios))
+	}
+	return nil
+}
+
+// RefreshAllUserMetrics recomputes portfolio_value_eur and top_5_holdings for every user with data.
+func (s *maintenanceServiceImpl) RefreshAllUserMetrics(ctx context.Context) error {
+	portfolios, err := listActivePortfolios()
+	if err != nil {
+		return err
+	}
+
+	// UpdateUserPortfolioMetrics aggregates across all portfolios of a user,
+	// so a single call per user is enough.
+	seenUsers := make(map[int64]bool)
+	var failed, total int
+	for _, pf := range portfolios {
+		if seenUsers[pf.UserID] {
+			continue
+		}
+		seenUsers[pf.UserID] = true
+		total++
+
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := s.uploadService.UpdateUserPortfolioMetrics(pf.UserID, pf.PortfolioID); err != nil {
+			logger.L.Error("Scheduled metrics refresh failed", "userID", pf.UserID, "error", err)
+			failed++
+		}
+	}
+
+	logger.L.Info("Scheduled user metrics refresh complete", "users", total, "failed", failed)
+	if failed > 0 {
+		return fmt.Errorf("%d of %d user metric refreshes failed", failed, total)
+	}
+	return nil
+}
+
+// listActivePortfolios returns every portfolio that holds at least one processed transaction.
+func listActivePortfolios() ([]portfolioRef, error) {
+	rows, err := database.DB.Query(`
+		SELECT p.user_id, p.id
+		FROM portfolios p
+		WHERE EXISTS (SELECT 1 FROM processed_transactions pt WHERE pt.portfolio_id = p.id)
+		ORDER BY p.user_id, p.id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list portfolios: %w", err)
+	}
+	defer rows.Close()
+
+	var portfolios []portfolioRef
+	for rows.Next() {
+		var ref portfolioRef
+		if err := rows.Scan(&ref.UserID, &ref.PortfolioID); err != nil {
+			return nil, fmt.Errorf("failed to scan portfolio row: %w", err)
+		}
+		portfolios = append(portfolios, ref)
+	}
+	return portfolios, rows.Err()
+}
@@ -0,0 +1,213 @@
+// backend/src/services/performance_service.go
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/username/taxfolio/backend/src/database"
+	"github.com/username/taxfolio/backend/src/logger"
+	"github.com/username/taxfolio/backend/src/models"
+	"github.com/username/taxfolio/backend/src/utils"
+)
+
+var ErrInvalidPeriod = errors.New("invalid period")
+
+// PerformanceService computes portfolio returns from the stored daily snapshots.
+type PerformanceService interface {
+	// GetReturns computes TWR and MWR for a period. from/to (YYYY-MM-DD) are only used for the custom period.
+	GetReturns(userID int64, portfolioID int64, period, from, to string) (*models.ReturnsResult, error)
+}
+
+type performanceServiceImpl struct{}
+
+func NewPerformanceService() PerformanceService {
+	return &performanceServiceImpl{}
+}
+
+// snapshotPoint is one row of portfolio_snapshots as used by the analytics services.
+type snapshotPoint struct {
+	Date               time.Time
+	Equity             float64
+	CumulativeCashFlow float64
+}
+
+// fetchPortfolioSnapshots loads all snapshots of a portfolio in date order.
+func fetchPortfolioSnapshots(userID int64, portfolioID int64) ([]snapshotPoint, error) {
+	rows, err := database.DB.Query(`
+		SELECT date, total_equity, cumulative_net_cashflow
+		FROM portfolio_snapshots
+		WHERE user_id = ? AND portfolio_id = ?
+		ORDER BY date ASC`, userID, portfolioID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query snapshots: %w", err)
+	}
+	defer rows.Close()
+
+	var points []snapshotPoint
+	for rows.Next() {
+		var dateStr string
+		var p snapshotPoint
+		if err := rows.Scan(&dateStr, &p.Equity, &p.CumulativeCashFlow); err != nil {
+			return nil, fmt.Errorf("failed to scan snapshot: %w", err)
+		}
+		p.Date, err = time.Parse("2006-01-02", dateStr)
+		if err != nil {
+			logger.L.Warn("Skipping snapshot with invalid date", "date", dateStr)
+			continue
+		}
+		points = append(points, p)
+	}
+	return points, rows.Err()
+}
+
+// resolvePeriod turns a named period into an inclusive [start, end] date range.
+func resolvePeriod(period, from, to string, today time.Time) (time.Time, time.Time, error) {
+	today = time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, time.UTC)
+	switch period {
+	case models.PeriodYTD:
+		return time.Date(today.Year(), 1, 1, 0, 0, 0, 0, time.UTC), today, nil
+	case models.Period1Y:
+		return today.AddDate(-1, 0, 0), today, nil
+	case models.Period3Y:
+		return today.AddDate(-3, 0, 0), today, nil
+	case "", models.PeriodInception:
+		return time.Time{}, today, nil
+	case models.PeriodCustom:
+		start, err := time.Parse("2006-01-02", from)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("%w: invalid from date", ErrInvalidPeriod)
+		}
+		end := today
+		if to != "" {
+			end, err = time.Parse("2006-01-02", to)
+			if err != nil {
+				return time.Time{}, time.Time{}, fmt.Errorf("%w: invalid to date", ErrInvalidPeriod)
+			}
+		}
+		if end.Before(start) {
+			return time.Time{}, time.Time{}, fmt.Errorf("%w: from must be before to", ErrInvalidPeriod)
+		}
+		return start, end, nil
+	default:
+		return time.Time{}, time.Time{}, fmt.Errorf("%w: %s", ErrInvalidPeriod, period)
+	}
+}
+
+// GetReturns computes the returns of a portfolio over a period.
+//
+// TWR chain-links daily sub-period returns, treating the day's external cash flow
+// (change in cumulative_net_cashflow) as arriving at the start of the day:
+//
+//	r_t = V_t / (V_{t-1} + CF_t) - 1
+//
+// MWR is the XIRR of the value before the period, the dated cash flows inside it and
+// the value at the end.
+func (s *performanceServiceImpl) GetReturns(userID int64, portfolioID int64, period, from, to string) (*models.ReturnsResult, error) {
+	if period == "" {
+		period = models.PeriodInception
+	}
+	start, end, err := resolvePeriod(period, from, to, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	points, err := fetchPortfolioSnapshots(userID, portfolioID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &models.ReturnsResult{Period: period}
+	series := sliceSnapshots(points, start, end)
+	if len(series.inRange) == 0 {
+		return result, nil
+	}
+
+	result.StartDate = series.inRange[0].Date.Format("2006-01-02")
+	result.EndDate = series.inRange[len(series.inRange)-1].Date.Format("2006-01-02")
+	result.StartValue = series.baseEquity
+	result.EndValue = series.inRange[len(series.inRange)-1].Equity
+	result.HasData = true
+
+	// Count the base day so that a one-day period spans one day.
+	firstDay := series.inRange[0].Date
+	if series.hasBase {
+		firstDay = series.baseDate
+	}
+	result.Days = int(series.inRange[len(series.inRange)-1].Date.Sub(firstDay).Hours() / 24)
+
+	growth := 1.0
+	prevEquity := series.baseEquity
+	prevCashFlow := series.baseCashFlow
+	flows := []utils.CashFlow{}
+	if series.hasBase && series.baseEquity != 0 {
+		flows = append(flows, utils.CashFlow{Date: series.baseDate, Amount: -series.baseEquity})
+	}
+
+	for _, p := range series.inRange {
+		dayFlow := p.CumulativeCashFlow - prevCashFlow
+		result.NetCashFlow += dayFlow
+		if dayFlow != 0 {
+			flows = append(flows, utils.CashFlow{Date: p.Date, Amount: -dayFlow})
+		}
+
+		invested := prevEquity + dayFlow
+		if invested > 0 {
+			growth *= p.Equity / invested
+		}
+
+		prevEquity = p.Equity
+		prevCashFlow = p.CumulativeCashFlow
+	}
+
+	result.TWR = growth - 1
+	result.Gain = result.EndValue - result.StartValue - result.NetCashFlow
+	if result.Days >= int(utils.DaysPerYear) {
+		annualized := utils.AnnualizeReturn(result.TWR, result.Days)
+		result.TWRAnnualized = &annualized
+	}
+
+	if result.EndValue != 0 {
+		flows = append(flows, utils.CashFlow{Date: series.inRange[len(series.inRange)-1].Date, Amount: result.EndValue})
+	}
+	if irr, err := utils.XIRR(flows); err == nil {
+		periodReturn := utils.DeannualizeReturn(irr, result.Days)
+		result.MWRAnnualized = &irr
+		result.MWR = &periodReturn
+	} else {
+		logger.L.Debug("XIRR did not converge", "userID", userID, "portfolioID", portfolioID, "period", period, "error", err)
+	}
+
+	return result, nil
+}
+
+// snapshotSeries is the part of a portfolio's history that falls in a period, together
+// with the state on the last day before it.
+type snapshotSeries struct {
+	inRange      []snapshotPoint
+	hasBase      bool
+	baseDate     time.Time
+	baseEquity   float64
+	baseCashFlow float64
+}
+
+// sliceSnapshots selects the snapshots within [start, end]. The last snapshot before
+// start becomes the base; without one the period starts at inception from zero.
+func sliceSnapshots(points []snapshotPoint, start, end time.Time) snapshotSeries {
+	var series snapshotSeries
+	for _, p := range points {
+		if p.Date.Before(start) {
+			series.hasBase = true
+			series.baseDate = p.Date
+			series.baseEquity = p.Equity
+			series.baseCashFlow = p.CumulativeCashFlow
+			continue
+		}
+		if p.Date.After(end) {
+			break
+		}
+		series.inRange = append(series.inRange, p)
+	}
+	return series
+}
@@ -2,6 +2,7 @@
 package services
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
 	"fmt"
@@ -16,7 +17,9 @@ import (
 	"github.com/username/taxfolio/backend/src/database"
 	"github.com/username/taxfolio/backend/src/logger"
 	"github.com/username/taxfolio/backend/src/model"
+	"github.com/username/taxfolio/backend/src/models"
 	"github.com/username/taxfolio/backend/src/processors"
+	"github.com/username/taxfolio/backend/src/utils"
 	"golang.org/x/net/publicsuffix"
 )
 
@@ -27,6 +30,18 @@ var manualTickerOverrides = map[string]string{
 	"IE00BK5BQT80": "VWRA.L",
 }
 
+// DefaultBenchmarkTicker is used when a portfolio has no benchmark configured.
+const DefaultBenchmarkTicker = "SPY"
+
+// minStoredHistoryDays is the number of stored rows below which a ticker's history is refetched.
+const minStoredHistoryDays = 30
+
+// Dividend events are kept for dividendHistoryYears and re-fetched after dividendEventsMaxAge.
+const (
+	dividendHistoryYears = 10
+	dividendEventsMaxAge = 24 * time.Hour
+)
+
 // --- API Response Structs ---
 
 type yahooSearchResponse struct {
@@ -108,6 +123,53 @@ type yahooQuoteSummaryResponse struct {
 	} `json:"quoteSummary"`
 }
 
+type yahooCalendarEventsResponse struct {
+	QuoteSummary struct {
+		Result []struct {
+			CalendarEvents struct {
+				ExDividendDate yahooRawValue `json:"exDividendDate"`
+				DividendDate   yahooRawValue `json:"dividendDate"` // Pay date
+			} `json:"calendarEvents"`
+		} `json:"result"`
+		Error interface{} `json:"error"`
+	} `json:"quoteSummary"`
+}
+
+type yahooRawValue struct {
+	Raw float64 `json:"raw"`
+}
+
+type yahooTopHoldingsResponse struct {
+	QuoteSummary struct {
+		Result []struct {
+			TopHoldings struct {
+				StockPosition    yahooRawValue              `json:"stockPosition"`
+				BondPosition     yahooRawValue              `json:"bondPosition"`
+				CashPosition     yahooRawValue              `json:"cashPosition"`
+				OtherPosition    yahooRawValue              `json:"otherPosition"`
+				SectorWeightings []map[string]yahooRawValue `json:"sectorWeightings"`
+			} `json:"topHoldings"`
+		} `json:"result"`
+		Error interface{} `json:"error"`
+	} `json:"quoteSummary"`
+}
+
+// yahooSectorNames maps the keys of topHoldings.sectorWeightings to the sector names
+// used by assetProfile, so that look-through and direct holdings share buckets.
+var yahooSectorNames = map[string]string{
+	"realestate":             "Real Estate",
+	"consumer_cyclical":      "Consumer Cyclical",
+	"basic_materials":        "Basic Materials",
+	"consumer_defensive":     "Consumer Defensive",
+	"technology":             "Technology",
+	"communication_services": "Communication Services",
+	"financial_services":     "Financial Services",
+	"utilities":              "Utilities",
+	"industrials":            "Industrials",
+	"energy":                 "Energy",
+	"healthcare":             "Healthcare",
+}
+
 type yahooEventsResponse struct {
 	Chart struct {
 		Result []struct {
@@ -313,9 +375,11 @@ func (s *priceServiceImpl) GetCurrentPrices(isins []string) (map[string]PriceInf
 		}
 
 		results[isin] = PriceInfo{
-			Status:   "OK",
-			Price:    priceEUR,
-			Currency: "EUR",
+			Status:        "OK",
+			Price:         priceEUR,
+			Currency:      "EUR",
+			LocalPrice:    priceInfo.Price,
+			LocalCurrency: priceInfo.Currency,
 		}
 	}
 	return results, nil
@@ -622,70 +686,179 @@ func (s *priceServiceImpl) GetHistoricalPrices(ticker string) (PriceMap, string,
 }
 
 func (s *priceServiceImpl) GetLastYearDividends(ticker string) (map[time.Month]float64, string, error) {
-	s.ensureSession()
-
 	now := time.Now()
-	oneYearAgo := now.AddDate(-1, 0, 0)
-	period1 := oneYearAgo.Unix()
-	period2 := now.Unix()
-
-	url := fmt.Sprintf("https://query1.finance.yahoo.com/v8/finance/chart/%s?symbol=%s&period1=%d&period2=%d&interval=1d&events=div&crumb=%s", ticker, ticker, period1, period2, s.crumb)
-
-	req, err := http.NewRequest("GET", url, nil)
+	events, err := s.GetDividendEvents(ticker, now.AddDate(-1, 0, 0))
 	if err != nil {
 		return nil, "", err
 	}
-	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Safari/537.36")
 
-	resp, err := s.httpClient.Do(req)
-	if err != nil {
-		return nil, "", fmt.Errorf("failed to call Yahoo events API: %w", err)
+	monthlyDividends := make(map[time.Month]float64)
+	currency := ""
+	today := now.Format("2006-01-02")
+	for _, event := range events {
+		if event.Source != models.DividendEventHistory || event.ExDate > today || event.Amount <= 0 {
+			continue
+		}
+		exDate, err := time.Parse("2006-01-02", event.ExDate)
+		if err != nil {
+			continue
+		}
+		monthlyDividends[exDate.Month()] += event.Amount
+		currency = event.Currency
 	}
-	defer resp.Body.Close()
+	return monthlyDividends, currency, nil
+}
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, "", fmt.Errorf("yahoo API error: status %d", resp.StatusCode)
+// GetDividendEvents returns the dividend events of a ticker with an ex-date on or after
+// `from`. Events are re-fetched from Yahoo at most once a day; the stored ones are used
+// when Yahoo is unavailable.
+func (s *priceServiceImpl) GetDividendEvents(ticker string, from time.Time) ([]models.DividendEvent, error) {
+	fetchedAt, err := model.GetDividendEventsFetchedAt(database.DB, ticker)
+	if err != nil {
+		return nil, err
+	}
+	if time.Since(fetchedAt) > dividendEventsMaxAge {
+		events, err := s.fetchDividendEvents(ticker)
+		if err != nil {
+			logger.L.Warn("Failed to fetch dividend events, using stored ones", "ticker", ticker, "error", err)
+		} else if err := model.ReplaceDividendEvents(database.DB, ticker, events); err != nil {
+			return nil, err
+		}
 	}
+	return model.GetDividendEvents(database.DB, ticker, from.Format("2006-01-02"))
+}
+
+// fetchDividendEvents reads the dividend history of a ticker from the chart API and adds
+// the pay date and the next ex-date from the calendarEvents module.
+func (s *priceServiceImpl) fetchDividendEvents(ticker string) ([]models.DividendEvent, error) {
+	s.ensureSession()
 
+	now := time.Now()
+	url := fmt.Sprintf("https://query1.finance.yahoo.com/v8/finance/chart/%s?symbol=%s&period1=%d&period2=%d&interval=1mo&events=div&crumb=%s",
+		ticker, ticker, now.AddDate(-dividendHistoryYears, 0, 0).Unix(), now.Unix(), s.crumb)
 	var data yahooEventsResponse
-	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
-		return nil, "", err
+	if err := s.getYahooJSON(url, &data); err != nil {
+		return nil, fmt.Errorf("failed to call Yahoo events API: %w", err)
 	}
 
+	events := []models.DividendEvent{}
 	if len(data.Chart.Result) == 0 {
-		return make(map[time.Month]float64), "", nil
+		return events, nil
 	}
-
 	result := data.Chart.Result[0]
-	currency := result.Meta.Currency
-	dividendsMap := result.Events.Dividends
-
-	monthlyDividends := make(map[time.Month]float64)
+	for _, div := range result.Events.Dividends {
+		if div.Amount <= 0 {
+			continue
+		}
+		events = append(events, models.DividendEvent{
+			Ticker:   ticker,
+			ExDate:   time.Unix(div.Date, 0).UTC().Format("2006-01-02"),
+			Amount:   div.Amount,
+			Currency: result.Meta.Currency,
+			Source:   models.DividendEventHistory,
+		})
+	}
+	sort.Slice(events, func(i, j int) bool { return events[i].ExDate < events[j].ExDate })
 
-	for _, div := range dividendsMap {
-		if div.Amount > 0 {
-			divDate := time.Unix(div.Date, 0)
-			monthlyDividends[divDate.Month()] += div.Amount
+	calendarURL := fmt.Sprintf("https://query1.finance.yahoo.com/v10/finance/quoteSummary/%s?modules=calendarEvents&crumb=%s", ticker, s.crumb)
+	var calendar yahooCalendarEventsResponse
+	if err := s.getYahooJSON(calendarURL, &calendar); err != nil {
+		logger.L.Debug("No dividend calendar from Yahoo", "ticker", ticker, "error", err)
+		return events, nil
+	}
+	if len(calendar.QuoteSummary.Result) == 0 || calendar.QuoteSummary.Result[0].CalendarEvents.ExDividendDate.Raw <= 0 {
+		return events, nil
+	}
+	calendarEvents := calendar.QuoteSummary.Result[0].CalendarEvents
+	exDate := time.Unix(int64(calendarEvents.ExDividendDate.Raw), 0).UTC().Format("2006-01-02")
+	payDate := ""
+	if calendarEvents.DividendDate.Raw > 0 {
+		payDate = time.Unix(int64(calendarEvents.DividendDate.Raw), 0).UTC().Format("2006-01-02")
+		if payDate < exDate {
+			payDate = "" // The pay date of an earlier dividend
 		}
 	}
+	for i := range events {
+		if events[i].ExDate == exDate {
+			events[i].PayDate = payDate
+			return events, nil
+		}
+	}
+	if len(events) > 0 && exDate > events[len(events)-1].ExDate {
+		last := events[len(events)-1]
+		events = append(events, models.DividendEvent{
+			Ticker:   ticker,
+			ExDate:   exDate,
+			PayDate:  payDate,
+			Amount:   last.Amount,
+			Currency: last.Currency,
+			Source:   models.DividendEventAnnounced,
+		})
+	}
+	return events, nil
+}
 
-	return monthlyDividends, currency, nil
+// getYahooJSON performs a GET request against Yahoo and decodes the JSON response into out.
+func (s *priceServiceImpl) getYahooJSON(url string, out interface{}) error {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Safari/537.36")
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == 401 {
+		s.mu.Lock()
+		s.isInitialized = false
+		s.mu.Unlock()
+		return fmt.Errorf("status 401 (Unauthorized) - Crumb invalid")
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("yahoo API error: status %d", resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// EnsureBenchmarkData makes sure the full price history of each ticker is stored in
+// daily_prices and up to date. Without arguments it covers the default benchmark (SPY).
+func (s *priceServiceImpl) EnsureBenchmarkData(tickers ...string) error {
+	if len(tickers) == 0 {
+		tickers = []string{DefaultBenchmarkTicker}
+	}
+	var errs []string
+	for _, ticker := range tickers {
+		if err := s.ensureTickerHistory(ticker); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("failed to ensure benchmark data: %s", strings.Join(errs, "; "))
+	}
+	return nil
 }
 
-func (s *priceServiceImpl) EnsureBenchmarkData() error {
-	benchmarkTicker := "SPY"
+func (s *priceServiceImpl) ensureTickerHistory(benchmarkTicker string) error {
+	// daily_prices also holds the single "today" price used for live valuations, so a
+	// recent row alone does not mean the history has been stored.
 	var count int
+	var latest sql.NullString
 	yesterday := time.Now().AddDate(0, 0, -1).Format("2006-01-02")
-	err := database.DB.QueryRow("SELECT COUNT(*) FROM daily_prices WHERE ticker_symbol = ? AND date >= ?", benchmarkTicker, yesterday).Scan(&count)
-	if err == nil && count > 0 {
+	err := database.DB.QueryRow("SELECT COUNT(*), MAX(date) FROM daily_prices WHERE ticker_symbol = ?", benchmarkTicker).Scan(&count, &latest)
+	if err == nil && count >= minStoredHistoryDays && latest.Valid && latest.String >= yesterday {
 		return nil
 	}
-	prices, _, err := s.GetHistoricalPrices(benchmarkTicker)
+	prices, currency, err := s.GetHistoricalPrices(benchmarkTicker)
 	if err != nil {
-		return fmt.Errorf("failed to fetch benchmark history: %w", err)
+		return fmt.Errorf("failed to fetch benchmark history for %s: %w", benchmarkTicker, err)
 	}
 	if len(prices) == 0 {
-		return fmt.Errorf("no benchmark prices returned")
+		return fmt.Errorf("no benchmark prices returned for %s", benchmarkTicker)
+	}
+	if currency == "" {
+		currency = "USD"
 	}
 	tx, err := database.DB.Begin()
 	if err != nil {
@@ -697,6 +870,7 @@ func (s *priceServiceImpl) EnsureBenchmarkData() error {
 		VALUES (?, ?, ?, ?, ?)
 		ON CONFLICT(ticker_symbol, date) DO UPDATE SET
 			price = excluded.price,
+			currency = excluded.currency,
 			updated_at = excluded.updated_at;
 	`)
 	if err != nil {
@@ -704,9 +878,9 @@ func (s *priceServiceImpl) EnsureBenchmarkData() error {
 	}
 	defer stmt.Close()
 	for date, price := range prices {
-		_, err := stmt.Exec(benchmarkTicker, date, price, "USD", time.Now())
+		_, err := stmt.Exec(benchmarkTicker, date, price, currency, time.Now())
 		if err != nil {
-			logger.L.Warn("Failed to save benchmark price", "date", date, "error", err)
+			logger.L.Warn("Failed to save benchmark price", "ticker", benchmarkTicker, "date", date, "error", err)
 			continue
 		}
 	}
@@ -716,6 +890,29 @@ func (s *priceServiceImpl) EnsureBenchmarkData() error {
 	return nil
 }
 
+// ResolveTicker turns a user-entered symbol into a Yahoo ticker. ISINs are looked up
+// through isin_ticker_map (and the Yahoo search API when unknown); anything else is
+// treated as a ticker already.
+func (s *priceServiceImpl) ResolveTicker(symbol string) (string, error) {
+	symbol = strings.ToUpper(strings.TrimSpace(symbol))
+	if symbol == "" {
+		return "", fmt.Errorf("empty symbol")
+	}
+	if !utils.IsISIN(symbol) {
+		return symbol, nil
+	}
+	s.ensureSession()
+	isinToTickerMap, err := s.getIsinToTickerMap([]string{symbol})
+	if err != nil {
+		return "", err
+	}
+	ticker, ok := isinToTickerMap[symbol]
+	if !ok || ticker == "" {
+		return "", fmt.Errorf("no ticker found for ISIN %s", symbol)
+	}
+	return ticker, nil
+}
+
 func (s *priceServiceImpl) fetchMetadata(ticker string) (string, string, string, error) {
 	url := fmt.Sprintf("https://query1.finance.yahoo.com/v10/finance/quoteSummary/%s?modules=assetProfile,quoteType,fundProfile,summaryProfile&crumb=%s", ticker, s.crumb)
 	req, err := http.NewRequest("GET", url, nil)
@@ -759,3 +956,164 @@ func (s *priceServiceImpl) fetchMetadata(ticker string) (string, string, string,
 	logger.L.Info("Metadata fetched", "ticker", ticker, "sector", sector, "type", qType)
 	return sector, industry, qType, nil
 }
+
+// RefreshAllPrices fetches the latest quote for every ticker in isin_ticker_map and
+// overwrites today's cached price. It is meant to run after market close so that
+// snapshots use closing prices instead of whatever was cached during the day.
+func (s *priceServiceImpl) RefreshAllPrices(ctx context.Context) (int, error) {
+	s.ensureSession()
+
+	tickers, err := model.GetAllMappedTickers(database.DB)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list mapped tickers: %w", err)
+	}
+
+	refreshed, failed, err := s.refreshTickerPrices(ctx, tickers)
+	if err != nil {
+		return refreshed, err
+	}
+
+	logger.L.Info("Refreshed mapped ticker prices", "total", len(tickers), "refreshed", refreshed, "fetchFailed", failed.fetched, "storeFailed", failed.stored)
+	if failed.stored > 0 {
+		return refreshed, fmt.Errorf("failed to store %d of %d ticker prices", failed.stored, len(tickers))
+	}
+	return refreshed, nil
+}
+
+// RefreshPricesForISINs overwrites today's cached price for the tickers mapped to the given ISINs.
+func (s *priceServiceImpl) RefreshPricesForISINs(ctx context.Context, isins []string) (int, error) {
+	s.ensureSession()
+
+	isinToTickerMap, err := s.getIsinToTickerMap(isins)
+	if err != nil {
+		return 0, err
+	}
+	uniqueTickers := make(map[string]bool)
+	var tickers []string
+	for _, ticker := range isinToTickerMap {
+		if ticker != "" && !uniqueTickers[ticker] {
+			uniqueTickers[ticker] = true
+			tickers = append(tickers, ticker)
+		}
+	}
+	refreshed, failed, err := s.refreshTickerPrices(ctx, tickers)
+	if err != nil {
+		return refreshed, err
+	}
+	if failed.stored > 0 {
+		return refreshed, fmt.Errorf("failed to store %d of %d ticker prices", failed.stored, len(tickers))
+	}
+	return refreshed, nil
+}
+
+// priceRefreshFailures counts the tickers whose quote could not be fetched, which happens for
+// delisted tickers, and those whose quote could not be stored, which points to a database problem.
+type priceRefreshFailures struct {
+	fetched, stored int
+}
+
+func (s *priceServiceImpl) refreshTickerPrices(ctx context.Context, tickers []string) (int, priceRefreshFailures, error) {
+	todayStr := time.Now().Format("2006-01-02")
+	refreshed := 0
+	var failed priceRefreshFailures
+	for _, ticker := range tickers {
+		if err := ctx.Err(); err != nil {
+			return refreshed, failed, err
+		}
+		time.Sleep(250 * time.Millisecond)
+		price, currency, err := s.getPriceForTicker(ticker)
+		if err != nil {
+			logger.L.Warn("Could not refresh price for ticker", "ticker", ticker, "error", err)
+			failed.fetched++
+			continue
+		}
+		if err := model.InsertOrUpdatePrice(database.DB, model.DailyPrice{
+			TickerSymbol: ticker,
+			Date:         todayStr,
+			Price:        price,
+			Currency:     currency,
+		}); err != nil {
+			logger.L.Error("Could not store refreshed price for ticker", "ticker", ticker, "error", err)
+			failed.stored++
+			continue
+		}
+		refreshed++
+	}
+	return refreshed, failed, nil
+}
+
+// FetchFundComposition returns the sector and asset-class weights of a fund from Yahoo's
+// topHoldings module, keyed by dimension and then bucket. Yahoo has no country data.
+func (s *priceServiceImpl) FetchFundComposition(isin string) (map[string]map[string]float64, error) {
+	ticker, err := s.ResolveTicker(isin)
+	if err != nil {
+		return nil, err
+	}
+	s.ensureSession()
+	url := fmt.Sprintf("https://query1.finance.yahoo.com/v10/finance/quoteSummary/%s?modules=topHoldings&crumb=%s", ticker, s.crumb)
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Safari/537.36")
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == 401 {
+		s.mu.Lock()
+		s.isInitialized = false
+		s.mu.Unlock()
+		return nil, fmt.Errorf("status 401 (Unauthorized) - Crumb invalid")
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("status %d", resp.StatusCode)
+	}
+	var data yahooTopHoldingsResponse
+	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
+		return nil, err
+	}
+	if len(data.QuoteSummary.Result) == 0 {
+		return nil, fmt.Errorf("no result")
+	}
+	holdings := data.QuoteSummary.Result[0].TopHoldings
+
+	composition := make(map[string]map[string]float64)
+	sectors := make(map[string]float64)
+	for _, entry := range holdings.SectorWeightings {
+		for key, value := range entry {
+			if value.Raw <= 0 {
+				continue
+			}
+			name, ok := yahooSectorNames[key]
+			if !ok {
+				name = key
+			}
+			sectors[name] += value.Raw
+		}
+	}
+	if len(sectors) > 0 {
+		composition[models.DimensionSector] = sectors
+	}
+
+	assetTypes := make(map[string]float64)
+	for name, value := range map[string]float64{
+		"EQUITY": holdings.StockPosition.Raw,
+		"BOND":   holdings.BondPosition.Raw,
+		"CASH":   holdings.CashPosition.Raw,
+		"OTHER":  holdings.OtherPosition.Raw,
+	} {
+		if value > 0 {
+			assetTypes[name] = value
+		}
+	}
+	if len(assetTypes) > 0 {
+		composition[models.DimensionAssetType] = assetTypes
+	}
+
+	if len(composition) == 0 {
+		return nil, fmt.Errorf("no composition data for %s", ticker)
+	}
+	return composition, nil
+}
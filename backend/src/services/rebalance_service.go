@@ -0,0 +1,377 @@
+// backend/src/services/rebalance_service.go
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/username/taxfolio/backend/src/database"
+	"github.com/username/taxfolio/backend/src/logger"
+	"github.com/username/taxfolio/backend/src/model"
+	"github.com/username/taxfolio/backend/src/models"
+	"github.com/username/taxfolio/backend/src/utils"
+)
+
+const maxPortfolioTargets = 100
+
+var (
+	ErrInvalidTargets        = errors.New("invalid targets")
+	ErrNoTargets             = errors.New("portfolio has no targets")
+	ErrInvalidRebalanceInput = errors.New("invalid rebalance constraints")
+)
+
+// RebalanceService manages target weights and builds rebalancing plans against them.
+type RebalanceService interface {
+	GetTargets(userID int64, portfolioID int64) (*models.TargetAllocation, error)
+	SetTargets(userID int64, allocation models.TargetAllocation) (*models.TargetAllocation, error)
+	DeleteTargets(userID int64, portfolioID int64) error
+	GetRebalancePlan(userID int64, portfolioID int64, constraints models.RebalanceConstraints) (*models.RebalancePlan, error)
+}
+
+type rebalanceServiceImpl struct {
+	uploadService UploadService
+	priceService  PriceService
+}
+
+func NewRebalanceService(uploadService UploadService, priceService PriceService) RebalanceService {
+	return &rebalanceServiceImpl{uploadService: uploadService, priceService: priceService}
+}
+
+func (s *rebalanceServiceImpl) GetTargets(userID int64, portfolioID int64) (*models.TargetAllocation, error) {
+	return model.GetPortfolioTargets(database.DB, userID, portfolioID)
+}
+
+func (s *rebalanceServiceImpl) DeleteTargets(userID int64, portfolioID int64) error {
+	return model.DeletePortfolioTargets(database.DB, userID, portfolioID)
+}
+
+// SetTargets validates and replaces the targets of a portfolio. Weights may be fractions or
+// percentages and must not add up to more than 100%; the rest is the cash target.
+func (s *rebalanceServiceImpl) SetTargets(userID int64, allocation models.TargetAllocation) (*models.TargetAllocation, error) {
+	if err := model.CheckPortfolioOwnership(database.DB, userID, allocation.PortfolioID); err != nil {
+		return nil, err
+	}
+	switch allocation.Dimension {
+	case models.DimensionISIN, models.DimensionSector, models.DimensionCountry, models.DimensionCurrency, models.DimensionAssetType:
+	default:
+		return nil, fmt.Errorf("%w: unknown dimension %q", ErrInvalidTargets, allocation.Dimension)
+	}
+	if len(allocation.Targets) == 0 || len(allocation.Targets) > maxPortfolioTargets {
+		return nil, fmt.Errorf("%w: between 1 and %d targets are required", ErrInvalidTargets, maxPortfolioTargets)
+	}
+
+	merged := make(map[string]float64)
+	sum := 0.0
+	for _, t := range allocation.Targets {
+		key := strings.TrimSpace(t.Key)
+		if key == "" || t.Weight <= 0 || math.IsNaN(t.Weight) || math.IsInf(t.Weight, 0) {
+			return nil, fmt.Errorf("%w: targets need a key and a positive weight", ErrInvalidTargets)
+		}
+		switch allocation.Dimension {
+		case models.DimensionISIN:
+			key = strings.ToUpper(key)
+			if !utils.IsISIN(key) {
+				return nil, fmt.Errorf("%w: %q is not an ISIN", ErrInvalidTargets, key)
+			}
+		case models.DimensionCountry:
+			if name := utils.GetCountryName(key); name != "" {
+				key = name
+			}
+		case models.DimensionCurrency, models.DimensionAssetType:
+			key = strings.ToUpper(key)
+		}
+		merged[key] += t.Weight
+		sum += t.Weight
+	}
+	if sum > 1.0001 {
+		// Percentages
+		for key := range merged {
+			merged[key] /= 100
+		}
+		sum /= 100
+	}
+	if sum > 1.0001 {
+		return nil, fmt.Errorf("%w: weights must add up to at most 100%%", ErrInvalidTargets)
+	}
+
+	allocation.Targets = make([]models.TargetWeight, 0, len(merged))
+	for key, weight := range merged {
+		allocation.Targets = append(allocation.Targets, models.TargetWeight{Key: key, Weight: weight})
+	}
+	sort.Slice(allocation.Targets, func(i, j int) bool {
+		if allocation.Targets[i].Weight != allocation.Targets[j].Weight {
+			return allocation.Targets[i].Weight > allocation.Targets[j].Weight
+		}
+		return allocation.Targets[i].Key < allocation.Targets[j].Key
+	})
+	if err := model.ReplacePortfolioTargets(database.DB, userID, allocation); err != nil {
+		return nil, fmt.Errorf("failed to save targets: %w", err)
+	}
+	return &allocation, nil
+}
+
+// rebalanceGroup is the set of holdings that share one target key.
+type rebalanceGroup struct {
+	key      string
+	target   float64
+	value    float64
+	holdings []models.HoldingWithValue
+}
+
+// GetRebalancePlan measures drift against the targets and suggests trades.
+//
+// Target values are computed on the total after the new cash arrives. Without
+// constraints, overweight keys are sold and underweight keys bought back to target;
+// holdings outside every target count as a target of zero.
+// With NoSells or NewCashOnly only buys are made, and when the budget does not cover
+// every shortfall it is shared in proportion to them. Money for a category is spread over
+// the holdings already in it, in proportion to their value. Sells are matched against the
+// open FIFO lots to estimate the realized gain they would trigger.
+func (s *rebalanceServiceImpl) GetRebalancePlan(userID int64, portfolioID int64, c models.RebalanceConstraints) (*models.RebalancePlan, error) {
+	if c.NewCashEUR < 0 || c.MinTradeEUR < 0 {
+		return nil, fmt.Errorf("%w: amounts cannot be negative", ErrInvalidRebalanceInput)
+	}
+	targets, err := model.GetPortfolioTargets(database.DB, userID, portfolioID)
+	if err != nil {
+		return nil, err
+	}
+	if len(targets.Targets) == 0 {
+		return nil, ErrNoTargets
+	}
+
+	holdings, err := s.uploadService.GetCurrentHoldingsWithValue(userID, portfolioID)
+	if err != nil {
+		return nil, err
+	}
+	cash, err := latestCashBalance(userID, portfolioID)
+	if err != nil {
+		return nil, err
+	}
+	isins := make([]string, 0, len(holdings))
+	for _, h := range holdings {
+		isins = append(isins, h.ISIN)
+	}
+	mappings, err := model.GetMappingsByISINs(database.DB, isins)
+	if err != nil {
+		logger.L.Warn("Could not load ISIN mappings for rebalance", "error", err)
+		mappings = map[string]model.ISINTickerMap{}
+	}
+
+	plan := &models.RebalancePlan{
+		Dimension:   targets.Dimension,
+		Constraints: c,
+		CashEUR:     cash,
+		Drift:       []models.DriftItem{},
+		Trades:      []models.RebalanceTrade{},
+		Warnings:    []string{},
+	}
+
+	groups := make(map[string]*rebalanceGroup)
+	groupFor := func(key string) *rebalanceGroup {
+		g, ok := groups[key]
+		if !ok {
+			g = &rebalanceGroup{key: key}
+			groups[key] = g
+		}
+		return g
+	}
+	for _, t := range targets.Targets {
+		groupFor(t.Key).target = t.Weight
+	}
+	holdingsValue := 0.0
+	for _, h := range holdings {
+		g := groupFor(holdingBucket(h, targets.Dimension, mappings))
+		g.value += h.MarketValueEUR
+		g.holdings = append(g.holdings, h)
+		holdingsValue += h.MarketValueEUR
+	}
+	plan.TotalValueEUR = holdingsValue + cash
+	totalAfter := plan.TotalValueEUR + c.NewCashEUR
+
+	keys := make([]string, 0, len(groups))
+	for key := range groups {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	// Amount to buy (positive) or sell (negative) per key.
+	amounts := make(map[string]float64, len(groups))
+	totalBuy := 0.0
+	for _, key := range keys {
+		g := groups[key]
+		diff := g.target*totalAfter - g.value
+		if diff < 0 && (c.NoSells || c.NewCashOnly) {
+			diff = 0
+		}
+		amounts[key] = diff
+		if diff > 0 {
+			totalBuy += diff
+		}
+	}
+
+	lots, err := latestOpenLots(s.uploadService, userID, portfolioID)
+	if err != nil {
+		logger.L.Warn("Could not load FIFO lots for gain estimate", "userID", userID, "portfolioID", portfolioID, "error", err)
+	}
+
+	// Sells go first so that buys are limited to what rounded sells actually raise.
+	traded := make(map[string]float64, len(groups))
+	addTrades := func(key string, amount float64) {
+		trades, warnings := s.placeTrades(groups[key], targets.Dimension, amount, c)
+		plan.Warnings = append(plan.Warnings, warnings...)
+		for _, trade := range trades {
+			if trade.Action == "SELL" {
+				if gain, ok := estimateFIFOGain(lots[trade.ISIN], trade.Quantity, trade.AmountEUR); ok {
+					plan.EstimatedRealizedGainEUR += gain
+					trade.EstimatedGainEUR = &gain
+				}
+				plan.TotalSellEUR += trade.AmountEUR
+				traded[key] -= trade.AmountEUR
+			} else {
+				plan.TotalBuyEUR += trade.AmountEUR
+				traded[key] += trade.AmountEUR
+			}
+			plan.Trades = append(plan.Trades, trade)
+		}
+	}
+	for _, key := range keys {
+		if amounts[key] <= -0.01 {
+			addTrades(key, amounts[key])
+		}
+	}
+
+	budget := cash + c.NewCashEUR + plan.TotalSellEUR
+	if c.NewCashOnly {
+		budget = c.NewCashEUR
+	}
+	if budget < 0 {
+		budget = 0
+	}
+	scale := 1.0
+	if totalBuy > budget {
+		scale = budget / totalBuy
+	}
+	for _, key := range keys {
+		if amount := amounts[key] * scale; amount >= 0.01 {
+			addTrades(key, amount)
+		}
+	}
+
+	plan.TotalBuyEUR = utils.RoundFloat(plan.TotalBuyEUR, 2)
+	plan.TotalSellEUR = utils.RoundFloat(plan.TotalSellEUR, 2)
+	plan.EstimatedRealizedGainEUR = utils.RoundFloat(plan.EstimatedRealizedGainEUR, 2)
+	plan.CashAfterEUR = utils.RoundFloat(cash+c.NewCashEUR+plan.TotalSellEUR-plan.TotalBuyEUR, 2)
+
+	for _, key := range keys {
+		g := groups[key]
+		item := models.DriftItem{
+			Key:             key,
+			TargetWeight:    g.target,
+			CurrentValueEUR: utils.RoundFloat(g.value, 2),
+			TargetValueEUR:  utils.RoundFloat(g.target*totalAfter, 2),
+		}
+		if plan.TotalValueEUR != 0 {
+			item.CurrentWeight = g.value / plan.TotalValueEUR
+		}
+		if totalAfter != 0 {
+			item.ProjectedWeight = (g.value + traded[key]) / totalAfter
+		}
+		item.Drift = item.CurrentWeight - item.TargetWeight
+		plan.Drift = append(plan.Drift, item)
+	}
+	sort.SliceStable(plan.Drift, func(i, j int) bool {
+		return math.Abs(plan.Drift[i].Drift) > math.Abs(plan.Drift[j].Drift)
+	})
+
+	return plan, nil
+}
+
+// placeTrades turns the amount for one target key into orders for individual holdings.
+func (s *rebalanceServiceImpl) placeTrades(g *rebalanceGroup, dimension string, amount float64, c models.RebalanceConstraints) ([]models.RebalanceTrade, []string) {
+	var warnings []string
+	candidates := make([]models.HoldingWithValue, 0, len(g.holdings))
+	for _, h := range g.holdings {
+		if h.Status == "OK" && h.CurrentPriceEUR > 0 {
+			candidates = append(candidates, h)
+		} else {
+			warnings = append(warnings, fmt.Sprintf("No current price for %s; it was left out of the plan.", h.ISIN))
+		}
+	}
+
+	// A target ISIN that is not held yet can still be bought at its current price.
+	if len(candidates) == 0 && amount > 0 && dimension == models.DimensionISIN {
+		prices, err := s.priceService.GetCurrentPrices([]string{g.key})
+		if info, ok := prices[g.key]; err == nil && ok && info.Status == "OK" && info.Price > 0 {
+			candidates = append(candidates, models.HoldingWithValue{ISIN: g.key, CurrentPriceEUR: info.Price})
+		}
+	}
+	if len(candidates) == 0 {
+		warnings = append(warnings, fmt.Sprintf("No priced holding for %q; %.2f EUR could not be placed.", g.key, math.Abs(amount)))
+		return nil, warnings
+	}
+
+	totalValue := 0.0
+	for _, h := range candidates {
+		totalValue += h.MarketValueEUR
+	}
+
+	var trades []models.RebalanceTrade
+	for _, h := range candidates {
+		share := 1 / float64(len(candidates))
+		if totalValue > 0 {
+			share = h.MarketValueEUR / totalValue
+		}
+		quantity := math.Abs(amount) * share / h.CurrentPriceEUR
+		action := "BUY"
+		if amount < 0 {
+			action = "SELL"
+			if quantity > float64(h.Quantity) {
+				quantity = float64(h.Quantity)
+			}
+		}
+		if c.WholeShares {
+			quantity = math.Floor(quantity + 1e-9)
+		} else {
+			quantity = utils.RoundFloat(quantity, 4)
+		}
+		value := utils.RoundFloat(quantity*h.CurrentPriceEUR, 2)
+		if quantity <= 0 || value < c.MinTradeEUR || value < 0.01 {
+			continue
+		}
+		trades = append(trades, models.RebalanceTrade{
+			ISIN:        h.ISIN,
+			ProductName: h.ProductName,
+			TargetKey:   g.key,
+			Action:      action,
+			Quantity:    quantity,
+			PriceEUR:    h.CurrentPriceEUR,
+			AmountEUR:   value,
+		})
+	}
+	return trades, warnings
+}
+
+// estimateFIFOGain matches a sale against the oldest lots first and returns proceeds minus
+// the EUR cost of the shares sold. It reports false when the lots do not cover the sale.
+func estimateFIFOGain(lots []models.PurchaseLot, quantity float64, proceedsEUR float64) (float64, bool) {
+	remaining := quantity
+	cost := 0.0
+	for _, lot := range lots {
+		if remaining <= 1e-9 {
+			break
+		}
+		if lot.Quantity <= 0 {
+			continue
+		}
+		matched := math.Min(remaining, float64(lot.Quantity))
+		cost += math.Abs(lot.BuyAmountEUR) * matched / float64(lot.Quantity)
+		remaining -= matched
+	}
+	if remaining > 1e-9 {
+		return 0, false
+	}
+	return utils.RoundFloat(proceedsEUR-cost, 2), true
+}
@@ -0,0 +1,238 @@
+// backend/src/services/risk_service.go
+package services
+
+import (
+	"errors"
+	"math"
+	"time"
+
+	"github.com/username/taxfolio/backend/src/logger"
+	"github.com/username/taxfolio/backend/src/model"
+	"github.com/username/taxfolio/backend/src/models"
+	"github.com/username/taxfolio/backend/src/utils"
+)
+
+const (
+	DefaultRollingWindow = 63 // About three months of trading days
+	minRollingWindow     = 5
+	maxRollingWindow     = 252
+)
+
+// RiskParams selects the period and assumptions for GetRiskMetrics.
+type RiskParams struct {
+	Period        string
+	From          string
+	To            string
+	Benchmark     string  // Benchmark name; empty selects the portfolio's first benchmark
+	RiskFreeRate  float64 // Annual rate
+	RollingWindow int     // Trading days; 0 selects DefaultRollingWindow
+}
+
+// RiskService computes risk metrics from the stored daily snapshots.
+type RiskService interface {
+	GetRiskMetrics(userID int64, portfolioID int64, params RiskParams) (*models.RiskResult, error)
+}
+
+type riskServiceImpl struct {
+	benchmarkService BenchmarkService
+}
+
+func NewRiskService(benchmarkService BenchmarkService) RiskService {
+	return &riskServiceImpl{benchmarkService: benchmarkService}
+}
+
+// GetRiskMetrics computes drawdown, volatility, Sharpe, Sortino, beta and correlation.
+//
+// Daily returns strip out deposits and withdrawals the same way TWR does
+// (r_t = V_t / (V_{t-1} + CF_t) - 1). Weekends are dropped so that non-trading days
+// do not dilute volatility; their flows are picked up on the next trading day because
+// cumulative_net_cashflow is cumulative. The benchmark return on each day comes from its
+// shadow portfolio, which receives the same flows as the real one.
+func (s *riskServiceImpl) GetRiskMetrics(userID int64, portfolioID int64, params RiskParams) (*models.RiskResult, error) {
+	if params.Period == "" {
+		params.Period = models.PeriodInception
+	}
+	start, end, err := resolvePeriod(params.Period, params.From, params.To, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	window := params.RollingWindow
+	if window == 0 {
+		window = DefaultRollingWindow
+	}
+	if window < minRollingWindow {
+		window = minRollingWindow
+	}
+	if window > maxRollingWindow {
+		window = maxRollingWindow
+	}
+
+	result := &models.RiskResult{
+		Period:            params.Period,
+		RiskFreeRate:      params.RiskFreeRate,
+		RollingWindow:     window,
+		RollingVolatility: []models.RollingVolatilityPoint{},
+	}
+
+	points, err := fetchPortfolioSnapshots(userID, portfolioID)
+	if err != nil {
+		return nil, err
+	}
+	if len(points) == 0 {
+		return result, nil
+	}
+
+	// The shadow portfolio must run from inception so that it holds the same capital
+	// as the real one when the period starts.
+	chartPoints := make([]models.HistoricalDataPoint, len(points))
+	for i, p := range points {
+		chartPoints[i] = models.HistoricalDataPoint{Date: p.Date.Format("2006-01-02"), CumulativeCashFlow: p.CumulativeCashFlow}
+	}
+	benchmarkName, benchmarkValues, err := s.benchmarkService.GetBenchmarkSeries(userID, portfolioID, params.Benchmark, chartPoints)
+	if errors.Is(err, model.ErrBenchmarkNotFound) {
+		return nil, err
+	}
+	if err != nil {
+		logger.L.Warn("Risk metrics computed without benchmark", "userID", userID, "portfolioID", portfolioID, "benchmark", params.Benchmark, "error", err)
+		benchmarkValues = nil
+	}
+	result.Benchmark = benchmarkName
+
+	// Keep trading days within the period, plus the last trading day before it as the base.
+	base := -1
+	var selected []int
+	for i, p := range points {
+		if p.Date.Weekday() == time.Saturday || p.Date.Weekday() == time.Sunday {
+			continue
+		}
+		if p.Date.Before(start) {
+			base = i
+			continue
+		}
+		if p.Date.After(end) {
+			break
+		}
+		selected = append(selected, i)
+	}
+	if len(selected) == 0 {
+		return result, nil
+	}
+	result.HasData = true
+	result.StartDate = points[selected[0]].Date.Format("2006-01-02")
+	result.EndDate = points[selected[len(selected)-1]].Date.Format("2006-01-02")
+
+	prev := base
+	if prev < 0 {
+		// No history before the period: the first day only provides the starting value.
+		prev = selected[0]
+		selected = selected[1:]
+	}
+
+	var returns, excess, pairedPortfolio, pairedBenchmark []float64
+	var returnDates []string
+	dailyRiskFree := math.Pow(1+params.RiskFreeRate, 1/utils.TradingDaysPerYear) - 1
+
+	index, peak := 1.0, 1.0
+	peakDate := points[prev].Date.Format("2006-01-02")
+	drawdown := &models.Drawdown{PeakDate: peakDate, TroughDate: peakDate}
+	recovered := true
+
+	for _, i := range selected {
+		flow := points[i].CumulativeCashFlow - points[prev].CumulativeCashFlow
+		prevEquity := points[prev].Equity
+		var prevBenchmark float64
+		if benchmarkValues != nil {
+			prevBenchmark = benchmarkValues[prev]
+		}
+		prev = i
+
+		invested := prevEquity + flow
+		if invested <= 0 {
+			continue // Nothing at risk yet (or everything withdrawn).
+		}
+		date := points[i].Date.Format("2006-01-02")
+		r := points[i].Equity/invested - 1
+		returns = append(returns, r)
+		excess = append(excess, r-dailyRiskFree)
+		returnDates = append(returnDates, date)
+
+		if benchmarkValues != nil && prevBenchmark+flow > 0 {
+			pairedPortfolio = append(pairedPortfolio, r)
+			pairedBenchmark = append(pairedBenchmark, benchmarkValues[i]/(prevBenchmark+flow)-1)
+		}
+
+		// Drawdowns are measured on the return index so that withdrawals do not count as losses.
+		index *= 1 + r
+		if index >= peak {
+			if !recovered {
+				recoveryDate := date
+				drawdown.RecoveryDate = &recoveryDate
+				recovered = true
+			}
+			peak = index
+			peakDate = date
+		} else if dd := index/peak - 1; dd < drawdown.Depth {
+			drawdown.Depth = dd
+			drawdown.PeakDate = peakDate
+			drawdown.TroughDate = date
+			drawdown.RecoveryDate = nil
+			recovered = false
+		}
+	}
+
+	result.Observations = len(returns)
+	if drawdown.Depth < 0 {
+		peakTime, _ := time.Parse("2006-01-02", drawdown.PeakDate)
+		endTime := points[prev].Date
+		if drawdown.RecoveryDate != nil {
+			endTime, _ = time.Parse("2006-01-02", *drawdown.RecoveryDate)
+		}
+		drawdown.DurationDays = int(endTime.Sub(peakTime).Hours() / 24)
+	}
+	result.MaxDrawdown = drawdown
+
+	if len(returns) < 2 {
+		return result, nil
+	}
+	annualizer := math.Sqrt(utils.TradingDaysPerYear)
+
+	volatility := utils.StdDev(returns) * annualizer
+	result.Volatility = &volatility
+
+	for end := window; end <= len(returns); end++ {
+		result.RollingVolatility = append(result.RollingVolatility, models.RollingVolatilityPoint{
+			Date:       returnDates[end-1],
+			Volatility: utils.StdDev(returns[end-window:end]) * annualizer,
+		})
+	}
+
+	meanExcess := utils.Mean(excess)
+	if sd := utils.StdDev(excess); sd > 0 {
+		sharpe := meanExcess / sd * annualizer
+		result.Sharpe = &sharpe
+	}
+	downside := 0.0
+	for _, e := range excess {
+		if e < 0 {
+			downside += e * e
+		}
+	}
+	if downside > 0 {
+		sortino := meanExcess / math.Sqrt(downside/float64(len(excess))) * annualizer
+		result.Sortino = &sortino
+	}
+
+	if len(pairedBenchmark) >= 2 {
+		covariance := utils.Covariance(pairedPortfolio, pairedBenchmark)
+		if benchmarkVariance := utils.Covariance(pairedBenchmark, pairedBenchmark); benchmarkVariance > 0 {
+			beta := covariance / benchmarkVariance
+			result.Beta = &beta
+			if portfolioSD := utils.StdDev(pairedPortfolio); portfolioSD > 0 {
+				correlation := covariance / (portfolioSD * math.Sqrt(benchmarkVariance))
+				result.Correlation = &correlation
+			}
+		}
+	}
+
+	return result, nil
+}
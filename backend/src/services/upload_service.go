@@ -2,6 +2,7 @@
 package services
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
 	"fmt"
@@ -48,8 +49,11 @@ type uploadServiceImpl struct {
 	optionProcessor       processors.OptionProcessor
 	cashMovementProcessor processors.CashMovementProcessor
 	feeProcessor          processors.FeeProcessor
+	cryptoProcessor       processors.CryptoProcessor
 	priceService          PriceService
+	benchmarkService      BenchmarkService
 	reportCache           *cache.Cache
+	jobQueue              JobEnqueuer
 }
 
 func NewUploadService(
@@ -59,7 +63,9 @@ func NewUploadService(
 	optionProcessor processors.OptionProcessor,
 	cashMovementProcessor processors.CashMovementProcessor,
 	feeProcessor processors.FeeProcessor,
+	cryptoProcessor processors.CryptoProcessor,
 	priceService PriceService,
+	benchmarkService BenchmarkService,
 	reportCache *cache.Cache,
 ) UploadService {
 	return &uploadServiceImpl{
@@ -69,11 +75,26 @@ func NewUploadService(
 		optionProcessor:       optionProcessor,
 		cashMovementProcessor: cashMovementProcessor,
 		feeProcessor:          feeProcessor,
+		cryptoProcessor:       cryptoProcessor,
 		priceService:          priceService,
+		benchmarkService:      benchmarkService,
 		reportCache:           reportCache,
 	}
 }
 
+func (s *uploadServiceImpl) SetJobQueue(queue JobEnqueuer) {
+	s.jobQueue = queue
+}
+
+// enqueueRebuild queues a history rebuild of the portfolio. A rebuild already waiting for
+// a worker is reused.
+func (s *uploadServiceImpl) enqueueRebuild(userID, portfolioID int64) (*model.BackgroundJob, error) {
+	if s.jobQueue == nil {
+		return nil, ErrNoJobQueue
+	}
+	return s.jobQueue.Enqueue(userID, portfolioID, model.JobTypeRebuildHistory)
+}
+
 // GetDividendMetrics calcula métricas baseadas na carteira ATUAL e histórico real.
 func (s *uploadServiceImpl) GetDividendMetrics(userID int64, portfolioID int64) (*models.DividendMetricsResult, error) {
 	// Nova chave de cache para garantir que os dados antigos são invalidados
@@ -295,9 +316,14 @@ func (s *uploadServiceImpl) RefreshDailySnapshot(userID int64, portfolioID int64
 	}
 
 	if shouldRebuild {
-		// RebuildUserHistory iterates from the first transaction to today,
-		// fetching historical prices and generating daily snapshots for all missing days.
-		return s.RebuildUserHistory(userID, portfolioID)
+		// RebuildUserHistory iterates from the first transaction to today, which is far
+		// too slow to run inside a request. Hand it to the job queue instead.
+		job, err := s.enqueueRebuild(userID, portfolioID)
+		if err != nil {
+			return fmt.Errorf("failed to enqueue history rebuild: %w", err)
+		}
+		logger.L.Info("History rebuild enqueued", "userID", userID, "portfolioID", portfolioID, "jobID", job.ID)
+		return nil
 	}
 
 	// 2. LIVE UPDATE (Standard "Today" Refresh)
@@ -310,6 +336,44 @@ func (s *uploadServiceImpl) RefreshDailySnapshot(userID int64, portfolioID int64
 		return nil
 	}
 
+	return s.writeLiveSnapshot(userID, portfolioID)
+}
+
+// RepricePortfolio re-fetches today's prices for the portfolio's open positions,
+// bypassing the daily price cache, and rewrites today's snapshot with them.
+func (s *uploadServiceImpl) RepricePortfolio(ctx context.Context, userID int64, portfolioID int64) error {
+	holdingsByYear, err := s.GetStockHoldings(userID, portfolioID)
+	if err != nil {
+		return fmt.Errorf("error retrieving stock holdings: %w", err)
+	}
+	latestYear := ""
+	for year := range holdingsByYear {
+		if latestYear == "" || year > latestYear {
+			latestYear = year
+		}
+	}
+	seen := make(map[string]bool)
+	var isins []string
+	for _, lot := range holdingsByYear[latestYear] {
+		if len(lot.ISIN) == 12 && !seen[lot.ISIN] {
+			seen[lot.ISIN] = true
+			isins = append(isins, lot.ISIN)
+		}
+	}
+
+	if _, err := s.priceService.RefreshPricesForISINs(ctx, isins); err != nil {
+		return err
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.InvalidateUserCache(userID, portfolioID)
+	return s.writeLiveSnapshot(userID, portfolioID)
+}
+
+// writeLiveSnapshot values the current holdings with live prices and upserts today's snapshot.
+func (s *uploadServiceImpl) writeLiveSnapshot(userID int64, portfolioID int64) error {
 	logger.L.Info("Calculating daily live snapshot", "userID", userID, "portfolioID", portfolioID)
 	todayStr := time.Now().Format("2006-01-02")
 
@@ -373,20 +437,30 @@ func (s *uploadServiceImpl) RefreshDailySnapshot(userID int64, portfolioID int64
 	return nil
 }
 
-func (s *uploadServiceImpl) ProcessUpload(fileReader io.Reader, userID int64, portfolioID int64, source, filename string, filesize int64) (*UploadResult, error) {
+func (s *uploadServiceImpl) ProcessUpload(files []io.Reader, userID int64, portfolioID int64, source, filename string, filesize int64) (*UploadResult, error) {
 	overallStartTime := time.Now()
 	logger.L.Info("ProcessUpload START", "userID", userID, "portfolioID", portfolioID, "source", source)
-	parser, err := parsers.GetParser(source)
+	if source == parsers.SourceAuto && len(files) > 0 {
+		detected, first, err := parsers.DetectSource(files[0])
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrParsingFailed, err)
+		}
+		logger.L.Info("Upload source detected", "userID", userID, "source", detected)
+		files[0], source = first, detected
+	}
+	parser, err := getParser(userID, source)
 	if err != nil {
 		return nil, fmt.Errorf("%w: %v", ErrParsingFailed, err)
 	}
-	canonicalTxs, err := parser.Parse(fileReader)
+	canonicalTxs, err := parsers.ParseFiles(parser, files)
 	if err != nil {
 		return nil, fmt.Errorf("%w: %v", ErrParsingFailed, err)
 	}
+	canonicalTxs, skipped := s.valueCryptoTransactions(canonicalTxs)
 	newlyProcessedTxs := s.transactionProcessor.Process(canonicalTxs)
 	if len(newlyProcessedTxs) == 0 {
-		return s.GetLatestUploadResult(userID, portfolioID)
+		result, err := s.GetLatestUploadResult(userID, portfolioID)
+		return withSkipped(result, skipped), err
 	}
 	dbTx, err := database.DB.Begin()
 	if err != nil {
@@ -397,19 +471,20 @@ func (s *uploadServiceImpl) ProcessUpload(fileReader io.Reader, userID int64, po
 		(user_id, portfolio_id, date, source, product_name, isin, quantity, original_quantity, price, 
 		transaction_type, transaction_subtype, buy_sell, description, amount, currency, 
 		commission, order_id, exchange_rate, amount_eur, country_code, input_string, hash_id,
-		cash_balance, balance_currency) 
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+		cash_balance, balance_currency, units) 
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`)
 	if err != nil {
 		return nil, fmt.Errorf("error preparing insert statement: %w", err)
 	}
 	defer stmt.Close()
 	insertedCount := 0
+	earliestInserted := ""
 	for _, tx := range newlyProcessedTxs {
 		_, err := stmt.Exec(
 			userID, portfolioID, tx.Date, tx.Source, tx.ProductName, tx.ISIN, tx.Quantity, tx.OriginalQuantity, tx.Price,
 			tx.TransactionType, tx.TransactionSubType, tx.BuySell, tx.Description, tx.Amount, tx.Currency,
 			tx.Commission, tx.OrderID, tx.ExchangeRate, tx.AmountEUR, tx.CountryCode, tx.InputString, tx.HashId,
-			tx.CashBalance, tx.BalanceCurrency,
+			tx.CashBalance, tx.BalanceCurrency, tx.Units,
 		)
 		if err != nil {
 			if strings.Contains(strings.ToLower(err.Error()), "unique constraint failed") {
@@ -419,8 +494,21 @@ func (s *uploadServiceImpl) ProcessUpload(fileReader io.Reader, userID int64, po
 			return nil, fmt.Errorf("error inserting transaction (OrderID: %s): %w", tx.OrderID, err)
 		}
 		insertedCount++
+		if txDate, err := time.Parse("02-01-2006", tx.Date); err == nil {
+			if d := txDate.Format("2006-01-02"); earliestInserted == "" || d < earliestInserted {
+				earliestInserted = d
+			}
+		}
 	}
 	if insertedCount > 0 {
+		// Only snapshots from the earliest new transaction onward need to be recomputed.
+		dirtyFrom := earliestInserted
+		if dirtyFrom == "" {
+			dirtyFrom = model.FullHistoryRebuild
+		}
+		if err := model.MarkHistoryDirty(dbTx, portfolioID, dirtyFrom); err != nil {
+			return nil, fmt.Errorf("failed to mark portfolio history as stale: %w", err)
+		}
 		_, err = dbTx.Exec(`
 			INSERT INTO uploads_history (user_id, portfolio_id, source, filename, file_size, transaction_count) 
 			VALUES (?, ?, ?, ?, ?, ?)`,
@@ -465,25 +553,59 @@ func (s *uploadServiceImpl) ProcessUpload(fileReader io.Reader, userID int64, po
 				}
 			}
 		}(userID)
-		go func() {
-			logger.L.Info("Triggering portfolio metrics update (pre-requisite for history)", "userID", userID)
-			if err := s.UpdateUserPortfolioMetrics(userID, portfolioID); err != nil {
-				logger.L.Error("Failed to update user portfolio metrics", "userID", userID, "error", err)
-			}
-			logger.L.Info("Triggering history rebuild", "userID", userID)
-			if err := s.RebuildUserHistory(userID, portfolioID); err != nil {
-				logger.L.Error("Failed to rebuild user history", "userID", userID, "error", err)
-			}
-		}()
+		// The rebuild job also refreshes the portfolio metrics before walking the history.
+		s.InvalidateUserCache(userID, portfolioID)
+		job, err := s.enqueueRebuild(userID, portfolioID)
+		if err != nil {
+			logger.L.Error("Failed to enqueue history rebuild after upload", "userID", userID, "portfolioID", portfolioID, "error", err)
+		} else {
+			logger.L.Info("History rebuild enqueued after upload", "userID", userID, "portfolioID", portfolioID, "jobID", job.ID)
+		}
 	} else {
 		s.InvalidateUserCache(userID, portfolioID)
 	}
 	logger.L.Info("ProcessUpload END", "userID", userID, "duration", time.Since(overallStartTime))
-	return s.GetLatestUploadResult(userID, portfolioID)
+	result, err := s.GetLatestUploadResult(userID, portfolioID)
+	return withSkipped(result, skipped), err
 }
 
 func (s *uploadServiceImpl) RebuildUserHistory(userID int64, portfolioID int64) error {
+	return s.RebuildUserHistoryWithProgress(context.Background(), userID, portfolioID, nil)
+}
+
+// RebuildUserHistoryWithProgress regenerates the daily snapshots of a portfolio.
+// Only snapshots from the earliest stale date onward are rewritten: the date comes from
+// the portfolio's history_dirty_from marker (set when transactions are added or removed)
+// or, when nothing is marked, the day after the last stored snapshot. Without any stored
+// snapshot the whole history is rebuilt.
+// It stops early when ctx is cancelled and reports its progress through progress (optional).
+func (s *uploadServiceImpl) RebuildUserHistoryWithProgress(ctx context.Context, userID int64, portfolioID int64, progress ProgressFunc) (retErr error) {
 	logger.L.Info("Starting history rebuild (True Currency Mode)", "userID", userID, "portfolioID", portfolioID)
+	report := func(percent int, message string) {
+		if progress != nil {
+			progress(percent, message)
+		}
+	}
+	report(0, "Loading transactions")
+
+	dirtyFrom, err := model.GetHistoryDirtyFrom(database.DB, portfolioID)
+	if err != nil {
+		return fmt.Errorf("failed to read portfolio history state: %w", err)
+	}
+	// Clear the marker before reading transactions so that anything changed while the
+	// rebuild runs marks the portfolio dirty again. Restore it if we do not finish.
+	if dirtyFrom != "" {
+		if err := model.ClearHistoryDirty(database.DB, portfolioID, dirtyFrom); err != nil {
+			return fmt.Errorf("failed to clear portfolio history marker: %w", err)
+		}
+		defer func() {
+			if retErr != nil {
+				if err := model.MarkHistoryDirty(database.DB, portfolioID, dirtyFrom); err != nil {
+					logger.L.Error("Failed to restore portfolio history marker", "portfolioID", portfolioID, "error", err)
+				}
+			}
+		}()
+	}
 
 	if err := s.priceService.EnsureBenchmarkData(); err != nil {
 		logger.L.Error("Failed to ensure benchmark data", "error", err)
@@ -494,14 +616,154 @@ func (s *uploadServiceImpl) RebuildUserHistory(userID int64, portfolioID int64)
 		return err
 	}
 	if len(txs) == 0 {
+		// Every transaction was removed, so any remaining history is meaningless.
+		if _, err := database.DB.Exec("DELETE FROM portfolio_snapshots WHERE user_id = ? AND portfolio_id = ?", userID, portfolioID); err != nil {
+			return fmt.Errorf("failed to clear snapshots: %w", err)
+		}
+		s.InvalidateUserCache(userID, portfolioID)
+		return nil
+	}
+
+	startDate, _ := time.Parse("02-01-2006", txs[0].Date)
+	endDate := time.Now()
+
+	var lastSnapshot sql.NullString
+	err = database.DB.QueryRow("SELECT MAX(date) FROM portfolio_snapshots WHERE user_id = ? AND portfolio_id = ?", userID, portfolioID).Scan(&lastSnapshot)
+	if err != nil {
+		return fmt.Errorf("failed to fetch last snapshot date: %w", err)
+	}
+
+	resumeFrom := startDate
+	if lastSnapshot.Valid {
+		if last, err := time.Parse("2006-01-02", lastSnapshot.String); err == nil {
+			resumeFrom = last.AddDate(0, 0, 1)
+		}
+		if dirtyFrom != "" {
+			if dirty, err := time.Parse("2006-01-02", dirtyFrom); err == nil && dirty.Before(resumeFrom) {
+				resumeFrom = dirty
+			}
+		}
+		if resumeFrom.Before(startDate) {
+			resumeFrom = startDate
+		}
+	}
+	if resumeFrom.After(endDate) {
+		logger.L.Info("History already up to date, nothing to rebuild", "userID", userID, "portfolioID", portfolioID)
+		report(100, "History already up to date")
 		return nil
 	}
+	resumeFromStr := resumeFrom.Format("2006-01-02")
+	logger.L.Info("History rebuild range", "userID", userID, "portfolioID", portfolioID, "from", resumeFromStr, "full", !resumeFrom.After(startDate))
+
+	type AssetInfo struct {
+		Quantity       float64
+		TotalCostBasis float64
+		Name           string
+	}
+
+	holdings := make(map[string]AssetInfo)
+	cumulativeNetInvested := 0.0
+	currentCash := 0.0 // Tracks Derived Cash Balance
+
+	applyTransaction := func(tx models.ProcessedTransaction) {
+		// --- 1. Net Invested Logic (Deposits/Withdrawals) ---
+		if tx.TransactionType == "CASH" {
+			cumulativeNetInvested += tx.AmountEUR
+		}
+
+		// --- 2. Derived Cash Balance Logic (The Fix) ---
+		var cashImpact float64
+
+		// Logic specific to IBKR Trades where Amount is Gross Value (not Cash Flow) and Commission is separate
+		if tx.Source == "ibkr" && (tx.TransactionType == "STOCK" || tx.TransactionType == "OPTION" || tx.TransactionType == "ETF" || tx.TransactionType == "WARRANT") {
+			tradeVal := math.Abs(tx.AmountEUR)
+			cost := math.Abs(tx.Commission) // Commission is already converted to EUR in DB
+
+			if tx.BuySell == "BUY" {
+				cashImpact = -tradeVal - cost // Cash Outflow
+			} else { // SELL
+				cashImpact = tradeVal - cost // Cash Inflow (Net of commission)
+			}
+		} else {
+			// Standard Logic (DeGiro, etc.): AmountEUR is the Net Cash Flow (Signed)
+			// Includes Deposits (+), Withdrawals (-), Fees (-), Dividends (+)
+			// DeGiro Trade Amounts are typically Net (include fees) in the source Amount column
+			cashImpact = tx.AmountEUR
+		}
+
+		currentCash += cashImpact
+
+		shouldTrustBalance := true
+		if tx.Source == "degiro" {
+			if tx.TransactionType != "CASH" {
+				shouldTrustBalance = false
+			}
+		}
+
+		// For other brokers (like IBKR) or valid DeGiro CASH lines, we sync with the broker's report.
+		if shouldTrustBalance && tx.BalanceCurrency == "EUR" && tx.CashBalance != 0 {
+			currentCash = tx.CashBalance
+		}
+
+		// --- 3. Asset Holdings Logic ---
+		if tx.TransactionType == "STOCK" || tx.TransactionType == "ETF" {
+			info := holdings[tx.ISIN]
+			info.Name = tx.ProductName
+			if tx.BuySell == "BUY" {
+				info.Quantity += float64(tx.Quantity)
+				info.TotalCostBasis += math.Abs(tx.AmountEUR)
+			} else if tx.BuySell == "SELL" {
+				if info.Quantity > 0 {
+					ratio := float64(tx.Quantity) / info.Quantity
+					info.TotalCostBasis -= (info.TotalCostBasis * ratio)
+				}
+				info.Quantity -= float64(tx.Quantity)
+			}
+			holdings[tx.ISIN] = info
+		}
+	}
 
+	// Replay everything before the resume date to rebuild the positions held on the
+	// previous day. This is cheap: no prices are needed for days we are not rewriting.
+	txIndex := 0
+	totalTxs := len(txs)
+	for txIndex < totalTxs {
+		txDate, err := time.Parse("02-01-2006", txs[txIndex].Date)
+		if err == nil && !txDate.Before(resumeFrom) {
+			break
+		}
+		applyTransaction(txs[txIndex])
+		txIndex++
+	}
+
+	// Resume cash and net invested from the stored snapshot of the previous day, which is
+	// what the chart already shows for that day.
+	if resumeFrom.After(startDate) {
+		var prevCash, prevNetInvested float64
+		err := database.DB.QueryRow(`
+			SELECT cash_balance, cumulative_net_cashflow FROM portfolio_snapshots
+			WHERE user_id = ? AND portfolio_id = ? AND date = ?`,
+			userID, portfolioID, resumeFrom.AddDate(0, 0, -1).Format("2006-01-02"),
+		).Scan(&prevCash, &prevNetInvested)
+		if err == nil {
+			currentCash = prevCash
+			cumulativeNetInvested = prevNetInvested
+		} else if err != sql.ErrNoRows {
+			return fmt.Errorf("failed to load previous snapshot state: %w", err)
+		}
+	}
+
+	// Only the instruments held on the resume date or traded afterwards need prices.
 	uniqueISINs := make(map[string]bool)
 	uniqueCurrencies := make(map[string]bool)
 	var isinList []string
-
-	for _, tx := range txs {
+	for isin, info := range holdings {
+		if info.Quantity > 0.0001 && len(isin) == 12 && !uniqueISINs[isin] {
+			uniqueISINs[isin] = true
+			isinList = append(isinList, isin)
+		}
+	}
+	for _, tx := range txs[txIndex:] {
 		if len(tx.ISIN) == 12 {
 			if !uniqueISINs[tx.ISIN] {
 				uniqueISINs[tx.ISIN] = true
@@ -513,7 +775,10 @@ func (s *uploadServiceImpl) RebuildUserHistory(userID int64, portfolioID int64)
 		}
 	}
 
-	// ... (Price fetching logic remains the same) ...
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	report(5, "Resolving tickers")
 	logger.L.Info("Pre-resolving ISINs to Tickers...", "count", len(isinList))
 	_, err = s.priceService.GetCurrentPrices(isinList)
 	if err != nil {
@@ -528,6 +793,11 @@ func (s *uploadServiceImpl) RebuildUserHistory(userID int64, portfolioID int64)
 	currencyRates := make(map[string]PriceMap)
 	var dataMu sync.Mutex
 
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	report(15, "Fetching historical prices")
+
 	// 1. Fetch Asset Prices (Existing logic)
 	for isin := range uniqueISINs {
 		mapEntry, ok := mappings[isin]
@@ -556,6 +826,11 @@ func (s *uploadServiceImpl) RebuildUserHistory(userID int64, portfolioID int64)
 	}
 	wg.Wait()
 
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	report(45, "Fetching exchange rates")
+
 	// 2. Fetch Currency Rates (Existing logic)
 	for curr := range uniqueCurrencies {
 		wg.Add(1)
@@ -574,20 +849,26 @@ func (s *uploadServiceImpl) RebuildUserHistory(userID int64, portfolioID int64)
 	}
 	wg.Wait()
 
-	// 3. Rebuild Daily Snapshots
-	startDate, _ := time.Parse("02-01-2006", txs[0].Date)
-	endDate := time.Now()
-
-	type AssetInfo struct {
-		Quantity       float64
-		TotalCostBasis float64
-		Name           string
+	if err := ctx.Err(); err != nil {
+		return err
 	}
+	report(55, "Computing daily snapshots")
 
-	holdings := make(map[string]AssetInfo)
-	cumulativeNetInvested := 0.0
-	currentCash := 0.0 // Tracks Derived Cash Balance
+	// Seed the last known prices from the days just before the resume date so that a
+	// resume on a weekend or holiday does not fall back to cost basis.
 	lastKnownPrices := make(map[string]float64)
+	for isin, pMap := range tickerPrices {
+		for back := 1; back <= 10; back++ {
+			if price := pMap[resumeFrom.AddDate(0, 0, -back).Format("2006-01-02")]; price > 0 {
+				lastKnownPrices[isin] = price
+				break
+			}
+		}
+	}
+
+	// 3. Rebuild Daily Snapshots
+	totalDays := int(endDate.Sub(resumeFrom).Hours()/24) + 1
+	dayCount := 0
 
 	type Snapshot struct {
 		Date        string
@@ -597,76 +878,24 @@ func (s *uploadServiceImpl) RebuildUserHistory(userID int64, portfolioID int64)
 	}
 	var snapshots []Snapshot
 
-	txIndex := 0
-	totalTxs := len(txs)
-
-	for d := startDate; !d.After(endDate); d = d.AddDate(0, 0, 1) {
+	for d := resumeFrom; !d.After(endDate); d = d.AddDate(0, 0, 1) {
 		dateStr := d.Format("2006-01-02")
 		txDateStr := d.Format("02-01-2006")
 
-		// Process transactions for this day
-		for txIndex < totalTxs && txs[txIndex].Date == txDateStr {
-			tx := txs[txIndex]
-
-			// --- 1. Net Invested Logic (Deposits/Withdrawals) ---
-			if tx.TransactionType == "CASH" {
-				cumulativeNetInvested += tx.AmountEUR
-			}
-
-			// --- 2. Derived Cash Balance Logic (The Fix) ---
-			var cashImpact float64
-
-			// Logic specific to IBKR Trades where Amount is Gross Value (not Cash Flow) and Commission is separate
-			if tx.Source == "ibkr" && (tx.TransactionType == "STOCK" || tx.TransactionType == "OPTION" || tx.TransactionType == "ETF" || tx.TransactionType == "WARRANT") {
-				tradeVal := math.Abs(tx.AmountEUR)
-				cost := math.Abs(tx.Commission) // Commission is already converted to EUR in DB
-
-				if tx.BuySell == "BUY" {
-					cashImpact = -tradeVal - cost // Cash Outflow
-				} else { // SELL
-					cashImpact = tradeVal - cost // Cash Inflow (Net of commission)
-				}
-			} else {
-				// Standard Logic (DeGiro, etc.): AmountEUR is the Net Cash Flow (Signed)
-				// Includes Deposits (+), Withdrawals (-), Fees (-), Dividends (+)
-				// DeGiro Trade Amounts are typically Net (include fees) in the source Amount column
-				cashImpact = tx.AmountEUR
-			}
-
-			currentCash += cashImpact
-
-			shouldTrustBalance := true
-			if tx.Source == "degiro" {
-				if tx.TransactionType != "CASH" {
-					shouldTrustBalance = false
-				}
-			}
-
-			// For other brokers (like IBKR) or valid DeGiro CASH lines, we sync with the broker's report.
-			if shouldTrustBalance && tx.BalanceCurrency == "EUR" && tx.CashBalance != 0 {
-				currentCash = tx.CashBalance
+		// Check for cancellation and report progress roughly once a month of history.
+		if dayCount%30 == 0 {
+			if err := ctx.Err(); err != nil {
+				return err
 			}
-
-			if shouldTrustBalance && tx.BalanceCurrency == "EUR" && tx.CashBalance != 0 {
-				currentCash = tx.CashBalance
+			if totalDays > 0 {
+				report(55+(30*dayCount)/totalDays, "Computing daily snapshots")
 			}
+		}
+		dayCount++
 
-			// --- 3. Asset Holdings Logic ---
-			if tx.TransactionType == "STOCK" || tx.TransactionType == "ETF" {
-				info := holdings[tx.ISIN]
-				info.Name = tx.ProductName
-				if tx.BuySell == "BUY" {
-					info.Quantity += float64(tx.Quantity)
-					info.TotalCostBasis += math.Abs(tx.AmountEUR)
-				} else if tx.BuySell == "SELL" {
-					if info.Quantity > 0 {
-						ratio := float64(tx.Quantity) / info.Quantity
-						info.TotalCostBasis -= (info.TotalCostBasis * ratio)
-					}
-					info.Quantity -= float64(tx.Quantity)
-				}
-				holdings[tx.ISIN] = info
-			}
+		// Process transactions for this day
+		for txIndex < totalTxs && txs[txIndex].Date == txDateStr {
+			applyTransaction(txs[txIndex])
 			txIndex++
 		}
 
@@ -682,12 +911,6 @@ func (s *uploadServiceImpl) RebuildUserHistory(userID int64, portfolioID int64)
 				price = pMap[dateStr]
 			}
 
-			if price == 0 && d.Weekday() != time.Saturday && d.Weekday() != time.Sunday {
-				if isin == "IE000U9J8HX9" {
-					logger.L.Debug("Still missing price for JEPQ", "date", dateStr)
-				}
-			}
-
 			if price > 0 {
 				lastKnownPrices[isin] = price
 			} else if lastPrice, exists := lastKnownPrices[isin]; exists {
@@ -726,9 +949,28 @@ func (s *uploadServiceImpl) RebuildUserHistory(userID int64, portfolioID int64)
 		})
 	}
 
-	// Batch Insert Snapshots (Existing logic)
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	report(85, "Saving snapshots")
+
+	// Replace the snapshots from the resume date onward inside a single transaction so
+	// that a failed or cancelled rebuild never leaves the portfolio without history.
 	if len(snapshots) > 0 {
-		_, _ = database.DB.Exec("DELETE FROM portfolio_snapshots WHERE user_id = ? AND portfolio_id = ?", userID, portfolioID)
+		dbTx, err := database.DB.BeginTx(ctx, nil)
+		if err != nil {
+			return fmt.Errorf("error beginning snapshot transaction: %w", err)
+		}
+		defer dbTx.Rollback()
+
+		// A full rebuild also drops snapshots dated before the (possibly newer) first transaction.
+		deleteFrom := resumeFromStr
+		if !resumeFrom.After(startDate) {
+			deleteFrom = model.FullHistoryRebuild
+		}
+		if _, err := dbTx.Exec("DELETE FROM portfolio_snapshots WHERE user_id = ? AND portfolio_id = ? AND date >= ?", userID, portfolioID, deleteFrom); err != nil {
+			return fmt.Errorf("failed to clear stale snapshots: %w", err)
+		}
 
 		chunkSize := 500
 		for i := 0; i < len(snapshots); i += chunkSize {
@@ -746,14 +988,20 @@ func (s *uploadServiceImpl) RebuildUserHistory(userID int64, portfolioID int64)
 			}
 			query = query[:len(query)-1]
 
-			if _, err := database.DB.Exec(query, vals...); err != nil {
+			if _, err := dbTx.Exec(query, vals...); err != nil {
 				logger.L.Error("Batch insert failed", "error", err)
 				return err
 			}
 		}
+
+		if err := dbTx.Commit(); err != nil {
+			return fmt.Errorf("error committing snapshots: %w", err)
+		}
 	}
+	s.InvalidateUserCache(userID, portfolioID)
 
-	logger.L.Info("History rebuild complete", "days", len(snapshots))
+	report(100, "History rebuilt")
+	logger.L.Info("History rebuild complete", "from", resumeFromStr, "days", len(snapshots))
 	return nil
 }
 
@@ -948,6 +1196,7 @@ func (s *uploadServiceImpl) GetLatestUploadResult(userID int64, portfolioID int6
 	optionSaleDetails, optionHoldings := s.optionProcessor.Process(allTxns)
 	cashMovements := s.cashMovementProcessor.Process(allTxns)
 	feeDetails := s.feeProcessor.Process(allTxns)
+	cryptoSaleDetails, cryptoHoldings, cryptoIncome := s.cryptoProcessor.Process(allTxns)
 	var dividendTransactionsList []models.ProcessedTransaction
 	for _, tx := range allTxns {
 		if tx.TransactionType == "DIVIDEND" {
@@ -962,6 +1211,9 @@ func (s *uploadServiceImpl) GetLatestUploadResult(userID int64, portfolioID int6
 		CashMovements:            cashMovements,
 		DividendTransactionsList: dividendTransactionsList,
 		FeeDetails:               feeDetails,
+		CryptoSaleDetails:        cryptoSaleDetails,
+		CryptoHoldings:           cryptoHoldings,
+		CryptoIncome:             cryptoIncome,
 	}
 	s.reportCache.Set(cacheKey, result, DefaultCacheExpiration)
 	return result, nil
@@ -1057,74 +1309,9 @@ func (s *uploadServiceImpl) GetHistoricalChartData(userID int64, portfolioID int
 		return snapshots, nil
 	}
 
-	bmPrices, _, err := s.priceService.GetHistoricalPrices("SPY")
-	if err != nil {
-		// Se falhar o preço do SPY, retorna os dados sem benchmark
-		return snapshots, nil
-	}
-
-	logger.L.Info("Benchmark Debug: Starting calculation", "snapshot_count", len(snapshots))
-
-	currentBenchmarkUnits := 0.0
-	previousCashFlow := 0.0
-	lastKnownPrice := 0.0
-	pendingCashToInvest := 0.0 // Acumula fluxos que ocorreram em dias sem preço (fds/feriados)
-
-	for i := range snapshots {
-		date := snapshots[i].Date
-		price := bmPrices[date]
-
-		// 1. Tentar obter o preço mais recente para evitar zeros no gráfico
-		if price > 0 {
-			lastKnownPrice = price
-		} else if lastKnownPrice > 0 {
-			price = lastKnownPrice
-		}
-
-		// 2. Calcular o fluxo de caixa novo neste dia (Depósitos ou Levantamentos)
-		// Se snapshots[i] > previous => Depósito (dailyNetFlow > 0)
-		// Se snapshots[i] < previous => Levantamento (dailyNetFlow < 0)
-		dailyNetFlow := snapshots[i].CumulativeCashFlow - previousCashFlow
-
-		// Adiciona ao "balde" pendente. Se for levantamento, reduz o pendente.
-		pendingCashToInvest += dailyNetFlow
-
-		// 3. LOGICA CRÍTICA: Se temos um preço real (do dia ou arrastado), executamos a simulação
-		// Usamos price > 0 para garantir que não dividimos por zero
-		if price > 0 {
-			// Compra (ou vende se negativo) unidades com o dinheiro pendente
-			unitsTrade := pendingCashToInvest / price
-			currentBenchmarkUnits += unitsTrade
-
-			// Logs para debug em datas específicas (abril/maio 2025)
-			if strings.Contains(date, "2025-04") || strings.Contains(date, "2025-05") {
-				if dailyNetFlow != 0 || pendingCashToInvest != 0 {
-					logger.L.Info("Benchmark Loop Debug",
-						"date", date,
-						"dailyNetFlow", dailyNetFlow,
-						"pendingCash", pendingCashToInvest,
-						"price", price,
-						"unitsTrade", unitsTrade,
-						"totalUnits", currentBenchmarkUnits)
-				}
-			}
-
-			// O dinheiro foi "usado", limpamos o pendente
-			pendingCashToInvest = 0
-		}
-
-		// 4. Calcular valor do benchmark
-		// Se não houver preço hoje nem histórico (ex: antes do SPY existir), vale 0
-		if price > 0 {
-			snapshots[i].BenchmarkValue = currentBenchmarkUnits * price
-			snapshots[i].SPYPrice = price
-		} else {
-			snapshots[i].BenchmarkValue = 0
-			snapshots[i].SPYPrice = 0
-		}
-
-		// Atualizar referência para o próximo loop
-		previousCashFlow = snapshots[i].CumulativeCashFlow
+	if err := s.benchmarkService.ApplyBenchmarks(userID, portfolioID, snapshots); err != nil {
+		// Se falhar o benchmark, retorna os dados sem benchmark
+		logger.L.Warn("Failed to compute benchmarks for chart", "userID", userID, "portfolioID", portfolioID, "error", err)
 	}
 
 	return snapshots, nil
@@ -1136,7 +1323,7 @@ func fetchUserProcessedTransactions(userID int64, portfolioID int64) ([]models.P
 		SELECT id, date, source, product_name, isin, quantity, original_quantity, price, 
 		       transaction_type, transaction_subtype, buy_sell, description, amount, 
 		       currency, commission, order_id, exchange_rate, amount_eur, country_code, 
-		       input_string, hash_id, cash_balance, balance_currency 
+		       input_string, hash_id, cash_balance, balance_currency, COALESCE(units, quantity) 
 		FROM processed_transactions 
 		WHERE user_id = ? AND portfolio_id = ?
 		ORDER BY 
@@ -1154,7 +1341,7 @@ func fetchUserProcessedTransactions(userID int64, portfolioID int64) ([]models.P
 			&tx.ID, &tx.Date, &tx.Source, &tx.ProductName, &tx.ISIN, &tx.Quantity, &tx.OriginalQuantity, &tx.Price,
 			&tx.TransactionType, &tx.TransactionSubType, &tx.BuySell, &tx.Description, &tx.Amount, &tx.Currency,
 			&tx.Commission, &tx.OrderID, &tx.ExchangeRate, &tx.AmountEUR, &tx.CountryCode, &tx.InputString, &tx.HashId,
-			&tx.CashBalance, &tx.BalanceCurrency,
+			&tx.CashBalance, &tx.BalanceCurrency, &tx.Units,
 		)
 		if scanErr != nil {
 			return nil, fmt.Errorf("error scanning transaction row for userID %d: %w", userID, scanErr)
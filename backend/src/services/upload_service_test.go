@@ -0,0 +1,119 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/patrickmn/go-cache"
+	"github.com/username/taxfolio/backend/src/database"
+	"github.com/username/taxfolio/backend/src/model"
+	"github.com/username/taxfolio/backend/src/processors"
+)
+
+// offlinePriceService stands in for the price service when the portfolio holds no securities.
+type offlinePriceService struct{ PriceService }
+
+func (offlinePriceService) GetCurrentPrices(isins []string) (map[string]PriceInfo, error) {
+	return map[string]PriceInfo{}, nil
+}
+
+func (offlinePriceService) GetHistoricalPrices(ticker string) (PriceMap, string, error) {
+	return nil, "", fmt.Errorf("no prices for %s offline", ticker)
+}
+
+func (offlinePriceService) EnsureBenchmarkData(tickers ...string) error { return nil }
+
+// recordingQueue records the jobs enqueued by the upload service instead of running them.
+type recordingQueue struct{ jobs []string }
+
+func (q *recordingQueue) Enqueue(userID, portfolioID int64, jobType string) (*model.BackgroundJob, error) {
+	q.jobs = append(q.jobs, jobType)
+	return &model.BackgroundJob{ID: int64(len(q.jobs)), UserID: userID, PortfolioID: portfolioID, JobType: jobType}, nil
+}
+
+// setupTestDB opens a migrated database in a temporary directory.
+func setupTestDB(t *testing.T) {
+	t.Chdir(filepath.Join("..", ".."))
+	path := filepath.Join(t.TempDir(), "test.db")
+	database.InitDB(path)
+	t.Cleanup(func() { database.DB.Close() })
+	database.RunMigrations(path)
+}
+
+func degiroDeposit(date time.Time, amount, balance float64) string {
+	d := date.Format("02-01-2006")
+	return fmt.Sprintf("%s,09:00,%s,,,Deposit,,EUR,%.2f,EUR,%.2f,\n", d, d, amount, balance)
+}
+
+func TestBackdatedUploadRewritesOnlyLaterSnapshots(t *testing.T) {
+	setupTestDB(t)
+	if _, err := database.DB.Exec(`INSERT INTO users (id, username, password, email) VALUES (1, 'investor', 'secret', 'investor@example.com')`); err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	if _, err := database.DB.Exec(`INSERT INTO portfolios (id, user_id, name) VALUES (1, 1, 'Main')`); err != nil {
+		t.Fatalf("create portfolio: %v", err)
+	}
+
+	queue := &recordingQueue{}
+	service := NewUploadService(processors.NewTransactionProcessor(), processors.NewDividendProcessor(), processors.NewStockProcessor(),
+		processors.NewOptionProcessor(), processors.NewCashMovementProcessor(), processors.NewFeeProcessor(), processors.NewCryptoProcessor(),
+		offlinePriceService{}, nil, cache.New(DefaultCacheExpiration, CacheCleanupInterval))
+	service.SetJobQueue(queue)
+
+	const header = "Date,Time,Value date,Product,ISIN,Description,FX,Change,,Balance,,Order Id\n"
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	first, backdated := today.AddDate(0, 0, -40), today.AddDate(0, 0, -20)
+	upload := func(csv string) {
+		t.Helper()
+		if _, err := service.ProcessUpload([]io.Reader{strings.NewReader(header + csv)}, 1, 1, "degiro", "Account.csv", int64(len(csv))); err != nil {
+			t.Fatalf("upload: %v", err)
+		}
+		if err := service.RebuildUserHistoryWithProgress(context.Background(), 1, 1, nil); err != nil {
+			t.Fatalf("rebuild: %v", err)
+		}
+	}
+
+	upload(degiroDeposit(first, 1000, 1000))
+	// Mark every snapshot so that the ones rewritten by the next rebuild can be told apart.
+	if _, err := database.DB.Exec(`UPDATE portfolio_snapshots SET total_equity = -1`); err != nil {
+		t.Fatalf("mark snapshots: %v", err)
+	}
+	upload(degiroDeposit(backdated, 500, 1500))
+
+	if len(queue.jobs) != 2 || queue.jobs[1] != model.JobTypeRebuildHistory {
+		t.Errorf("enqueued jobs = %v, want a history rebuild after each upload", queue.jobs)
+	}
+	if dirtyFrom, err := model.GetHistoryDirtyFrom(database.DB, 1); err != nil || dirtyFrom != "" {
+		t.Errorf("history_dirty_from = %q (%v), want it cleared by the rebuild", dirtyFrom, err)
+	}
+
+	rows, err := database.DB.Query(`SELECT date, total_equity FROM portfolio_snapshots WHERE portfolio_id = 1 ORDER BY date`)
+	if err != nil {
+		t.Fatalf("list snapshots: %v", err)
+	}
+	defer rows.Close()
+	cutoff := backdated.Format("2006-01-02")
+	days := 0
+	for rows.Next() {
+		var date string
+		var equity float64
+		if err := rows.Scan(&date, &equity); err != nil {
+			t.Fatalf("read snapshot: %v", err)
+		}
+		days++
+		switch {
+		case date < cutoff && equity != -1:
+			t.Errorf("snapshot %s before the back-dated upload was rewritten (equity %.2f)", date, equity)
+		case date >= cutoff && equity != 1500:
+			t.Errorf("snapshot %s equity = %.2f, want 1500", date, equity)
+		}
+	}
+	if want := int(today.Sub(first).Hours()/24) + 1; days != want {
+		t.Errorf("got %d snapshots, want %d", days, want)
+	}
+}
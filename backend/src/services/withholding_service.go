@@ -0,0 +1,334 @@
+// backend/src/services/withholding_service.go
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/username/taxfolio/backend/src/database"
+	"github.com/username/taxfolio/backend/src/model"
+	"github.com/username/taxfolio/backend/src/models"
+	"github.com/username/taxfolio/backend/src/utils"
+)
+
+const (
+	// withholdingMatchWindowDays is how far a withholding may be booked from its dividend.
+	withholdingMatchWindowDays = 10
+	maxReclaimNotesLength      = 500
+)
+
+var ErrInvalidReclaim = errors.New("invalid reclaim")
+
+// dividendWithholdingRates holds the withholding on dividends paid to Portuguese residents,
+// keyed by the ISO alpha-2 code of the issuer (the ISIN prefix).
+var dividendWithholdingRates = map[string]models.WithholdingRate{
+	"US": {StatutoryRate: 0.30, TreatyRate: 0.15, Note: "15% with a valid W-8BEN"},
+	"CH": {StatutoryRate: 0.35, TreatyRate: 0.15},
+	"FR": {StatutoryRate: 0.128, TreatyRate: 0.128, Note: "12.8% for individuals when the residence form is on file"},
+	"DE": {StatutoryRate: 0.26375, TreatyRate: 0.15},
+	"NL": {StatutoryRate: 0.15, TreatyRate: 0.15},
+	"ES": {StatutoryRate: 0.19, TreatyRate: 0.15},
+	"IT": {StatutoryRate: 0.26, TreatyRate: 0.15},
+	"BE": {StatutoryRate: 0.30, TreatyRate: 0.15},
+	"AT": {StatutoryRate: 0.275, TreatyRate: 0.15},
+	"DK": {StatutoryRate: 0.27, TreatyRate: 0.15},
+	"FI": {StatutoryRate: 0.35, TreatyRate: 0.15},
+	"SE": {StatutoryRate: 0.30, TreatyRate: 0.15},
+	"NO": {StatutoryRate: 0.25, TreatyRate: 0.15},
+	"IE": {StatutoryRate: 0.25, TreatyRate: 0.15, Note: "Irish-domiciled funds pay without withholding"},
+	"LU": {StatutoryRate: 0.15, TreatyRate: 0.15, Note: "Luxembourg funds pay without withholding"},
+	"CA": {StatutoryRate: 0.25, TreatyRate: 0.15},
+	"GB": {StatutoryRate: 0, TreatyRate: 0},
+	"PT": {StatutoryRate: 0.28, TreatyRate: 0.28, Note: "Domestic withholding, not reclaimable"},
+}
+
+// WithholdingService reconciles dividends with the tax withheld on them and tracks reclaims.
+type WithholdingService interface {
+	GetReconciliation(userID int64, portfolioID int64, year int) (*models.WithholdingReconciliation, error)
+	ListReclaims(userID int64, portfolioID int64) ([]models.DividendReclaim, error)
+	CreateReclaim(userID int64, reclaim models.DividendReclaim) (*models.DividendReclaim, error)
+	UpdateReclaim(userID int64, reclaim models.DividendReclaim) (*models.DividendReclaim, error)
+	DeleteReclaim(userID int64, portfolioID int64, reclaimID int64) error
+}
+
+type withholdingServiceImpl struct {
+	uploadService UploadService
+}
+
+func NewWithholdingService(uploadService UploadService) WithholdingService {
+	return &withholdingServiceImpl{uploadService: uploadService}
+}
+
+// dividendPayment collects the gross amount and withholding booked for one ISIN on one date.
+type dividendPayment struct {
+	date        time.Time
+	isin        string
+	productName string
+	grossEUR    float64
+	withheldEUR float64
+}
+
+// matchDividendPayments pairs every dividend with its withholding. Withholding booked on
+// another date is moved to the nearest dividend of the same ISIN within the match window;
+// what cannot be placed is returned as unmatched withholding per year.
+func matchDividendPayments(transactions []models.ProcessedTransaction) ([]*dividendPayment, map[int]float64) {
+	byKey := make(map[string]*dividendPayment)
+	var payments []*dividendPayment
+	for _, tx := range transactions {
+		if !strings.EqualFold(tx.TransactionType, "DIVIDEND") {
+			continue
+		}
+		date := utils.ParseDate(tx.Date)
+		key := tx.ISIN + "|" + date.Format("2006-01-02")
+		payment, ok := byKey[key]
+		if !ok {
+			payment = &dividendPayment{date: date, isin: tx.ISIN, productName: tx.ProductName}
+			byKey[key] = payment
+			payments = append(payments, payment)
+		}
+		if tx.TransactionSubType == "TAX" {
+			payment.withheldEUR -= tx.AmountEUR
+		} else {
+			payment.grossEUR += tx.AmountEUR
+		}
+	}
+
+	unmatched := make(map[int]float64)
+	for _, orphan := range payments {
+		if orphan.grossEUR > 0.005 || math.Abs(orphan.withheldEUR) < 0.005 {
+			continue
+		}
+		var nearest *dividendPayment
+		for _, p := range payments {
+			if p.isin != orphan.isin || p.grossEUR <= 0.005 {
+				continue
+			}
+			distance := math.Abs(p.date.Sub(orphan.date).Hours() / 24)
+			if distance > withholdingMatchWindowDays {
+				continue
+			}
+			if nearest == nil || distance < math.Abs(nearest.date.Sub(orphan.date).Hours()/24) {
+				nearest = p
+			}
+		}
+		if nearest == nil {
+			unmatched[orphan.date.Year()] += orphan.withheldEUR
+		} else {
+			nearest.withheldEUR += orphan.withheldEUR
+		}
+		orphan.withheldEUR = 0
+	}
+
+	matched := payments[:0]
+	for _, p := range payments {
+		if p.grossEUR > 0.005 {
+			matched = append(matched, p)
+		}
+	}
+	sort.SliceStable(matched, func(i, j int) bool { return matched[i].date.Before(matched[j].date) })
+	return matched, unmatched
+}
+
+// reconcilePayment compares the withholding of a payment with the treaty rate of its source country.
+func reconcilePayment(p *dividendPayment) models.WithholdingMatch {
+	countryCode := ""
+	if len(p.isin) >= 2 {
+		countryCode = strings.ToUpper(p.isin[:2])
+	}
+	match := models.WithholdingMatch{
+		Date:          p.date.Format("2006-01-02"),
+		ISIN:          p.isin,
+		ProductName:   p.productName,
+		CountryCode:   countryCode,
+		Country:       utils.GetCountryName(countryCode),
+		GrossEUR:      utils.RoundFloat(p.grossEUR, 2),
+		WithheldEUR:   utils.RoundFloat(p.withheldEUR, 2),
+		EffectiveRate: p.withheldEUR / p.grossEUR,
+	}
+	rate, known := dividendWithholdingRates[countryCode]
+	if !known {
+		match.Status = models.WithholdingUnknownTreaty
+		return match
+	}
+	treatyRate := rate.TreatyRate
+	expected := utils.RoundFloat(p.grossEUR*treatyRate, 2)
+	match.TreatyRate = &treatyRate
+	match.ExpectedEUR = &expected
+
+	excess := p.withheldEUR - p.grossEUR*treatyRate
+	switch {
+	case p.withheldEUR < 0.005:
+		match.Status = models.WithholdingNone
+	case excess > math.Max(0.01, p.grossEUR*0.005): // Ignore rounding by the broker
+		match.Status = models.WithholdingExcess
+		match.ExcessEUR = utils.RoundFloat(excess, 2)
+	default:
+		match.Status = models.WithholdingOK
+	}
+	return match
+}
+
+// GetReconciliation matches each dividend to its withholding and totals the excess and the
+// reclaims per year and country. A year of 0 covers all years.
+func (s *withholdingServiceImpl) GetReconciliation(userID int64, portfolioID int64, year int) (*models.WithholdingReconciliation, error) {
+	transactions, err := s.uploadService.GetDividendTransactions(userID, portfolioID)
+	if err != nil {
+		return nil, err
+	}
+	reclaims, err := model.GetDividendReclaims(database.DB, userID, portfolioID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &models.WithholdingReconciliation{
+		Dividends:     []models.WithholdingMatch{},
+		ByYearCountry: []models.WithholdingCountryYear{},
+		Reclaims:      []models.DividendReclaim{},
+	}
+	groups := make(map[string]*models.WithholdingCountryYear)
+	group := func(year int, countryCode string) *models.WithholdingCountryYear {
+		key := fmt.Sprintf("%d|%s", year, countryCode)
+		g, ok := groups[key]
+		if !ok {
+			g = &models.WithholdingCountryYear{Year: year, CountryCode: countryCode, Country: utils.GetCountryName(countryCode)}
+			if rate, known := dividendWithholdingRates[countryCode]; known {
+				treatyRate := rate.TreatyRate
+				g.TreatyRate = &treatyRate
+			}
+			groups[key] = g
+		}
+		return g
+	}
+
+	payments, unmatched := matchDividendPayments(transactions)
+	for _, p := range payments {
+		if year != 0 && p.date.Year() != year {
+			continue
+		}
+		match := reconcilePayment(p)
+		result.Dividends = append(result.Dividends, match)
+		g := group(p.date.Year(), match.CountryCode)
+		g.Dividends++
+		g.GrossEUR += p.grossEUR
+		g.WithheldEUR += p.withheldEUR
+		g.ExcessEUR += match.ExcessEUR
+	}
+
+	rejected := make(map[*models.WithholdingCountryYear]bool)
+	for _, reclaim := range reclaims {
+		if year != 0 && reclaim.Year != year {
+			continue
+		}
+		result.Reclaims = append(result.Reclaims, reclaim)
+		g := group(reclaim.Year, reclaim.CountryCode)
+		g.ClaimedEUR += reclaim.ClaimedEUR
+		g.RefundedEUR += reclaim.RefundedEUR
+		g.ReclaimStatus = reclaim.Status // Reclaims are ordered oldest first
+		rejected[g] = reclaim.Status == models.ReclaimRejected
+	}
+
+	for _, g := range groups {
+		if g.GrossEUR > 0 {
+			g.EffectiveRate = g.WithheldEUR / g.GrossEUR
+		}
+		if !rejected[g] {
+			g.OutstandingEUR = utils.RoundFloat(math.Max(g.ExcessEUR-g.RefundedEUR, 0), 2)
+		}
+		g.GrossEUR = utils.RoundFloat(g.GrossEUR, 2)
+		g.WithheldEUR = utils.RoundFloat(g.WithheldEUR, 2)
+		g.ExcessEUR = utils.RoundFloat(g.ExcessEUR, 2)
+		g.ClaimedEUR = utils.RoundFloat(g.ClaimedEUR, 2)
+		g.RefundedEUR = utils.RoundFloat(g.RefundedEUR, 2)
+		result.TotalExcessEUR += g.ExcessEUR
+		result.TotalRefundedEUR += g.RefundedEUR
+		result.ByYearCountry = append(result.ByYearCountry, *g)
+	}
+	sort.Slice(result.ByYearCountry, func(i, j int) bool {
+		a, b := result.ByYearCountry[i], result.ByYearCountry[j]
+		if a.Year != b.Year {
+			return a.Year > b.Year
+		}
+		return a.CountryCode < b.CountryCode
+	})
+	result.TotalExcessEUR = utils.RoundFloat(result.TotalExcessEUR, 2)
+	result.TotalRefundedEUR = utils.RoundFloat(result.TotalRefundedEUR, 2)
+	for unmatchedYear, amount := range unmatched {
+		if year == 0 || unmatchedYear == year {
+			result.UnmatchedTaxEUR += amount
+		}
+	}
+	result.UnmatchedTaxEUR = utils.RoundFloat(result.UnmatchedTaxEUR, 2)
+	return result, nil
+}
+
+func (s *withholdingServiceImpl) ListReclaims(userID int64, portfolioID int64) ([]models.DividendReclaim, error) {
+	return model.GetDividendReclaims(database.DB, userID, portfolioID)
+}
+
+func (s *withholdingServiceImpl) CreateReclaim(userID int64, reclaim models.DividendReclaim) (*models.DividendReclaim, error) {
+	if err := model.CheckPortfolioOwnership(database.DB, userID, reclaim.PortfolioID); err != nil {
+		return nil, err
+	}
+	if err := normalizeReclaim(&reclaim); err != nil {
+		return nil, err
+	}
+	if err := model.InsertDividendReclaim(database.DB, userID, &reclaim); err != nil {
+		return nil, fmt.Errorf("failed to save reclaim: %w", err)
+	}
+	return &reclaim, nil
+}
+
+func (s *withholdingServiceImpl) UpdateReclaim(userID int64, reclaim models.DividendReclaim) (*models.DividendReclaim, error) {
+	if err := normalizeReclaim(&reclaim); err != nil {
+		return nil, err
+	}
+	if err := model.UpdateDividendReclaim(database.DB, userID, &reclaim); err != nil {
+		return nil, err
+	}
+	return &reclaim, nil
+}
+
+func (s *withholdingServiceImpl) DeleteReclaim(userID int64, portfolioID int64, reclaimID int64) error {
+	return model.DeleteDividendReclaim(database.DB, userID, portfolioID, reclaimID)
+}
+
+// normalizeReclaim validates a reclaim and defaults its status to pending.
+func normalizeReclaim(c *models.DividendReclaim) error {
+	c.CountryCode = strings.ToUpper(strings.TrimSpace(c.CountryCode))
+	if len(c.CountryCode) != 2 {
+		return fmt.Errorf("%w: country_code must be an ISO alpha-2 code", ErrInvalidReclaim)
+	}
+	if c.Year < 1900 || c.Year > time.Now().Year() {
+		return fmt.Errorf("%w: invalid year %d", ErrInvalidReclaim, c.Year)
+	}
+	if c.Status == "" {
+		c.Status = models.ReclaimPending
+	}
+	switch c.Status {
+	case models.ReclaimPending, models.ReclaimFiled, models.ReclaimRefunded, models.ReclaimRejected:
+	default:
+		return fmt.Errorf("%w: unknown status %q", ErrInvalidReclaim, c.Status)
+	}
+	for _, amount := range []float64{c.ClaimedEUR, c.RefundedEUR} {
+		if amount < 0 || math.IsNaN(amount) || math.IsInf(amount, 0) {
+			return fmt.Errorf("%w: amounts must be zero or positive", ErrInvalidReclaim)
+		}
+	}
+	for _, date := range []string{c.FiledDate, c.RefundedDate} {
+		if date == "" {
+			continue
+		}
+		if _, err := time.Parse("2006-01-02", date); err != nil {
+			return fmt.Errorf("%w: dates must use YYYY-MM-DD", ErrInvalidReclaim)
+		}
+	}
+	c.Notes = strings.TrimSpace(c.Notes)
+	if len(c.Notes) > maxReclaimNotesLength {
+		return fmt.Errorf("%w: notes must be at most %d characters", ErrInvalidReclaim, maxReclaimNotesLength)
+	}
+	return nil
+}
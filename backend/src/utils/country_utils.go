@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"regexp"
 	"strings"
 	"sync"
 
@@ -22,8 +23,18 @@ var (
 	loadOnce   sync.Once
 	loadError  error
 	dataLoaded bool = false
+
+	isinPattern = regexp.MustCompile(`^[A-Z]{2}[A-Z0-9]{9}[0-9]$`)
 )
 
+// IsISIN reports whether s has the format of an ISIN. It expects an uppercase value, as the
+// ISIN field holds either an ISIN or the ticker of an instrument without one (see
+// models.CanonicalTransaction), and a ticker's first letters must not be mistaken for a
+// country code. Callers holding unnormalised input uppercase it first.
+func IsISIN(s string) bool {
+	return isinPattern.MatchString(s)
+}
+
 // InitCountryData loads country data from the given file path.
 // This should be called once from main.go after config is loaded.
 func InitCountryData(filePath string) error {
@@ -67,8 +78,12 @@ func GetCountryCodeString(isin string) string {
 	if len(isin) < 2 {
 		return "Invalid ISIN (Too Short)"
 	}
+	isin = strings.ToUpper(isin)
+	if !IsISIN(isin) {
+		return "Invalid ISIN"
+	}
 
-	alpha2Code := strings.ToUpper(isin[:2])
+	alpha2Code := isin[:2]
 	countryInfo, found := countryMap[alpha2Code]
 	if !found {
 		return "Unknown Code: " + alpha2Code
@@ -80,3 +95,14 @@ func GetCountryCodeString(isin string) string {
 	}
 	return fmt.Sprintf("%s - %s", numericCode, countryInfo.Country)
 }
+
+// GetCountryName returns the English name for an ISO 3166 alpha-2 code, or "" if unknown.
+func GetCountryName(alpha2 string) string {
+	if !dataLoaded {
+		return ""
+	}
+	if info, found := countryMap[strings.ToUpper(strings.TrimSpace(alpha2))]; found {
+		return info.Country
+	}
+	return ""
+}
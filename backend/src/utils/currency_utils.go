@@ -0,0 +1,17 @@
+package utils
+
+import "strings"
+
+// fiatCurrencies lists the currencies in which brokers and exchanges settle trades. Any other
+// code found in a currency field is taken to be a crypto asset.
+var fiatCurrencies = map[string]bool{
+	"EUR": true, "USD": true, "GBP": true, "CHF": true, "JPY": true, "CAD": true, "AUD": true,
+	"NZD": true, "SEK": true, "NOK": true, "DKK": true, "PLN": true, "CZK": true, "HUF": true,
+	"RON": true, "BGN": true, "TRY": true, "BRL": true, "MXN": true, "ZAR": true, "HKD": true,
+	"SGD": true, "CNY": true, "KRW": true, "INR": true, "ILS": true, "UAH": true, "RUB": true,
+}
+
+// IsFiatCurrency reports whether code is a fiat currency rather than a crypto asset.
+func IsFiatCurrency(code string) bool {
+	return fiatCurrencies[strings.ToUpper(strings.TrimSpace(code))]
+}
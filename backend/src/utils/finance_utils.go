@@ -0,0 +1,166 @@
+package utils
+
+import (
+	"errors"
+	"math"
+	"time"
+)
+
+// DaysPerYear is the day count used to annualise returns (Actual/365).
+const DaysPerYear = 365.0
+
+// TradingDaysPerYear is used to annualise statistics of daily returns.
+const TradingDaysPerYear = 252.0
+
+var ErrXIRRNoSolution = errors.New("xirr: no solution found")
+
+// CashFlow is a dated amount from the investor's point of view:
+// negative when money goes into the portfolio, positive when it comes out.
+type CashFlow struct {
+	Date   time.Time
+	Amount float64
+}
+
+// AnnualizeReturn converts a total return over the given number of days into a yearly rate.
+func AnnualizeReturn(totalReturn float64, days int) float64 {
+	if days <= 0 || totalReturn <= -1 {
+		return totalReturn
+	}
+	return math.Pow(1+totalReturn, DaysPerYear/float64(days)) - 1
+}
+
+// DeannualizeReturn converts a yearly rate into the total return over the given number of days.
+func DeannualizeReturn(annualRate float64, days int) float64 {
+	if annualRate <= -1 {
+		return -1
+	}
+	return math.Pow(1+annualRate, float64(days)/DaysPerYear) - 1
+}
+
+// XIRR returns the annual internal rate of return of irregularly spaced cash flows.
+// It uses Newton-Raphson and falls back to bisection when Newton does not converge.
+func XIRR(flows []CashFlow) (float64, error) {
+	if len(flows) < 2 {
+		return 0, ErrXIRRNoSolution
+	}
+	hasPositive, hasNegative := false, false
+	start := flows[0].Date
+	for _, f := range flows {
+		if f.Amount > 0 {
+			hasPositive = true
+		} else if f.Amount < 0 {
+			hasNegative = true
+		}
+		if f.Date.Before(start) {
+			start = f.Date
+		}
+	}
+	if !hasPositive || !hasNegative {
+		return 0, ErrXIRRNoSolution
+	}
+
+	years := make([]float64, len(flows))
+	for i, f := range flows {
+		years[i] = f.Date.Sub(start).Hours() / 24 / DaysPerYear
+	}
+	npv := func(rate float64) float64 {
+		total := 0.0
+		for i, f := range flows {
+			total += f.Amount / math.Pow(1+rate, years[i])
+		}
+		return total
+	}
+	derivative := func(rate float64) float64 {
+		total := 0.0
+		for i, f := range flows {
+			total -= years[i] * f.Amount / math.Pow(1+rate, years[i]+1)
+		}
+		return total
+	}
+
+	const tolerance = 1e-7
+	rate := 0.1
+	for i := 0; i < 100; i++ {
+		value := npv(rate)
+		if math.Abs(value) < tolerance {
+			return rate, nil
+		}
+		d := derivative(rate)
+		if d == 0 || math.IsNaN(d) || math.IsInf(d, 0) {
+			break
+		}
+		next := rate - value/d
+		if next <= -1 || math.IsNaN(next) || math.IsInf(next, 0) {
+			break
+		}
+		if math.Abs(next-rate) < tolerance {
+			return next, nil
+		}
+		rate = next
+	}
+
+	// Bisection fallback: widen the upper bound until the sign changes.
+	low, high := -0.9999, 1.0
+	for npv(low)*npv(high) > 0 && high < 1e6 {
+		high *= 2
+	}
+	if npv(low)*npv(high) > 0 {
+		return 0, ErrXIRRNoSolution
+	}
+	for i := 0; i < 200; i++ {
+		mid := (low + high) / 2
+		value := npv(mid)
+		if math.Abs(value) < tolerance || (high-low)/2 < tolerance {
+			return mid, nil
+		}
+		if npv(low)*value < 0 {
+			high = mid
+		} else {
+			low = mid
+		}
+	}
+	return (low + high) / 2, nil
+}
+
+// Mean returns the arithmetic mean of the values, or 0 for an empty slice.
+func Mean(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	total := 0.0
+	for _, v := range values {
+		total += v
+	}
+	return total / float64(len(values))
+}
+
+// StdDev returns the sample standard deviation of the values, or 0 with fewer than two values.
+func StdDev(values []float64) float64 {
+	if len(values) < 2 {
+		return 0
+	}
+	return math.Sqrt(Covariance(values, values))
+}
+
+// Covariance returns the sample covariance of two equally long series.
+func Covariance(a, b []float64) float64 {
+	n := len(a)
+	if n < 2 || len(b) != n {
+		return 0
+	}
+	meanA, meanB := Mean(a), Mean(b)
+	total := 0.0
+	for i := range a {
+		total += (a[i] - meanA) * (b[i] - meanB)
+	}
+	return total / float64(n-1)
+}
+
+// CAGR returns the compound annual growth rate from start to end over the given number of
+// years. It is undefined (false) unless both values are positive.
+func CAGR(start, end float64, years int) (float64, bool) {
+	if start <= 0 || end <= 0 || years <= 0 {
+		return 0, false
+	}
+	return math.Pow(end/start, 1/float64(years)) - 1, true
+}